@@ -0,0 +1,182 @@
+package conf
+
+import (
+	"errors"
+	"strings"
+
+	"faizalmaulana/lsp/helper"
+	"faizalmaulana/lsp/models/entity"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// runDataMigrations backfills data for schema changes AutoMigrate cannot
+// express. Every step must be idempotent because it runs on each startup.
+func runDataMigrations(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := migrateMoneyColumns(tx); err != nil {
+			return err
+		}
+		if err := migrateItemTypesToCategories(tx); err != nil {
+			return err
+		}
+		if err := backfillPivotLineIds(tx); err != nil {
+			return err
+		}
+		if err := backfillTaxTotals(tx); err != nil {
+			return err
+		}
+		return backfillCustomers(tx, phoneCountryCode())
+	})
+}
+
+// migrateItemTypesToCategories turns the free-text items.item_type values into
+// category rows. Values differing only in case or surrounding spaces share a
+// category; the first spelling seen becomes its name.
+func migrateItemTypesToCategories(tx *gorm.DB) error {
+	var types []string
+	if err := tx.Model(&entity.Items{}).
+		Where("(id_category IS NULL OR id_category = '') AND TRIM(COALESCE(item_type, '')) <> ''").
+		Distinct().Pluck("item_type", &types).Error; err != nil {
+		return err
+	}
+	if len(types) == 0 {
+		return nil
+	}
+
+	var existing []entity.Categories
+	if err := tx.Where("is_deleted = ?", false).Find(&existing).Error; err != nil {
+		return err
+	}
+	byKey := map[string]string{}
+	for _, c := range existing {
+		byKey[strings.ToLower(strings.TrimSpace(c.Name))] = c.IdCategory
+	}
+
+	for _, t := range types {
+		key := strings.ToLower(strings.TrimSpace(t))
+		id, ok := byKey[key]
+		if !ok {
+			cat := &entity.Categories{IdCategory: helper.Uuid(), Name: strings.TrimSpace(t), IsActive: true}
+			if err := tx.Create(cat).Error; err != nil {
+				return err
+			}
+			id = cat.IdCategory
+			byKey[key] = id
+		}
+		if err := tx.Model(&entity.Items{}).
+			Where("(id_category IS NULL OR id_category = '') AND item_type = ?", t).
+			Update("id_category", id).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// backfillPivotLineIds gives transaction lines created before lines had their
+// own id a random UUID, and treats their stored price as the base price.
+func backfillPivotLineIds(tx *gorm.DB) error {
+	return tx.Exec(`UPDATE pivot_items_to_transactions
+		SET id_pivot = md5(random()::text || clock_timestamp()::text)::uuid::text,
+			base_price = COALESCE(NULLIF(base_price, 0), price)
+		WHERE id_pivot IS NULL OR id_pivot = ''`).Error
+}
+
+// backfillTaxTotals fills the tax breakdown of sales made before taxes were
+// recorded: they were untaxed, so the subtotal and every line total are what
+// was charged.
+func backfillTaxTotals(tx *gorm.DB) error {
+	if err := tx.Exec(`UPDATE transactions SET subtotal = total_price
+		WHERE subtotal = 0 AND tax_total = 0 AND service_charge = 0 AND total_price <> 0`).Error; err != nil {
+		return err
+	}
+	return tx.Exec(`UPDATE pivot_items_to_transactions SET line_total = quantity * price - COALESCE(discount, 0)
+		WHERE line_total = 0 AND tax = 0 AND service_charge = 0 AND quantity * price - COALESCE(discount, 0) <> 0`).Error
+}
+
+// moneyColumns are the amount columns created before amounts were kept in
+// cents: unconstrained numerics filled from floats, and decimal(10,2) item
+// prices.
+var moneyColumns = []struct{ table, column string }{
+	{"items", "price"},
+	{"transactions", "total_price"},
+	{"pivot_items_to_transactions", "base_price"},
+	{"pivot_items_to_transactions", "price"},
+}
+
+// migrateMoneyColumns turns moneyColumns into decimal(12,2), rounding away
+// the float noise (0.30000000000000004 and the like) older rows carry.
+// Columns already converted are left alone.
+func migrateMoneyColumns(tx *gorm.DB) error {
+	for _, c := range moneyColumns {
+		var n int64
+		if err := tx.Raw(`SELECT COUNT(*) FROM information_schema.columns
+			WHERE table_schema = CURRENT_SCHEMA() AND table_name = ? AND column_name = ?
+			AND (numeric_precision IS DISTINCT FROM 12 OR numeric_scale IS DISTINCT FROM 2)`,
+			c.table, c.column).Scan(&n).Error; err != nil {
+			return err
+		}
+		if n == 0 {
+			continue
+		}
+		col := clause.Column{Name: c.column}
+		if err := tx.Exec("ALTER TABLE ? ALTER COLUMN ? TYPE decimal(12,2) USING ROUND(?::numeric, 2)",
+			clause.Table{Name: c.table}, col, col).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// backfillCustomers registers the phone numbers sales were rung up with
+// before the customer directory existed and links those sales to them. The
+// contacts and voucher redemptions are rewritten in E.164 so per-customer
+// voucher limits keep counting earlier uses. Contacts that are not phone
+// numbers stay as free text.
+func backfillCustomers(tx *gorm.DB, countryCode string) error {
+	var contacts []string
+	if err := tx.Model(&entity.Transactions{}).
+		Where("(id_customer IS NULL OR id_customer = '') AND TRIM(COALESCE(buyer_contact, '')) <> ''").
+		Distinct().Pluck("buyer_contact", &contacts).Error; err != nil {
+		return err
+	}
+	for _, contact := range contacts {
+		phone, ok := helper.NormalizePhone(contact, countryCode)
+		if !ok {
+			continue
+		}
+		var c entity.Customers
+		err := tx.Where("phone = ? AND is_deleted = ?", phone, false).First(&c).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c = entity.Customers{IdCustomer: helper.Uuid(), Phone: phone}
+			err = tx.Create(&c).Error
+		}
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&entity.Transactions{}).
+			Where("(id_customer IS NULL OR id_customer = '') AND buyer_contact = ?", contact).
+			Updates(map[string]interface{}{"id_customer": c.IdCustomer, "buyer_contact": phone}).Error; err != nil {
+			return err
+		}
+	}
+
+	var keys []string
+	if err := tx.Model(&entity.VoucherRedemptions{}).
+		Where("customer <> '' AND customer NOT LIKE '+%'").
+		Distinct().Pluck("customer", &keys).Error; err != nil {
+		return err
+	}
+	for _, key := range keys {
+		phone, ok := helper.NormalizePhone(key, countryCode)
+		if !ok {
+			continue
+		}
+		if err := tx.Model(&entity.VoucherRedemptions{}).Where("customer = ?", key).
+			Update("customer", phone).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
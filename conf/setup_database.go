@@ -36,10 +36,56 @@ func SetupDatabaseConnection(dbHost, dbPort, dbUser, dbPass, dbName string) *gor
 		&entity.Transactions{},
 		&entity.PivotItemsToTransaction{},
 		&entity.Images{},
+		&entity.ImageVariants{},
+		&entity.Categories{},
+		&entity.ModifierGroups{},
+		&entity.ModifierOptions{},
+		&entity.PivotLineModifiers{},
+		&entity.BundleComponents{},
+		&entity.PivotLineComponents{},
+		&entity.Payments{},
+		&entity.PaymentIntents{},
+		&entity.GatewayCallbacks{},
+		&entity.Refunds{},
+		&entity.RefundLines{},
+		&entity.Promotions{},
+		&entity.Vouchers{},
+		&entity.VoucherRedemptions{},
+		&entity.PivotLineDiscounts{},
+		&entity.Customers{},
+		&entity.LoyaltyRules{},
+		&entity.LoyaltyTiers{},
+		&entity.LoyaltyLedger{},
+		&entity.GiftCards{},
+		&entity.GiftCardLedger{},
+		&entity.Shifts{},
+		&entity.ShiftMovements{},
+		&entity.ShiftCounts{},
+		&entity.DayCloses{},
+		&entity.HeldOrders{},
+		&entity.HeldOrderLines{},
+		&entity.TableAreas{},
+		&entity.DiningTables{},
+		&entity.KitchenStations{},
+		&entity.KitchenTickets{},
+		&entity.KitchenTicketItems{},
+		&entity.QueueCounters{},
+		&entity.Suppliers{},
+		&entity.PurchaseOrders{},
+		&entity.PurchaseOrderLines{},
+		&entity.GoodsReceipts{},
+		&entity.GoodsReceiptLines{},
+		&entity.ItemStocks{},
+		&entity.StockLedger{},
+		&entity.ItemCosts{},
 	); err != nil {
 		log.Fatalf("auto migrate failed: %v", err)
 	}
 
+	if err := runDataMigrations(db); err != nil {
+		log.Fatalf("data migration failed: %v", err)
+	}
+
 	sqlDB, err := db.DB()
 	if err != nil {
 		log.Fatalf("failed to get sql.DB: %v", err)
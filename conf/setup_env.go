@@ -3,7 +3,11 @@ package conf
 import (
 	"log"
 	"os"
+	"sort"
 	"strconv"
+	"strings"
+
+	"faizalmaulana/lsp/models/entity"
 
 	"github.com/joho/godotenv"
 	"gorm.io/gorm"
@@ -14,6 +18,63 @@ type Config struct {
 	DB        *gorm.DB
 	JWTSecret string
 	JWTTTL    int 
+
+	ImageMaxBytes     int64
+	ImageMaxDimension int
+
+	PaymentGateway       string
+	PaymentGatewaySecret string
+	PaymentIntentTTL     int
+	QRISMerchantName     string
+	QRISMerchantCity     string
+
+	// Manual discounts a cashier or manager may give, as a percentage of the
+	// amount discounted. Admins are not capped.
+	DiscountCapCashier float64
+	DiscountCapManager float64
+
+	// TaxRate is the store's default tax rate in percent, used for items and
+	// categories without their own. With TaxInclusive prices already contain
+	// the tax. TaxRounding is "invoice" (round once per rate) or "line".
+	TaxRate       float64
+	TaxInclusive  bool
+	TaxRounding   string
+	TaxLabel      string
+	ServiceCharge float64
+
+	// Cash payments are rounded to CashRoundingStep, e.g. Rp100 or Rp500,
+	// "nearest", "up" or "down" as CashRoundingMode says. A zero step turns
+	// cash rounding off.
+	CashRoundingStep entity.Money
+	CashRoundingMode string
+
+	// PhoneCountryCode is the calling code, without "+", assumed for local
+	// phone numbers such as 0812..., which are stored in E.164.
+	PhoneCountryCode string
+
+	// A loyalty point is worth LoyaltyPointValue when paid with. Earned
+	// points expire after LoyaltyExpiryDays (0 keeps them), and tiers go by
+	// what a customer spent over the last LoyaltyTierWindowDays.
+	LoyaltyPointValue     entity.Money
+	LoyaltyExpiryDays     int
+	LoyaltyTierWindowDays int
+
+	// With ShiftRequired a cashier must open a shift before ringing up
+	// sales. CashDenominations are the notes and coins the drawer is
+	// counted in at close, largest first.
+	ShiftRequired     bool
+	CashDenominations []entity.Money
+
+	// HeldOrderPricing is "lock" to charge a held order's lines at the
+	// price they were added at, or "recalculate" to charge current prices
+	// at checkout.
+	HeldOrderPricing string
+
+	// QueueDisplayToken opens the public queue display; it is off while
+	// empty. Ready numbers stay on the display until they are collected or
+	// for QueueReadyMinutes.
+	QueueDisplayToken string
+	QueueReadyMinutes int
 }
 
 func NewEnvConfig() *Config {
@@ -40,12 +101,144 @@ func NewEnvConfig() *Config {
 		jwtTTL = 60
 	}
 
+	imageMaxBytes, err := strconv.ParseInt(getEnv("IMAGE_MAX_BYTES", "5242880"), 10, 64)
+	if err != nil || imageMaxBytes <= 0 {
+		imageMaxBytes = 5 << 20
+	}
+	imageMaxDimension, err := strconv.Atoi(getEnv("IMAGE_MAX_DIMENSION", "4096"))
+	if err != nil || imageMaxDimension <= 0 {
+		imageMaxDimension = 4096
+	}
+
+	paymentIntentTTL, err := strconv.Atoi(getEnv("PAYMENT_INTENT_TTL", "900"))
+	if err != nil || paymentIntentTTL <= 0 {
+		paymentIntentTTL = 900
+	}
+
+	discountCapCashier := getEnvPercent("DISCOUNT_CAP_CASHIER", 10)
+	discountCapManager := getEnvPercent("DISCOUNT_CAP_MANAGER", 50)
+
+	taxInclusive, err := strconv.ParseBool(getEnv("TAX_INCLUSIVE", "false"))
+	if err != nil {
+		taxInclusive = false
+	}
+	taxRounding := getEnv("TAX_ROUNDING", "invoice")
+	if taxRounding != "line" {
+		taxRounding = "invoice"
+	}
+
+	cashRoundingStep, err := entity.ParseMoney(getEnv("CASH_ROUNDING_STEP", "0"))
+	if err != nil || cashRoundingStep < 0 {
+		cashRoundingStep = 0
+	}
+	cashRoundingMode := getEnv("CASH_ROUNDING_MODE", "nearest")
+	if cashRoundingMode != "up" && cashRoundingMode != "down" {
+		cashRoundingMode = "nearest"
+	}
+
+	loyaltyPointValue, err := entity.ParseMoney(getEnv("LOYALTY_POINT_VALUE", "1"))
+	if err != nil || loyaltyPointValue <= 0 {
+		loyaltyPointValue = entity.NewMoney(1)
+	}
+	loyaltyExpiryDays, err := strconv.Atoi(getEnv("LOYALTY_POINTS_EXPIRY_DAYS", "365"))
+	if err != nil || loyaltyExpiryDays < 0 {
+		loyaltyExpiryDays = 365
+	}
+	loyaltyTierWindowDays, err := strconv.Atoi(getEnv("LOYALTY_TIER_WINDOW_DAYS", "365"))
+	if err != nil || loyaltyTierWindowDays <= 0 {
+		loyaltyTierWindowDays = 365
+	}
+
+	shiftRequired, err := strconv.ParseBool(getEnv("SHIFT_REQUIRED", "true"))
+	if err != nil {
+		shiftRequired = true
+	}
+
+	heldOrderPricing := getEnv("HELD_ORDER_PRICING", "recalculate")
+	if heldOrderPricing != "lock" {
+		heldOrderPricing = "recalculate"
+	}
+
+	queueReadyMinutes, err := strconv.Atoi(getEnv("QUEUE_READY_MINUTES", "15"))
+	if err != nil || queueReadyMinutes <= 0 {
+		queueReadyMinutes = 15
+	}
+
 	return &Config{
 		Port:      getEnv("APP_PORT", "8000"),
 		DB:        db,
 		JWTSecret: jwtSecret,
 		JWTTTL:    jwtTTL,
+
+		ImageMaxBytes:     imageMaxBytes,
+		ImageMaxDimension: imageMaxDimension,
+
+		PaymentGateway:       getEnv("PAYMENT_GATEWAY", "simulator"),
+		PaymentGatewaySecret: getEnv("PAYMENT_GATEWAY_SECRET", ""),
+		PaymentIntentTTL:     paymentIntentTTL,
+		QRISMerchantName:     getEnv("QRIS_MERCHANT_NAME", "LSP CASHIER"),
+		QRISMerchantCity:     getEnv("QRIS_MERCHANT_CITY", "JAKARTA"),
+
+		DiscountCapCashier: discountCapCashier,
+		DiscountCapManager: discountCapManager,
+
+		TaxRate:       getEnvPercent("TAX_RATE", 0),
+		TaxInclusive:  taxInclusive,
+		TaxRounding:   taxRounding,
+		TaxLabel:      getEnv("TAX_LABEL", "PPN"),
+		ServiceCharge: getEnvPercent("SERVICE_CHARGE", 0),
+
+		CashRoundingStep: cashRoundingStep,
+		CashRoundingMode: cashRoundingMode,
+
+		PhoneCountryCode: phoneCountryCode(),
+
+		LoyaltyPointValue:     loyaltyPointValue,
+		LoyaltyExpiryDays:     loyaltyExpiryDays,
+		LoyaltyTierWindowDays: loyaltyTierWindowDays,
+
+		ShiftRequired:     shiftRequired,
+		CashDenominations: cashDenominations(),
+
+		HeldOrderPricing: heldOrderPricing,
+
+		QueueDisplayToken: getEnv("QUEUE_DISPLAY_TOKEN", ""),
+		QueueReadyMinutes: queueReadyMinutes,
+	}
+}
+
+// getEnvPercent reads a percentage between 0 and 100, falling back when the
+// value is missing or out of range.
+func getEnvPercent(key string, fallback float64) float64 {
+	v, err := strconv.ParseFloat(getEnv(key, ""), 64)
+	if err != nil || v < 0 || v > 100 {
+		return fallback
+	}
+	return v
+}
+
+// phoneCountryCode reads PHONE_COUNTRY_CODE, defaulting to Indonesia.
+func phoneCountryCode() string {
+	code := strings.TrimPrefix(strings.TrimSpace(getEnv("PHONE_COUNTRY_CODE", "62")), "+")
+	if code == "" {
+		return "62"
+	}
+	return code
+}
+
+// cashDenominations reads CASH_DENOMINATIONS, a comma separated list that
+// defaults to the rupiah notes and coins in circulation.
+func cashDenominations() []entity.Money {
+	var out []entity.Money
+	for _, part := range strings.Split(getEnv("CASH_DENOMINATIONS", "100000,50000,20000,10000,5000,2000,1000,500,200,100"), ",") {
+		d, err := entity.ParseMoney(strings.TrimSpace(part))
+		if err != nil || d <= 0 {
+			continue
+		}
+		out = append(out, d)
 	}
+	sort.Slice(out, func(i, j int) bool { return out[i] > out[j] })
+	return out
 }
 
 func getEnv(key, fallback string) string {
@@ -42,6 +42,47 @@ func ProvidePivotItemsToTransactionsRepo(db *gorm.DB) repo.PivotItemsToTransacti
 	return repo.NewGormPivotItemsToTransactionsRepo(db)
 }
 func ProvideImagesRepo(db *gorm.DB) repo.ImagesRepo { return repo.NewGormImagesRepo(db) }
+func ProvideCategoriesRepo(db *gorm.DB) repo.CategoriesRepo {
+	return repo.NewGormCategoriesRepo(db)
+}
+func ProvideModifiersRepo(db *gorm.DB) repo.ModifiersRepo { return repo.NewGormModifiersRepo(db) }
+func ProvidePivotLineModifiersRepo(db *gorm.DB) repo.PivotLineModifiersRepo {
+	return repo.NewGormPivotLineModifiersRepo(db)
+}
+func ProvideBundlesRepo(db *gorm.DB) repo.BundlesRepo { return repo.NewGormBundlesRepo(db) }
+func ProvidePivotLineComponentsRepo(db *gorm.DB) repo.PivotLineComponentsRepo {
+	return repo.NewGormPivotLineComponentsRepo(db)
+}
+func ProvidePaymentsRepo(db *gorm.DB) repo.PaymentsRepo { return repo.NewGormPaymentsRepo(db) }
+func ProvidePaymentIntentsRepo(db *gorm.DB) repo.PaymentIntentsRepo {
+	return repo.NewGormPaymentIntentsRepo(db)
+}
+func ProvideGatewayCallbacksRepo(db *gorm.DB) repo.GatewayCallbacksRepo {
+	return repo.NewGormGatewayCallbacksRepo(db)
+}
+func ProvideRefundsRepo(db *gorm.DB) repo.RefundsRepo { return repo.NewGormRefundsRepo(db) }
+func ProvidePromotionsRepo(db *gorm.DB) repo.PromotionsRepo {
+	return repo.NewGormPromotionsRepo(db)
+}
+func ProvidePivotLineDiscountsRepo(db *gorm.DB) repo.PivotLineDiscountsRepo {
+	return repo.NewGormPivotLineDiscountsRepo(db)
+}
+func ProvideVouchersRepo(db *gorm.DB) repo.VouchersRepo     { return repo.NewGormVouchersRepo(db) }
+func ProvideUnitOfWork(db *gorm.DB) repo.UnitOfWork         { return repo.NewGormUnitOfWork(db) }
+func ProvideCustomersRepo(db *gorm.DB) repo.CustomersRepo   { return repo.NewGormCustomersRepo(db) }
+func ProvideLoyaltyRepo(db *gorm.DB) repo.LoyaltyRepo       { return repo.NewGormLoyaltyRepo(db) }
+func ProvideGiftCardsRepo(db *gorm.DB) repo.GiftCardsRepo   { return repo.NewGormGiftCardsRepo(db) }
+func ProvideShiftsRepo(db *gorm.DB) repo.ShiftsRepo         { return repo.NewGormShiftsRepo(db) }
+func ProvideDayClosesRepo(db *gorm.DB) repo.DayClosesRepo   { return repo.NewGormDayClosesRepo(db) }
+func ProvideHeldOrdersRepo(db *gorm.DB) repo.HeldOrdersRepo { return repo.NewGormHeldOrdersRepo(db) }
+func ProvideTablesRepo(db *gorm.DB) repo.TablesRepo         { return repo.NewGormTablesRepo(db) }
+func ProvideKitchenRepo(db *gorm.DB) repo.KitchenRepo       { return repo.NewGormKitchenRepo(db) }
+func ProvideSuppliersRepo(db *gorm.DB) repo.SuppliersRepo   { return repo.NewGormSuppliersRepo(db) }
+func ProvidePurchaseOrdersRepo(db *gorm.DB) repo.PurchaseOrdersRepo {
+	return repo.NewGormPurchaseOrdersRepo(db)
+}
+func ProvideInventoryRepo(db *gorm.DB) repo.InventoryRepo { return repo.NewGormInventoryRepo(db) }
+func ProvideQueueRepo(db *gorm.DB) repo.QueueRepo         { return repo.NewGormQueueRepo(db) }
 
 // Services
 func ProvideAuthenticationService(r repo.UsersRepo) services.AuthenticationService {
@@ -59,11 +100,91 @@ func ProvideProfilesService(r repo.ProfilesRepo) services.ProfilesService {
 func ProvideItemsService(r repo.ItemsRepo) services.ItemsService {
 	return services.NewItemsService(r)
 }
-func ProvideTransactionsService(r repo.TransactionsRepo) services.TransactionsService {
-	return services.NewTransactionsService(r)
+func ProvideTransactionsService(r repo.TransactionsRepo, uow repo.UnitOfWork, items repo.ItemsRepo, pivot repo.PivotItemsToTransactionsRepo, lineMods repo.PivotLineModifiersRepo, lineComps repo.PivotLineComponentsRepo, lineDisc repo.PivotLineDiscountsRepo, payments repo.PaymentsRepo, intents repo.PaymentIntentsRepo, modifiers services.ModifiersService, bundles services.BundlesService, promos services.PromotionsService, vouchers repo.VouchersRepo, taxes services.TaxesService, gateway services.PaymentIntentsService, customers repo.CustomersRepo, loyalty repo.LoyaltyRepo, giftCards repo.GiftCardsRepo, shifts repo.ShiftsRepo, closes repo.DayClosesRepo, refunds repo.RefundsRepo, inventory repo.InventoryRepo, held repo.HeldOrdersRepo, queue repo.QueueRepo, kitchen services.KitchenService, events services.EventBus, cfg *conf.Config) services.TransactionsService {
+	return services.NewTransactionsService(r, uow, items, pivot, lineMods, lineComps, lineDisc, payments, intents, modifiers, bundles, promos, vouchers, taxes, gateway, customers, loyalty, giftCards, shifts, closes, refunds, inventory, held, queue, kitchen, events, cfg)
+}
+func ProvideTaxesService(categories repo.CategoriesRepo, cfg *conf.Config) services.TaxesService {
+	return services.NewTaxesService(categories, cfg)
+}
+func ProvidePromotionsService(r repo.PromotionsRepo, items repo.ItemsRepo, categories services.CategoriesService, cfg *conf.Config) services.PromotionsService {
+	return services.NewPromotionsService(r, items, categories, cfg)
+}
+func ProvideVouchersService(r repo.VouchersRepo, promotions repo.PromotionsRepo, cfg *conf.Config) services.VouchersService {
+	return services.NewVouchersService(r, promotions, cfg)
+}
+func ProvideCustomersService(r repo.CustomersRepo, tx repo.TransactionsRepo, refunds repo.RefundsRepo, cfg *conf.Config) services.CustomersService {
+	return services.NewCustomersService(r, tx, refunds, cfg)
+}
+
+func ProvideLoyaltyService(r repo.LoyaltyRepo, customers repo.CustomersRepo, items repo.ItemsRepo, cfg *conf.Config) services.LoyaltyService {
+	return services.NewLoyaltyService(r, customers, items, cfg)
+}
+
+func ProvideGiftCardsService(r repo.GiftCardsRepo, uow repo.UnitOfWork, customers repo.CustomersRepo) services.GiftCardsService {
+	return services.NewGiftCardsService(r, uow, customers)
+}
+
+func ProvideShiftsService(r repo.ShiftsRepo, uow repo.UnitOfWork, tx repo.TransactionsRepo, payments repo.PaymentsRepo, refunds repo.RefundsRepo, closes repo.DayClosesRepo, cfg *conf.Config) services.ShiftsService {
+	return services.NewShiftsService(r, uow, tx, payments, refunds, closes, cfg)
+}
+
+func ProvideDayClosesService(r repo.DayClosesRepo, uow repo.UnitOfWork, reports services.ReportsService, tx repo.TransactionsRepo, shifts repo.ShiftsRepo) services.DayClosesService {
+	return services.NewDayClosesService(r, uow, reports, tx, shifts)
+}
+
+func ProvideHeldOrdersService(r repo.HeldOrdersRepo, uow repo.UnitOfWork, items repo.ItemsRepo, modifiers services.ModifiersService, tx services.TransactionsService, tables repo.TablesRepo, cfg *conf.Config) services.HeldOrdersService {
+	return services.NewHeldOrdersService(r, uow, items, modifiers, tx, tables, cfg)
+}
+
+func ProvideTablesService(r repo.TablesRepo, held repo.HeldOrdersRepo) services.TablesService {
+	return services.NewTablesService(r, held)
+}
+func ProvideKitchenService(r repo.KitchenRepo, uow repo.UnitOfWork, tx repo.TransactionsRepo, pivot repo.PivotItemsToTransactionsRepo, lineMods repo.PivotLineModifiersRepo, lineComps repo.PivotLineComponentsRepo, items repo.ItemsRepo, categories repo.CategoriesRepo, held repo.HeldOrdersRepo) services.KitchenService {
+	return services.NewKitchenService(r, uow, tx, pivot, lineMods, lineComps, items, categories, held)
+}
+
+func ProvideQueueService(kitchen repo.KitchenRepo, cfg *conf.Config) services.QueueService {
+	return services.NewQueueService(kitchen, cfg)
+}
+
+func ProvideSuppliersService(r repo.SuppliersRepo) services.SuppliersService {
+	return services.NewSuppliersService(r)
+}
+
+func ProvidePurchaseOrdersService(r repo.PurchaseOrdersRepo, uow repo.UnitOfWork, suppliers repo.SuppliersRepo, items repo.ItemsRepo, inventory repo.InventoryRepo) services.PurchaseOrdersService {
+	return services.NewPurchaseOrdersService(r, uow, suppliers, items, inventory)
+}
+
+func ProvideInventoryService(r repo.InventoryRepo, items repo.ItemsRepo) services.InventoryService {
+	return services.NewInventoryService(r, items)
+}
+func ProvidePaymentGateway(cfg *conf.Config) services.PaymentGateway {
+	return services.NewPaymentGateway(cfg)
+}
+func ProvidePaymentIntentsService(gateway services.PaymentGateway, uow repo.UnitOfWork, intents repo.PaymentIntentsRepo, callbacks repo.GatewayCallbacksRepo, tx repo.TransactionsRepo, payments repo.PaymentsRepo, vouchers repo.VouchersRepo, loyalty repo.LoyaltyRepo, giftCards repo.GiftCardsRepo, closes repo.DayClosesRepo, inventory repo.InventoryRepo, kitchen services.KitchenService, events services.EventBus, cfg *conf.Config) services.PaymentIntentsService {
+	return services.NewPaymentIntentsService(gateway, uow, intents, callbacks, tx, payments, vouchers, loyalty, giftCards, closes, inventory, kitchen, events, cfg)
+}
+func ProvideImagesService(r repo.ImagesRepo, cfg *conf.Config) services.ImagesService {
+	return services.NewImagesService(r, cfg)
+}
+func ProvideCategoriesService(r repo.CategoriesRepo) services.CategoriesService {
+	return services.NewCategoriesService(r)
 }
-func ProvideImagesService(r repo.ImagesRepo) services.ImagesService {
-	return services.NewImagesService(r)
+func ProvideModifiersService(r repo.ModifiersRepo) services.ModifiersService {
+	return services.NewModifiersService(r)
+}
+func ProvideBundlesService(r repo.BundlesRepo, items repo.ItemsRepo) services.BundlesService {
+	return services.NewBundlesService(r, items)
+}
+func ProvideReportsService(tx repo.TransactionsRepo, pivot repo.PivotItemsToTransactionsRepo, lineMods repo.PivotLineModifiersRepo, lineComps repo.PivotLineComponentsRepo, lineDisc repo.PivotLineDiscountsRepo, payments repo.PaymentsRepo, refunds repo.RefundsRepo, items repo.ItemsRepo) services.ReportsService {
+	return services.NewReportsService(tx, pivot, lineMods, lineComps, lineDisc, payments, refunds, items)
+}
+func ProvideEventBus() services.EventBus { return services.NewEventBus() }
+func ProvideDashboardService(reports services.ReportsService, bus services.EventBus) services.DashboardService {
+	return services.NewDashboardService(reports, bus)
+}
+func ProvideRefundsService(r repo.RefundsRepo, uow repo.UnitOfWork, tx repo.TransactionsRepo, pivot repo.PivotItemsToTransactionsRepo, items repo.ItemsRepo, loyalty repo.LoyaltyRepo, giftCards repo.GiftCardsRepo, shifts repo.ShiftsRepo, closes repo.DayClosesRepo, inventory repo.InventoryRepo, events services.EventBus, cfg *conf.Config) services.RefundsService {
+	return services.NewRefundsService(r, uow, tx, pivot, items, loyalty, giftCards, shifts, closes, inventory, events, cfg)
 }
 
 // Handlers
@@ -75,23 +196,94 @@ func ProvideUsersHandler(cfg *conf.Config, profile services.ProfilesService, use
 	return handler.NewUsersHandler(cfg, profile, users)
 }
 
-func ProvideItemsHandler(cfg *conf.Config, items services.ItemsService, images services.ImagesService) *handler.ItemsHandler {
-	return handler.NewItemsHandler(cfg, items, images)
+func ProvideItemsHandler(cfg *conf.Config, items services.ItemsService, images services.ImagesService, categories services.CategoriesService) *handler.ItemsHandler {
+	return handler.NewItemsHandler(cfg, items, images, categories)
+}
+
+func ProvideCategoriesHandler(cfg *conf.Config, categories services.CategoriesService, items services.ItemsService) *handler.CategoriesHandler {
+	return handler.NewCategoriesHandler(cfg, categories, items)
+}
+
+func ProvideReportHandler(cfg *conf.Config, reports services.ReportsService, dashboard services.DashboardService) *handler.ReportHandler {
+	return handler.NewReportHandler(cfg, reports, dashboard)
+}
+
+func ProvideTransactionsHandler(cfg *conf.Config, tx services.TransactionsService, pivot repo.PivotItemsToTransactionsRepo) *handler.TransactionsHandler {
+	return handler.NewTransactionsHandler(cfg, tx, pivot)
 }
 
-func ProvideReportHandler(cfg *conf.Config, tx services.TransactionsService, pivot repo.PivotItemsToTransactionsRepo, items repo.ItemsRepo) *handler.ReportHandler {
-	return handler.NewReportHandler(cfg, tx, pivot, items)
+func ProvideModifiersHandler(cfg *conf.Config, modifiers services.ModifiersService, items services.ItemsService) *handler.ModifiersHandler {
+	return handler.NewModifiersHandler(cfg, modifiers, items)
 }
 
-func ProvideTransactionsHandler(cfg *conf.Config, tx services.TransactionsService, items repo.ItemsRepo, pivot repo.PivotItemsToTransactionsRepo) *handler.TransactionsHandler {
-	return handler.NewTransactionsHandler(cfg, tx, items, pivot)
+func ProvideBundlesHandler(cfg *conf.Config, bundles services.BundlesService, items services.ItemsService) *handler.BundlesHandler {
+	return handler.NewBundlesHandler(cfg, bundles, items)
+}
+
+func ProvidePaymentsHandler(cfg *conf.Config, intents services.PaymentIntentsService) *handler.PaymentsHandler {
+	return handler.NewPaymentsHandler(cfg, intents)
+}
+
+func ProvideRefundsHandler(cfg *conf.Config, refunds services.RefundsService) *handler.RefundsHandler {
+	return handler.NewRefundsHandler(cfg, refunds)
+}
+
+func ProvidePromotionsHandler(cfg *conf.Config, promotions services.PromotionsService) *handler.PromotionsHandler {
+	return handler.NewPromotionsHandler(cfg, promotions)
+}
+
+func ProvideVouchersHandler(cfg *conf.Config, vouchers services.VouchersService, promotions services.PromotionsService, tx services.TransactionsService) *handler.VouchersHandler {
+	return handler.NewVouchersHandler(cfg, vouchers, promotions, tx)
+}
+
+func ProvideCustomersHandler(cfg *conf.Config, customers services.CustomersService) *handler.CustomersHandler {
+	return handler.NewCustomersHandler(cfg, customers)
+}
+
+func ProvideLoyaltyHandler(cfg *conf.Config, loyalty services.LoyaltyService) *handler.LoyaltyHandler {
+	return handler.NewLoyaltyHandler(cfg, loyalty)
+}
+
+func ProvideGiftCardsHandler(cfg *conf.Config, giftCards services.GiftCardsService) *handler.GiftCardsHandler {
+	return handler.NewGiftCardsHandler(cfg, giftCards)
+}
+
+func ProvideShiftsHandler(cfg *conf.Config, shifts services.ShiftsService) *handler.ShiftsHandler {
+	return handler.NewShiftsHandler(cfg, shifts)
+}
+
+func ProvideDayClosesHandler(cfg *conf.Config, closes services.DayClosesService) *handler.DayClosesHandler {
+	return handler.NewDayClosesHandler(cfg, closes)
+}
+
+func ProvideHeldOrdersHandler(cfg *conf.Config, held services.HeldOrdersService) *handler.HeldOrdersHandler {
+	return handler.NewHeldOrdersHandler(cfg, held)
+}
+
+func ProvideTablesHandler(cfg *conf.Config, tables services.TablesService, held services.HeldOrdersService) *handler.TablesHandler {
+	return handler.NewTablesHandler(cfg, tables, held)
+}
+func ProvideKitchenHandler(cfg *conf.Config, kitchen services.KitchenService) *handler.KitchenHandler {
+	return handler.NewKitchenHandler(cfg, kitchen)
+}
+func ProvideQueueHandler(cfg *conf.Config, queue services.QueueService) *handler.QueueHandler {
+	return handler.NewQueueHandler(cfg, queue)
+}
+func ProvideSuppliersHandler(cfg *conf.Config, suppliers services.SuppliersService) *handler.SuppliersHandler {
+	return handler.NewSuppliersHandler(cfg, suppliers)
+}
+func ProvidePurchaseOrdersHandler(cfg *conf.Config, orders services.PurchaseOrdersService) *handler.PurchaseOrdersHandler {
+	return handler.NewPurchaseOrdersHandler(cfg, orders)
+}
+func ProvideInventoryHandler(cfg *conf.Config, inventory services.InventoryService) *handler.InventoryHandler {
+	return handler.NewInventoryHandler(cfg, inventory)
 }
 
 func ProvideImagesHandler(cfg *conf.Config, svc services.ImagesService) *handler.ImagesHandler {
 	return handler.NewImagesHandler(cfg, svc)
 }
 
-func ProvideRouterWithRoutes(ah *handler.AuthenticationHandler, uh *handler.UsersHandler, ih *handler.ItemsHandler, th *handler.TransactionsHandler, rh *handler.ReportHandler, imh *handler.ImagesHandler) *gin.Engine {
+func ProvideRouterWithRoutes(ah *handler.AuthenticationHandler, uh *handler.UsersHandler, ih *handler.ItemsHandler, th *handler.TransactionsHandler, rh *handler.ReportHandler, imh *handler.ImagesHandler, ch *handler.CategoriesHandler, mh *handler.ModifiersHandler, bh *handler.BundlesHandler, ph *handler.PaymentsHandler, rfh *handler.RefundsHandler, prh *handler.PromotionsHandler, vh *handler.VouchersHandler, cuh *handler.CustomersHandler, lh *handler.LoyaltyHandler, gh *handler.GiftCardsHandler, sh *handler.ShiftsHandler, dch *handler.DayClosesHandler, hoh *handler.HeldOrdersHandler, tbh *handler.TablesHandler, kh *handler.KitchenHandler, qh *handler.QueueHandler, suh *handler.SuppliersHandler, poh *handler.PurchaseOrdersHandler, inh *handler.InventoryHandler) *gin.Engine {
 	r := ProvideRouter()
 	api := r.Group("/api")
 	ah.Register(api)
@@ -100,6 +292,25 @@ func ProvideRouterWithRoutes(ah *handler.AuthenticationHandler, uh *handler.User
 	th.Register(api)
 	rh.Register(api)
 	imh.Register(api)
+	ch.Register(api)
+	mh.Register(api)
+	bh.Register(api)
+	ph.Register(api)
+	rfh.Register(api)
+	prh.Register(api)
+	vh.Register(api)
+	cuh.Register(api)
+	lh.Register(api)
+	gh.Register(api)
+	sh.Register(api)
+	dch.Register(api)
+	hoh.Register(api)
+	tbh.Register(api)
+	kh.Register(api)
+	qh.Register(api)
+	suh.Register(api)
+	poh.Register(api)
+	inh.Register(api)
 
 	for _, rt := range r.Routes() {
 		log.Printf("route: %s %s", rt.Method, rt.Path)
@@ -109,9 +320,9 @@ func ProvideRouterWithRoutes(ah *handler.AuthenticationHandler, uh *handler.User
 
 var (
 	ConfigSet  = wire.NewSet(ProvideEnvConfig, ProvideDB)
-	RepoSet    = wire.NewSet(ProvideUsersRepo, ProvideProfilesRepo, ProvideSessionsRepo, ProvideItemsRepo, ProvideTransactionsRepo, ProvidePivotItemsToTransactionsRepo, ProvideImagesRepo)
-	ServiceSet = wire.NewSet(ProvideAuthenticationService, ProvideSessionService, ProvideUsersService, ProvideProfilesService, ProvideItemsService, ProvideTransactionsService, ProvideImagesService)
-	HandlerSet = wire.NewSet(ProvideAuthenticationHandler, ProvideUsersHandler, ProvideItemsHandler, ProvideTransactionsHandler, ProvideReportHandler, ProvideImagesHandler)
+	RepoSet    = wire.NewSet(ProvideUsersRepo, ProvideProfilesRepo, ProvideSessionsRepo, ProvideItemsRepo, ProvideTransactionsRepo, ProvidePivotItemsToTransactionsRepo, ProvideImagesRepo, ProvideCategoriesRepo, ProvideModifiersRepo, ProvidePivotLineModifiersRepo, ProvideBundlesRepo, ProvidePivotLineComponentsRepo, ProvidePaymentsRepo, ProvidePaymentIntentsRepo, ProvideGatewayCallbacksRepo, ProvideRefundsRepo, ProvidePromotionsRepo, ProvidePivotLineDiscountsRepo, ProvideVouchersRepo, ProvideUnitOfWork, ProvideCustomersRepo, ProvideLoyaltyRepo, ProvideGiftCardsRepo, ProvideShiftsRepo, ProvideDayClosesRepo, ProvideHeldOrdersRepo, ProvideTablesRepo, ProvideKitchenRepo, ProvideQueueRepo, ProvideSuppliersRepo, ProvidePurchaseOrdersRepo, ProvideInventoryRepo)
+	ServiceSet = wire.NewSet(ProvideAuthenticationService, ProvideSessionService, ProvideUsersService, ProvideProfilesService, ProvideItemsService, ProvideTransactionsService, ProvideImagesService, ProvideCategoriesService, ProvideModifiersService, ProvideBundlesService, ProvideReportsService, ProvidePaymentGateway, ProvidePaymentIntentsService, ProvideRefundsService, ProvidePromotionsService, ProvideVouchersService, ProvideTaxesService, ProvideCustomersService, ProvideLoyaltyService, ProvideGiftCardsService, ProvideShiftsService, ProvideDayClosesService, ProvideHeldOrdersService, ProvideTablesService, ProvideKitchenService, ProvideEventBus, ProvideDashboardService, ProvideQueueService, ProvideSuppliersService, ProvidePurchaseOrdersService, ProvideInventoryService)
+	HandlerSet = wire.NewSet(ProvideAuthenticationHandler, ProvideUsersHandler, ProvideItemsHandler, ProvideTransactionsHandler, ProvideReportHandler, ProvideImagesHandler, ProvideCategoriesHandler, ProvideModifiersHandler, ProvideBundlesHandler, ProvidePaymentsHandler, ProvideRefundsHandler, ProvidePromotionsHandler, ProvideVouchersHandler, ProvideCustomersHandler, ProvideLoyaltyHandler, ProvideGiftCardsHandler, ProvideShiftsHandler, ProvideDayClosesHandler, ProvideHeldOrdersHandler, ProvideTablesHandler, ProvideKitchenHandler, ProvideQueueHandler, ProvideSuppliersHandler, ProvidePurchaseOrdersHandler, ProvideInventoryHandler)
 	RouterSet  = wire.NewSet(ProvideRouterWithRoutes)
 	ServerSet  = wire.NewSet(ProvideHTTPServer)
 )
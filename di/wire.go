@@ -6,6 +6,7 @@ package di
 import (
 	"net/http"
 
+	"faizalmaulana/lsp/http/services"
 	"faizalmaulana/lsp/models/repo"
 
 	"github.com/gin-gonic/gin"
@@ -13,8 +14,10 @@ import (
 )
 
 type App struct {
-	Server *http.Server
-	Router *gin.Engine
+	Server         *http.Server
+	Router         *gin.Engine
+	PaymentIntents services.PaymentIntentsService
+	Dashboard      services.DashboardService
 }
 
 type Repos struct {
@@ -30,7 +33,7 @@ func InitializeApp() *App {
 		HandlerSet,
 		RouterSet,
 		ServerSet,
-		wire.Struct(new(App), "Server", "Router"),
+		wire.Struct(new(App), "Server", "Router", "PaymentIntents", "Dashboard"),
 	))
 }
 
@@ -7,6 +7,7 @@
 package di
 
 import (
+	"faizalmaulana/lsp/http/services"
 	"faizalmaulana/lsp/models/repo"
 	"github.com/gin-gonic/gin"
 	"net/http"
@@ -29,19 +30,89 @@ func InitializeApp() *App {
 	itemsRepo := ProvideItemsRepo(db)
 	itemsService := ProvideItemsService(itemsRepo)
 	transactionsRepo := ProvideTransactionsRepo(db)
-	transactionsService := ProvideTransactionsService(transactionsRepo)
+	unitOfWork := ProvideUnitOfWork(db)
 	pivotItemsToTransactionsRepo := ProvidePivotItemsToTransactionsRepo(db)
-	transactionsHandler := ProvideTransactionsHandler(config, transactionsService, itemsRepo, pivotItemsToTransactionsRepo)
-	reportHandler := ProvideReportHandler(config, transactionsService, pivotItemsToTransactionsRepo, itemsRepo)
+	pivotLineModifiersRepo := ProvidePivotLineModifiersRepo(db)
+	modifiersRepo := ProvideModifiersRepo(db)
+	modifiersService := ProvideModifiersService(modifiersRepo)
+	pivotLineComponentsRepo := ProvidePivotLineComponentsRepo(db)
+	bundlesRepo := ProvideBundlesRepo(db)
+	bundlesService := ProvideBundlesService(bundlesRepo, itemsRepo)
+	paymentsRepo := ProvidePaymentsRepo(db)
+	paymentIntentsRepo := ProvidePaymentIntentsRepo(db)
+	paymentGateway := ProvidePaymentGateway(config)
+	gatewayCallbacksRepo := ProvideGatewayCallbacksRepo(db)
+	vouchersRepo := ProvideVouchersRepo(db)
+	loyaltyRepo := ProvideLoyaltyRepo(db)
+	giftCardsRepo := ProvideGiftCardsRepo(db)
+	shiftsRepo := ProvideShiftsRepo(db)
+	dayClosesRepo := ProvideDayClosesRepo(db)
+	heldOrdersRepo := ProvideHeldOrdersRepo(db)
+	tablesRepo := ProvideTablesRepo(db)
+	kitchenRepo := ProvideKitchenRepo(db)
+	categoriesRepo := ProvideCategoriesRepo(db)
+	kitchenService := ProvideKitchenService(kitchenRepo, unitOfWork, transactionsRepo, pivotItemsToTransactionsRepo, pivotLineModifiersRepo, pivotLineComponentsRepo, itemsRepo, categoriesRepo, heldOrdersRepo)
+	eventBus := ProvideEventBus()
+	inventoryRepo := ProvideInventoryRepo(db)
+	paymentIntentsService := ProvidePaymentIntentsService(paymentGateway, unitOfWork, paymentIntentsRepo, gatewayCallbacksRepo, transactionsRepo, paymentsRepo, vouchersRepo, loyaltyRepo, giftCardsRepo, dayClosesRepo, inventoryRepo, kitchenService, eventBus, config)
+	pivotLineDiscountsRepo := ProvidePivotLineDiscountsRepo(db)
+	promotionsRepo := ProvidePromotionsRepo(db)
+	categoriesService := ProvideCategoriesService(categoriesRepo)
+	promotionsService := ProvidePromotionsService(promotionsRepo, itemsRepo, categoriesService, config)
+	taxesService := ProvideTaxesService(categoriesRepo, config)
+	customersRepo := ProvideCustomersRepo(db)
+	queueRepo := ProvideQueueRepo(db)
+	refundsRepo := ProvideRefundsRepo(db)
+	transactionsService := ProvideTransactionsService(transactionsRepo, unitOfWork, itemsRepo, pivotItemsToTransactionsRepo, pivotLineModifiersRepo, pivotLineComponentsRepo, pivotLineDiscountsRepo, paymentsRepo, paymentIntentsRepo, modifiersService, bundlesService, promotionsService, vouchersRepo, taxesService, paymentIntentsService, customersRepo, loyaltyRepo, giftCardsRepo, shiftsRepo, dayClosesRepo, refundsRepo, inventoryRepo, heldOrdersRepo, queueRepo, kitchenService, eventBus, config)
+	transactionsHandler := ProvideTransactionsHandler(config, transactionsService, pivotItemsToTransactionsRepo)
+	reportsService := ProvideReportsService(transactionsRepo, pivotItemsToTransactionsRepo, pivotLineModifiersRepo, pivotLineComponentsRepo, pivotLineDiscountsRepo, paymentsRepo, refundsRepo, itemsRepo)
+	dashboardService := ProvideDashboardService(reportsService, eventBus)
+	reportHandler := ProvideReportHandler(config, reportsService, dashboardService)
 	imagesRepo := ProvideImagesRepo(db)
-	imagesService := ProvideImagesService(imagesRepo)
+	imagesService := ProvideImagesService(imagesRepo, config)
 	imagesHandler := ProvideImagesHandler(config, imagesService)
-	itemsHandler := ProvideItemsHandler(config, itemsService, imagesService)
-	engine := ProvideRouterWithRoutes(authenticationHandler, usersHandler, itemsHandler, transactionsHandler, reportHandler, imagesHandler)
+	itemsHandler := ProvideItemsHandler(config, itemsService, imagesService, categoriesService)
+	categoriesHandler := ProvideCategoriesHandler(config, categoriesService, itemsService)
+	modifiersHandler := ProvideModifiersHandler(config, modifiersService, itemsService)
+	bundlesHandler := ProvideBundlesHandler(config, bundlesService, itemsService)
+	paymentsHandler := ProvidePaymentsHandler(config, paymentIntentsService)
+	refundsService := ProvideRefundsService(refundsRepo, unitOfWork, transactionsRepo, pivotItemsToTransactionsRepo, itemsRepo, loyaltyRepo, giftCardsRepo, shiftsRepo, dayClosesRepo, inventoryRepo, eventBus, config)
+	refundsHandler := ProvideRefundsHandler(config, refundsService)
+	promotionsHandler := ProvidePromotionsHandler(config, promotionsService)
+	vouchersService := ProvideVouchersService(vouchersRepo, promotionsRepo, config)
+	vouchersHandler := ProvideVouchersHandler(config, vouchersService, promotionsService, transactionsService)
+	customersService := ProvideCustomersService(customersRepo, transactionsRepo, refundsRepo, config)
+	customersHandler := ProvideCustomersHandler(config, customersService)
+	loyaltyService := ProvideLoyaltyService(loyaltyRepo, customersRepo, itemsRepo, config)
+	loyaltyHandler := ProvideLoyaltyHandler(config, loyaltyService)
+	giftCardsService := ProvideGiftCardsService(giftCardsRepo, unitOfWork, customersRepo)
+	giftCardsHandler := ProvideGiftCardsHandler(config, giftCardsService)
+	shiftsService := ProvideShiftsService(shiftsRepo, unitOfWork, transactionsRepo, paymentsRepo, refundsRepo, dayClosesRepo, config)
+	shiftsHandler := ProvideShiftsHandler(config, shiftsService)
+	dayClosesService := ProvideDayClosesService(dayClosesRepo, unitOfWork, reportsService, transactionsRepo, shiftsRepo)
+	dayClosesHandler := ProvideDayClosesHandler(config, dayClosesService)
+	heldOrdersService := ProvideHeldOrdersService(heldOrdersRepo, unitOfWork, itemsRepo, modifiersService, transactionsService, tablesRepo, config)
+	heldOrdersHandler := ProvideHeldOrdersHandler(config, heldOrdersService)
+	tablesService := ProvideTablesService(tablesRepo, heldOrdersRepo)
+	tablesHandler := ProvideTablesHandler(config, tablesService, heldOrdersService)
+	kitchenHandler := ProvideKitchenHandler(config, kitchenService)
+	queueService := ProvideQueueService(kitchenRepo, config)
+	queueHandler := ProvideQueueHandler(config, queueService)
+	suppliersRepo := ProvideSuppliersRepo(db)
+	suppliersService := ProvideSuppliersService(suppliersRepo)
+	suppliersHandler := ProvideSuppliersHandler(config, suppliersService)
+	purchaseOrdersRepo := ProvidePurchaseOrdersRepo(db)
+	purchaseOrdersService := ProvidePurchaseOrdersService(purchaseOrdersRepo, unitOfWork, suppliersRepo, itemsRepo, inventoryRepo)
+	purchaseOrdersHandler := ProvidePurchaseOrdersHandler(config, purchaseOrdersService)
+	inventoryService := ProvideInventoryService(inventoryRepo, itemsRepo)
+	inventoryHandler := ProvideInventoryHandler(config, inventoryService)
+	engine := ProvideRouterWithRoutes(authenticationHandler, usersHandler, itemsHandler, transactionsHandler, reportHandler, imagesHandler, categoriesHandler, modifiersHandler, bundlesHandler, paymentsHandler, refundsHandler, promotionsHandler, vouchersHandler, customersHandler, loyaltyHandler, giftCardsHandler, shiftsHandler, dayClosesHandler, heldOrdersHandler, tablesHandler, kitchenHandler, queueHandler, suppliersHandler, purchaseOrdersHandler, inventoryHandler)
 	server := ProvideHTTPServer(config, engine)
 	app := &App{
-		Server: server,
-		Router: engine,
+		Server:         server,
+		Router:         engine,
+		PaymentIntents: paymentIntentsService,
+		Dashboard:      dashboardService,
 	}
 	return app
 }
@@ -61,8 +132,10 @@ func InitializeRepos() *Repos {
 // wire.go:
 
 type App struct {
-	Server *http.Server
-	Router *gin.Engine
+	Server         *http.Server
+	Router         *gin.Engine
+	PaymentIntents services.PaymentIntentsService
+	Dashboard      services.DashboardService
 }
 
 type Repos struct {
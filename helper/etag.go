@@ -0,0 +1,49 @@
+package helper
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// Checksum returns the hex encoded SHA-256 of data.
+func Checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// StrongETag returns a quoted ETag derived from the exact bytes served.
+func StrongETag(data []byte) string {
+	return ChecksumETag(Checksum(data))
+}
+
+// ChecksumETag quotes an already computed hex checksum.
+func ChecksumETag(checksum string) string {
+	return `"` + checksum + `"`
+}
+
+// WeakETag builds a W/ validator from the parts that identify a response
+// representation, e.g. a version stamp and the query string.
+func WeakETag(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "|")))
+	return `W/"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// ETagMatches reports whether an If-None-Match header matches etag using the
+// weak comparison required for GET/HEAD.
+func ETagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" || etag == "" {
+		return false
+	}
+	want := strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if strings.TrimPrefix(candidate, "W/") == want {
+			return true
+		}
+	}
+	return false
+}
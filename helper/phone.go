@@ -0,0 +1,40 @@
+package helper
+
+import "strings"
+
+// NormalizePhone turns a phone number as typed at the till, e.g.
+// "0812-3456-7890" or "+62 812 3456 7890", into E.164 ("+6281234567890").
+// Numbers without an international prefix are taken as local to
+// countryCode; a leading trunk 0 is dropped. It reports false for anything
+// that is not a plausible phone number, such as an email address.
+func NormalizePhone(raw, countryCode string) (string, bool) {
+	s := strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '.', '(', ')', '/':
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(raw))
+
+	switch {
+	case strings.HasPrefix(s, "+"):
+		s = s[1:]
+	case strings.HasPrefix(s, "00"):
+		s = s[2:]
+	case strings.HasPrefix(s, "0"):
+		s = countryCode + s[1:]
+	case countryCode != "" && strings.HasPrefix(s, countryCode):
+	default:
+		s = countryCode + s
+	}
+
+	if len(s) < 8 || len(s) > 15 || s[0] == '0' {
+		return "", false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return "", false
+		}
+	}
+	return "+" + s, true
+}
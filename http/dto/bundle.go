@@ -0,0 +1,28 @@
+package dto
+
+import "faizalmaulana/lsp/models/entity"
+
+type BundleComponentRequest struct {
+	IdItem   string `json:"id_item" binding:"required"`
+	Quantity int    `json:"quantity"`
+}
+
+type SetBundleComponentsRequest struct {
+	Components []BundleComponentRequest `json:"components"`
+}
+
+type BundleComponentDetail struct {
+	IdItem    string       `json:"id_item"`
+	ItemName  string       `json:"item_name"`
+	Quantity  int          `json:"quantity"`
+	UnitPrice entity.Money `json:"unit_price"`
+}
+
+type BundleResponse struct {
+	IdItem     string                  `json:"id_item"`
+	ItemName   string                  `json:"item_name"`
+	Kind       string                  `json:"kind"`
+	Price      entity.Money            `json:"price"`
+	ListPrice  entity.Money            `json:"list_price"`
+	Components []BundleComponentDetail `json:"components"`
+}
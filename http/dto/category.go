@@ -0,0 +1,31 @@
+package dto
+
+import "faizalmaulana/lsp/models/entity"
+
+type CreateCategoryRequest struct {
+	Name      string `json:"name" binding:"required"`
+	IdParent  string `json:"id_parent"`
+	SortOrder int    `json:"sort_order"`
+	Color     string `json:"color"`
+	IdImage   string `json:"id_image"`
+	IsActive  *bool  `json:"is_active"`
+	// TaxRate is the category's tax rate; leave it out to inherit.
+	TaxRate *float64 `json:"tax_rate" binding:"omitempty,min=0,max=100"`
+}
+
+type UpdateCategoryRequest struct {
+	Name      *string `json:"name"`
+	IdParent  *string `json:"id_parent"`
+	SortOrder *int    `json:"sort_order"`
+	Color     *string `json:"color"`
+	IdImage   *string `json:"id_image"`
+	IsActive  *bool   `json:"is_active"`
+	// ClearTaxRate makes the category inherit its tax rate again.
+	TaxRate      *float64 `json:"tax_rate" binding:"omitempty,min=0,max=100"`
+	ClearTaxRate bool     `json:"clear_tax_rate"`
+}
+
+type CategoryNode struct {
+	entity.Categories
+	Children []CategoryNode `json:"children"`
+}
@@ -0,0 +1,48 @@
+package dto
+
+import (
+	"time"
+
+	"faizalmaulana/lsp/models/entity"
+)
+
+type CreateCustomerRequest struct {
+	Name             string `json:"name"`
+	Phone            string `json:"phone" binding:"required"`
+	Email            string `json:"email"`
+	Notes            string `json:"notes"`
+	MarketingConsent bool   `json:"marketing_consent"`
+}
+
+// UpdateCustomerRequest changes the fields that are present; an empty
+// string clears name, email or notes.
+type UpdateCustomerRequest struct {
+	Name             *string `json:"name"`
+	Phone            *string `json:"phone"`
+	Email            *string `json:"email"`
+	Notes            *string `json:"notes"`
+	MarketingConsent *bool   `json:"marketing_consent"`
+}
+
+// CheckoutCustomerRequest names the buyer of a sale: id_customer for a
+// customer picked from the directory, or a phone that is looked up and
+// registered with name and email when it is new.
+type CheckoutCustomerRequest struct {
+	IdCustomer string `json:"id_customer"`
+	Phone      string `json:"phone"`
+	Name       string `json:"name"`
+	Email      string `json:"email"`
+}
+
+type CustomerHistoryResponse struct {
+	Customer      *entity.Customers     `json:"customer"`
+	Visits        int64                 `json:"visits"`
+	Sales         entity.Money          `json:"sales"`
+	Refunded      entity.Money          `json:"refunded"`
+	LifetimeValue entity.Money          `json:"lifetime_value"`
+	FirstVisit    *time.Time            `json:"first_visit"`
+	LastVisit     *time.Time            `json:"last_visit"`
+	Page          int                   `json:"page"`
+	Count         int                   `json:"count"`
+	Transactions  []entity.Transactions `json:"transactions"`
+}
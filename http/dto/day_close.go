@@ -0,0 +1,6 @@
+package dto
+
+// CloseDayRequest closes Date (YYYY-MM-DD), today when empty.
+type CloseDayRequest struct {
+	Date string `json:"date"`
+}
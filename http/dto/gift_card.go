@@ -0,0 +1,52 @@
+package dto
+
+import "faizalmaulana/lsp/models/entity"
+
+type IssueGiftCardRequest struct {
+	Kind       string       `json:"kind" binding:"required"`
+	Amount     entity.Money `json:"amount" binding:"required"`
+	IdCustomer string       `json:"id_customer"`
+	Note       string       `json:"note"`
+}
+
+// AdjustGiftCardRequest corrects a balance; Amount is negative to take
+// money off.
+type AdjustGiftCardRequest struct {
+	Amount entity.Money `json:"amount" binding:"required"`
+	Note   string       `json:"note" binding:"required"`
+}
+
+type IssuedGiftCardResponse struct {
+	Card *entity.GiftCards `json:"card"`
+	Pin  string            `json:"pin"`
+}
+
+type GiftCardStatusRequest struct {
+	Status string `json:"status" binding:"required"`
+}
+
+type GiftCardBalanceRequest struct {
+	Code string `json:"code" binding:"required"`
+	Pin  string `json:"pin" binding:"required"`
+}
+
+// GiftCardBalanceResponse is what the public balance check shows of a card.
+type GiftCardBalanceResponse struct {
+	Code    string       `json:"code"`
+	Kind    string       `json:"kind"`
+	Balance entity.Money `json:"balance"`
+	Status  string       `json:"status"`
+}
+
+type GiftCardLiabilityResponse struct {
+	At       string                   `json:"at"`
+	Total    entity.Money             `json:"total"`
+	Accounts int64                    `json:"accounts"`
+	ByKind   []GiftCardLiabilityEntry `json:"by_kind"`
+}
+
+type GiftCardLiabilityEntry struct {
+	Kind     string       `json:"kind"`
+	Accounts int64        `json:"accounts"`
+	Balance  entity.Money `json:"balance"`
+}
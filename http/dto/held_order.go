@@ -0,0 +1,51 @@
+package dto
+
+// CreateHeldOrderRequest parks an order on Terminal. Label names it, e.g.
+// the customer or the table of a tab; Items may be added later. IdTable
+// opens it on a free table, for Guests guests.
+type CreateHeldOrderRequest struct {
+	Terminal     string                   `json:"terminal"`
+	Label        string                   `json:"label"`
+	Note         string                   `json:"note"`
+	IdCustomer   string                   `json:"id_customer"`
+	BuyerContact string                   `json:"buyer_contact"`
+	IdTable      string                   `json:"id_table"`
+	Guests       int                      `json:"guests" binding:"min=0"`
+	Items        []TransactionItemRequest `json:"items"`
+}
+
+type UpdateHeldOrderRequest struct {
+	Terminal     *string `json:"terminal"`
+	Label        *string `json:"label"`
+	Note         *string `json:"note"`
+	Guests       *int    `json:"guests" binding:"omitempty,min=0"`
+	IdCustomer   *string `json:"id_customer"`
+	BuyerContact *string `json:"buyer_contact"`
+}
+
+// SplitEquallyRequest splits a bill into Parts equal shares, one per guest
+// of the order when it is 0.
+type SplitEquallyRequest struct {
+	Parts int `json:"parts" binding:"omitempty,min=2,max=50"`
+}
+
+// SplitItemsRequest moves the lines of each part to an order of its own.
+type SplitItemsRequest struct {
+	Parts [][]SplitLineRequest `json:"parts" binding:"required,min=1"`
+}
+
+// SplitLineRequest takes Quantity units of a line; 0 takes all of them.
+type SplitLineRequest struct {
+	IdHeldOrderLine string `json:"id_held_order_line"`
+	Quantity        int    `json:"quantity"`
+}
+
+// CheckoutHeldOrderRequest pays for a held order; the customer and buyer
+// contact default to the order's.
+type CheckoutHeldOrderRequest struct {
+	BuyerContact string                   `json:"buyer_contact"`
+	Customer     *CheckoutCustomerRequest `json:"customer"`
+	Discount     *DiscountRequest         `json:"discount"`
+	VoucherCode  string                   `json:"voucher_code"`
+	Payments     []PaymentRequest         `json:"payments"`
+}
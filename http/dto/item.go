@@ -1,23 +1,35 @@
 package dto
 
+import "faizalmaulana/lsp/models/entity"
+
 type CreateItemRequest struct {
-	ItemName    string  `json:"item_name" binding:"required"`
-	ItemType    string  `json:"item_type"`
-	IsAvailable *bool   `json:"is_available"`
-	Price       float64 `json:"price" binding:"required"`
-	Description string  `json:"description"`
-	ImageUrl    string  `json:"image_url"`
-	ImageBase64 string  `json:"image_base64"`
-	ImageType   string  `json:"image_type"`  
+	ItemName    string       `json:"item_name" binding:"required"`
+	ItemType    string       `json:"item_type"`
+	IdCategory  string       `json:"id_category"`
+	IsAvailable *bool        `json:"is_available"`
+	Price       entity.Money `json:"price" binding:"required"`
+	TaxRate     *float64     `json:"tax_rate" binding:"omitempty,min=0,max=100"`
+	Description string       `json:"description"`
+	ImageUrl    string       `json:"image_url"`
+	ImageBase64 string       `json:"image_base64"`
+	ImageType   string       `json:"image_type"`  
+	// GiftCard makes the item a gift card, loaded with its price when sold.
+	GiftCard bool `json:"gift_card"`
 }
 
 type UpdateItemRequest struct {
-	ItemName    *string  `json:"item_name"`
-	ItemType    *string  `json:"item_type"`
-	IsAvailable *bool    `json:"is_available"`
-	Price       *float64 `json:"price"`
-	Description *string  `json:"description"`
-	ImageUrl    *string  `json:"image_url"`
-	ImageBase64 *string  `json:"image_base64"`
-	ImageType   *string  `json:"image_type"`
+	ItemName    *string       `json:"item_name"`
+	ItemType    *string       `json:"item_type"`
+	IdCategory  *string       `json:"id_category"`
+	IsAvailable *bool         `json:"is_available"`
+	Price       *entity.Money `json:"price"`
+	// TaxRate sets the item's own tax rate; ClearTaxRate makes it inherit
+	// its category's again.
+	TaxRate      *float64 `json:"tax_rate" binding:"omitempty,min=0,max=100"`
+	ClearTaxRate bool     `json:"clear_tax_rate"`
+	Description  *string  `json:"description"`
+	ImageUrl     *string  `json:"image_url"`
+	ImageBase64  *string  `json:"image_base64"`
+	ImageType    *string  `json:"image_type"`
+	GiftCard     *bool    `json:"gift_card"`
 }
@@ -0,0 +1,18 @@
+package dto
+
+// CreateKitchenStationRequest sets up a kitchen screen. Categories are the
+// ids of the categories it prepares, subcategories included; a default
+// station gets what no station claims.
+type CreateKitchenStationRequest struct {
+	Name       string   `json:"name" binding:"required"`
+	Categories []string `json:"categories"`
+	IsDefault  bool     `json:"is_default"`
+	SortOrder  int      `json:"sort_order"`
+}
+
+type UpdateKitchenStationRequest struct {
+	Name       *string   `json:"name"`
+	Categories *[]string `json:"categories"`
+	IsDefault  *bool     `json:"is_default"`
+	SortOrder  *int      `json:"sort_order"`
+}
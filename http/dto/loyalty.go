@@ -0,0 +1,44 @@
+package dto
+
+import (
+	"time"
+
+	"faizalmaulana/lsp/models/entity"
+)
+
+type LoyaltyRuleRequest struct {
+	Name     string       `json:"name" binding:"required"`
+	Kind     string       `json:"kind" binding:"required"`
+	Step     entity.Money `json:"step"`
+	IdItem   string       `json:"id_item"`
+	Points   int          `json:"points" binding:"required"`
+	IsActive *bool        `json:"is_active"`
+}
+
+type LoyaltyTierRequest struct {
+	Name       string       `json:"name" binding:"required"`
+	MinSpend   entity.Money `json:"min_spend"`
+	Multiplier float64      `json:"multiplier" binding:"required"`
+}
+
+// LoyaltyBalanceResponse is what the public balance lookup shows: points
+// and tier only, nothing that identifies the customer.
+type LoyaltyBalanceResponse struct {
+	Points          int          `json:"points"`
+	Value           entity.Money `json:"value"`
+	Tier            string       `json:"tier"`
+	Multiplier      float64      `json:"multiplier"`
+	NextTier        string       `json:"next_tier"`
+	SpendToNextTier entity.Money `json:"spend_to_next_tier"`
+	ExpiringPoints  int          `json:"expiring_points"`
+	ExpiringAt      *time.Time   `json:"expiring_at"`
+}
+
+type LoyaltyCustomerResponse struct {
+	Customer *entity.Customers `json:"customer"`
+	LoyaltyBalanceResponse
+	Spend  entity.Money           `json:"spend"`
+	Page   int                    `json:"page"`
+	Count  int                    `json:"count"`
+	Ledger []entity.LoyaltyLedger `json:"ledger"`
+}
@@ -0,0 +1,38 @@
+package dto
+
+import "faizalmaulana/lsp/models/entity"
+
+type ModifierOptionRequest struct {
+	Name        string       `json:"name" binding:"required"`
+	PriceDelta  entity.Money `json:"price_delta"`
+	IsDefault   bool         `json:"is_default"`
+	IsAvailable *bool        `json:"is_available"`
+	SortOrder   int          `json:"sort_order"`
+}
+
+type CreateModifierGroupRequest struct {
+	Name       string                  `json:"name" binding:"required"`
+	Kind       string                  `json:"kind"`
+	IsRequired bool                    `json:"is_required"`
+	MinSelect  int                     `json:"min_select"`
+	MaxSelect  int                     `json:"max_select"`
+	SortOrder  int                     `json:"sort_order"`
+	Options    []ModifierOptionRequest `json:"options"`
+}
+
+type UpdateModifierGroupRequest struct {
+	Name       *string `json:"name"`
+	Kind       *string `json:"kind"`
+	IsRequired *bool   `json:"is_required"`
+	MinSelect  *int    `json:"min_select"`
+	MaxSelect  *int    `json:"max_select"`
+	SortOrder  *int    `json:"sort_order"`
+}
+
+type UpdateModifierOptionRequest struct {
+	Name        *string       `json:"name"`
+	PriceDelta  *entity.Money `json:"price_delta"`
+	IsDefault   *bool         `json:"is_default"`
+	IsAvailable *bool         `json:"is_available"`
+	SortOrder   *int          `json:"sort_order"`
+}
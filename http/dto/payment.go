@@ -0,0 +1,14 @@
+package dto
+
+type SimulatePaymentRequest struct {
+	Status string `json:"status"`
+}
+
+type WebhookResponse struct {
+	Duplicate       bool   `json:"duplicate"`
+	Outcome         string `json:"outcome"`
+	RefundNeeded    bool   `json:"refund_needed"`
+	IdPaymentIntent string `json:"id_payment_intent"`
+	IdTransaction   string `json:"id_transaction"`
+	Status          string `json:"status"`
+}
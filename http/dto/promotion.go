@@ -0,0 +1,49 @@
+package dto
+
+import "faizalmaulana/lsp/models/entity"
+
+import "time"
+
+type CreatePromotionRequest struct {
+	Name        string       `json:"name" binding:"required"`
+	Kind        string       `json:"kind" binding:"required"`
+	Scope       string       `json:"scope" binding:"required"`
+	IdTarget    string       `json:"id_target"`
+	Value       float64      `json:"value"`
+	BuyQuantity int          `json:"buy_quantity"`
+	GetQuantity int          `json:"get_quantity"`
+	MinSpend    entity.Money `json:"min_spend"`
+	StartsAt    *time.Time   `json:"starts_at"`
+	EndsAt      *time.Time   `json:"ends_at"`
+	DaysOfWeek  string       `json:"days_of_week"`
+	StartTime   string       `json:"start_time"`
+	EndTime     string       `json:"end_time"`
+	Priority    int          `json:"priority"`
+	Stackable   bool         `json:"stackable"`
+	VoucherOnly bool         `json:"voucher_only"`
+	IsActive    *bool        `json:"is_active"`
+}
+
+// UpdatePromotionRequest changes the fields that are present. starts_at and
+// ends_at are cleared with clear_starts_at and clear_ends_at.
+type UpdatePromotionRequest struct {
+	Name          *string       `json:"name"`
+	Kind          *string       `json:"kind"`
+	Scope         *string       `json:"scope"`
+	IdTarget      *string       `json:"id_target"`
+	Value         *float64      `json:"value"`
+	BuyQuantity   *int          `json:"buy_quantity"`
+	GetQuantity   *int          `json:"get_quantity"`
+	MinSpend      *entity.Money `json:"min_spend"`
+	StartsAt      *time.Time    `json:"starts_at"`
+	EndsAt        *time.Time    `json:"ends_at"`
+	ClearStartsAt bool          `json:"clear_starts_at"`
+	ClearEndsAt   bool          `json:"clear_ends_at"`
+	DaysOfWeek    *string       `json:"days_of_week"`
+	StartTime     *string       `json:"start_time"`
+	EndTime       *string       `json:"end_time"`
+	Priority      *int          `json:"priority"`
+	Stackable     *bool         `json:"stackable"`
+	VoucherOnly   *bool         `json:"voucher_only"`
+	IsActive      *bool         `json:"is_active"`
+}
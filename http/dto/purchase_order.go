@@ -0,0 +1,42 @@
+package dto
+
+import (
+	"time"
+
+	"faizalmaulana/lsp/models/entity"
+)
+
+type PurchaseOrderLineRequest struct {
+	IdItem   string       `json:"id_item" binding:"required"`
+	Quantity int          `json:"quantity" binding:"required,min=1"`
+	UnitCost entity.Money `json:"unit_cost"`
+}
+
+type CreatePurchaseOrderRequest struct {
+	IdSupplier string                     `json:"id_supplier" binding:"required"`
+	Note       string                     `json:"note"`
+	ExpectedAt *time.Time                 `json:"expected_at"`
+	Lines      []PurchaseOrderLineRequest `json:"lines"`
+}
+
+// UpdatePurchaseOrderRequest changes the fields of a draft that are
+// present; lines, when present, replace all of its lines.
+type UpdatePurchaseOrderRequest struct {
+	IdSupplier *string                     `json:"id_supplier"`
+	Note       *string                     `json:"note"`
+	ExpectedAt *time.Time                  `json:"expected_at"`
+	Lines      *[]PurchaseOrderLineRequest `json:"lines"`
+}
+
+// GoodsReceiptLineRequest receives quantity units of an order line;
+// unit_cost, when left out, is the cost they were ordered at.
+type GoodsReceiptLineRequest struct {
+	IdPurchaseOrderLine string        `json:"id_purchase_order_line" binding:"required"`
+	Quantity            int           `json:"quantity" binding:"required,min=1"`
+	UnitCost            *entity.Money `json:"unit_cost"`
+}
+
+type GoodsReceiptRequest struct {
+	Note  string                    `json:"note"`
+	Lines []GoodsReceiptLineRequest `json:"lines" binding:"required"`
+}
@@ -0,0 +1,43 @@
+package dto
+
+import "faizalmaulana/lsp/models/entity"
+
+type RefundLineRequest struct {
+	IdPivot  string `json:"id_pivot" binding:"required"`
+	Quantity int    `json:"quantity" binding:"required"`
+	Restock  bool   `json:"restock"`
+}
+
+type CreateRefundRequest struct {
+	Reason    string              `json:"reason"`
+	Method    string              `json:"method" binding:"required"`
+	Reference string              `json:"reference"`
+	Lines     []RefundLineRequest `json:"lines" binding:"required"`
+}
+
+type RefundLineDetail struct {
+	IdRefundLine string       `json:"id_refund_line"`
+	IdPivot      string       `json:"id_pivot"`
+	IdItem       string       `json:"id_item"`
+	ItemName     string       `json:"item_name"`
+	Quantity     int          `json:"quantity"`
+	UnitPrice    entity.Money `json:"unit_price"`
+	Amount       entity.Money `json:"amount"`
+	Restock      bool         `json:"restock"`
+}
+
+type RefundResponse struct {
+	IdRefund          string             `json:"id_refund"`
+	IdTransaction     string             `json:"id_transaction"`
+	IdUser            string             `json:"id_user"`
+	Reason            string             `json:"reason"`
+	Method            string             `json:"method"`
+	Reference         string             `json:"reference"`
+	Amount            entity.Money       `json:"amount"`
+	TransactionStatus string             `json:"transaction_status"`
+	Timestamp         string             `json:"timestamp"`
+	Lines             []RefundLineDetail `json:"lines"`
+	// Credit is the store credit account opened for the refund, with its
+	// PIN, which is not shown again.
+	Credit *IssuedGiftCardResponse `json:"credit,omitempty"`
+}
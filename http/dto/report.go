@@ -1,55 +1,150 @@
 package dto
 
+import "faizalmaulana/lsp/models/entity"
+
+// ReportBody is what a sales report shows, whatever its period.
+type ReportBody struct {
+	Total             int                  `json:"total_transactions"`
+	Sum               entity.Money         `json:"sum_total_price"`
+	TotalProductsSold int                  `json:"total_products_sold"`
+	AverageOrderValue entity.Money         `json:"average_order_value"`
+	MinOrderValue     entity.Money         `json:"min_order_value"`
+	MaxOrderValue     entity.Money         `json:"max_order_value"`
+	AvgItemsPerTx     float64              `json:"average_items_per_transaction"`
+	TopItems          []TopItem            `json:"top_items"`
+	PaymentMethods    []PaymentMethodTotal `json:"payment_methods"`
+	DiscountGiven     entity.Money         `json:"discount_given"`
+	Discounts         []ReportDiscount     `json:"discounts"`
+	Subtotal          entity.Money         `json:"subtotal"`
+	ServiceCharge     entity.Money         `json:"service_charge_total"`
+	TaxTotal          entity.Money         `json:"tax_total"`
+	GiftCardSales     entity.Money         `json:"gift_card_sales"`
+	GiftCardsSold     int                  `json:"gift_cards_sold"`
+	CashRounding      entity.Money         `json:"cash_rounding"`
+	VoidedCount       int                  `json:"voided_transactions"`
+	VoidedTotal       entity.Money         `json:"voided_total"`
+	Voids             []ReportVoid         `json:"voids"`
+	RefundCount       int                  `json:"refund_count"`
+	RefundTotal       entity.Money         `json:"refund_total"`
+	RefundedProducts  int                  `json:"refunded_products"`
+	NetSales          entity.Money         `json:"net_sales"`
+	Refunds           []ReportRefund       `json:"refunds"`
+	CashRoundingByDay []ReportRoundingDay  `json:"cash_rounding_by_day"`
+	Items             []ReportTransaction  `json:"transactions"`
+}
+
 type ReportResponse struct {
-	Month             int                 `json:"month"`
-	Year              int                 `json:"year"`
-	Total             int                 `json:"total_transactions"`
-	Sum               float64             `json:"sum_total_price"`
-	TotalProductsSold int                 `json:"total_products_sold"`
-	AverageOrderValue float64             `json:"average_order_value"`
-	MinOrderValue     float64             `json:"min_order_value"`
-	MaxOrderValue     float64             `json:"max_order_value"`
-	AvgItemsPerTx     float64             `json:"average_items_per_transaction"`
-	TopItems          []TopItem           `json:"top_items"`
-	Items             []ReportTransaction `json:"transactions"`
+	Month int `json:"month"`
+	Year  int `json:"year"`
+	ReportBody
 }
 
 type ReportTransaction struct {
-	IdTransaction string  `json:"id_transaction"`
-	TotalPrice    float64 `json:"total_price"`
-	BuyerContact  string  `json:"buyer_contact"`
-	Timestamp     string  `json:"timestamp"`
+	IdTransaction string       `json:"id_transaction"`
+	TotalPrice    entity.Money `json:"total_price"`
+	BuyerContact  string       `json:"buyer_contact"`
+	IdCustomer    string       `json:"id_customer,omitempty"`
+	Timestamp     string       `json:"timestamp"`
+}
+
+type ReportVoid struct {
+	IdTransaction string       `json:"id_transaction"`
+	TotalPrice    entity.Money `json:"total_price"`
+	Reason        string       `json:"reason"`
+	VoidedBy      string       `json:"voided_by"`
+	Timestamp     string       `json:"timestamp"`
+	VoidedAt      string       `json:"voided_at"`
+}
+
+type ReportRefund struct {
+	IdRefund      string       `json:"id_refund"`
+	IdTransaction string       `json:"id_transaction"`
+	Method        string       `json:"method"`
+	Amount        entity.Money `json:"amount"`
+	Reason        string       `json:"reason"`
+	Timestamp     string       `json:"timestamp"`
 }
 
 type TodayReportResponse struct {
-	Date              string              `json:"date"`
-	Total             int                 `json:"total_transactions"`
-	Sum               float64             `json:"sum_total_price"`
-	TotalProductsSold int                 `json:"total_products_sold"`
-	AverageOrderValue float64             `json:"average_order_value"`
-	MinOrderValue     float64             `json:"min_order_value"`
-	MaxOrderValue     float64             `json:"max_order_value"`
-	AvgItemsPerTx     float64             `json:"average_items_per_transaction"`
-	TopItems          []TopItem           `json:"top_items"`
-	Items             []ReportTransaction `json:"transactions"`
+	Date string `json:"date"`
+	ReportBody
 }
 
 type TodaySummaryResponse struct {
-	Date              string    `json:"date"`
-	TotalTransactions int       `json:"total_transactions"`
-	TotalProductsSold int       `json:"total_products_sold"`
-	SumTotalPrice     float64   `json:"sum_total_price"`
-	AverageOrderValue float64   `json:"average_order_value"`
-	MinOrderValue     float64   `json:"min_order_value"`
-	MaxOrderValue     float64   `json:"max_order_value"`
-	AvgItemsPerTx     float64   `json:"average_items_per_transaction"`
-	TopItems          []TopItem `json:"top_items"`
+	Date              string               `json:"date"`
+	TotalTransactions int                  `json:"total_transactions"`
+	TotalProductsSold int                  `json:"total_products_sold"`
+	SumTotalPrice     entity.Money         `json:"sum_total_price"`
+	AverageOrderValue entity.Money         `json:"average_order_value"`
+	MinOrderValue     entity.Money         `json:"min_order_value"`
+	MaxOrderValue     entity.Money         `json:"max_order_value"`
+	AvgItemsPerTx     float64              `json:"average_items_per_transaction"`
+	TopItems          []TopItem            `json:"top_items"`
+	PaymentMethods    []PaymentMethodTotal `json:"payment_methods"`
+	DiscountGiven     entity.Money         `json:"discount_given"`
+	Discounts         []ReportDiscount     `json:"discounts"`
+	Subtotal          entity.Money         `json:"subtotal"`
+	ServiceCharge     entity.Money         `json:"service_charge_total"`
+	TaxTotal          entity.Money         `json:"tax_total"`
+	GiftCardSales     entity.Money         `json:"gift_card_sales"`
+	GiftCardsSold     int                  `json:"gift_cards_sold"`
+	CashRounding      entity.Money         `json:"cash_rounding"`
+	VoidedCount       int                  `json:"voided_transactions"`
+	VoidedTotal       entity.Money         `json:"voided_total"`
+	RefundCount       int                  `json:"refund_count"`
+	RefundTotal       entity.Money         `json:"refund_total"`
+	RefundedProducts  int                  `json:"refunded_products"`
+	NetSales          entity.Money         `json:"net_sales"`
 }
 
 type TopItem struct {
-	IdItem       string  `json:"id_item"`
-	ItemName     string  `json:"item_name"`
-	ImageUrl     string  `json:"image_url"`
-	QuantitySold int     `json:"quantity_sold"`
-	Revenue      float64 `json:"revenue"`
+	IdItem           string            `json:"id_item"`
+	ItemName         string            `json:"item_name"`
+	ImageUrl         string            `json:"image_url"`
+	QuantitySold     int               `json:"quantity_sold"`
+	Revenue          entity.Money      `json:"revenue"`
+	Discount         entity.Money      `json:"discount"`
+	BundleQuantity   int               `json:"bundle_quantity"`
+	BundleRevenue    entity.Money      `json:"bundle_revenue"`
+	RefundedQuantity int               `json:"refunded_quantity"`
+	RefundedRevenue  entity.Money      `json:"refunded_revenue"`
+	Modifiers        []TopItemModifier `json:"modifiers"`
+}
+
+type TopItemModifier struct {
+	GroupName    string       `json:"group_name"`
+	OptionName   string       `json:"option_name"`
+	QuantitySold int          `json:"quantity_sold"`
+	Revenue      entity.Money `json:"revenue"`
+}
+
+type ReportRoundingDay struct {
+	Date   string       `json:"date"`
+	Count  int          `json:"count"`
+	Amount entity.Money `json:"amount"`
+}
+
+type ReportDiscount struct {
+	IdPromotion string       `json:"id_promotion"`
+	Name        string       `json:"name"`
+	Source      string       `json:"source"`
+	Count       int          `json:"count"`
+	Amount      entity.Money `json:"amount"`
+}
+
+type PaymentMethodTotal struct {
+	Method   string       `json:"method"`
+	Count    int          `json:"count"`
+	Amount   entity.Money `json:"amount"`
+	Refunded entity.Money `json:"refunded"`
+}
+
+// DashboardEvent is what the live dashboard stream sends: the sale, void or
+// refund that changed today's figures, if any, and the figures since.
+type DashboardEvent struct {
+	Transaction *ReportTransaction   `json:"transaction,omitempty"`
+	Void        *ReportVoid          `json:"void,omitempty"`
+	Refund      *ReportRefund        `json:"refund,omitempty"`
+	Summary     TodaySummaryResponse `json:"summary"`
+	Latest      []ReportTransaction  `json:"latest_transactions"`
 }
@@ -0,0 +1,67 @@
+package dto
+
+import "faizalmaulana/lsp/models/entity"
+
+type OpenShiftRequest struct {
+	OpeningFloat entity.Money `json:"opening_float"`
+	Note         string       `json:"note"`
+}
+
+type ShiftMovementRequest struct {
+	Kind   string       `json:"kind" binding:"required"`
+	Amount entity.Money `json:"amount" binding:"required"`
+	Reason string       `json:"reason"`
+}
+
+type DenominationCountRequest struct {
+	Denomination entity.Money `json:"denomination" binding:"required"`
+	Quantity     int          `json:"quantity"`
+}
+
+type CloseShiftRequest struct {
+	Counts []DenominationCountRequest `json:"counts"`
+	Note   string                     `json:"note"`
+}
+
+type ShiftDetailResponse struct {
+	*entity.Shifts
+	Movements []entity.ShiftMovements `json:"movements"`
+	Counts    []entity.ShiftCounts    `json:"counts"`
+}
+
+// ShiftCashResponse leaves out the count while the shift is open.
+type ShiftCashResponse struct {
+	OpeningFloat entity.Money  `json:"opening_float"`
+	Sales        entity.Money  `json:"cash_sales"`
+	Refunds      entity.Money  `json:"cash_refunds"`
+	CashIn       entity.Money  `json:"cash_in"`
+	CashOut      entity.Money  `json:"cash_out"`
+	Drops        entity.Money  `json:"drops"`
+	Expected     entity.Money  `json:"expected"`
+	Counted      *entity.Money `json:"counted,omitempty"`
+	Difference   *entity.Money `json:"difference,omitempty"`
+	// Result is "over", "short" or "balanced" once counted.
+	Result string `json:"result,omitempty"`
+}
+
+type ShiftReportResponse struct {
+	Report        string                  `json:"report"`
+	Shift         *entity.Shifts          `json:"shift"`
+	GeneratedAt   string                  `json:"generated_at"`
+	Sales         int                     `json:"sales"`
+	SalesTotal    entity.Money            `json:"sales_total"`
+	Subtotal      entity.Money            `json:"subtotal"`
+	DiscountTotal entity.Money            `json:"discount_total"`
+	ServiceCharge entity.Money            `json:"service_charge_total"`
+	TaxTotal      entity.Money            `json:"tax_total"`
+	CashRounding  entity.Money            `json:"cash_rounding"`
+	Payments      []PaymentMethodTotal    `json:"payment_methods"`
+	RefundCount   int                     `json:"refund_count"`
+	RefundTotal   entity.Money            `json:"refund_total"`
+	NetSales      entity.Money            `json:"net_sales"`
+	VoidedCount   int                     `json:"voided_transactions"`
+	VoidedTotal   entity.Money            `json:"voided_total"`
+	Movements     []entity.ShiftMovements `json:"movements"`
+	Counts        []entity.ShiftCounts    `json:"counts"`
+	Cash          ShiftCashResponse       `json:"cash"`
+}
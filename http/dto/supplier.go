@@ -0,0 +1,21 @@
+package dto
+
+type CreateSupplierRequest struct {
+	Name        string `json:"name" binding:"required"`
+	ContactName string `json:"contact_name"`
+	Phone       string `json:"phone"`
+	Email       string `json:"email"`
+	Address     string `json:"address"`
+	Note        string `json:"note"`
+}
+
+// UpdateSupplierRequest changes the fields that are present; an empty
+// string clears all but the name.
+type UpdateSupplierRequest struct {
+	Name        *string `json:"name"`
+	ContactName *string `json:"contact_name"`
+	Phone       *string `json:"phone"`
+	Email       *string `json:"email"`
+	Address     *string `json:"address"`
+	Note        *string `json:"note"`
+}
@@ -0,0 +1,47 @@
+package dto
+
+type CreateTableAreaRequest struct {
+	Name      string `json:"name" binding:"required"`
+	SortOrder int    `json:"sort_order"`
+}
+
+type UpdateTableAreaRequest struct {
+	Name      *string `json:"name"`
+	SortOrder *int    `json:"sort_order"`
+}
+
+// CreateTableRequest places a table on the floor plan of its area; Shape is
+// square, round or rect.
+type CreateTableRequest struct {
+	IdTableArea string `json:"id_table_area" binding:"required"`
+	Name        string `json:"name" binding:"required"`
+	Seats       int    `json:"seats" binding:"min=0"`
+	Shape       string `json:"shape"`
+	PosX        int    `json:"pos_x" binding:"min=0"`
+	PosY        int    `json:"pos_y" binding:"min=0"`
+	Width       int    `json:"width" binding:"min=0"`
+	Height      int    `json:"height" binding:"min=0"`
+	IsActive    *bool  `json:"is_active"`
+}
+
+type UpdateTableRequest struct {
+	IdTableArea *string `json:"id_table_area"`
+	Name        *string `json:"name"`
+	Seats       *int    `json:"seats" binding:"omitempty,min=0"`
+	Shape       *string `json:"shape"`
+	PosX        *int    `json:"pos_x" binding:"omitempty,min=0"`
+	PosY        *int    `json:"pos_y" binding:"omitempty,min=0"`
+	Width       *int    `json:"width" binding:"omitempty,min=0"`
+	Height      *int    `json:"height" binding:"omitempty,min=0"`
+	IsActive    *bool   `json:"is_active"`
+}
+
+// MoveTableRequest moves the orders of a table to the free table To.
+type MoveTableRequest struct {
+	To string `json:"to" binding:"required"`
+}
+
+// MergeTableRequest serves a table together with the table Into.
+type MergeTableRequest struct {
+	Into string `json:"into" binding:"required"`
+}
@@ -1,13 +1,44 @@
 package dto
 
+import "faizalmaulana/lsp/models/entity"
+
+// TransactionItemRequest is one line of a sale. GiftCard is the code of the
+// card a gift card item tops up.
 type TransactionItemRequest struct {
-	IdItem   string `json:"id_item" binding:"required"`
-	Quantity int    `json:"quantity" binding:"required,min=1"`
+	IdItem    string           `json:"id_item" binding:"required"`
+	Quantity  int              `json:"quantity" binding:"required,min=1"`
+	Modifiers []string         `json:"modifiers"`
+	Discount  *DiscountRequest `json:"discount"`
+	GiftCard  string           `json:"gift_card"`
+}
+
+// DiscountRequest is a manual discount; Value is a percentage or an amount
+// depending on Kind.
+type DiscountRequest struct {
+	Kind   string  `json:"kind" binding:"required"`
+	Value  float64 `json:"value" binding:"required"`
+	Reason string  `json:"reason"`
+}
+
+type PaymentRequest struct {
+	Method    string       `json:"method" binding:"required"`
+	Amount    entity.Money `json:"amount" binding:"required"`
+	Reference string       `json:"reference"`
+	Pin       string       `json:"pin"`
+	Gateway   bool         `json:"gateway"`
 }
 
 type CreateTransactionRequest struct {
 	BuyerContact string                   `json:"buyer_contact"`
+	Customer     *CheckoutCustomerRequest `json:"customer"`
 	Items        []TransactionItemRequest `json:"items" binding:"required"`
+	Discount     *DiscountRequest         `json:"discount"`
+	VoucherCode  string                   `json:"voucher_code"`
+	Payments     []PaymentRequest         `json:"payments"`
+}
+
+type VoidTransactionRequest struct {
+	Reason string `json:"reason" binding:"required"`
 }
 
 type UpdateTransactionRequest struct {
@@ -15,9 +46,42 @@ type UpdateTransactionRequest struct {
 }
 
 type TransactionItemDetail struct {
-	IdItem   string  `json:"id_item"`
-	ItemName string  `json:"item_name"`
-	ImageUrl string  `json:"image_url"`
-	Quantity int     `json:"quantity"`
-	Price    float64 `json:"price"`
+	IdPivot    string                     `json:"id_pivot"`
+	IdItem     string                     `json:"id_item"`
+	ItemName   string                     `json:"item_name"`
+	ImageUrl   string                     `json:"image_url"`
+	Quantity   int                        `json:"quantity"`
+	BasePrice  entity.Money               `json:"base_price"`
+	Price      entity.Money               `json:"price"`
+	Discount   entity.Money               `json:"discount"`
+	TaxRate    float64                    `json:"tax_rate"`
+	Tax        entity.Money               `json:"tax"`
+	Service    entity.Money               `json:"service_charge"`
+	LineTotal  entity.Money               `json:"line_total"`
+	Modifiers  []TransactionItemModifier  `json:"modifiers"`
+	Components []TransactionItemComponent `json:"components"`
+	Discounts  []TransactionItemDiscount  `json:"discounts"`
+}
+
+type TransactionItemDiscount struct {
+	IdPromotion string       `json:"id_promotion"`
+	IdVoucher   string       `json:"id_voucher"`
+	Source      string       `json:"source"`
+	Name        string       `json:"name"`
+	Reason      string       `json:"reason"`
+	Amount      entity.Money `json:"amount"`
+}
+
+type TransactionItemModifier struct {
+	IdModifierOption string       `json:"id_modifier_option"`
+	GroupName        string       `json:"group_name"`
+	OptionName       string       `json:"option_name"`
+	PriceDelta       entity.Money `json:"price_delta"`
+}
+
+type TransactionItemComponent struct {
+	IdItem   string       `json:"id_item"`
+	ItemName string       `json:"item_name"`
+	Quantity int          `json:"quantity"`
+	Revenue  entity.Money `json:"revenue"`
 }
@@ -0,0 +1,43 @@
+package dto
+
+import "time"
+
+type CreateVoucherRequest struct {
+	Code               string     `json:"code" binding:"required"`
+	IdPromotion        string     `json:"id_promotion" binding:"required"`
+	StartsAt           *time.Time `json:"starts_at"`
+	EndsAt             *time.Time `json:"ends_at"`
+	MaxUses            int        `json:"max_uses"`
+	MaxUsesPerCustomer int        `json:"max_uses_per_customer"`
+	IsActive           *bool      `json:"is_active"`
+}
+
+// UpdateVoucherRequest changes the fields that are present. starts_at and
+// ends_at are cleared with clear_starts_at and clear_ends_at.
+type UpdateVoucherRequest struct {
+	StartsAt           *time.Time `json:"starts_at"`
+	EndsAt             *time.Time `json:"ends_at"`
+	ClearStartsAt      bool       `json:"clear_starts_at"`
+	ClearEndsAt        bool       `json:"clear_ends_at"`
+	MaxUses            *int       `json:"max_uses"`
+	MaxUsesPerCustomer *int       `json:"max_uses_per_customer"`
+	IsActive           *bool      `json:"is_active"`
+}
+
+type GenerateVouchersRequest struct {
+	IdPromotion        string     `json:"id_promotion" binding:"required"`
+	Count              int        `json:"count" binding:"required,min=1"`
+	Prefix             string     `json:"prefix"`
+	Length             int        `json:"length"`
+	StartsAt           *time.Time `json:"starts_at"`
+	EndsAt             *time.Time `json:"ends_at"`
+	MaxUsesPerCustomer int        `json:"max_uses_per_customer"`
+}
+
+// ValidateVoucherRequest previews a voucher; with items it also prices the
+// basket the way checkout would.
+type ValidateVoucherRequest struct {
+	Code         string                   `json:"code" binding:"required"`
+	BuyerContact string                   `json:"buyer_contact"`
+	Items        []TransactionItemRequest `json:"items"`
+}
@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"faizalmaulana/lsp/conf"
+	"faizalmaulana/lsp/helper"
+	"faizalmaulana/lsp/http/dto"
+	"faizalmaulana/lsp/http/middleware"
+	"faizalmaulana/lsp/http/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type BundlesHandler struct {
+	cfg     *conf.Config
+	bundles services.BundlesService
+	items   services.ItemsService
+}
+
+func NewBundlesHandler(cfg *conf.Config, bundles services.BundlesService, items services.ItemsService) *BundlesHandler {
+	return &BundlesHandler{cfg: cfg, bundles: bundles, items: items}
+}
+
+func (h *BundlesHandler) Register(rr *gin.RouterGroup) {
+	rr.GET("/items/:id/components", h.get)
+	rr.PUT("/items/:id/components", middleware.JWTMiddleware(h.cfg), h.set)
+}
+
+func (h *BundlesHandler) get(c *gin.Context) {
+	id := c.Param("id")
+	if _, err := h.items.GetByID(id); err != nil {
+		c.JSON(http.StatusNotFound, helper.NotFoundResponse("item not found"))
+		return
+	}
+	comps, err := h.bundles.GetComponents(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, helper.InternalErrorResponse("failed to list components"))
+		return
+	}
+	h.respond(c, id, comps, "OK")
+}
+
+func (h *BundlesHandler) set(c *gin.Context) {
+	id := c.Param("id")
+	if _, err := h.items.GetByID(id); err != nil {
+		c.JSON(http.StatusNotFound, helper.NotFoundResponse("item not found"))
+		return
+	}
+	var req dto.SetBundleComponentsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, helper.BadRequestResponse(err.Error()))
+		return
+	}
+	in := make([]services.BundleComponentInput, 0, len(req.Components))
+	for _, rc := range req.Components {
+		in = append(in, services.BundleComponentInput{IdItem: rc.IdItem, Quantity: rc.Quantity})
+	}
+	comps, err := h.bundles.SetComponents(id, in)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidBundle) {
+			c.JSON(http.StatusBadRequest, helper.BadRequestResponse(err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, helper.InternalErrorResponse("failed to save components"))
+		return
+	}
+	h.respond(c, id, comps, "updated")
+}
+
+// respond reloads the item so the kind reflects the saved components.
+func (h *BundlesHandler) respond(c *gin.Context, id string, comps []services.BundleComponent, message string) {
+	it, err := h.items.GetByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, helper.NotFoundResponse("item not found"))
+		return
+	}
+	resp := dto.BundleResponse{IdItem: it.IdItem, ItemName: it.ItemName, Kind: it.Kind, Price: it.Price, Components: make([]dto.BundleComponentDetail, 0, len(comps))}
+	for _, bc := range comps {
+		resp.ListPrice += bc.Price.Mul(bc.Quantity)
+		resp.Components = append(resp.Components, dto.BundleComponentDetail{IdItem: bc.IdItem, ItemName: bc.ItemName, Quantity: bc.Quantity, UnitPrice: bc.Price})
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse(message, resp))
+}
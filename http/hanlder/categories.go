@@ -0,0 +1,201 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"faizalmaulana/lsp/conf"
+	"faizalmaulana/lsp/helper"
+	"faizalmaulana/lsp/http/dto"
+	"faizalmaulana/lsp/http/middleware"
+	"faizalmaulana/lsp/http/services"
+	"faizalmaulana/lsp/models/entity"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CategoriesHandler struct {
+	cfg        *conf.Config
+	categories services.CategoriesService
+	items      services.ItemsService
+}
+
+func NewCategoriesHandler(cfg *conf.Config, categories services.CategoriesService, items services.ItemsService) *CategoriesHandler {
+	return &CategoriesHandler{cfg: cfg, categories: categories, items: items}
+}
+
+func (h *CategoriesHandler) Register(rr *gin.RouterGroup) {
+	rg := rr.Group("/categories")
+	rg.GET("", h.list)
+	rg.GET(":id", h.get)
+	rg.GET(":id/items", h.listItems)
+	rg.POST("", middleware.JWTMiddleware(h.cfg), h.create)
+	rg.PUT(":id", middleware.JWTMiddleware(h.cfg), h.update)
+	rg.DELETE(":id", middleware.JWTMiddleware(h.cfg), h.delete)
+}
+
+func (h *CategoriesHandler) list(c *gin.Context) {
+	activeOnly := c.Query("active") == "true"
+	out, err := h.categories.GetAll(activeOnly)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, helper.InternalErrorResponse("failed to list categories"))
+		return
+	}
+	if c.Query("tree") == "true" {
+		c.JSON(http.StatusOK, helper.SuccessResponse("OK", buildCategoryTree(out)))
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("OK", out))
+}
+
+// buildCategoryTree nests categories under their parent. Categories whose
+// parent is missing (deleted or filtered out as inactive) become roots.
+func buildCategoryTree(list []entity.Categories) []dto.CategoryNode {
+	known := map[string]bool{}
+	for _, cat := range list {
+		known[cat.IdCategory] = true
+	}
+	children := map[string][]entity.Categories{}
+	for _, cat := range list {
+		parent := cat.IdParent
+		if !known[parent] {
+			parent = ""
+		}
+		children[parent] = append(children[parent], cat)
+	}
+	var build func(parent string) []dto.CategoryNode
+	build = func(parent string) []dto.CategoryNode {
+		nodes := make([]dto.CategoryNode, 0, len(children[parent]))
+		for _, cat := range children[parent] {
+			nodes = append(nodes, dto.CategoryNode{Categories: cat, Children: build(cat.IdCategory)})
+		}
+		return nodes
+	}
+	return build("")
+}
+
+func (h *CategoriesHandler) get(c *gin.Context) {
+	cat, err := h.categories.GetByID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, helper.NotFoundResponse("category not found"))
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("OK", cat))
+}
+
+func (h *CategoriesHandler) listItems(c *gin.Context) {
+	id := c.Param("id")
+	if _, err := h.categories.GetByID(id); err != nil {
+		c.JSON(http.StatusNotFound, helper.NotFoundResponse("category not found"))
+		return
+	}
+	ids, err := h.categories.Descendants(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, helper.InternalErrorResponse("failed to list items"))
+		return
+	}
+	count, page := pagination(c)
+	out, err := h.items.GetAllByCategories(count, page, ids)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, helper.InternalErrorResponse("failed to list items"))
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("OK", out))
+}
+
+func (h *CategoriesHandler) create(c *gin.Context) {
+	var req dto.CreateCategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, helper.BadRequestResponse(err.Error()))
+		return
+	}
+	cat := &entity.Categories{
+		IdCategory: helper.Uuid(),
+		IdParent:   req.IdParent,
+		Name:       req.Name,
+		SortOrder:  req.SortOrder,
+		Color:      req.Color,
+		IdImage:    req.IdImage,
+		IsActive:   true,
+		TaxRate:    req.TaxRate,
+	}
+	if req.IsActive != nil {
+		cat.IsActive = *req.IsActive
+	}
+	saved, err := h.categories.Create(cat)
+	if err != nil {
+		h.writeError(c, err, "failed to create category")
+		return
+	}
+	c.JSON(http.StatusCreated, helper.SuccessResponse("created", saved))
+}
+
+func (h *CategoriesHandler) update(c *gin.Context) {
+	id := c.Param("id")
+	var req dto.UpdateCategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, helper.BadRequestResponse(err.Error()))
+		return
+	}
+	existing, err := h.categories.GetByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, helper.NotFoundResponse("category not found"))
+		return
+	}
+	if req.Name != nil {
+		existing.Name = *req.Name
+	}
+	if req.IdParent != nil {
+		existing.IdParent = *req.IdParent
+	}
+	if req.SortOrder != nil {
+		existing.SortOrder = *req.SortOrder
+	}
+	if req.Color != nil {
+		existing.Color = *req.Color
+	}
+	if req.IdImage != nil {
+		existing.IdImage = *req.IdImage
+	}
+	if req.IsActive != nil {
+		existing.IsActive = *req.IsActive
+	}
+	if req.TaxRate != nil {
+		existing.TaxRate = req.TaxRate
+	}
+	if req.ClearTaxRate {
+		existing.TaxRate = nil
+	}
+	updated, err := h.categories.Update(id, existing)
+	if err != nil {
+		h.writeError(c, err, "failed to update category")
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("updated", updated))
+}
+
+func (h *CategoriesHandler) delete(c *gin.Context) {
+	id := c.Param("id")
+	if _, err := h.categories.GetByID(id); err != nil {
+		c.JSON(http.StatusNotFound, helper.NotFoundResponse("category not found"))
+		return
+	}
+	if err := h.categories.Delete(id); err != nil {
+		h.writeError(c, err, "failed to delete category")
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("deleted", gin.H{"id": id}))
+}
+
+func (h *CategoriesHandler) writeError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrCategoryNotFound):
+		c.JSON(http.StatusBadRequest, helper.BadRequestResponse("parent category not found"))
+	case errors.Is(err, services.ErrCategoryCycle), errors.Is(err, services.ErrCategoryHasChildren), errors.Is(err, services.ErrCategoryNameEmpty):
+		c.JSON(http.StatusBadRequest, helper.BadRequestResponse(err.Error()))
+	case errors.Is(err, services.ErrCategoryInUse):
+		c.JSON(http.StatusConflict, helper.ErrorResponse("CONFLICT", err.Error()))
+	default:
+		c.JSON(http.StatusInternalServerError, helper.InternalErrorResponse(fallback))
+	}
+}
@@ -0,0 +1,162 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"faizalmaulana/lsp/conf"
+	"faizalmaulana/lsp/helper"
+	"faizalmaulana/lsp/http/dto"
+	"faizalmaulana/lsp/http/middleware"
+	"faizalmaulana/lsp/http/services"
+	"faizalmaulana/lsp/models/entity"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CustomersHandler struct {
+	cfg       *conf.Config
+	customers services.CustomersService
+}
+
+func NewCustomersHandler(cfg *conf.Config, customers services.CustomersService) *CustomersHandler {
+	return &CustomersHandler{cfg: cfg, customers: customers}
+}
+
+// Register keeps the directory behind a login since it holds personal data;
+// removing a customer takes a manager.
+func (h *CustomersHandler) Register(rr *gin.RouterGroup) {
+	rg := rr.Group("/customers")
+	rg.GET("", middleware.JWTMiddleware(h.cfg), h.list)
+	rg.GET(":id", middleware.JWTMiddleware(h.cfg), h.get)
+	rg.GET(":id/transactions", middleware.JWTMiddleware(h.cfg), h.transactions)
+	rg.POST("", middleware.JWTMiddleware(h.cfg), h.create)
+	rg.PUT(":id", middleware.JWTMiddleware(h.cfg), h.update)
+	rg.DELETE(":id", middleware.JWTMiddleware(h.cfg), middleware.RequireRole(entity.RoleAdmin, entity.RoleManager), h.delete)
+}
+
+func (h *CustomersHandler) list(c *gin.Context) {
+	count, page := pagination(c)
+	out, err := h.customers.GetAll(c.Query("search"), count, page)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, helper.InternalErrorResponse("failed to list customers"))
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("OK", out))
+}
+
+func (h *CustomersHandler) get(c *gin.Context) {
+	cu, err := h.customers.GetByID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, helper.NotFoundResponse("customer not found"))
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("OK", cu))
+}
+
+// transactions is the customer's purchase history with their lifetime value
+// and visit count.
+func (h *CustomersHandler) transactions(c *gin.Context) {
+	count, page := pagination(c)
+	hist, err := h.customers.History(c.Param("id"), count, page)
+	if err != nil {
+		h.writeError(c, err, "failed to load customer history")
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("OK", dto.CustomerHistoryResponse{
+		Customer:      hist.Customer,
+		Visits:        hist.Visits,
+		Sales:         hist.Sales,
+		Refunded:      hist.Refunded,
+		LifetimeValue: hist.LifetimeValue,
+		FirstVisit:    hist.FirstVisit,
+		LastVisit:     hist.LastVisit,
+		Page:          page,
+		Count:         len(hist.Transactions),
+		Transactions:  hist.Transactions,
+	}))
+}
+
+func (h *CustomersHandler) create(c *gin.Context) {
+	var req dto.CreateCustomerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, helper.BadRequestResponse(err.Error()))
+		return
+	}
+	saved, err := h.customers.Create(&entity.Customers{
+		Name:             req.Name,
+		Phone:            req.Phone,
+		Email:            req.Email,
+		Notes:            req.Notes,
+		MarketingConsent: req.MarketingConsent,
+	})
+	if err != nil {
+		h.writeError(c, err, "failed to create customer")
+		return
+	}
+	c.JSON(http.StatusCreated, helper.SuccessResponse("created", saved))
+}
+
+func (h *CustomersHandler) update(c *gin.Context) {
+	id := c.Param("id")
+	var req dto.UpdateCustomerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, helper.BadRequestResponse(err.Error()))
+		return
+	}
+	cu, err := h.customers.GetByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, helper.NotFoundResponse("customer not found"))
+		return
+	}
+	if req.Name != nil {
+		cu.Name = *req.Name
+	}
+	if req.Phone != nil {
+		cu.Phone = *req.Phone
+	}
+	if req.Email != nil {
+		cu.Email = *req.Email
+	}
+	if req.Notes != nil {
+		cu.Notes = *req.Notes
+	}
+	if req.MarketingConsent != nil {
+		cu.MarketingConsent = *req.MarketingConsent
+	}
+	saved, err := h.customers.Update(id, cu)
+	if err != nil {
+		h.writeError(c, err, "failed to update customer")
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("updated", saved))
+}
+
+// delete hides the customer from the directory. Their sales keep the link
+// and the phone in buyer_contact.
+func (h *CustomersHandler) delete(c *gin.Context) {
+	id := c.Param("id")
+	if err := h.customers.Delete(id); err != nil {
+		h.writeError(c, err, "failed to delete customer")
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("deleted", gin.H{"id": id}))
+}
+
+func (h *CustomersHandler) writeError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrCustomerNotFound):
+		c.JSON(http.StatusNotFound, helper.NotFoundResponse(err.Error()))
+	case errors.Is(err, services.ErrInvalidCustomer):
+		c.JSON(http.StatusBadRequest, helper.BadRequestResponse(err.Error()))
+	default:
+		c.JSON(http.StatusInternalServerError, helper.InternalErrorResponse(fallback))
+	}
+}
+
+func checkoutCustomer(req *dto.CheckoutCustomerRequest) *services.CustomerInput {
+	if req == nil {
+		return nil
+	}
+	return &services.CustomerInput{IdCustomer: req.IdCustomer, Phone: req.Phone, Name: req.Name, Email: req.Email}
+}
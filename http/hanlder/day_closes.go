@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"faizalmaulana/lsp/conf"
+	"faizalmaulana/lsp/helper"
+	"faizalmaulana/lsp/http/dto"
+	"faizalmaulana/lsp/http/middleware"
+	"faizalmaulana/lsp/http/services"
+	"faizalmaulana/lsp/models/entity"
+
+	"github.com/gin-gonic/gin"
+)
+
+type DayClosesHandler struct {
+	cfg    *conf.Config
+	closes services.DayClosesService
+}
+
+func NewDayClosesHandler(cfg *conf.Config, closes services.DayClosesService) *DayClosesHandler {
+	return &DayClosesHandler{cfg: cfg, closes: closes}
+}
+
+// Register keeps closing the day and its reports to managers.
+func (h *DayClosesHandler) Register(rr *gin.RouterGroup) {
+	rg := rr.Group("/day-closes")
+	rg.GET("", middleware.JWTMiddleware(h.cfg), middleware.RequireRole(entity.RoleAdmin, entity.RoleManager), h.list)
+	rg.GET("preview", middleware.JWTMiddleware(h.cfg), middleware.RequireRole(entity.RoleAdmin, entity.RoleManager), h.preview)
+	rg.POST("", middleware.JWTMiddleware(h.cfg), middleware.RequireRole(entity.RoleAdmin, entity.RoleManager), h.close)
+	rg.GET(":id", middleware.JWTMiddleware(h.cfg), middleware.RequireRole(entity.RoleAdmin, entity.RoleManager), h.get)
+}
+
+// businessDate parses a YYYY-MM-DD date, today when empty.
+func businessDate(date string) (time.Time, error) {
+	if date == "" {
+		return time.Now(), nil
+	}
+	day, err := time.ParseInLocation("2006-01-02", date, time.Local)
+	if err != nil {
+		return time.Time{}, errors.New("date must be YYYY-MM-DD")
+	}
+	return day, nil
+}
+
+func (h *DayClosesHandler) list(c *gin.Context) {
+	count, page := pagination(c)
+	out, err := h.closes.GetAll(count, page)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, helper.InternalErrorResponse("failed to list day closes"))
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("OK", out))
+}
+
+func (h *DayClosesHandler) preview(c *gin.Context) {
+	date, err := businessDate(c.Query("date"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, helper.BadRequestResponse(err.Error()))
+		return
+	}
+	report, err := h.closes.Preview(date)
+	if err != nil {
+		writeDayCloseError(c, err, "failed to build day report")
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("OK", report))
+}
+
+func (h *DayClosesHandler) close(c *gin.Context) {
+	var req dto.CloseDayRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, helper.BadRequestResponse(err.Error()))
+		return
+	}
+	date, err := businessDate(req.Date)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, helper.BadRequestResponse(err.Error()))
+		return
+	}
+	d, err := h.closes.Close(date, claimString(c, "sub"))
+	if err != nil {
+		writeDayCloseError(c, err, "failed to close day")
+		return
+	}
+	c.JSON(http.StatusCreated, helper.SuccessResponse("created", gin.H{"close": d.Close, "report": d.Report}))
+}
+
+func (h *DayClosesHandler) get(c *gin.Context) {
+	d, err := h.closes.Get(c.Param("id"))
+	if err != nil {
+		writeDayCloseError(c, err, "failed to load day close")
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("OK", gin.H{"close": d.Close, "report": d.Report}))
+}
+
+func writeDayCloseError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrDayCloseNotFound):
+		c.JSON(http.StatusNotFound, helper.NotFoundResponse(err.Error()))
+	case errors.Is(err, services.ErrInvalidDayClose):
+		c.JSON(http.StatusBadRequest, helper.BadRequestResponse(err.Error()))
+	case errors.Is(err, services.ErrDayClosed), errors.Is(err, services.ErrDayNotReady):
+		c.JSON(http.StatusConflict, helper.ErrorResponse("CONFLICT", err.Error()))
+	default:
+		c.JSON(http.StatusInternalServerError, helper.InternalErrorResponse(fallback))
+	}
+}
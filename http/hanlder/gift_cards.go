@@ -0,0 +1,159 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"faizalmaulana/lsp/conf"
+	"faizalmaulana/lsp/helper"
+	"faizalmaulana/lsp/http/dto"
+	"faizalmaulana/lsp/http/middleware"
+	"faizalmaulana/lsp/http/services"
+	"faizalmaulana/lsp/models/entity"
+
+	"github.com/gin-gonic/gin"
+)
+
+type GiftCardsHandler struct {
+	cfg       *conf.Config
+	giftCards services.GiftCardsService
+}
+
+func NewGiftCardsHandler(cfg *conf.Config, giftCards services.GiftCardsService) *GiftCardsHandler {
+	return &GiftCardsHandler{cfg: cfg, giftCards: giftCards}
+}
+
+// Register leaves the balance check open to card holders, who prove the
+// card is theirs with its PIN; managing accounts takes a manager.
+func (h *GiftCardsHandler) Register(rr *gin.RouterGroup) {
+	rg := rr.Group("/gift-cards")
+	rg.POST("balance", h.balance)
+	rg.GET("", middleware.JWTMiddleware(h.cfg), middleware.RequireRole(entity.RoleAdmin, entity.RoleManager), h.list)
+	rg.GET("liability", middleware.JWTMiddleware(h.cfg), middleware.RequireRole(entity.RoleAdmin, entity.RoleManager), h.liability)
+	rg.GET(":id", middleware.JWTMiddleware(h.cfg), middleware.RequireRole(entity.RoleAdmin, entity.RoleManager), h.get)
+	rg.POST("", middleware.JWTMiddleware(h.cfg), middleware.RequireRole(entity.RoleAdmin, entity.RoleManager), h.issue)
+	rg.POST(":id/adjust", middleware.JWTMiddleware(h.cfg), middleware.RequireRole(entity.RoleAdmin, entity.RoleManager), h.adjust)
+	rg.PUT(":id/status", middleware.JWTMiddleware(h.cfg), middleware.RequireRole(entity.RoleAdmin, entity.RoleManager), h.status)
+}
+
+// balance takes the code and PIN in the body so they stay out of access
+// logs.
+func (h *GiftCardsHandler) balance(c *gin.Context) {
+	var req dto.GiftCardBalanceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, helper.BadRequestResponse(err.Error()))
+		return
+	}
+	g, err := h.giftCards.Balance(req.Code, req.Pin)
+	if err != nil {
+		h.writeError(c, err, "failed to check balance")
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("OK", dto.GiftCardBalanceResponse{Code: g.Code, Kind: g.Kind, Balance: g.Balance, Status: g.Status}))
+}
+
+func (h *GiftCardsHandler) list(c *gin.Context) {
+	count, page := pagination(c)
+	out, err := h.giftCards.GetAll(c.Query("kind"), c.Query("id_customer"), count, page)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, helper.InternalErrorResponse("failed to list gift cards"))
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("OK", out))
+}
+
+func (h *GiftCardsHandler) get(c *gin.Context) {
+	count, page := pagination(c)
+	d, err := h.giftCards.GetByID(c.Param("id"), count, page)
+	if err != nil {
+		h.writeError(c, err, "failed to load gift card")
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("OK", d))
+}
+
+func (h *GiftCardsHandler) issue(c *gin.Context) {
+	var req dto.IssueGiftCardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, helper.BadRequestResponse(err.Error()))
+		return
+	}
+	issued, err := h.giftCards.Issue(services.GiftCardIssue{
+		Kind:       req.Kind,
+		Amount:     req.Amount,
+		IdCustomer: req.IdCustomer,
+		IdUser:     claimString(c, "sub"),
+		Note:       req.Note,
+	})
+	if err != nil {
+		h.writeError(c, err, "failed to issue gift card")
+		return
+	}
+	c.JSON(http.StatusCreated, helper.SuccessResponse("created", issued))
+}
+
+func (h *GiftCardsHandler) adjust(c *gin.Context) {
+	var req dto.AdjustGiftCardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, helper.BadRequestResponse(err.Error()))
+		return
+	}
+	g, err := h.giftCards.Adjust(c.Param("id"), req.Amount, claimString(c, "sub"), req.Note)
+	if err != nil {
+		h.writeError(c, err, "failed to adjust gift card")
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("updated", g))
+}
+
+func (h *GiftCardsHandler) status(c *gin.Context) {
+	var req dto.GiftCardStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, helper.BadRequestResponse(err.Error()))
+		return
+	}
+	g, err := h.giftCards.SetStatus(c.Param("id"), req.Status)
+	if err != nil {
+		h.writeError(c, err, "failed to update gift card")
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("updated", g))
+}
+
+// liability is what is owed on gift cards and store credit at the end of
+// date (YYYY-MM-DD), or now.
+func (h *GiftCardsHandler) liability(c *gin.Context) {
+	at := time.Now()
+	if date := c.Query("date"); date != "" {
+		day, err := time.ParseInLocation("2006-01-02", date, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, helper.BadRequestResponse("date must be YYYY-MM-DD"))
+			return
+		}
+		at = day.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+	rows, err := h.giftCards.Liability(at)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, helper.InternalErrorResponse("failed to compute liability"))
+		return
+	}
+	out := dto.GiftCardLiabilityResponse{At: at.Format(time.RFC3339), ByKind: make([]dto.GiftCardLiabilityEntry, 0, len(rows))}
+	for _, r := range rows {
+		out.Total += r.Balance
+		out.Accounts += r.Accounts
+		out.ByKind = append(out.ByKind, dto.GiftCardLiabilityEntry{Kind: r.Kind, Accounts: r.Accounts, Balance: r.Balance})
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("OK", out))
+}
+
+func (h *GiftCardsHandler) writeError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrGiftCardNotFound), errors.Is(err, services.ErrCustomerNotFound):
+		c.JSON(http.StatusNotFound, helper.NotFoundResponse(err.Error()))
+	case errors.Is(err, services.ErrInvalidGiftCard), errors.Is(err, services.ErrGiftCardUnavailable), errors.Is(err, services.ErrInsufficientBalance):
+		c.JSON(http.StatusBadRequest, helper.BadRequestResponse(err.Error()))
+	default:
+		c.JSON(http.StatusInternalServerError, helper.InternalErrorResponse(fallback))
+	}
+}
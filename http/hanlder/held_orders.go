@@ -0,0 +1,273 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"faizalmaulana/lsp/conf"
+	"faizalmaulana/lsp/helper"
+	"faizalmaulana/lsp/http/dto"
+	"faizalmaulana/lsp/http/middleware"
+	"faizalmaulana/lsp/http/services"
+	"faizalmaulana/lsp/models/entity"
+
+	"github.com/gin-gonic/gin"
+)
+
+type HeldOrdersHandler struct {
+	cfg  *conf.Config
+	held services.HeldOrdersService
+}
+
+func NewHeldOrdersHandler(cfg *conf.Config, held services.HeldOrdersService) *HeldOrdersHandler {
+	return &HeldOrdersHandler{cfg: cfg, held: held}
+}
+
+// Register lets any signed-in cashier park, change and ring up orders, so a
+// tab can be settled by whoever is at the till.
+func (h *HeldOrdersHandler) Register(rr *gin.RouterGroup) {
+	rg := rr.Group("/held-orders")
+	rg.GET("", middleware.JWTMiddleware(h.cfg), h.list)
+	rg.POST("", middleware.JWTMiddleware(h.cfg), h.create)
+	rg.GET(":id", middleware.JWTMiddleware(h.cfg), h.get)
+	rg.PUT(":id", middleware.JWTMiddleware(h.cfg), h.update)
+	rg.DELETE(":id", middleware.JWTMiddleware(h.cfg), h.cancel)
+	rg.POST(":id/lines", middleware.JWTMiddleware(h.cfg), h.addLine)
+	rg.PUT(":id/lines/:line", middleware.JWTMiddleware(h.cfg), h.updateLine)
+	rg.DELETE(":id/lines/:line", middleware.JWTMiddleware(h.cfg), h.removeLine)
+	rg.POST(":id/quote", middleware.JWTMiddleware(h.cfg), h.quote)
+	rg.POST(":id/checkout", middleware.JWTMiddleware(h.cfg), h.checkout)
+	rg.POST(":id/split/equal", middleware.JWTMiddleware(h.cfg), h.splitEqually)
+	rg.POST(":id/split/items", middleware.JWTMiddleware(h.cfg), h.splitItems)
+}
+
+// list shows the open orders unless ?status= asks for others; all shows
+// every status.
+func (h *HeldOrdersHandler) list(c *gin.Context) {
+	count, page := pagination(c)
+	status := c.DefaultQuery("status", entity.HeldOrderStatusOpen)
+	if status == "all" {
+		status = ""
+	}
+	out, err := h.held.GetAll(c.Query("terminal"), status, count, page)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, helper.InternalErrorResponse("failed to list held orders"))
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("OK", out))
+}
+
+func (h *HeldOrdersHandler) create(c *gin.Context) {
+	var req dto.CreateHeldOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, helper.BadRequestResponse(err.Error()))
+		return
+	}
+	o, err := h.held.Create(claimString(c, "sub"), services.HeldOrderInput{
+		Terminal:     req.Terminal,
+		Label:        req.Label,
+		Note:         req.Note,
+		IdCustomer:   req.IdCustomer,
+		BuyerContact: req.BuyerContact,
+		IdTable:      req.IdTable,
+		Guests:       req.Guests,
+	}, checkoutLines(req.Items))
+	if err != nil {
+		writeHeldOrderError(c, err, "failed to hold order")
+		return
+	}
+	c.JSON(http.StatusCreated, helper.SuccessResponse("created", o))
+}
+
+func (h *HeldOrdersHandler) get(c *gin.Context) {
+	o, err := h.held.Get(c.Param("id"))
+	if err != nil {
+		writeHeldOrderError(c, err, "failed to load held order")
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("OK", o))
+}
+
+func (h *HeldOrdersHandler) update(c *gin.Context) {
+	var req dto.UpdateHeldOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, helper.BadRequestResponse(err.Error()))
+		return
+	}
+	o, err := h.held.Update(c.Param("id"), services.HeldOrderUpdate{
+		Terminal:     req.Terminal,
+		Label:        req.Label,
+		Note:         req.Note,
+		Guests:       req.Guests,
+		IdCustomer:   req.IdCustomer,
+		BuyerContact: req.BuyerContact,
+	})
+	if err != nil {
+		writeHeldOrderError(c, err, "failed to update held order")
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("updated", o))
+}
+
+func (h *HeldOrdersHandler) cancel(c *gin.Context) {
+	o, err := h.held.Cancel(c.Param("id"), claimString(c, "sub"))
+	if err != nil {
+		writeHeldOrderError(c, err, "failed to cancel held order")
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("cancelled", o))
+}
+
+func (h *HeldOrdersHandler) addLine(c *gin.Context) {
+	var req dto.TransactionItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, helper.BadRequestResponse(err.Error()))
+		return
+	}
+	o, err := h.held.AddLine(c.Param("id"), checkoutLines([]dto.TransactionItemRequest{req})[0])
+	if err != nil {
+		writeHeldOrderError(c, err, "failed to add line")
+		return
+	}
+	c.JSON(http.StatusCreated, helper.SuccessResponse("created", o))
+}
+
+func (h *HeldOrdersHandler) updateLine(c *gin.Context) {
+	var req dto.TransactionItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, helper.BadRequestResponse(err.Error()))
+		return
+	}
+	o, err := h.held.UpdateLine(c.Param("id"), c.Param("line"), checkoutLines([]dto.TransactionItemRequest{req})[0])
+	if err != nil {
+		writeHeldOrderError(c, err, "failed to update line")
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("updated", o))
+}
+
+func (h *HeldOrdersHandler) removeLine(c *gin.Context) {
+	o, err := h.held.RemoveLine(c.Param("id"), c.Param("line"))
+	if err != nil {
+		writeHeldOrderError(c, err, "failed to remove line")
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("deleted", o))
+}
+
+// bindHeldOrderCheckout reads the optional payment details of a quote or a
+// checkout.
+func bindHeldOrderCheckout(c *gin.Context) (services.HeldOrderCheckout, bool) {
+	var req dto.CheckoutHeldOrderRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, helper.BadRequestResponse(err.Error()))
+			return services.HeldOrderCheckout{}, false
+		}
+	}
+	return services.HeldOrderCheckout{
+		IdUser:       claimString(c, "sub"),
+		Role:         claimString(c, "role"),
+		BuyerContact: req.BuyerContact,
+		Customer:     checkoutCustomer(req.Customer),
+		Discount:     manualDiscount(req.Discount),
+		Voucher:      req.VoucherCode,
+		Payments:     paymentInputs(req.Payments),
+	}, true
+}
+
+// quote prices the order as it would be checked out now.
+func (h *HeldOrdersHandler) quote(c *gin.Context) {
+	req, ok := bindHeldOrderCheckout(c)
+	if !ok {
+		return
+	}
+	q, err := h.held.Quote(c.Param("id"), req)
+	if err != nil {
+		writeHeldOrderError(c, err, "failed to price held order")
+		return
+	}
+	details := make([]dto.TransactionItemDetail, 0, len(q.Lines))
+	for _, l := range q.Lines {
+		details = append(details, transactionItemDetail(services.TransactionLine{PivotItemsToTransaction: l}))
+	}
+	t := q.Transaction
+	c.JSON(http.StatusOK, helper.SuccessResponse("OK", gin.H{
+		"discount_total": t.DiscountTotal,
+		"subtotal":       t.Subtotal,
+		"service_charge": t.ServiceCharge,
+		"tax_total":      t.TaxTotal,
+		"total":          t.TotalPrice,
+		"items":          details,
+	}))
+}
+
+func (h *HeldOrdersHandler) checkout(c *gin.Context) {
+	req, ok := bindHeldOrderCheckout(c)
+	if !ok {
+		return
+	}
+	if req.IdUser == "" {
+		c.JSON(http.StatusUnauthorized, helper.UnauthorizedResponse())
+		return
+	}
+	res, err := h.held.Checkout(c.Param("id"), req)
+	if err != nil {
+		writeHeldOrderError(c, err, "failed to check out held order")
+		return
+	}
+	c.JSON(http.StatusCreated, helper.SuccessResponse("created", checkoutResponse(res)))
+}
+
+// splitEqually splits the bill into equal shares, each paid on its own.
+func (h *HeldOrdersHandler) splitEqually(c *gin.Context) {
+	var req dto.SplitEquallyRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, helper.BadRequestResponse(err.Error()))
+			return
+		}
+	}
+	out, err := h.held.SplitEqually(c.Param("id"), req.Parts)
+	if err != nil {
+		writeHeldOrderError(c, err, "failed to split held order")
+		return
+	}
+	c.JSON(http.StatusCreated, helper.SuccessResponse("created", out))
+}
+
+// splitItems moves lines of the order to orders of their own.
+func (h *HeldOrdersHandler) splitItems(c *gin.Context) {
+	var req dto.SplitItemsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, helper.BadRequestResponse(err.Error()))
+		return
+	}
+	parts := make([][]services.SplitLine, 0, len(req.Parts))
+	for _, p := range req.Parts {
+		lines := make([]services.SplitLine, 0, len(p))
+		for _, l := range p {
+			lines = append(lines, services.SplitLine{IdHeldOrderLine: l.IdHeldOrderLine, Quantity: l.Quantity})
+		}
+		parts = append(parts, lines)
+	}
+	out, err := h.held.SplitItems(c.Param("id"), parts)
+	if err != nil {
+		writeHeldOrderError(c, err, "failed to split held order")
+		return
+	}
+	c.JSON(http.StatusCreated, helper.SuccessResponse("created", out))
+}
+
+func writeHeldOrderError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrHeldOrderNotFound), errors.Is(err, services.ErrTableNotFound):
+		c.JSON(http.StatusNotFound, helper.NotFoundResponse(err.Error()))
+	case errors.Is(err, services.ErrInvalidHeldOrder), errors.Is(err, services.ErrInvalidTable):
+		c.JSON(http.StatusBadRequest, helper.BadRequestResponse(err.Error()))
+	case errors.Is(err, services.ErrHeldOrderClosed), errors.Is(err, services.ErrTableOccupied), errors.Is(err, services.ErrTableFree):
+		c.JSON(http.StatusConflict, helper.ErrorResponse("CONFLICT", err.Error()))
+	default:
+		writeCheckoutError(c, err, fallback)
+	}
+}
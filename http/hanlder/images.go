@@ -1,11 +1,15 @@
 package handler
 
 import (
+	"bytes"
+	"encoding/base64"
+	"errors"
 	"io"
 	"mime"
 	"net/http"
 	"os"
 	"path/filepath"
+	"time"
 
 	"faizalmaulana/lsp/conf"
 	"faizalmaulana/lsp/helper"
@@ -35,9 +39,28 @@ func (h *ImagesHandler) Register(rr *gin.RouterGroup) {
 	rg.DELETE(":id", middleware.JWTMiddleware(h.cfg), h.delete)
 }
 
+// limitBody caps the request body at n bytes plus room for the form or JSON
+// around the image, so an oversized upload is refused while it is read
+// instead of after it was buffered.
+func (h *ImagesHandler) limitBody(c *gin.Context, n int64) {
+	if h.cfg.ImageMaxBytes > 0 {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, n+64<<10)
+	}
+}
+
+func tooLarge(err error) bool {
+	var mbe *http.MaxBytesError
+	return errors.As(err, &mbe)
+}
+
 func (h *ImagesHandler) uploadMultipart(c *gin.Context) {
+	h.limitBody(c, h.cfg.ImageMaxBytes)
 	file, err := c.FormFile("file")
 	if err != nil {
+		if tooLarge(err) {
+			writeUploadError(c, services.ErrImageTooLarge)
+			return
+		}
 		c.JSON(http.StatusBadRequest, helper.BadRequestResponse("file is required"))
 		return
 	}
@@ -47,41 +70,84 @@ func (h *ImagesHandler) uploadMultipart(c *gin.Context) {
 		return
 	}
 	defer f.Close()
-	data, err := io.ReadAll(f)
+	var r io.Reader = f
+	if h.cfg.ImageMaxBytes > 0 {
+		// One byte over the limit is enough for the service to refuse it.
+		r = io.LimitReader(f, h.cfg.ImageMaxBytes+1)
+	}
+	data, err := io.ReadAll(r)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, helper.BadRequestResponse("cannot read file"))
 		return
 	}
 	id, stored, err := h.svc.UploadBlob(file.Filename, file.Header.Get("Content-Type"), data)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, helper.InternalErrorResponse("failed to upload"))
+		writeUploadError(c, err)
 		return
 	}
 	c.JSON(http.StatusCreated, helper.SuccessResponse("created", dto.UploadResponse{IdImage: id, FileName: stored}))
 }
 
 func (h *ImagesHandler) uploadBase64(c *gin.Context) {
+	h.limitBody(c, int64(base64.StdEncoding.EncodedLen(int(h.cfg.ImageMaxBytes))))
 	var req dto.UploadBase64Request
 	if err := c.ShouldBindJSON(&req); err != nil {
+		if tooLarge(err) {
+			writeUploadError(c, services.ErrImageTooLarge)
+			return
+		}
 		c.JSON(http.StatusBadRequest, helper.BadRequestResponse(err.Error()))
 		return
 	}
 	id, stored, err := h.svc.UploadBase64(req.FileName, req.ContentType, req.DataBase64)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, helper.InternalErrorResponse("failed to upload"))
+		writeUploadError(c, err)
 		return
 	}
 	c.JSON(http.StatusCreated, helper.SuccessResponse("created", dto.UploadResponse{IdImage: id, FileName: stored}))
 }
 
+// writeUploadError maps an image upload error; items with an image in their
+// body answer with it too.
+func writeUploadError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrImageTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, helper.ErrorResponse("REQUEST_ENTITY_TOO_LARGE", err.Error()))
+	case errors.Is(err, services.ErrInvalidImage), errors.Is(err, services.ErrImageDimensions):
+		c.JSON(http.StatusBadRequest, helper.BadRequestResponse(err.Error()))
+	default:
+		c.JSON(http.StatusInternalServerError, helper.InternalErrorResponse("failed to upload"))
+	}
+}
+
 func (h *ImagesHandler) downloadBlob(c *gin.Context) {
 	id := c.Param("id")
-	img, err := h.svc.GetBlob(id)
+	size := c.Query("size")
+	switch size {
+	case "", services.VariantOriginal, services.VariantMedium, services.VariantThumb:
+	default:
+		c.JSON(http.StatusBadRequest, helper.BadRequestResponse("size must be original, medium or thumb"))
+		return
+	}
+	img, err := h.svc.GetBlobVariant(id, size)
 	if err != nil {
 		c.JSON(http.StatusNotFound, helper.NotFoundResponse("image not found"))
 		return
 	}
-	c.Data(http.StatusOK, img.ContentType, img.Data)
+	serveImage(c, img.FileName, img.ContentType, helper.ChecksumETag(img.Checksum), img.Timestamp, img.Data)
+}
+
+// serveImage writes image bytes through http.ServeContent so conditional
+// requests (If-None-Match, If-Modified-Since) and Range are handled. Image
+// ids and stored file names never change content, so they are cached hard.
+func serveImage(c *gin.Context, name, contentType, etag string, modTime time.Time, data []byte) {
+	hdr := c.Writer.Header()
+	if contentType != "" {
+		hdr.Set("Content-Type", contentType)
+	}
+	hdr.Set("ETag", etag)
+	hdr.Set("Cache-Control", "public, max-age=31536000, immutable")
+	http.ServeContent(c.Writer, c.Request, name, modTime, bytes.NewReader(data))
 }
 
 func (h *ImagesHandler) downloadBase64(c *gin.Context) {
@@ -117,5 +183,9 @@ func (h *ImagesHandler) downloadBlobByName(c *gin.Context) {
 	if ct == "" {
 		ct = "application/octet-stream"
 	}
-	c.Data(http.StatusOK, ct, data)
+	var modTime time.Time
+	if fi, err := os.Stat(full); err == nil {
+		modTime = fi.ModTime()
+	}
+	serveImage(c, name, ct, helper.StrongETag(data), modTime, data)
 }
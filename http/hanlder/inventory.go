@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"faizalmaulana/lsp/conf"
+	"faizalmaulana/lsp/helper"
+	"faizalmaulana/lsp/http/middleware"
+	"faizalmaulana/lsp/http/services"
+	"faizalmaulana/lsp/models/entity"
+
+	"github.com/gin-gonic/gin"
+)
+
+type InventoryHandler struct {
+	cfg       *conf.Config
+	inventory services.InventoryService
+}
+
+func NewInventoryHandler(cfg *conf.Config, inventory services.InventoryService) *InventoryHandler {
+	return &InventoryHandler{cfg: cfg, inventory: inventory}
+}
+
+// Register shows stock and costs to managers.
+func (h *InventoryHandler) Register(rr *gin.RouterGroup) {
+	rg := rr.Group("/inventory")
+	rg.GET("", middleware.JWTMiddleware(h.cfg), middleware.RequireRole(entity.RoleAdmin, entity.RoleManager), h.levels)
+	rg.GET(":id", middleware.JWTMiddleware(h.cfg), middleware.RequireRole(entity.RoleAdmin, entity.RoleManager), h.get)
+	rg.GET(":id/ledger", middleware.JWTMiddleware(h.cfg), middleware.RequireRole(entity.RoleAdmin, entity.RoleManager), h.ledger)
+	rg.GET(":id/costs", middleware.JWTMiddleware(h.cfg), middleware.RequireRole(entity.RoleAdmin, entity.RoleManager), h.costs)
+}
+
+func (h *InventoryHandler) levels(c *gin.Context) {
+	count, page := pagination(c)
+	out, err := h.inventory.Levels(count, page)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, helper.InternalErrorResponse("failed to list stock"))
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("OK", out))
+}
+
+func (h *InventoryHandler) get(c *gin.Context) {
+	out, err := h.inventory.Get(c.Param("id"))
+	if err != nil {
+		writeInventoryError(c, err, "failed to load stock")
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("OK", out))
+}
+
+func (h *InventoryHandler) ledger(c *gin.Context) {
+	count, page := pagination(c)
+	out, err := h.inventory.Ledger(c.Param("id"), count, page)
+	if err != nil {
+		writeInventoryError(c, err, "failed to load stock ledger")
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("OK", out))
+}
+
+func (h *InventoryHandler) costs(c *gin.Context) {
+	count, page := pagination(c)
+	out, err := h.inventory.Costs(c.Param("id"), count, page)
+	if err != nil {
+		writeInventoryError(c, err, "failed to load cost history")
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("OK", out))
+}
+
+func writeInventoryError(c *gin.Context, err error, fallback string) {
+	if errors.Is(err, services.ErrStockNotFound) {
+		c.JSON(http.StatusNotFound, helper.NotFoundResponse(err.Error()))
+		return
+	}
+	c.JSON(http.StatusInternalServerError, helper.InternalErrorResponse(fallback))
+}
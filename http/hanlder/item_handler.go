@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
 	"faizalmaulana/lsp/conf"
 	"faizalmaulana/lsp/helper"
@@ -16,13 +17,14 @@ import (
 )
 
 type ItemsHandler struct {
-	cfg    *conf.Config
-	items  services.ItemsService
-	images services.ImagesService
+	cfg        *conf.Config
+	items      services.ItemsService
+	images     services.ImagesService
+	categories services.CategoriesService
 }
 
-func NewItemsHandler(cfg *conf.Config, items services.ItemsService, images services.ImagesService) *ItemsHandler {
-	return &ItemsHandler{cfg: cfg, items: items, images: images}
+func NewItemsHandler(cfg *conf.Config, items services.ItemsService, images services.ImagesService, categories services.CategoriesService) *ItemsHandler {
+	return &ItemsHandler{cfg: cfg, items: items, images: images, categories: categories}
 }
 
 func (h *ItemsHandler) Register(rr *gin.RouterGroup) {
@@ -35,19 +37,33 @@ func (h *ItemsHandler) Register(rr *gin.RouterGroup) {
 }
 
 func (h *ItemsHandler) list(c *gin.Context) {
-	count := 10
-	page := 1
-	if v := c.Query("count"); v != "" {
-		if n, err := strconv.Atoi(v); err == nil {
-			count = n
-		}
+	if h.notModified(c) {
+		return
 	}
-	if v := c.Query("page"); v != "" {
-		if n, err := strconv.Atoi(v); err == nil {
-			page = n
+	count, page := pagination(c)
+
+	var (
+		items []entity.Items
+		err   error
+	)
+	switch {
+	case c.Query("category") != "":
+		idCategory := c.Query("category")
+		if _, cerr := h.categories.GetByID(idCategory); cerr != nil {
+			c.JSON(http.StatusNotFound, helper.NotFoundResponse("category not found"))
+			return
+		}
+		ids, derr := h.categories.Descendants(idCategory)
+		if derr != nil {
+			c.JSON(http.StatusInternalServerError, helper.InternalErrorResponse("failed to list items"))
+			return
 		}
+		items, err = h.items.GetAllByCategories(count, page, ids)
+	case c.Query("type") != "":
+		items, err = h.items.GetAllByType(count, page, c.Query("type"))
+	default:
+		items, err = h.items.GetAll(count, page)
 	}
-	items, err := h.items.GetAll(count, page)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, helper.InternalErrorResponse("failed to list items"))
 		return
@@ -55,6 +71,37 @@ func (h *ItemsHandler) list(c *gin.Context) {
 	c.JSON(http.StatusOK, helper.SuccessResponse("OK", items))
 }
 
+// notModified sets a weak ETag and Last-Modified for the catalog listing and
+// answers 304 when the client's copy is still current. Clients are asked to
+// revalidate on every use so price changes show up immediately.
+func (h *ItemsHandler) notModified(c *gin.Context) bool {
+	lastChange, total, err := h.items.CatalogVersion()
+	if err != nil {
+		return false
+	}
+	etag := helper.WeakETag(lastChange.UTC().Format(time.RFC3339Nano), strconv.FormatInt(total, 10), c.Request.URL.RawQuery)
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "no-cache")
+	if !lastChange.IsZero() {
+		c.Header("Last-Modified", lastChange.UTC().Format(http.TimeFormat))
+	}
+
+	if inm := c.GetHeader("If-None-Match"); inm != "" {
+		if helper.ETagMatches(inm, etag) {
+			c.Status(http.StatusNotModified)
+			return true
+		}
+		return false
+	}
+	if ims := c.GetHeader("If-Modified-Since"); ims != "" && !lastChange.IsZero() {
+		if t, err := http.ParseTime(ims); err == nil && !lastChange.Truncate(time.Second).After(t) {
+			c.Status(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
+
 func (h *ItemsHandler) get(c *gin.Context) {
 	id := c.Param("id")
 	item, err := h.items.GetByID(id)
@@ -76,14 +123,24 @@ func (h *ItemsHandler) create(c *gin.Context) {
 	imageFileName := req.ImageUrl
 	if h.images != nil && req.ImageBase64 != "" {
 		_, stored, err := h.images.UploadBase64(req.ItemName, req.ImageType, req.ImageBase64)
-		if err == nil {
-			imageFileName = stored
+		if err != nil {
+			writeUploadError(c, err)
+			return
 		}
+		imageFileName = stored
 	}
-	it := &entity.Items{IdItem: helper.Uuid(), ItemName: req.ItemName, ItemType: req.ItemType, Price: req.Price, Description: req.Description, ImageUrl: imageFileName}
+	it := &entity.Items{IdItem: helper.Uuid(), ItemName: req.ItemName, ItemType: req.ItemType, Price: req.Price, TaxRate: req.TaxRate, Description: req.Description, ImageUrl: imageFileName}
 	if req.IsAvailable != nil {
 		it.IsAvailable = *req.IsAvailable
 	}
+	if req.GiftCard {
+		it.Kind = entity.ItemKindGiftCard
+	}
+	if req.IdCategory != "" {
+		if !h.applyCategory(c, it, req.IdCategory) {
+			return
+		}
+	}
 	saved, err := h.items.Create(it)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, helper.InternalErrorResponse("failed to create item"))
@@ -110,12 +167,39 @@ func (h *ItemsHandler) update(c *gin.Context) {
 	if req.ItemType != nil {
 		existing.ItemType = *req.ItemType
 	}
+	switch {
+	case req.IdCategory == nil:
+	case *req.IdCategory == "":
+		existing.IdCategory = ""
+		if req.ItemType == nil {
+			existing.ItemType = ""
+		}
+	case !h.applyCategory(c, existing, *req.IdCategory):
+		return
+	}
 	if req.IsAvailable != nil {
 		existing.IsAvailable = *req.IsAvailable
 	}
 	if req.Price != nil {
 		existing.Price = *req.Price
 	}
+	if req.TaxRate != nil {
+		existing.TaxRate = req.TaxRate
+	}
+	if req.ClearTaxRate {
+		existing.TaxRate = nil
+	}
+	if req.GiftCard != nil {
+		switch {
+		case *req.GiftCard && existing.Kind == entity.ItemKindBundle:
+			c.JSON(http.StatusBadRequest, helper.BadRequestResponse("a bundle cannot be a gift card"))
+			return
+		case *req.GiftCard:
+			existing.Kind = entity.ItemKindGiftCard
+		case existing.Kind == entity.ItemKindGiftCard:
+			existing.Kind = entity.ItemKindSingle
+		}
+	}
 	if req.Description != nil {
 		existing.Description = *req.Description
 	}
@@ -128,9 +212,11 @@ func (h *ItemsHandler) update(c *gin.Context) {
 			ct = *req.ImageType
 		}
 		_, stored, err := h.images.UploadBase64(existing.ItemName, ct, *req.ImageBase64)
-		if err == nil {
-			existing.ImageUrl = stored
+		if err != nil {
+			writeUploadError(c, err)
+			return
 		}
+		existing.ImageUrl = stored
 	}
 	updated, err := h.items.Update(id, existing)
 	if err != nil {
@@ -140,6 +226,19 @@ func (h *ItemsHandler) update(c *gin.Context) {
 	c.JSON(http.StatusOK, helper.SuccessResponse("updated", updated))
 }
 
+// applyCategory links the item to a category and mirrors its name into the
+// legacy item_type field for clients that still read it.
+func (h *ItemsHandler) applyCategory(c *gin.Context, it *entity.Items, idCategory string) bool {
+	cat, err := h.categories.GetByID(idCategory)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, helper.BadRequestResponse("invalid category: "+idCategory))
+		return false
+	}
+	it.IdCategory = cat.IdCategory
+	it.ItemType = cat.Name
+	return true
+}
+
 func (h *ItemsHandler) delete(c *gin.Context) {
 	id := c.Param("id")
 	if err := h.items.Delete(id); err != nil {
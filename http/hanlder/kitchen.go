@@ -0,0 +1,237 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"faizalmaulana/lsp/conf"
+	"faizalmaulana/lsp/helper"
+	"faizalmaulana/lsp/http/dto"
+	"faizalmaulana/lsp/http/middleware"
+	"faizalmaulana/lsp/http/services"
+	"faizalmaulana/lsp/models/entity"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+)
+
+type KitchenHandler struct {
+	cfg     *conf.Config
+	kitchen services.KitchenService
+}
+
+func NewKitchenHandler(cfg *conf.Config, kitchen services.KitchenService) *KitchenHandler {
+	return &KitchenHandler{cfg: cfg, kitchen: kitchen}
+}
+
+// Register lets managers set up the stations. Kitchen screens and cashiers
+// follow the tickets and bump or recall them with any signed-in account.
+func (h *KitchenHandler) Register(rr *gin.RouterGroup) {
+	rg := rr.Group("/kitchen")
+	rg.GET("stations", middleware.JWTMiddleware(h.cfg), h.listStations)
+	rg.POST("stations", middleware.JWTMiddleware(h.cfg), middleware.RequireRole(entity.RoleAdmin, entity.RoleManager), h.createStation)
+	rg.PUT("stations/:id", middleware.JWTMiddleware(h.cfg), middleware.RequireRole(entity.RoleAdmin, entity.RoleManager), h.updateStation)
+	rg.DELETE("stations/:id", middleware.JWTMiddleware(h.cfg), middleware.RequireRole(entity.RoleAdmin, entity.RoleManager), h.deleteStation)
+	rg.GET("tickets", middleware.JWTMiddleware(h.cfg), h.listTickets)
+	rg.GET("tickets/:id", middleware.JWTMiddleware(h.cfg), h.getTicket)
+	rg.POST("tickets/:id/bump", middleware.JWTMiddleware(h.cfg), h.bump)
+	rg.POST("tickets/:id/recall", middleware.JWTMiddleware(h.cfg), h.recall)
+	rg.GET("orders/:id", middleware.JWTMiddleware(h.cfg), h.order)
+	rg.POST("orders/:id/send", middleware.JWTMiddleware(h.cfg), h.send)
+	rg.GET("stream", middleware.JWTMiddleware(h.cfg), h.stream)
+}
+
+func (h *KitchenHandler) listStations(c *gin.Context) {
+	out, err := h.kitchen.GetStations()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, helper.InternalErrorResponse("failed to list kitchen stations"))
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("OK", out))
+}
+
+func (h *KitchenHandler) createStation(c *gin.Context) {
+	var req dto.CreateKitchenStationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, helper.BadRequestResponse(err.Error()))
+		return
+	}
+	st, err := h.kitchen.CreateStation(&entity.KitchenStations{
+		Name:       req.Name,
+		Categories: req.Categories,
+		IsDefault:  req.IsDefault,
+		SortOrder:  req.SortOrder,
+	})
+	if err != nil {
+		writeKitchenError(c, err, "failed to create kitchen station")
+		return
+	}
+	c.JSON(http.StatusCreated, helper.SuccessResponse("created", st))
+}
+
+func (h *KitchenHandler) updateStation(c *gin.Context) {
+	var req dto.UpdateKitchenStationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, helper.BadRequestResponse(err.Error()))
+		return
+	}
+	existing, err := h.kitchen.GetStation(c.Param("id"))
+	if err != nil {
+		writeKitchenError(c, err, "failed to update kitchen station")
+		return
+	}
+	if req.Name != nil {
+		existing.Name = *req.Name
+	}
+	if req.Categories != nil {
+		existing.Categories = *req.Categories
+	}
+	if req.IsDefault != nil {
+		existing.IsDefault = *req.IsDefault
+	}
+	if req.SortOrder != nil {
+		existing.SortOrder = *req.SortOrder
+	}
+	st, err := h.kitchen.UpdateStation(existing.IdStation, existing)
+	if err != nil {
+		writeKitchenError(c, err, "failed to update kitchen station")
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("updated", st))
+}
+
+func (h *KitchenHandler) deleteStation(c *gin.Context) {
+	id := c.Param("id")
+	if err := h.kitchen.DeleteStation(id); err != nil {
+		writeKitchenError(c, err, "failed to delete kitchen station")
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("deleted", gin.H{"id": id}))
+}
+
+// listTickets shows the new tickets unless ?status= asks for others, as a
+// comma-separated list; all shows every status.
+func (h *KitchenHandler) listTickets(c *gin.Context) {
+	count, page := pagination(c)
+	status := c.DefaultQuery("status", entity.KitchenTicketNew)
+	if status == "all" {
+		status = ""
+	}
+	out, err := h.kitchen.GetTickets(c.Query("station"), status, c.Query("id_transaction"), count, page)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, helper.InternalErrorResponse("failed to list kitchen tickets"))
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("OK", out))
+}
+
+func (h *KitchenHandler) getTicket(c *gin.Context) {
+	t, err := h.kitchen.GetTicket(c.Param("id"))
+	if err != nil {
+		writeKitchenError(c, err, "failed to load kitchen ticket")
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("OK", t))
+}
+
+func (h *KitchenHandler) bump(c *gin.Context) {
+	t, err := h.kitchen.Bump(c.Param("id"), claimString(c, "sub"))
+	if err != nil {
+		writeKitchenError(c, err, "failed to bump kitchen ticket")
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("updated", t))
+}
+
+func (h *KitchenHandler) recall(c *gin.Context) {
+	t, err := h.kitchen.Recall(c.Param("id"))
+	if err != nil {
+		writeKitchenError(c, err, "failed to recall kitchen ticket")
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("updated", t))
+}
+
+// order shows how far the kitchen is with a sale.
+func (h *KitchenHandler) order(c *gin.Context) {
+	o, err := h.kitchen.Order(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, helper.InternalErrorResponse("failed to load kitchen order"))
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("OK", o))
+}
+
+// send sends a sale to the kitchen again when its tickets were not opened
+// at checkout; a sale that has tickets keeps them.
+func (h *KitchenHandler) send(c *gin.Context) {
+	if _, err := h.kitchen.Send(c.Param("id")); err != nil {
+		writeKitchenError(c, err, "failed to send to the kitchen")
+		return
+	}
+	o, err := h.kitchen.Order(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, helper.InternalErrorResponse("failed to load kitchen order"))
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("OK", o))
+}
+
+// stream pushes the kitchen events of ?station=, or of every station, as
+// server-sent events until the client goes away. A ping every 20 seconds
+// keeps proxies from closing an idle stream.
+func (h *KitchenHandler) stream(c *gin.Context) {
+	events, stop := h.kitchen.Subscribe(c.Query("station"))
+	defer stop()
+	streamEvents(c, nil, events, nil)
+}
+
+// streamEvents writes the backlog and then events to c as server-sent
+// events. render, when not nil, turns the data of an event into what is
+// sent.
+func streamEvents(c *gin.Context, backlog []services.Event, events <-chan services.Event, render func(interface{}) interface{}) {
+	ping := time.NewTicker(20 * time.Second)
+	defer ping.Stop()
+	send := func(e services.Event) {
+		if render != nil {
+			e.Data = render(e.Data)
+		}
+		c.Render(-1, sse.Event{Id: e.Id, Event: e.Type, Data: e.Data})
+	}
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.SSEvent("ping", time.Now().Unix())
+	for _, e := range backlog {
+		send(e)
+	}
+	c.Writer.Flush()
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case e, ok := <-events:
+			if !ok {
+				return false
+			}
+			send(e)
+		case <-ping.C:
+			c.SSEvent("ping", time.Now().Unix())
+		}
+		return true
+	})
+}
+
+func writeKitchenError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrStationNotFound), errors.Is(err, services.ErrTicketNotFound), errors.Is(err, services.ErrTransactionNotFound):
+		c.JSON(http.StatusNotFound, helper.NotFoundResponse(err.Error()))
+	case errors.Is(err, services.ErrInvalidStation):
+		c.JSON(http.StatusBadRequest, helper.BadRequestResponse(err.Error()))
+	case errors.Is(err, services.ErrTicketStatus):
+		c.JSON(http.StatusConflict, helper.ErrorResponse("CONFLICT", err.Error()))
+	default:
+		c.JSON(http.StatusInternalServerError, helper.InternalErrorResponse(fallback))
+	}
+}
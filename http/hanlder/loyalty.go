@@ -0,0 +1,230 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"faizalmaulana/lsp/conf"
+	"faizalmaulana/lsp/helper"
+	"faizalmaulana/lsp/http/dto"
+	"faizalmaulana/lsp/http/middleware"
+	"faizalmaulana/lsp/http/services"
+	"faizalmaulana/lsp/models/entity"
+
+	"github.com/gin-gonic/gin"
+)
+
+type LoyaltyHandler struct {
+	cfg     *conf.Config
+	loyalty services.LoyaltyService
+}
+
+func NewLoyaltyHandler(cfg *conf.Config, loyalty services.LoyaltyService) *LoyaltyHandler {
+	return &LoyaltyHandler{cfg: cfg, loyalty: loyalty}
+}
+
+// Register leaves the balance lookup open so customers can check their
+// points by phone; the ledger takes a login and the program settings a
+// manager.
+func (h *LoyaltyHandler) Register(rr *gin.RouterGroup) {
+	rg := rr.Group("/loyalty")
+	rg.GET("balance", h.balance)
+	rg.GET("customers/:id", middleware.JWTMiddleware(h.cfg), h.customer)
+	rg.GET("rules", middleware.JWTMiddleware(h.cfg), middleware.RequireRole(entity.RoleAdmin, entity.RoleManager), h.listRules)
+	rg.GET("rules/:id", middleware.JWTMiddleware(h.cfg), middleware.RequireRole(entity.RoleAdmin, entity.RoleManager), h.getRule)
+	rg.POST("rules", middleware.JWTMiddleware(h.cfg), middleware.RequireRole(entity.RoleAdmin, entity.RoleManager), h.createRule)
+	rg.PUT("rules/:id", middleware.JWTMiddleware(h.cfg), middleware.RequireRole(entity.RoleAdmin, entity.RoleManager), h.updateRule)
+	rg.DELETE("rules/:id", middleware.JWTMiddleware(h.cfg), middleware.RequireRole(entity.RoleAdmin, entity.RoleManager), h.deleteRule)
+	rg.GET("tiers", middleware.JWTMiddleware(h.cfg), h.listTiers)
+	rg.GET("tiers/:id", middleware.JWTMiddleware(h.cfg), h.getTier)
+	rg.POST("tiers", middleware.JWTMiddleware(h.cfg), middleware.RequireRole(entity.RoleAdmin, entity.RoleManager), h.createTier)
+	rg.PUT("tiers/:id", middleware.JWTMiddleware(h.cfg), middleware.RequireRole(entity.RoleAdmin, entity.RoleManager), h.updateTier)
+	rg.DELETE("tiers/:id", middleware.JWTMiddleware(h.cfg), middleware.RequireRole(entity.RoleAdmin, entity.RoleManager), h.deleteTier)
+}
+
+func (h *LoyaltyHandler) balance(c *gin.Context) {
+	phone := c.Query("phone")
+	if phone == "" {
+		c.JSON(http.StatusBadRequest, helper.BadRequestResponse("phone required"))
+		return
+	}
+	b, err := h.loyalty.BalanceByPhone(phone)
+	if err != nil {
+		h.writeError(c, err, "failed to load balance")
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("OK", balanceResponse(b)))
+}
+
+func (h *LoyaltyHandler) customer(c *gin.Context) {
+	id := c.Param("id")
+	b, err := h.loyalty.Balance(id)
+	if err != nil {
+		h.writeError(c, err, "failed to load balance")
+		return
+	}
+	count, page := pagination(c)
+	ledger, err := h.loyalty.Ledger(id, count, page)
+	if err != nil {
+		h.writeError(c, err, "failed to load ledger")
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("OK", dto.LoyaltyCustomerResponse{
+		Customer:               b.Customer,
+		LoyaltyBalanceResponse: balanceResponse(b),
+		Spend:                  b.Spend,
+		Page:                   page,
+		Count:                  len(ledger),
+		Ledger:                 ledger,
+	}))
+}
+
+func balanceResponse(b *services.LoyaltyBalance) dto.LoyaltyBalanceResponse {
+	out := dto.LoyaltyBalanceResponse{
+		Points:          b.Points,
+		Value:           b.Value,
+		Multiplier:      1,
+		SpendToNextTier: b.SpendToNextTier,
+		ExpiringPoints:  b.ExpiringPoints,
+		ExpiringAt:      b.ExpiringAt,
+	}
+	if b.Tier != nil {
+		out.Tier = b.Tier.Name
+		out.Multiplier = b.Tier.Multiplier
+	}
+	if b.NextTier != nil {
+		out.NextTier = b.NextTier.Name
+	}
+	return out
+}
+
+func (h *LoyaltyHandler) listRules(c *gin.Context) {
+	out, err := h.loyalty.GetRules()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, helper.InternalErrorResponse("failed to list rules"))
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("OK", out))
+}
+
+func (h *LoyaltyHandler) getRule(c *gin.Context) {
+	r, err := h.loyalty.GetRule(c.Param("id"))
+	if err != nil {
+		h.writeError(c, err, "failed to load rule")
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("OK", r))
+}
+
+func (h *LoyaltyHandler) createRule(c *gin.Context) {
+	var req dto.LoyaltyRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, helper.BadRequestResponse(err.Error()))
+		return
+	}
+	saved, err := h.loyalty.CreateRule(loyaltyRule(req))
+	if err != nil {
+		h.writeError(c, err, "failed to create rule")
+		return
+	}
+	c.JSON(http.StatusCreated, helper.SuccessResponse("created", saved))
+}
+
+func (h *LoyaltyHandler) updateRule(c *gin.Context) {
+	var req dto.LoyaltyRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, helper.BadRequestResponse(err.Error()))
+		return
+	}
+	saved, err := h.loyalty.UpdateRule(c.Param("id"), loyaltyRule(req))
+	if err != nil {
+		h.writeError(c, err, "failed to update rule")
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("updated", saved))
+}
+
+func (h *LoyaltyHandler) deleteRule(c *gin.Context) {
+	id := c.Param("id")
+	if err := h.loyalty.DeleteRule(id); err != nil {
+		h.writeError(c, err, "failed to delete rule")
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("deleted", gin.H{"id": id}))
+}
+
+// loyaltyRule maps a rule request; a rule is active unless is_active is
+// false.
+func loyaltyRule(req dto.LoyaltyRuleRequest) *entity.LoyaltyRules {
+	active := true
+	if req.IsActive != nil {
+		active = *req.IsActive
+	}
+	return &entity.LoyaltyRules{Name: req.Name, Kind: req.Kind, Step: req.Step, IdItem: req.IdItem, Points: req.Points, IsActive: active}
+}
+
+func (h *LoyaltyHandler) listTiers(c *gin.Context) {
+	out, err := h.loyalty.GetTiers()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, helper.InternalErrorResponse("failed to list tiers"))
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("OK", out))
+}
+
+func (h *LoyaltyHandler) getTier(c *gin.Context) {
+	t, err := h.loyalty.GetTier(c.Param("id"))
+	if err != nil {
+		h.writeError(c, err, "failed to load tier")
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("OK", t))
+}
+
+func (h *LoyaltyHandler) createTier(c *gin.Context) {
+	var req dto.LoyaltyTierRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, helper.BadRequestResponse(err.Error()))
+		return
+	}
+	saved, err := h.loyalty.CreateTier(&entity.LoyaltyTiers{Name: req.Name, MinSpend: req.MinSpend, Multiplier: req.Multiplier})
+	if err != nil {
+		h.writeError(c, err, "failed to create tier")
+		return
+	}
+	c.JSON(http.StatusCreated, helper.SuccessResponse("created", saved))
+}
+
+func (h *LoyaltyHandler) updateTier(c *gin.Context) {
+	var req dto.LoyaltyTierRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, helper.BadRequestResponse(err.Error()))
+		return
+	}
+	saved, err := h.loyalty.UpdateTier(c.Param("id"), &entity.LoyaltyTiers{Name: req.Name, MinSpend: req.MinSpend, Multiplier: req.Multiplier})
+	if err != nil {
+		h.writeError(c, err, "failed to update tier")
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("updated", saved))
+}
+
+func (h *LoyaltyHandler) deleteTier(c *gin.Context) {
+	id := c.Param("id")
+	if err := h.loyalty.DeleteTier(id); err != nil {
+		h.writeError(c, err, "failed to delete tier")
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("deleted", gin.H{"id": id}))
+}
+
+func (h *LoyaltyHandler) writeError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrLoyaltyNotFound), errors.Is(err, services.ErrCustomerNotFound):
+		c.JSON(http.StatusNotFound, helper.NotFoundResponse(err.Error()))
+	case errors.Is(err, services.ErrInvalidLoyalty), errors.Is(err, services.ErrInvalidCustomer):
+		c.JSON(http.StatusBadRequest, helper.BadRequestResponse(err.Error()))
+	default:
+		c.JSON(http.StatusInternalServerError, helper.InternalErrorResponse(fallback))
+	}
+}
@@ -0,0 +1,236 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"faizalmaulana/lsp/conf"
+	"faizalmaulana/lsp/helper"
+	"faizalmaulana/lsp/http/dto"
+	"faizalmaulana/lsp/http/middleware"
+	"faizalmaulana/lsp/http/services"
+	"faizalmaulana/lsp/models/entity"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ModifiersHandler struct {
+	cfg       *conf.Config
+	modifiers services.ModifiersService
+	items     services.ItemsService
+}
+
+func NewModifiersHandler(cfg *conf.Config, modifiers services.ModifiersService, items services.ItemsService) *ModifiersHandler {
+	return &ModifiersHandler{cfg: cfg, modifiers: modifiers, items: items}
+}
+
+func (h *ModifiersHandler) Register(rr *gin.RouterGroup) {
+	rr.GET("/items/:id/modifier-groups", h.listByItem)
+	rr.POST("/items/:id/modifier-groups", middleware.JWTMiddleware(h.cfg), h.createGroup)
+
+	rg := rr.Group("/modifier-groups")
+	rg.GET(":id", h.getGroup)
+	rg.PUT(":id", middleware.JWTMiddleware(h.cfg), h.updateGroup)
+	rg.DELETE(":id", middleware.JWTMiddleware(h.cfg), h.deleteGroup)
+	rg.POST(":id/options", middleware.JWTMiddleware(h.cfg), h.createOption)
+
+	og := rr.Group("/modifier-options")
+	og.PUT(":id", middleware.JWTMiddleware(h.cfg), h.updateOption)
+	og.DELETE(":id", middleware.JWTMiddleware(h.cfg), h.deleteOption)
+}
+
+func optionFromRequest(idGroup string, req dto.ModifierOptionRequest) entity.ModifierOptions {
+	o := entity.ModifierOptions{
+		IdModifierOption: helper.Uuid(),
+		IdModifierGroup:  idGroup,
+		Name:             req.Name,
+		PriceDelta:       req.PriceDelta,
+		IsDefault:        req.IsDefault,
+		IsAvailable:      true,
+		SortOrder:        req.SortOrder,
+	}
+	if req.IsAvailable != nil {
+		o.IsAvailable = *req.IsAvailable
+	}
+	return o
+}
+
+func (h *ModifiersHandler) listByItem(c *gin.Context) {
+	id := c.Param("id")
+	if _, err := h.items.GetByID(id); err != nil {
+		c.JSON(http.StatusNotFound, helper.NotFoundResponse("item not found"))
+		return
+	}
+	out, err := h.modifiers.ListByItem(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, helper.InternalErrorResponse("failed to list modifier groups"))
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("OK", out))
+}
+
+func (h *ModifiersHandler) createGroup(c *gin.Context) {
+	idItem := c.Param("id")
+	if _, err := h.items.GetByID(idItem); err != nil {
+		c.JSON(http.StatusNotFound, helper.NotFoundResponse("item not found"))
+		return
+	}
+	var req dto.CreateModifierGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, helper.BadRequestResponse(err.Error()))
+		return
+	}
+	g := &entity.ModifierGroups{
+		IdModifierGroup: helper.Uuid(),
+		IdItem:          idItem,
+		Name:            req.Name,
+		Kind:            req.Kind,
+		IsRequired:      req.IsRequired,
+		MinSelect:       req.MinSelect,
+		MaxSelect:       req.MaxSelect,
+		SortOrder:       req.SortOrder,
+	}
+	for _, o := range req.Options {
+		g.Options = append(g.Options, optionFromRequest(g.IdModifierGroup, o))
+	}
+	saved, err := h.modifiers.CreateGroup(g)
+	if err != nil {
+		h.writeError(c, err, "failed to create modifier group")
+		return
+	}
+	c.JSON(http.StatusCreated, helper.SuccessResponse("created", saved))
+}
+
+func (h *ModifiersHandler) getGroup(c *gin.Context) {
+	g, err := h.modifiers.GetGroup(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, helper.NotFoundResponse("modifier group not found"))
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("OK", g))
+}
+
+func (h *ModifiersHandler) updateGroup(c *gin.Context) {
+	id := c.Param("id")
+	var req dto.UpdateModifierGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, helper.BadRequestResponse(err.Error()))
+		return
+	}
+	existing, err := h.modifiers.GetGroup(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, helper.NotFoundResponse("modifier group not found"))
+		return
+	}
+	if req.Name != nil {
+		existing.Name = *req.Name
+	}
+	if req.Kind != nil {
+		existing.Kind = *req.Kind
+	}
+	if req.IsRequired != nil {
+		existing.IsRequired = *req.IsRequired
+	}
+	if req.MinSelect != nil {
+		existing.MinSelect = *req.MinSelect
+	}
+	if req.MaxSelect != nil {
+		existing.MaxSelect = *req.MaxSelect
+	}
+	if req.SortOrder != nil {
+		existing.SortOrder = *req.SortOrder
+	}
+	updated, err := h.modifiers.UpdateGroup(id, existing)
+	if err != nil {
+		h.writeError(c, err, "failed to update modifier group")
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("updated", updated))
+}
+
+func (h *ModifiersHandler) deleteGroup(c *gin.Context) {
+	id := c.Param("id")
+	if _, err := h.modifiers.GetGroup(id); err != nil {
+		c.JSON(http.StatusNotFound, helper.NotFoundResponse("modifier group not found"))
+		return
+	}
+	if err := h.modifiers.DeleteGroup(id); err != nil {
+		c.JSON(http.StatusInternalServerError, helper.InternalErrorResponse("failed to delete modifier group"))
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("deleted", gin.H{"id": id}))
+}
+
+func (h *ModifiersHandler) createOption(c *gin.Context) {
+	var req dto.ModifierOptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, helper.BadRequestResponse(err.Error()))
+		return
+	}
+	o := optionFromRequest(c.Param("id"), req)
+	saved, err := h.modifiers.CreateOption(&o)
+	if err != nil {
+		h.writeError(c, err, "failed to create modifier option")
+		return
+	}
+	c.JSON(http.StatusCreated, helper.SuccessResponse("created", saved))
+}
+
+func (h *ModifiersHandler) updateOption(c *gin.Context) {
+	id := c.Param("id")
+	var req dto.UpdateModifierOptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, helper.BadRequestResponse(err.Error()))
+		return
+	}
+	existing, err := h.modifiers.GetOption(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, helper.NotFoundResponse("modifier option not found"))
+		return
+	}
+	if req.Name != nil {
+		existing.Name = *req.Name
+	}
+	if req.PriceDelta != nil {
+		existing.PriceDelta = *req.PriceDelta
+	}
+	if req.IsDefault != nil {
+		existing.IsDefault = *req.IsDefault
+	}
+	if req.IsAvailable != nil {
+		existing.IsAvailable = *req.IsAvailable
+	}
+	if req.SortOrder != nil {
+		existing.SortOrder = *req.SortOrder
+	}
+	updated, err := h.modifiers.UpdateOption(id, existing)
+	if err != nil {
+		h.writeError(c, err, "failed to update modifier option")
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("updated", updated))
+}
+
+func (h *ModifiersHandler) deleteOption(c *gin.Context) {
+	id := c.Param("id")
+	if _, err := h.modifiers.GetOption(id); err != nil {
+		c.JSON(http.StatusNotFound, helper.NotFoundResponse("modifier option not found"))
+		return
+	}
+	if err := h.modifiers.DeleteOption(id); err != nil {
+		c.JSON(http.StatusInternalServerError, helper.InternalErrorResponse("failed to delete modifier option"))
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("deleted", gin.H{"id": id}))
+}
+
+func (h *ModifiersHandler) writeError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrModifierGroupNotFound):
+		c.JSON(http.StatusNotFound, helper.NotFoundResponse(err.Error()))
+	case errors.Is(err, services.ErrInvalidModifierGroup):
+		c.JSON(http.StatusBadRequest, helper.BadRequestResponse(err.Error()))
+	default:
+		c.JSON(http.StatusInternalServerError, helper.InternalErrorResponse(fallback))
+	}
+}
@@ -0,0 +1,25 @@
+package handler
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// pagination reads the ?count= and ?page= query parameters shared by list
+// endpoints. Bounds are enforced by the services.
+func pagination(c *gin.Context) (int, int) {
+	count := 10
+	page := 1
+	if v := c.Query("count"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			count = n
+		}
+	}
+	if v := c.Query("page"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			page = n
+		}
+	}
+	return count, page
+}
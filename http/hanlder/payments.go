@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"faizalmaulana/lsp/conf"
+	"faizalmaulana/lsp/helper"
+	"faizalmaulana/lsp/http/dto"
+	"faizalmaulana/lsp/http/middleware"
+	"faizalmaulana/lsp/http/services"
+	"faizalmaulana/lsp/models/entity"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxWebhookBytes bounds the callback bodies read from payment gateways.
+const maxWebhookBytes = 64 << 10
+
+type PaymentsHandler struct {
+	cfg     *conf.Config
+	intents services.PaymentIntentsService
+}
+
+func NewPaymentsHandler(cfg *conf.Config, intents services.PaymentIntentsService) *PaymentsHandler {
+	return &PaymentsHandler{cfg: cfg, intents: intents}
+}
+
+func (h *PaymentsHandler) Register(rr *gin.RouterGroup) {
+	rg := rr.Group("/payments")
+	rg.GET("/methods", h.methods)
+	rg.GET("/intents/:id", middleware.JWTMiddleware(h.cfg), h.getIntent)
+	// Webhooks are authenticated by their HMAC signature, not by JWT.
+	rg.POST("/webhooks/:gateway", h.webhook)
+	rg.POST("/simulator/intents/:id", middleware.JWTMiddleware(h.cfg), h.simulate)
+	rg.GET("/callbacks", middleware.JWTMiddleware(h.cfg), middleware.RequireRole(entity.RoleAdmin, entity.RoleManager), h.callbacks)
+}
+
+func (h *PaymentsHandler) methods(c *gin.Context) {
+	c.JSON(http.StatusOK, helper.SuccessResponse("OK", gin.H{"methods": services.PaymentMethods, "gateway": h.intents.GatewayName()}))
+}
+
+func (h *PaymentsHandler) getIntent(c *gin.Context) {
+	i, err := h.intents.Get(c.Param("id"))
+	if err != nil {
+		h.writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("OK", i))
+}
+
+func (h *PaymentsHandler) webhook(c *gin.Context) {
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxWebhookBytes))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, helper.BadRequestResponse("invalid body"))
+		return
+	}
+	res, err := h.intents.HandleWebhook(c.Param("gateway"), c.Request.Header, body)
+	if err != nil {
+		h.writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("OK", webhookResponse(res)))
+}
+
+// callbacks lists the gateway callbacks; ?refund_needed=true keeps the
+// payments that have to be refunded by hand.
+func (h *PaymentsHandler) callbacks(c *gin.Context) {
+	count, page := pagination(c)
+	list, err := h.intents.Callbacks(c.Query("refund_needed") == "true", count, page)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, helper.InternalErrorResponse("failed to list callbacks"))
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("OK", list))
+}
+
+func (h *PaymentsHandler) simulate(c *gin.Context) {
+	var req dto.SimulatePaymentRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, helper.BadRequestResponse(err.Error()))
+			return
+		}
+	}
+	res, err := h.intents.Simulate(c.Param("id"), req.Status)
+	if err != nil {
+		h.writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("OK", webhookResponse(res)))
+}
+
+func webhookResponse(res *services.WebhookResult) dto.WebhookResponse {
+	out := dto.WebhookResponse{Duplicate: res.Duplicate, Outcome: res.Outcome, RefundNeeded: res.RefundNeeded}
+	if res.Intent != nil {
+		out.IdPaymentIntent = res.Intent.IdPaymentIntent
+		out.IdTransaction = res.Intent.IdTransaction
+		out.Status = res.Intent.Status
+	}
+	return out
+}
+
+func (h *PaymentsHandler) writeError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrPaymentIntentNotFound):
+		c.JSON(http.StatusNotFound, helper.NotFoundResponse(err.Error()))
+	case errors.Is(err, services.ErrGatewayUnavailable):
+		c.JSON(http.StatusNotFound, helper.NotFoundResponse(err.Error()))
+	case errors.Is(err, services.ErrInvalidSignature):
+		c.JSON(http.StatusUnauthorized, helper.ErrorResponse("UNAUTHORIZED", err.Error()))
+	case errors.Is(err, services.ErrWebhookMismatch):
+		c.JSON(http.StatusBadRequest, helper.BadRequestResponse(err.Error()))
+	default:
+		c.JSON(http.StatusInternalServerError, helper.InternalErrorResponse("failed to process payment"))
+	}
+}
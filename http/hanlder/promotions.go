@@ -0,0 +1,186 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"faizalmaulana/lsp/conf"
+	"faizalmaulana/lsp/helper"
+	"faizalmaulana/lsp/http/dto"
+	"faizalmaulana/lsp/http/middleware"
+	"faizalmaulana/lsp/http/services"
+	"faizalmaulana/lsp/models/entity"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PromotionsHandler struct {
+	cfg        *conf.Config
+	promotions services.PromotionsService
+}
+
+func NewPromotionsHandler(cfg *conf.Config, promotions services.PromotionsService) *PromotionsHandler {
+	return &PromotionsHandler{cfg: cfg, promotions: promotions}
+}
+
+func (h *PromotionsHandler) Register(rr *gin.RouterGroup) {
+	rg := rr.Group("/promotions")
+	rg.GET("", h.list)
+	rg.GET(":id", h.get)
+	rg.POST("", middleware.JWTMiddleware(h.cfg), middleware.RequireRole(entity.RoleAdmin, entity.RoleManager), h.create)
+	rg.PUT(":id", middleware.JWTMiddleware(h.cfg), middleware.RequireRole(entity.RoleAdmin, entity.RoleManager), h.update)
+	rg.DELETE(":id", middleware.JWTMiddleware(h.cfg), middleware.RequireRole(entity.RoleAdmin, entity.RoleManager), h.delete)
+}
+
+func (h *PromotionsHandler) list(c *gin.Context) {
+	out, err := h.promotions.GetAll(c.Query("active") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, helper.InternalErrorResponse("failed to list promotions"))
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("OK", out))
+}
+
+func (h *PromotionsHandler) get(c *gin.Context) {
+	p, err := h.promotions.GetByID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, helper.NotFoundResponse("promotion not found"))
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("OK", p))
+}
+
+func (h *PromotionsHandler) create(c *gin.Context) {
+	var req dto.CreatePromotionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, helper.BadRequestResponse(err.Error()))
+		return
+	}
+	p := &entity.Promotions{
+		IdPromotion: helper.Uuid(),
+		Name:        req.Name,
+		Kind:        req.Kind,
+		Scope:       req.Scope,
+		IdTarget:    req.IdTarget,
+		Value:       req.Value,
+		BuyQuantity: req.BuyQuantity,
+		GetQuantity: req.GetQuantity,
+		MinSpend:    req.MinSpend,
+		StartsAt:    req.StartsAt,
+		EndsAt:      req.EndsAt,
+		DaysOfWeek:  req.DaysOfWeek,
+		StartTime:   req.StartTime,
+		EndTime:     req.EndTime,
+		Priority:    req.Priority,
+		Stackable:   req.Stackable,
+		VoucherOnly: req.VoucherOnly,
+		IsActive:    true,
+	}
+	if req.IsActive != nil {
+		p.IsActive = *req.IsActive
+	}
+	saved, err := h.promotions.Create(p)
+	if err != nil {
+		h.writeError(c, err, "failed to create promotion")
+		return
+	}
+	c.JSON(http.StatusCreated, helper.SuccessResponse("created", saved))
+}
+
+func (h *PromotionsHandler) update(c *gin.Context) {
+	id := c.Param("id")
+	var req dto.UpdatePromotionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, helper.BadRequestResponse(err.Error()))
+		return
+	}
+	p, err := h.promotions.GetByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, helper.NotFoundResponse("promotion not found"))
+		return
+	}
+	if req.Name != nil {
+		p.Name = *req.Name
+	}
+	if req.Kind != nil {
+		p.Kind = *req.Kind
+	}
+	if req.Scope != nil {
+		p.Scope = *req.Scope
+	}
+	if req.IdTarget != nil {
+		p.IdTarget = *req.IdTarget
+	}
+	if req.Value != nil {
+		p.Value = *req.Value
+	}
+	if req.BuyQuantity != nil {
+		p.BuyQuantity = *req.BuyQuantity
+	}
+	if req.GetQuantity != nil {
+		p.GetQuantity = *req.GetQuantity
+	}
+	if req.MinSpend != nil {
+		p.MinSpend = *req.MinSpend
+	}
+	if req.StartsAt != nil {
+		p.StartsAt = req.StartsAt
+	}
+	if req.ClearStartsAt {
+		p.StartsAt = nil
+	}
+	if req.EndsAt != nil {
+		p.EndsAt = req.EndsAt
+	}
+	if req.ClearEndsAt {
+		p.EndsAt = nil
+	}
+	if req.DaysOfWeek != nil {
+		p.DaysOfWeek = *req.DaysOfWeek
+	}
+	if req.StartTime != nil {
+		p.StartTime = *req.StartTime
+	}
+	if req.EndTime != nil {
+		p.EndTime = *req.EndTime
+	}
+	if req.Priority != nil {
+		p.Priority = *req.Priority
+	}
+	if req.Stackable != nil {
+		p.Stackable = *req.Stackable
+	}
+	if req.VoucherOnly != nil {
+		p.VoucherOnly = *req.VoucherOnly
+	}
+	if req.IsActive != nil {
+		p.IsActive = *req.IsActive
+	}
+	updated, err := h.promotions.Update(id, p)
+	if err != nil {
+		h.writeError(c, err, "failed to update promotion")
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("updated", updated))
+}
+
+func (h *PromotionsHandler) delete(c *gin.Context) {
+	id := c.Param("id")
+	if _, err := h.promotions.GetByID(id); err != nil {
+		c.JSON(http.StatusNotFound, helper.NotFoundResponse("promotion not found"))
+		return
+	}
+	if err := h.promotions.Delete(id); err != nil {
+		h.writeError(c, err, "failed to delete promotion")
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("deleted", gin.H{"id": id}))
+}
+
+func (h *PromotionsHandler) writeError(c *gin.Context, err error, fallback string) {
+	if errors.Is(err, services.ErrInvalidPromotion) {
+		c.JSON(http.StatusBadRequest, helper.BadRequestResponse(err.Error()))
+		return
+	}
+	c.JSON(http.StatusInternalServerError, helper.InternalErrorResponse(fallback))
+}
@@ -0,0 +1,152 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"faizalmaulana/lsp/conf"
+	"faizalmaulana/lsp/helper"
+	"faizalmaulana/lsp/http/dto"
+	"faizalmaulana/lsp/http/middleware"
+	"faizalmaulana/lsp/http/services"
+	"faizalmaulana/lsp/models/entity"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PurchaseOrdersHandler struct {
+	cfg    *conf.Config
+	orders services.PurchaseOrdersService
+}
+
+func NewPurchaseOrdersHandler(cfg *conf.Config, orders services.PurchaseOrdersService) *PurchaseOrdersHandler {
+	return &PurchaseOrdersHandler{cfg: cfg, orders: orders}
+}
+
+// Register keeps purchasing to managers: orders carry costs, and receiving
+// goods changes the stock.
+func (h *PurchaseOrdersHandler) Register(rr *gin.RouterGroup) {
+	rg := rr.Group("/purchase-orders")
+	rg.GET("", middleware.JWTMiddleware(h.cfg), middleware.RequireRole(entity.RoleAdmin, entity.RoleManager), h.list)
+	rg.POST("", middleware.JWTMiddleware(h.cfg), middleware.RequireRole(entity.RoleAdmin, entity.RoleManager), h.create)
+	rg.GET(":id", middleware.JWTMiddleware(h.cfg), middleware.RequireRole(entity.RoleAdmin, entity.RoleManager), h.get)
+	rg.PUT(":id", middleware.JWTMiddleware(h.cfg), middleware.RequireRole(entity.RoleAdmin, entity.RoleManager), h.update)
+	rg.POST(":id/send", middleware.JWTMiddleware(h.cfg), middleware.RequireRole(entity.RoleAdmin, entity.RoleManager), h.send)
+	rg.POST(":id/receipts", middleware.JWTMiddleware(h.cfg), middleware.RequireRole(entity.RoleAdmin, entity.RoleManager), h.receive)
+	rg.POST(":id/close", middleware.JWTMiddleware(h.cfg), middleware.RequireRole(entity.RoleAdmin, entity.RoleManager), h.close)
+}
+
+// list shows the orders of every status unless ?status= picks one.
+func (h *PurchaseOrdersHandler) list(c *gin.Context) {
+	count, page := pagination(c)
+	out, err := h.orders.GetAll(c.Query("supplier"), c.Query("status"), count, page)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, helper.InternalErrorResponse("failed to list purchase orders"))
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("OK", out))
+}
+
+func purchaseOrderLines(req []dto.PurchaseOrderLineRequest) []services.PurchaseOrderLineInput {
+	out := make([]services.PurchaseOrderLineInput, 0, len(req))
+	for _, l := range req {
+		out = append(out, services.PurchaseOrderLineInput{IdItem: l.IdItem, Quantity: l.Quantity, UnitCost: l.UnitCost})
+	}
+	return out
+}
+
+func (h *PurchaseOrdersHandler) create(c *gin.Context) {
+	var req dto.CreatePurchaseOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, helper.BadRequestResponse(err.Error()))
+		return
+	}
+	o, err := h.orders.Create(claimString(c, "sub"), services.PurchaseOrderInput{
+		IdSupplier: req.IdSupplier,
+		Note:       req.Note,
+		ExpectedAt: req.ExpectedAt,
+		Lines:      purchaseOrderLines(req.Lines),
+	})
+	if err != nil {
+		writePurchaseOrderError(c, err, "failed to create purchase order")
+		return
+	}
+	c.JSON(http.StatusCreated, helper.SuccessResponse("created", o))
+}
+
+func (h *PurchaseOrdersHandler) get(c *gin.Context) {
+	o, err := h.orders.Get(c.Param("id"))
+	if err != nil {
+		writePurchaseOrderError(c, err, "failed to load purchase order")
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("OK", o))
+}
+
+func (h *PurchaseOrdersHandler) update(c *gin.Context) {
+	var req dto.UpdatePurchaseOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, helper.BadRequestResponse(err.Error()))
+		return
+	}
+	in := services.PurchaseOrderUpdate{IdSupplier: req.IdSupplier, Note: req.Note, ExpectedAt: req.ExpectedAt}
+	if req.Lines != nil {
+		in.Lines = purchaseOrderLines(*req.Lines)
+	}
+	o, err := h.orders.Update(c.Param("id"), in)
+	if err != nil {
+		writePurchaseOrderError(c, err, "failed to update purchase order")
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("updated", o))
+}
+
+func (h *PurchaseOrdersHandler) send(c *gin.Context) {
+	o, err := h.orders.Send(c.Param("id"))
+	if err != nil {
+		writePurchaseOrderError(c, err, "failed to send purchase order")
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("updated", o))
+}
+
+// receive records a delivery against the order and adds it to stock.
+func (h *PurchaseOrdersHandler) receive(c *gin.Context) {
+	var req dto.GoodsReceiptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, helper.BadRequestResponse(err.Error()))
+		return
+	}
+	lines := make([]services.GoodsReceiptLineInput, 0, len(req.Lines))
+	for _, l := range req.Lines {
+		lines = append(lines, services.GoodsReceiptLineInput{IdPurchaseOrderLine: l.IdPurchaseOrderLine, Quantity: l.Quantity, UnitCost: l.UnitCost})
+	}
+	o, err := h.orders.Receive(c.Param("id"), claimString(c, "sub"), services.GoodsReceiptInput{Note: req.Note, Lines: lines})
+	if err != nil {
+		writePurchaseOrderError(c, err, "failed to receive goods")
+		return
+	}
+	c.JSON(http.StatusCreated, helper.SuccessResponse("created", o))
+}
+
+func (h *PurchaseOrdersHandler) close(c *gin.Context) {
+	o, err := h.orders.Close(c.Param("id"), claimString(c, "sub"))
+	if err != nil {
+		writePurchaseOrderError(c, err, "failed to close purchase order")
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("updated", o))
+}
+
+func writePurchaseOrderError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrPurchaseOrderNotFound), errors.Is(err, services.ErrSupplierNotFound):
+		c.JSON(http.StatusNotFound, helper.NotFoundResponse(err.Error()))
+	case errors.Is(err, services.ErrInvalidPurchaseOrder), errors.Is(err, services.ErrInvalidItem):
+		c.JSON(http.StatusBadRequest, helper.BadRequestResponse(err.Error()))
+	case errors.Is(err, services.ErrPurchaseOrderStatus):
+		c.JSON(http.StatusConflict, helper.ErrorResponse("CONFLICT", err.Error()))
+	default:
+		c.JSON(http.StatusInternalServerError, helper.InternalErrorResponse(fallback))
+	}
+}
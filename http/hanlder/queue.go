@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"faizalmaulana/lsp/conf"
+	"faizalmaulana/lsp/helper"
+	"faizalmaulana/lsp/http/middleware"
+	"faizalmaulana/lsp/http/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type QueueHandler struct {
+	cfg   *conf.Config
+	queue services.QueueService
+}
+
+func NewQueueHandler(cfg *conf.Config, queue services.QueueService) *QueueHandler {
+	return &QueueHandler{cfg: cfg, queue: queue}
+}
+
+// Register leaves the display open to the screen facing the customers,
+// which signs in with the display token instead of an account. Handing
+// orders over takes a signed-in user.
+func (h *QueueHandler) Register(rr *gin.RouterGroup) {
+	rg := rr.Group("/queue")
+	rg.GET("display", h.display)
+	rg.POST(":number/collect", middleware.JWTMiddleware(h.cfg), h.collect)
+}
+
+// display takes the token from ?token= or the X-Display-Token header.
+func (h *QueueHandler) display(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		token = c.GetHeader("X-Display-Token")
+	}
+	out, err := h.queue.Display(token)
+	if err != nil {
+		writeQueueError(c, err, "failed to load queue")
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("OK", out))
+}
+
+func (h *QueueHandler) collect(c *gin.Context) {
+	number, err := strconv.Atoi(c.Param("number"))
+	if err != nil || number <= 0 {
+		c.JSON(http.StatusBadRequest, helper.BadRequestResponse("invalid queue number"))
+		return
+	}
+	if err := h.queue.Collect(number); err != nil {
+		writeQueueError(c, err, "failed to collect order")
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("updated", gin.H{"queue_number": number}))
+}
+
+func writeQueueError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrQueueDisplayOff), errors.Is(err, services.ErrQueueNumberNotFound):
+		c.JSON(http.StatusNotFound, helper.NotFoundResponse(err.Error()))
+	case errors.Is(err, services.ErrQueueDisplayToken):
+		c.JSON(http.StatusForbidden, helper.ErrorResponse("FORBIDDEN", err.Error()))
+	default:
+		c.JSON(http.StatusInternalServerError, helper.InternalErrorResponse(fallback))
+	}
+}
@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"faizalmaulana/lsp/conf"
+	"faizalmaulana/lsp/helper"
+	"faizalmaulana/lsp/http/dto"
+	"faizalmaulana/lsp/http/middleware"
+	"faizalmaulana/lsp/http/services"
+	"faizalmaulana/lsp/models/entity"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RefundsHandler struct {
+	cfg     *conf.Config
+	refunds services.RefundsService
+}
+
+func NewRefundsHandler(cfg *conf.Config, refunds services.RefundsService) *RefundsHandler {
+	return &RefundsHandler{cfg: cfg, refunds: refunds}
+}
+
+func (h *RefundsHandler) Register(rr *gin.RouterGroup) {
+	rr.GET("/transactions/:id/refunds", h.listByTransaction)
+	rr.POST("/transactions/:id/refunds", middleware.JWTMiddleware(h.cfg), middleware.RequireRole(entity.RoleAdmin, entity.RoleManager), h.create)
+	rr.GET("/refunds/:id", h.get)
+	rr.GET("/refunds/:id/receipt", h.receipt)
+}
+
+func (h *RefundsHandler) create(c *gin.Context) {
+	var req dto.CreateRefundRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, helper.BadRequestResponse(err.Error()))
+		return
+	}
+	userID := claimString(c, "sub")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, helper.UnauthorizedResponse())
+		return
+	}
+	lines := make([]services.RefundLineInput, 0, len(req.Lines))
+	for _, l := range req.Lines {
+		lines = append(lines, services.RefundLineInput{IdPivot: l.IdPivot, Quantity: l.Quantity, Restock: l.Restock})
+	}
+	d, err := h.refunds.Create(services.RefundRequest{
+		IdTransaction: c.Param("id"),
+		IdUser:        userID,
+		Reason:        req.Reason,
+		Method:        req.Method,
+		Reference:     req.Reference,
+		Lines:         lines,
+	})
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidRefund) {
+			c.JSON(http.StatusBadRequest, helper.BadRequestResponse(err.Error()))
+			return
+		}
+		writeTransactionError(c, err, "failed to create refund")
+		return
+	}
+	c.JSON(http.StatusCreated, helper.SuccessResponse("created", refundResponse(d)))
+}
+
+func (h *RefundsHandler) listByTransaction(c *gin.Context) {
+	list, err := h.refunds.ListByTransaction(c.Param("id"))
+	if err != nil {
+		writeTransactionError(c, err, "failed to list refunds")
+		return
+	}
+	out := make([]dto.RefundResponse, 0, len(list))
+	for i := range list {
+		out = append(out, refundResponse(&list[i]))
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("OK", out))
+}
+
+func (h *RefundsHandler) get(c *gin.Context) {
+	d, err := h.refunds.Get(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, helper.NotFoundResponse("refund not found"))
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("OK", refundResponse(d)))
+}
+
+func (h *RefundsHandler) receipt(c *gin.Context) {
+	r, err := h.refunds.Receipt(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, helper.NotFoundResponse("refund not found"))
+		return
+	}
+	writeReceipt(c, r)
+}
+
+func refundResponse(d *services.RefundDetail) dto.RefundResponse {
+	r := d.Refund
+	out := dto.RefundResponse{
+		IdRefund:      r.IdRefund,
+		IdTransaction: r.IdTransaction,
+		IdUser:        r.IdUser,
+		Reason:        r.Reason,
+		Method:        r.Method,
+		Reference:     r.Reference,
+		Amount:        r.Amount,
+		Timestamp:     r.Timestamp.Format(time.RFC3339),
+		Lines:         make([]dto.RefundLineDetail, 0, len(d.Lines)),
+	}
+	if d.Transaction != nil {
+		out.TransactionStatus = d.Transaction.Status
+	}
+	if d.Credit != nil {
+		out.Credit = &dto.IssuedGiftCardResponse{Card: d.Credit.Card, Pin: d.Credit.Pin}
+	}
+	for _, l := range d.Lines {
+		out.Lines = append(out.Lines, dto.RefundLineDetail{
+			IdRefundLine: l.IdRefundLine,
+			IdPivot:      l.IdPivot,
+			IdItem:       l.IdItem,
+			ItemName:     l.ItemName,
+			Quantity:     l.Quantity,
+			UnitPrice:    l.UnitPrice,
+			Amount:       l.Amount,
+			Restock:      l.Restock,
+		})
+	}
+	return out
+}
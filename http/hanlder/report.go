@@ -8,21 +8,21 @@ import (
 	"faizalmaulana/lsp/conf"
 	"faizalmaulana/lsp/helper"
 	"faizalmaulana/lsp/http/dto"
+	"faizalmaulana/lsp/http/middleware"
 	"faizalmaulana/lsp/http/services"
-	"faizalmaulana/lsp/models/repo"
+	"faizalmaulana/lsp/models/entity"
 
 	"github.com/gin-gonic/gin"
 )
 
 type ReportHandler struct {
 	cfg       *conf.Config
-	txSvc     services.TransactionsService
-	pivotRepo repo.PivotItemsToTransactionsRepo
-	itemsRepo repo.ItemsRepo
+	reports   services.ReportsService
+	dashboard services.DashboardService
 }
 
-func NewReportHandler(cfg *conf.Config, tx services.TransactionsService, pivot repo.PivotItemsToTransactionsRepo, items repo.ItemsRepo) *ReportHandler {
-	return &ReportHandler{cfg: cfg, txSvc: tx, pivotRepo: pivot, itemsRepo: items}
+func NewReportHandler(cfg *conf.Config, reports services.ReportsService, dashboard services.DashboardService) *ReportHandler {
+	return &ReportHandler{cfg: cfg, reports: reports, dashboard: dashboard}
 }
 
 func (h *ReportHandler) Register(rg *gin.RouterGroup) {
@@ -30,6 +30,7 @@ func (h *ReportHandler) Register(rg *gin.RouterGroup) {
 	rg.GET("/reports/:bulan/:tahun", h.reportByMonthYear)
 	rg.GET("/reports/today", h.reportToday)
 	rg.GET("/reports/today/summary", h.reportTodaySummary)
+	rg.GET("/reports/today/stream", middleware.JWTMiddleware(h.cfg), middleware.RequireRole(entity.RoleAdmin, entity.RoleManager), h.streamToday)
 }
 
 func (h *ReportHandler) reportByExactDate(c *gin.Context) {
@@ -45,86 +46,14 @@ func (h *ReportHandler) reportByExactDate(c *gin.Context) {
 		return
 	}
 
-	list, err := h.txSvc.GetAll(1000, 1)
+	from := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.Local)
+	sum, err := h.reports.Summarize(from, from.AddDate(0, 0, 1), 5)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, helper.InternalErrorResponse("failed to query transactions"))
 		return
 	}
 
-	var items []dto.ReportTransaction
-	total := 0
-	var sum float64
-	totalProducts := 0
-	var minOrder float64 = 0
-	var maxOrder float64 = 0
-	type agg struct {
-		qty     int
-		revenue float64
-	}
-	perItem := map[string]*agg{}
-
-	for _, t := range list {
-		ts := t.Timestamp
-		if ts.IsZero() {
-			continue
-		}
-		if ts.Year() == year && int(ts.Month()) == month && ts.Day() == day {
-			items = append(items, dto.ReportTransaction{
-				IdTransaction: t.IdTransaction,
-				TotalPrice:    t.TotalPrice,
-				BuyerContact:  t.BuyerContact,
-				Timestamp:     ts.Format(time.RFC3339),
-			})
-			sum += t.TotalPrice
-			total++
-			pivots, _ := h.pivotRepo.ListByTransaction(t.IdTransaction)
-			for _, p := range pivots {
-				totalProducts += p.Quantity
-				if minOrder == 0 || t.TotalPrice < minOrder {
-					minOrder = t.TotalPrice
-				}
-				if t.TotalPrice > maxOrder {
-					maxOrder = t.TotalPrice
-				}
-				a := perItem[p.IdItem]
-				if a == nil {
-					a = &agg{}
-					perItem[p.IdItem] = a
-				}
-				a.qty += p.Quantity
-				a.revenue += float64(p.Quantity) * p.Price
-			}
-		}
-	}
-
-	top := make([]dto.TopItem, 0, len(perItem))
-	for id, a := range perItem {
-		it, err := h.itemsRepo.GetByID(id)
-		if err != nil {
-			continue
-		}
-		top = append(top, dto.TopItem{IdItem: it.IdItem, ItemName: it.ItemName, ImageUrl: it.ImageUrl, QuantitySold: a.qty, Revenue: a.revenue})
-	}
-	if len(top) > 1 {
-		for i := 0; i < len(top)-1; i++ {
-			for j := i + 1; j < len(top); j++ {
-				if top[j].QuantitySold > top[i].QuantitySold || (top[j].QuantitySold == top[i].QuantitySold && top[j].Revenue > top[i].Revenue) {
-					top[i], top[j] = top[j], top[i]
-				}
-			}
-		}
-		if len(top) > 5 {
-			top = top[:5]
-		}
-	}
-	aov := 0.0
-	avgItems := 0.0
-	if total > 0 {
-		aov = sum / float64(total)
-		avgItems = float64(totalProducts) / float64(total)
-	}
-
-	resp := dto.TodayReportResponse{Date: time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.Local).Format("2006-01-02"), Total: total, Sum: sum, TotalProductsSold: totalProducts, AverageOrderValue: aov, MinOrderValue: minOrder, MaxOrderValue: maxOrder, AvgItemsPerTx: avgItems, TopItems: top, Items: items}
+	resp := dto.TodayReportResponse{Date: from.Format("2006-01-02"), ReportBody: reportBody(sum)}
 	c.JSON(http.StatusOK, helper.SuccessResponse("OK", resp))
 }
 
@@ -138,263 +67,242 @@ func (h *ReportHandler) reportByMonthYear(c *gin.Context) {
 		return
 	}
 
-	list, err := h.txSvc.GetAll(1000, 1)
+	from := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.Local)
+	sum, err := h.reports.Summarize(from, from.AddDate(0, 1, 0), 5)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, helper.InternalErrorResponse("failed to query transactions"))
 		return
 	}
 
-	var items []dto.ReportTransaction
-	total := 0
-	var sum float64
-	totalProducts := 0
-	var minOrder float64 = 0
-	var maxOrder float64 = 0
-	type agg struct {
-		qty     int
-		revenue float64
-	}
-	perItem := map[string]*agg{}
-	for _, t := range list {
-		ts := t.Timestamp
-		if ts.IsZero() {
-			continue
-		}
-		if int(ts.Month()) == month && ts.Year() == year {
-			items = append(items, dto.ReportTransaction{
-				IdTransaction: t.IdTransaction,
-				TotalPrice:    t.TotalPrice,
-				BuyerContact:  t.BuyerContact,
-				Timestamp:     ts.Format(time.RFC3339),
-			})
-			sum += t.TotalPrice
-			total++
-			pivots, _ := h.pivotRepo.ListByTransaction(t.IdTransaction)
-			for _, p := range pivots {
-				totalProducts += p.Quantity
-				if minOrder == 0 || t.TotalPrice < minOrder {
-					minOrder = t.TotalPrice
-				}
-				if t.TotalPrice > maxOrder {
-					maxOrder = t.TotalPrice
-				}
-				a := perItem[p.IdItem]
-				if a == nil {
-					a = &agg{}
-					perItem[p.IdItem] = a
-				}
-				a.qty += p.Quantity
-				a.revenue += float64(p.Quantity) * p.Price
-			}
-		}
-	}
+	resp := dto.ReportResponse{Month: month, Year: year, ReportBody: reportBody(sum)}
+	c.JSON(http.StatusOK, helper.SuccessResponse("OK", resp))
+}
 
-	top := make([]dto.TopItem, 0, len(perItem))
-	for id, a := range perItem {
-		it, err := h.itemsRepo.GetByID(id)
-		if err != nil {
-			continue
-		}
-		top = append(top, dto.TopItem{IdItem: it.IdItem, ItemName: it.ItemName, ImageUrl: it.ImageUrl, QuantitySold: a.qty, Revenue: a.revenue})
-	}
-	if len(top) > 1 {
-		for i := 0; i < len(top)-1; i++ {
-			for j := i + 1; j < len(top); j++ {
-				if top[j].QuantitySold > top[i].QuantitySold || (top[j].QuantitySold == top[i].QuantitySold && top[j].Revenue > top[i].Revenue) {
-					top[i], top[j] = top[j], top[i]
-				}
-			}
-		}
-		if len(top) > 5 {
-			top = top[:5]
-		}
-	}
-	aov := 0.0
-	avgItems := 0.0
-	if total > 0 {
-		aov = sum / float64(total)
-		avgItems = float64(totalProducts) / float64(total)
+func (h *ReportHandler) reportToday(c *gin.Context) {
+	now := time.Now()
+	from := startOfDay(now)
+
+	sum, err := h.reports.Summarize(from, from.AddDate(0, 0, 1), 5)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, helper.InternalErrorResponse("failed to query transactions"))
+		return
 	}
 
-	resp := dto.ReportResponse{Month: month, Year: year, Total: total, Sum: sum, TotalProductsSold: totalProducts, AverageOrderValue: aov, MinOrderValue: minOrder, MaxOrderValue: maxOrder, AvgItemsPerTx: avgItems, TopItems: top, Items: items}
+	resp := dto.TodayReportResponse{Date: now.Format("2006-01-02"), ReportBody: reportBody(sum)}
 	c.JSON(http.StatusOK, helper.SuccessResponse("OK", resp))
 }
 
-func (h *ReportHandler) reportToday(c *gin.Context) {
+func (h *ReportHandler) reportTodaySummary(c *gin.Context) {
 	now := time.Now()
-	y, m, d := now.Date()
+	from := startOfDay(now)
 
-	list, err := h.txSvc.GetAll(1000, 1)
+	sum, err := h.reports.Summarize(from, from.AddDate(0, 0, 1), 5)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, helper.InternalErrorResponse("failed to query transactions"))
 		return
 	}
 
-	var items []dto.ReportTransaction
-	total := 0
-	var sum float64
-	totalProducts := 0
-	var minOrder float64 = 0
-	var maxOrder float64 = 0
-	type agg struct {
-		qty     int
-		revenue float64
+	c.JSON(http.StatusOK, helper.SuccessResponse("OK", todaySummary(now.Format("2006-01-02"), sum)))
+}
+
+// streamToday pushes today's summary to a dashboard as server-sent events,
+// every time a sale, void or refund changes it. A dashboard reconnecting
+// with Last-Event-ID gets the events it missed, or a snapshot when they are
+// gone.
+func (h *ReportHandler) streamToday(c *gin.Context) {
+	backlog, events, stop, err := h.dashboard.Subscribe(c.GetHeader("Last-Event-ID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, helper.InternalErrorResponse("failed to query transactions"))
+		return
 	}
-	perItem := map[string]*agg{}
-	for _, t := range list {
-		ts := t.Timestamp
-		if ts.IsZero() {
-			continue
-		}
-		ty, tm, td := ts.Date()
-		if ty == y && tm == m && td == d {
-			items = append(items, dto.ReportTransaction{
-				IdTransaction: t.IdTransaction,
-				TotalPrice:    t.TotalPrice,
-				BuyerContact:  t.BuyerContact,
-				Timestamp:     ts.Format(time.RFC3339),
-			})
-			sum += t.TotalPrice
-			total++
-			pivots, _ := h.pivotRepo.ListByTransaction(t.IdTransaction)
-			for _, p := range pivots {
-				totalProducts += p.Quantity
-				if minOrder == 0 || t.TotalPrice < minOrder {
-					minOrder = t.TotalPrice
-				}
-				if t.TotalPrice > maxOrder {
-					maxOrder = t.TotalPrice
-				}
-				a := perItem[p.IdItem]
-				if a == nil {
-					a = &agg{}
-					perItem[p.IdItem] = a
-				}
-				a.qty += p.Quantity
-				a.revenue += float64(p.Quantity) * p.Price
-			}
-		}
+	defer stop()
+	streamEvents(c, backlog, events, dashboardEvent)
+}
+
+func dashboardEvent(data interface{}) interface{} {
+	u, ok := data.(services.DashboardUpdate)
+	if !ok {
+		return data
+	}
+	out := dto.DashboardEvent{
+		Summary: todaySummary(u.Dashboard.Date, u.Dashboard.Summary),
+		Latest:  make([]dto.ReportTransaction, 0, len(u.Dashboard.Latest)),
+	}
+	for _, t := range u.Dashboard.Latest {
+		out.Latest = append(out.Latest, reportTransaction(t))
+	}
+	switch {
+	case u.Transaction != nil && u.Transaction.Status == entity.TransactionStatusVoided:
+		v := reportVoid(*u.Transaction)
+		out.Void = &v
+	case u.Transaction != nil:
+		t := reportTransaction(*u.Transaction)
+		out.Transaction = &t
+	case u.Refund != nil:
+		r := reportRefund(*u.Refund)
+		out.Refund = &r
 	}
+	return out
+}
 
-	top := make([]dto.TopItem, 0, len(perItem))
-	for id, a := range perItem {
-		it, err := h.itemsRepo.GetByID(id)
-		if err != nil {
-			continue
-		}
-		top = append(top, dto.TopItem{IdItem: it.IdItem, ItemName: it.ItemName, ImageUrl: it.ImageUrl, QuantitySold: a.qty, Revenue: a.revenue})
+func todaySummary(date string, sum *services.ReportSummary) dto.TodaySummaryResponse {
+	return dto.TodaySummaryResponse{
+		Date:              date,
+		TotalTransactions: sum.TotalTransactions,
+		TotalProductsSold: sum.TotalProductsSold,
+		SumTotalPrice:     sum.SumTotalPrice,
+		AverageOrderValue: sum.AverageOrderValue,
+		MinOrderValue:     sum.MinOrderValue,
+		MaxOrderValue:     sum.MaxOrderValue,
+		AvgItemsPerTx:     sum.AvgItemsPerTx,
+		TopItems:          topItems(sum),
+		PaymentMethods:    paymentMethods(sum),
+		DiscountGiven:     sum.DiscountGiven,
+		Discounts:         reportDiscounts(sum),
+		Subtotal:          sum.Subtotal,
+		ServiceCharge:     sum.ServiceChargeTotal,
+		TaxTotal:          sum.TaxTotal,
+		GiftCardSales:     sum.GiftCardSales,
+		GiftCardsSold:     sum.GiftCardsSold,
+		CashRounding:      sum.CashRounding,
+		VoidedCount:       sum.VoidedCount,
+		VoidedTotal:       sum.VoidedTotal,
+		RefundCount:       sum.RefundCount,
+		RefundTotal:       sum.RefundTotal,
+		RefundedProducts:  sum.RefundedProducts,
+		NetSales:          sum.NetSales,
 	}
-	if len(top) > 1 {
-		for i := 0; i < len(top)-1; i++ {
-			for j := i + 1; j < len(top); j++ {
-				if top[j].QuantitySold > top[i].QuantitySold || (top[j].QuantitySold == top[i].QuantitySold && top[j].Revenue > top[i].Revenue) {
-					top[i], top[j] = top[j], top[i]
-				}
-			}
-		}
-		if len(top) > 5 {
-			top = top[:5]
+}
+
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// reportBody fills in what every sales report shows from sum.
+func reportBody(sum *services.ReportSummary) dto.ReportBody {
+	return dto.ReportBody{
+		Total:             sum.TotalTransactions,
+		Sum:               sum.SumTotalPrice,
+		TotalProductsSold: sum.TotalProductsSold,
+		AverageOrderValue: sum.AverageOrderValue,
+		MinOrderValue:     sum.MinOrderValue,
+		MaxOrderValue:     sum.MaxOrderValue,
+		AvgItemsPerTx:     sum.AvgItemsPerTx,
+		TopItems:          topItems(sum),
+		PaymentMethods:    paymentMethods(sum),
+		DiscountGiven:     sum.DiscountGiven,
+		Discounts:         reportDiscounts(sum),
+		Subtotal:          sum.Subtotal,
+		ServiceCharge:     sum.ServiceChargeTotal,
+		TaxTotal:          sum.TaxTotal,
+		GiftCardSales:     sum.GiftCardSales,
+		GiftCardsSold:     sum.GiftCardsSold,
+		CashRounding:      sum.CashRounding,
+		VoidedCount:       sum.VoidedCount,
+		VoidedTotal:       sum.VoidedTotal,
+		Voids:             reportVoids(sum),
+		RefundCount:       sum.RefundCount,
+		RefundTotal:       sum.RefundTotal,
+		RefundedProducts:  sum.RefundedProducts,
+		NetSales:          sum.NetSales,
+		Refunds:           reportRefunds(sum),
+		CashRoundingByDay: reportRounding(sum),
+		Items:             reportTransactions(sum),
+	}
+}
+
+func topItems(sum *services.ReportSummary) []dto.TopItem {
+	out := make([]dto.TopItem, 0, len(sum.TopItems))
+	for _, it := range sum.TopItems {
+		mods := make([]dto.TopItemModifier, 0, len(it.Modifiers))
+		for _, m := range it.Modifiers {
+			mods = append(mods, dto.TopItemModifier{GroupName: m.GroupName, OptionName: m.OptionName, QuantitySold: m.QuantitySold, Revenue: m.Revenue})
 		}
+		out = append(out, dto.TopItem{IdItem: it.IdItem, ItemName: it.ItemName, ImageUrl: it.ImageUrl, QuantitySold: it.QuantitySold, Revenue: it.Revenue, Discount: it.Discount, BundleQuantity: it.BundleQuantity, BundleRevenue: it.BundleRevenue, RefundedQuantity: it.RefundedQuantity, RefundedRevenue: it.RefundedRevenue, Modifiers: mods})
 	}
-	aov := 0.0
-	avgItems := 0.0
-	if total > 0 {
-		aov = sum / float64(total)
-		avgItems = float64(totalProducts) / float64(total)
+	return out
+}
+
+func paymentMethods(sum *services.ReportSummary) []dto.PaymentMethodTotal {
+	out := make([]dto.PaymentMethodTotal, 0, len(sum.PaymentMethods))
+	for _, m := range sum.PaymentMethods {
+		out = append(out, dto.PaymentMethodTotal{Method: m.Method, Count: m.Count, Amount: m.Amount, Refunded: m.Refunded})
 	}
+	return out
+}
 
-	resp := dto.TodayReportResponse{Date: now.Format("2006-01-02"), Total: total, Sum: sum, TotalProductsSold: totalProducts, AverageOrderValue: aov, MinOrderValue: minOrder, MaxOrderValue: maxOrder, AvgItemsPerTx: avgItems, TopItems: top, Items: items}
-	c.JSON(http.StatusOK, helper.SuccessResponse("OK", resp))
+func reportDiscounts(sum *services.ReportSummary) []dto.ReportDiscount {
+	out := make([]dto.ReportDiscount, 0, len(sum.Discounts))
+	for _, d := range sum.Discounts {
+		out = append(out, dto.ReportDiscount{IdPromotion: d.IdPromotion, Name: d.Name, Source: d.Source, Count: d.Count, Amount: d.Amount})
+	}
+	return out
 }
 
-func (h *ReportHandler) reportTodaySummary(c *gin.Context) {
-	now := time.Now()
-	y, m, d := now.Date()
+func reportRounding(sum *services.ReportSummary) []dto.ReportRoundingDay {
+	out := make([]dto.ReportRoundingDay, 0, len(sum.CashRoundingByDay))
+	for _, r := range sum.CashRoundingByDay {
+		out = append(out, dto.ReportRoundingDay{Date: r.Date, Count: r.Count, Amount: r.Amount})
+	}
+	return out
+}
 
-	list, err := h.txSvc.GetAll(1000, 1)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, helper.InternalErrorResponse("failed to query transactions"))
-		return
+func reportTransactions(sum *services.ReportSummary) []dto.ReportTransaction {
+	var out []dto.ReportTransaction
+	for _, t := range sum.Transactions {
+		out = append(out, reportTransaction(t))
 	}
+	return out
+}
 
-	totalTx := 0
-	var sum float64
-	totalProducts := 0
-	var minOrder float64 = 0
-	var maxOrder float64 = 0
-	type agg struct {
-		qty     int
-		revenue float64
+func reportTransaction(t entity.Transactions) dto.ReportTransaction {
+	return dto.ReportTransaction{
+		IdTransaction: t.IdTransaction,
+		TotalPrice:    t.TotalPrice,
+		BuyerContact:  t.BuyerContact,
+		IdCustomer:    t.IdCustomer,
+		Timestamp:     t.Timestamp.Format(time.RFC3339),
 	}
-	perItem := map[string]*agg{}
+}
 
-	for _, t := range list {
-		ts := t.Timestamp
-		if ts.IsZero() {
-			continue
-		}
-		ty, tm, td := ts.Date()
-		if ty == y && tm == m && td == d {
-			totalTx++
-			sum += t.TotalPrice
-			if minOrder == 0 || t.TotalPrice < minOrder {
-				minOrder = t.TotalPrice
-			}
-			if t.TotalPrice > maxOrder {
-				maxOrder = t.TotalPrice
-			}
-			pivots, _ := h.pivotRepo.ListByTransaction(t.IdTransaction)
-			for _, p := range pivots {
-				totalProducts += p.Quantity
-				a := perItem[p.IdItem]
-				if a == nil {
-					a = &agg{}
-					perItem[p.IdItem] = a
-				}
-				a.qty += p.Quantity
-				a.revenue += float64(p.Quantity) * p.Price
-			}
-		}
+func reportVoids(sum *services.ReportSummary) []dto.ReportVoid {
+	out := make([]dto.ReportVoid, 0, len(sum.Voids))
+	for _, t := range sum.Voids {
+		out = append(out, reportVoid(t))
 	}
+	return out
+}
 
-	top := make([]dto.TopItem, 0, len(perItem))
-	for id, a := range perItem {
-		it, err := h.itemsRepo.GetByID(id)
-		if err != nil {
-			continue
-		}
-		top = append(top, dto.TopItem{IdItem: it.IdItem, ItemName: it.ItemName, ImageUrl: it.ImageUrl, QuantitySold: a.qty, Revenue: a.revenue})
+func reportVoid(t entity.Transactions) dto.ReportVoid {
+	v := dto.ReportVoid{
+		IdTransaction: t.IdTransaction,
+		TotalPrice:    t.TotalPrice,
+		Reason:        t.VoidReason,
+		VoidedBy:      t.VoidedBy,
+		Timestamp:     t.Timestamp.Format(time.RFC3339),
 	}
-	if len(top) > 1 {
-		for i := 0; i < len(top)-1; i++ {
-			for j := i + 1; j < len(top); j++ {
-				if top[j].QuantitySold > top[i].QuantitySold || (top[j].QuantitySold == top[i].QuantitySold && top[j].Revenue > top[i].Revenue) {
-					top[i], top[j] = top[j], top[i]
-				}
-			}
-		}
-		if len(top) > 5 {
-			top = top[:5]
-		}
+	if t.VoidedAt != nil {
+		v.VoidedAt = t.VoidedAt.Format(time.RFC3339)
 	}
+	return v
+}
 
-	aov := 0.0
-	avgItems := 0.0
-	if totalTx > 0 {
-		aov = sum / float64(totalTx)
-		avgItems = float64(totalProducts) / float64(totalTx)
+func reportRefunds(sum *services.ReportSummary) []dto.ReportRefund {
+	out := make([]dto.ReportRefund, 0, len(sum.Refunds))
+	for _, r := range sum.Refunds {
+		out = append(out, reportRefund(r))
 	}
+	return out
+}
 
-	resp := dto.TodaySummaryResponse{
-		Date:              now.Format("2006-01-02"),
-		TotalTransactions: totalTx,
-		TotalProductsSold: totalProducts,
-		SumTotalPrice:     sum,
-		AverageOrderValue: aov,
-		MinOrderValue:     minOrder,
-		MaxOrderValue:     maxOrder,
-		AvgItemsPerTx:     avgItems,
-		TopItems:          top,
+func reportRefund(r entity.Refunds) dto.ReportRefund {
+	return dto.ReportRefund{
+		IdRefund:      r.IdRefund,
+		IdTransaction: r.IdTransaction,
+		Method:        r.Method,
+		Amount:        r.Amount,
+		Reason:        r.Reason,
+		Timestamp:     r.Timestamp.Format(time.RFC3339),
 	}
-	c.JSON(http.StatusOK, helper.SuccessResponse("OK", resp))
 }
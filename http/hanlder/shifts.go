@@ -0,0 +1,189 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"faizalmaulana/lsp/conf"
+	"faizalmaulana/lsp/helper"
+	"faizalmaulana/lsp/http/dto"
+	"faizalmaulana/lsp/http/middleware"
+	"faizalmaulana/lsp/http/services"
+	"faizalmaulana/lsp/models/entity"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ShiftsHandler struct {
+	cfg    *conf.Config
+	shifts services.ShiftsService
+}
+
+func NewShiftsHandler(cfg *conf.Config, shifts services.ShiftsService) *ShiftsHandler {
+	return &ShiftsHandler{cfg: cfg, shifts: shifts}
+}
+
+// Register lets cashiers work their own shifts; listing every shift takes a
+// manager.
+func (h *ShiftsHandler) Register(rr *gin.RouterGroup) {
+	rg := rr.Group("/shifts")
+	rg.GET("", middleware.JWTMiddleware(h.cfg), middleware.RequireRole(entity.RoleAdmin, entity.RoleManager), h.list)
+	rg.POST("open", middleware.JWTMiddleware(h.cfg), h.open)
+	rg.GET("current", middleware.JWTMiddleware(h.cfg), h.current)
+	rg.GET(":id", middleware.JWTMiddleware(h.cfg), h.get)
+	rg.POST(":id/movements", middleware.JWTMiddleware(h.cfg), h.movement)
+	rg.POST(":id/close", middleware.JWTMiddleware(h.cfg), h.close)
+	rg.GET(":id/report", middleware.JWTMiddleware(h.cfg), h.report)
+}
+
+func shiftActor(c *gin.Context) services.ShiftActor {
+	return services.ShiftActor{IdUser: claimString(c, "sub"), Role: claimString(c, "role")}
+}
+
+func (h *ShiftsHandler) list(c *gin.Context) {
+	count, page := pagination(c)
+	out, err := h.shifts.GetAll(c.Query("status"), c.Query("id_user"), count, page)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, helper.InternalErrorResponse("failed to list shifts"))
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("OK", out))
+}
+
+func (h *ShiftsHandler) open(c *gin.Context) {
+	var req dto.OpenShiftRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, helper.BadRequestResponse(err.Error()))
+		return
+	}
+	sh, err := h.shifts.Open(claimString(c, "sub"), req.OpeningFloat, req.Note)
+	if err != nil {
+		writeShiftError(c, err, "failed to open shift")
+		return
+	}
+	c.JSON(http.StatusCreated, helper.SuccessResponse("created", sh))
+}
+
+func (h *ShiftsHandler) current(c *gin.Context) {
+	r, err := h.shifts.Current(claimString(c, "sub"))
+	if err != nil {
+		writeShiftError(c, err, "failed to load shift")
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("OK", shiftReportResponse(r)))
+}
+
+func (h *ShiftsHandler) get(c *gin.Context) {
+	d, err := h.shifts.Get(c.Param("id"), shiftActor(c))
+	if err != nil {
+		writeShiftError(c, err, "failed to load shift")
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("OK", dto.ShiftDetailResponse{Shifts: d.Shift, Movements: d.Movements, Counts: d.Counts}))
+}
+
+func (h *ShiftsHandler) movement(c *gin.Context) {
+	var req dto.ShiftMovementRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, helper.BadRequestResponse(err.Error()))
+		return
+	}
+	m, err := h.shifts.AddMovement(c.Param("id"), shiftActor(c), services.ShiftMovementInput{Kind: req.Kind, Amount: req.Amount, Reason: req.Reason})
+	if err != nil {
+		writeShiftError(c, err, "failed to record cash movement")
+		return
+	}
+	c.JSON(http.StatusCreated, helper.SuccessResponse("created", m))
+}
+
+func (h *ShiftsHandler) close(c *gin.Context) {
+	var req dto.CloseShiftRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, helper.BadRequestResponse(err.Error()))
+		return
+	}
+	counts := make([]services.DenominationCount, 0, len(req.Counts))
+	for _, n := range req.Counts {
+		counts = append(counts, services.DenominationCount{Denomination: n.Denomination, Quantity: n.Quantity})
+	}
+	r, err := h.shifts.Close(c.Param("id"), shiftActor(c), counts, req.Note)
+	if err != nil {
+		writeShiftError(c, err, "failed to close shift")
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("closed", shiftReportResponse(r)))
+}
+
+func (h *ShiftsHandler) report(c *gin.Context) {
+	r, err := h.shifts.Report(c.Param("id"), shiftActor(c))
+	if err != nil {
+		writeShiftError(c, err, "failed to build shift report")
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("OK", shiftReportResponse(r)))
+}
+
+func shiftReportResponse(r *services.ShiftReport) dto.ShiftReportResponse {
+	payments := make([]dto.PaymentMethodTotal, 0, len(r.Payments))
+	for _, m := range r.Payments {
+		payments = append(payments, dto.PaymentMethodTotal{Method: m.Method, Count: m.Count, Amount: m.Amount, Refunded: m.Refunded})
+	}
+	cash := dto.ShiftCashResponse{
+		OpeningFloat: r.Cash.OpeningFloat,
+		Sales:        r.Cash.Sales,
+		Refunds:      r.Cash.Refunds,
+		CashIn:       r.Cash.CashIn,
+		CashOut:      r.Cash.CashOut,
+		Drops:        r.Cash.Drops,
+		Expected:     r.Cash.Expected,
+	}
+	if r.Kind == services.ShiftReportZ {
+		counted, diff := r.Cash.Counted, r.Cash.Difference
+		cash.Counted, cash.Difference = &counted, &diff
+		switch {
+		case diff > 0:
+			cash.Result = "over"
+		case diff < 0:
+			cash.Result = "short"
+		default:
+			cash.Result = "balanced"
+		}
+	}
+	return dto.ShiftReportResponse{
+		Report:        r.Kind,
+		Shift:         r.Shift,
+		GeneratedAt:   r.GeneratedAt.Format(time.RFC3339),
+		Sales:         r.Sales,
+		SalesTotal:    r.SalesTotal,
+		Subtotal:      r.Subtotal,
+		DiscountTotal: r.DiscountTotal,
+		ServiceCharge: r.ServiceCharge,
+		TaxTotal:      r.TaxTotal,
+		CashRounding:  r.CashRounding,
+		Payments:      payments,
+		RefundCount:   r.RefundCount,
+		RefundTotal:   r.RefundTotal,
+		NetSales:      r.NetSales,
+		VoidedCount:   r.VoidedCount,
+		VoidedTotal:   r.VoidedTotal,
+		Movements:     r.Movements,
+		Counts:        r.Counts,
+		Cash:          cash,
+	}
+}
+
+func writeShiftError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrShiftNotFound), errors.Is(err, services.ErrNoOpenShift):
+		c.JSON(http.StatusNotFound, helper.NotFoundResponse(err.Error()))
+	case errors.Is(err, services.ErrInvalidShift):
+		c.JSON(http.StatusBadRequest, helper.BadRequestResponse(err.Error()))
+	case errors.Is(err, services.ErrShiftForbidden):
+		c.JSON(http.StatusForbidden, helper.ErrorResponse("FORBIDDEN", err.Error()))
+	case errors.Is(err, services.ErrShiftOpen), errors.Is(err, services.ErrShiftClosed), errors.Is(err, services.ErrPeriodClosed):
+		c.JSON(http.StatusConflict, helper.ErrorResponse("CONFLICT", err.Error()))
+	default:
+		c.JSON(http.StatusInternalServerError, helper.InternalErrorResponse(fallback))
+	}
+}
@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"faizalmaulana/lsp/conf"
+	"faizalmaulana/lsp/helper"
+	"faizalmaulana/lsp/http/dto"
+	"faizalmaulana/lsp/http/middleware"
+	"faizalmaulana/lsp/http/services"
+	"faizalmaulana/lsp/models/entity"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SuppliersHandler struct {
+	cfg       *conf.Config
+	suppliers services.SuppliersService
+}
+
+func NewSuppliersHandler(cfg *conf.Config, suppliers services.SuppliersService) *SuppliersHandler {
+	return &SuppliersHandler{cfg: cfg, suppliers: suppliers}
+}
+
+// Register keeps the suppliers to managers, who do the buying.
+func (h *SuppliersHandler) Register(rr *gin.RouterGroup) {
+	rg := rr.Group("/suppliers")
+	rg.GET("", middleware.JWTMiddleware(h.cfg), middleware.RequireRole(entity.RoleAdmin, entity.RoleManager), h.list)
+	rg.POST("", middleware.JWTMiddleware(h.cfg), middleware.RequireRole(entity.RoleAdmin, entity.RoleManager), h.create)
+	rg.GET(":id", middleware.JWTMiddleware(h.cfg), middleware.RequireRole(entity.RoleAdmin, entity.RoleManager), h.get)
+	rg.PUT(":id", middleware.JWTMiddleware(h.cfg), middleware.RequireRole(entity.RoleAdmin, entity.RoleManager), h.update)
+	rg.DELETE(":id", middleware.JWTMiddleware(h.cfg), middleware.RequireRole(entity.RoleAdmin, entity.RoleManager), h.delete)
+}
+
+func (h *SuppliersHandler) list(c *gin.Context) {
+	out, err := h.suppliers.GetAll(c.Query("search"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, helper.InternalErrorResponse("failed to list suppliers"))
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("OK", out))
+}
+
+func (h *SuppliersHandler) create(c *gin.Context) {
+	var req dto.CreateSupplierRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, helper.BadRequestResponse(err.Error()))
+		return
+	}
+	s, err := h.suppliers.Create(&entity.Suppliers{
+		Name:        req.Name,
+		ContactName: req.ContactName,
+		Phone:       req.Phone,
+		Email:       req.Email,
+		Address:     req.Address,
+		Note:        req.Note,
+	})
+	if err != nil {
+		writeSupplierError(c, err, "failed to create supplier")
+		return
+	}
+	c.JSON(http.StatusCreated, helper.SuccessResponse("created", s))
+}
+
+func (h *SuppliersHandler) get(c *gin.Context) {
+	s, err := h.suppliers.GetByID(c.Param("id"))
+	if err != nil {
+		writeSupplierError(c, err, "failed to load supplier")
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("OK", s))
+}
+
+func (h *SuppliersHandler) update(c *gin.Context) {
+	var req dto.UpdateSupplierRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, helper.BadRequestResponse(err.Error()))
+		return
+	}
+	existing, err := h.suppliers.GetByID(c.Param("id"))
+	if err != nil {
+		writeSupplierError(c, err, "failed to update supplier")
+		return
+	}
+	if req.Name != nil {
+		existing.Name = *req.Name
+	}
+	if req.ContactName != nil {
+		existing.ContactName = *req.ContactName
+	}
+	if req.Phone != nil {
+		existing.Phone = *req.Phone
+	}
+	if req.Email != nil {
+		existing.Email = *req.Email
+	}
+	if req.Address != nil {
+		existing.Address = *req.Address
+	}
+	if req.Note != nil {
+		existing.Note = *req.Note
+	}
+	s, err := h.suppliers.Update(existing.IdSupplier, existing)
+	if err != nil {
+		writeSupplierError(c, err, "failed to update supplier")
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("updated", s))
+}
+
+func (h *SuppliersHandler) delete(c *gin.Context) {
+	id := c.Param("id")
+	if err := h.suppliers.Delete(id); err != nil {
+		writeSupplierError(c, err, "failed to delete supplier")
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("deleted", gin.H{"id": id}))
+}
+
+func writeSupplierError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrSupplierNotFound):
+		c.JSON(http.StatusNotFound, helper.NotFoundResponse(err.Error()))
+	case errors.Is(err, services.ErrInvalidSupplier):
+		c.JSON(http.StatusBadRequest, helper.BadRequestResponse(err.Error()))
+	default:
+		c.JSON(http.StatusInternalServerError, helper.InternalErrorResponse(fallback))
+	}
+}
@@ -0,0 +1,279 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"faizalmaulana/lsp/conf"
+	"faizalmaulana/lsp/helper"
+	"faizalmaulana/lsp/http/dto"
+	"faizalmaulana/lsp/http/middleware"
+	"faizalmaulana/lsp/http/services"
+	"faizalmaulana/lsp/models/entity"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TablesHandler struct {
+	cfg    *conf.Config
+	tables services.TablesService
+	held   services.HeldOrdersService
+}
+
+func NewTablesHandler(cfg *conf.Config, tables services.TablesService, held services.HeldOrdersService) *TablesHandler {
+	return &TablesHandler{cfg: cfg, tables: tables, held: held}
+}
+
+// Register lets managers lay out the floor; any signed-in cashier can see
+// it and seat, move and merge tables.
+func (h *TablesHandler) Register(rr *gin.RouterGroup) {
+	rg := rr.Group("/tables")
+	rg.GET("areas", middleware.JWTMiddleware(h.cfg), h.listAreas)
+	rg.POST("areas", middleware.JWTMiddleware(h.cfg), middleware.RequireRole(entity.RoleAdmin, entity.RoleManager), h.createArea)
+	rg.PUT("areas/:id", middleware.JWTMiddleware(h.cfg), middleware.RequireRole(entity.RoleAdmin, entity.RoleManager), h.updateArea)
+	rg.DELETE("areas/:id", middleware.JWTMiddleware(h.cfg), middleware.RequireRole(entity.RoleAdmin, entity.RoleManager), h.deleteArea)
+	rg.GET("floor", middleware.JWTMiddleware(h.cfg), h.floor)
+	rg.GET("", middleware.JWTMiddleware(h.cfg), h.list)
+	rg.POST("", middleware.JWTMiddleware(h.cfg), middleware.RequireRole(entity.RoleAdmin, entity.RoleManager), h.create)
+	rg.GET(":id", middleware.JWTMiddleware(h.cfg), h.get)
+	rg.PUT(":id", middleware.JWTMiddleware(h.cfg), middleware.RequireRole(entity.RoleAdmin, entity.RoleManager), h.update)
+	rg.DELETE(":id", middleware.JWTMiddleware(h.cfg), middleware.RequireRole(entity.RoleAdmin, entity.RoleManager), h.delete)
+	rg.POST(":id/open", middleware.JWTMiddleware(h.cfg), h.open)
+	rg.POST(":id/move", middleware.JWTMiddleware(h.cfg), h.move)
+	rg.POST(":id/merge", middleware.JWTMiddleware(h.cfg), h.merge)
+}
+
+func (h *TablesHandler) listAreas(c *gin.Context) {
+	out, err := h.tables.GetAreas()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, helper.InternalErrorResponse("failed to list table areas"))
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("OK", out))
+}
+
+func (h *TablesHandler) createArea(c *gin.Context) {
+	var req dto.CreateTableAreaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, helper.BadRequestResponse(err.Error()))
+		return
+	}
+	a, err := h.tables.CreateArea(&entity.TableAreas{Name: req.Name, SortOrder: req.SortOrder})
+	if err != nil {
+		writeTableError(c, err, "failed to create table area")
+		return
+	}
+	c.JSON(http.StatusCreated, helper.SuccessResponse("created", a))
+}
+
+func (h *TablesHandler) updateArea(c *gin.Context) {
+	var req dto.UpdateTableAreaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, helper.BadRequestResponse(err.Error()))
+		return
+	}
+	existing, err := h.tables.GetArea(c.Param("id"))
+	if err != nil {
+		writeTableError(c, err, "failed to update table area")
+		return
+	}
+	if req.Name != nil {
+		existing.Name = *req.Name
+	}
+	if req.SortOrder != nil {
+		existing.SortOrder = *req.SortOrder
+	}
+	a, err := h.tables.UpdateArea(existing.IdTableArea, existing)
+	if err != nil {
+		writeTableError(c, err, "failed to update table area")
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("updated", a))
+}
+
+func (h *TablesHandler) deleteArea(c *gin.Context) {
+	id := c.Param("id")
+	if err := h.tables.DeleteArea(id); err != nil {
+		writeTableError(c, err, "failed to delete table area")
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("deleted", gin.H{"id": id}))
+}
+
+// floor is the live view of the floor: every table, whether it is free and
+// what is open on it.
+func (h *TablesHandler) floor(c *gin.Context) {
+	out, err := h.tables.Floor()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, helper.InternalErrorResponse("failed to load floor"))
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("OK", out))
+}
+
+func (h *TablesHandler) list(c *gin.Context) {
+	out, err := h.tables.GetAll(c.Query("area"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, helper.InternalErrorResponse("failed to list tables"))
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("OK", out))
+}
+
+func (h *TablesHandler) create(c *gin.Context) {
+	var req dto.CreateTableRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, helper.BadRequestResponse(err.Error()))
+		return
+	}
+	t := &entity.DiningTables{
+		IdTableArea: req.IdTableArea,
+		Name:        req.Name,
+		Seats:       req.Seats,
+		Shape:       req.Shape,
+		PosX:        req.PosX,
+		PosY:        req.PosY,
+		Width:       req.Width,
+		Height:      req.Height,
+		IsActive:    true,
+	}
+	if req.IsActive != nil {
+		t.IsActive = *req.IsActive
+	}
+	saved, err := h.tables.Create(t)
+	if err != nil {
+		writeTableError(c, err, "failed to create table")
+		return
+	}
+	c.JSON(http.StatusCreated, helper.SuccessResponse("created", saved))
+}
+
+func (h *TablesHandler) get(c *gin.Context) {
+	t, err := h.tables.GetByID(c.Param("id"))
+	if err != nil {
+		writeTableError(c, err, "failed to load table")
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("OK", t))
+}
+
+func (h *TablesHandler) update(c *gin.Context) {
+	var req dto.UpdateTableRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, helper.BadRequestResponse(err.Error()))
+		return
+	}
+	existing, err := h.tables.GetByID(c.Param("id"))
+	if err != nil {
+		writeTableError(c, err, "failed to update table")
+		return
+	}
+	if req.IdTableArea != nil {
+		existing.IdTableArea = *req.IdTableArea
+	}
+	if req.Name != nil {
+		existing.Name = *req.Name
+	}
+	if req.Seats != nil {
+		existing.Seats = *req.Seats
+	}
+	if req.Shape != nil {
+		existing.Shape = *req.Shape
+	}
+	if req.PosX != nil {
+		existing.PosX = *req.PosX
+	}
+	if req.PosY != nil {
+		existing.PosY = *req.PosY
+	}
+	if req.Width != nil {
+		existing.Width = *req.Width
+	}
+	if req.Height != nil {
+		existing.Height = *req.Height
+	}
+	if req.IsActive != nil {
+		existing.IsActive = *req.IsActive
+	}
+	t, err := h.tables.Update(existing.IdTable, existing)
+	if err != nil {
+		writeTableError(c, err, "failed to update table")
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("updated", t))
+}
+
+func (h *TablesHandler) delete(c *gin.Context) {
+	id := c.Param("id")
+	if err := h.tables.Delete(id); err != nil {
+		writeTableError(c, err, "failed to delete table")
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("deleted", gin.H{"id": id}))
+}
+
+// open seats guests at a free table by opening a held order on it.
+func (h *TablesHandler) open(c *gin.Context) {
+	var req dto.CreateHeldOrderRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, helper.BadRequestResponse(err.Error()))
+			return
+		}
+	}
+	o, err := h.held.Create(claimString(c, "sub"), services.HeldOrderInput{
+		Terminal:     req.Terminal,
+		Label:        req.Label,
+		Note:         req.Note,
+		IdCustomer:   req.IdCustomer,
+		BuyerContact: req.BuyerContact,
+		IdTable:      c.Param("id"),
+		Guests:       req.Guests,
+	}, checkoutLines(req.Items))
+	if err != nil {
+		writeHeldOrderError(c, err, "failed to open table")
+		return
+	}
+	c.JSON(http.StatusCreated, helper.SuccessResponse("created", o))
+}
+
+func (h *TablesHandler) move(c *gin.Context) {
+	var req dto.MoveTableRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, helper.BadRequestResponse(err.Error()))
+		return
+	}
+	out, err := h.held.MoveTable(c.Param("id"), req.To)
+	if err != nil {
+		writeHeldOrderError(c, err, "failed to move table")
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("updated", out))
+}
+
+func (h *TablesHandler) merge(c *gin.Context) {
+	var req dto.MergeTableRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, helper.BadRequestResponse(err.Error()))
+		return
+	}
+	o, err := h.held.MergeTables(c.Param("id"), req.Into, claimString(c, "sub"))
+	if err != nil {
+		writeHeldOrderError(c, err, "failed to merge tables")
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("updated", o))
+}
+
+func writeTableError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrTableNotFound), errors.Is(err, services.ErrTableAreaNotFound):
+		c.JSON(http.StatusNotFound, helper.NotFoundResponse(err.Error()))
+	case errors.Is(err, services.ErrInvalidTable):
+		c.JSON(http.StatusBadRequest, helper.BadRequestResponse(err.Error()))
+	case errors.Is(err, services.ErrTableAreaInUse), errors.Is(err, services.ErrTableOccupied):
+		c.JSON(http.StatusConflict, helper.ErrorResponse("CONFLICT", err.Error()))
+	default:
+		c.JSON(http.StatusInternalServerError, helper.InternalErrorResponse(fallback))
+	}
+}
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -19,23 +20,41 @@ import (
 type TransactionsHandler struct {
 	cfg       *conf.Config
 	txSvc     services.TransactionsService
-	itemsRepo repo.ItemsRepo
 	pivotRepo repo.PivotItemsToTransactionsRepo
 }
 
-func NewTransactionsHandler(cfg *conf.Config, tx services.TransactionsService, items repo.ItemsRepo, pivot repo.PivotItemsToTransactionsRepo) *TransactionsHandler {
-	return &TransactionsHandler{cfg: cfg, txSvc: tx, itemsRepo: items, pivotRepo: pivot}
+func NewTransactionsHandler(cfg *conf.Config, tx services.TransactionsService, pivot repo.PivotItemsToTransactionsRepo) *TransactionsHandler {
+	return &TransactionsHandler{cfg: cfg, txSvc: tx, pivotRepo: pivot}
 }
 
 func (h *TransactionsHandler) Register(rr *gin.RouterGroup) {
 	rg := rr.Group("/transactions")
 	rg.GET("", h.list)
 	rg.GET(":id", h.get)
+	rg.GET(":id/receipt", h.receipt)
 	rg.POST("", middleware.JWTMiddleware(h.cfg), h.create)
 	rg.PUT(":id", middleware.JWTMiddleware(h.cfg), h.update)
+	rg.POST(":id/void", middleware.JWTMiddleware(h.cfg), middleware.RequireRole(entity.RoleAdmin, entity.RoleManager), h.void)
 	rg.DELETE(":id", middleware.JWTMiddleware(h.cfg), h.delete)
 }
 
+// claimString reads a string claim of the JWT set by JWTMiddleware.
+func claimString(c *gin.Context, key string) string {
+	v, ok := c.Get("claims")
+	if !ok {
+		return ""
+	}
+	switch claims := v.(type) {
+	case jwt.MapClaims:
+		s, _ := claims[key].(string)
+		return s
+	case map[string]any:
+		s, _ := claims[key].(string)
+		return s
+	}
+	return ""
+}
+
 func (h *TransactionsHandler) list(c *gin.Context) {
 	count := 10
 	page := 1
@@ -59,30 +78,77 @@ func (h *TransactionsHandler) list(c *gin.Context) {
 
 func (h *TransactionsHandler) get(c *gin.Context) {
 	id := c.Param("id")
-	t, err := h.txSvc.GetByID(id)
+	d, err := h.txSvc.GetDetail(id)
 	if err != nil {
 		c.JSON(http.StatusNotFound, helper.NotFoundResponse("transaction not found"))
 		return
 	}
-	pivots, _ := h.pivotRepo.ListByTransaction(id)
-	details := make([]dto.TransactionItemDetail, 0, len(pivots))
-	for _, p := range pivots {
-		it, err := h.itemsRepo.GetByID(p.IdItem)
-		if err != nil {
-			continue
-		}
-		details = append(details, dto.TransactionItemDetail{
-			IdItem:   it.IdItem,
-			ItemName: it.ItemName,
-			ImageUrl: it.ImageUrl,
-			Quantity: p.Quantity,
-			Price:    p.Price,
-		})
-	}
-	resp := gin.H{"transaction": t, "items": details}
+	details := make([]dto.TransactionItemDetail, 0, len(d.Lines))
+	for _, l := range d.Lines {
+		details = append(details, transactionItemDetail(l))
+	}
+	resp := gin.H{"transaction": d.Transaction, "items": details, "payments": d.Payments, "payment_intents": d.Intents, "loyalty": d.Points, "gift_cards": d.GiftCards}
 	c.JSON(http.StatusOK, helper.SuccessResponse("OK", resp))
 }
 
+func transactionItemDetail(l services.TransactionLine) dto.TransactionItemDetail {
+	mods := make([]dto.TransactionItemModifier, 0, len(l.Modifiers))
+	for _, m := range l.Modifiers {
+		mods = append(mods, dto.TransactionItemModifier{IdModifierOption: m.IdModifierOption, GroupName: m.GroupName, OptionName: m.OptionName, PriceDelta: m.PriceDelta})
+	}
+	comps := make([]dto.TransactionItemComponent, 0, len(l.Components))
+	for _, p := range l.Components {
+		comps = append(comps, dto.TransactionItemComponent{IdItem: p.IdItem, ItemName: p.ItemName, Quantity: p.Quantity, Revenue: p.Revenue})
+	}
+	discounts := make([]dto.TransactionItemDiscount, 0, len(l.Discounts))
+	for _, d := range l.Discounts {
+		discounts = append(discounts, dto.TransactionItemDiscount{IdPromotion: d.IdPromotion, IdVoucher: d.IdVoucher, Source: d.Source, Name: d.Name, Reason: d.Reason, Amount: d.Amount})
+	}
+	return dto.TransactionItemDetail{
+		IdPivot:    l.IdPivot,
+		IdItem:     l.IdItem,
+		ItemName:   l.ItemName,
+		ImageUrl:   l.ImageUrl,
+		Quantity:   l.Quantity,
+		BasePrice:  l.BasePrice,
+		Price:      l.Price,
+		Discount:   l.Discount,
+		TaxRate:    l.TaxRate,
+		Tax:        l.Tax,
+		Service:    l.ServiceCharge,
+		LineTotal:  l.LineTotal,
+		Modifiers:  mods,
+		Components: comps,
+		Discounts:  discounts,
+	}
+}
+
+// receipt returns the receipt as 58mm-wide plain text, or as JSON with
+// ?format=json for clients that lay it out themselves.
+func (h *TransactionsHandler) receipt(c *gin.Context) {
+	r, err := h.txSvc.Receipt(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, helper.NotFoundResponse("transaction not found"))
+		return
+	}
+	writeReceipt(c, r)
+}
+
+func writeReceipt(c *gin.Context, r *services.Receipt) {
+	width := services.ReceiptWidth
+	if v := c.Query("width"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 24 && n <= 80 {
+			width = n
+		}
+	}
+	text := services.RenderReceipt(r, width)
+	if c.Query("format") == "json" {
+		c.JSON(http.StatusOK, helper.SuccessResponse("OK", gin.H{"receipt": r, "text": text}))
+		return
+	}
+	c.String(http.StatusOK, text)
+}
+
 func (h *TransactionsHandler) create(c *gin.Context) {
 	var req dto.CreateTransactionRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -95,66 +161,75 @@ func (h *TransactionsHandler) create(c *gin.Context) {
 		return
 	}
 
-	userID := ""
-	if v, ok := c.Get("claims"); ok {
-		switch claims := v.(type) {
-		case jwt.MapClaims:
-			if sub, ok := claims["sub"].(string); ok {
-				userID = sub
-			}
-		case map[string]any:
-			if sub, ok := claims["sub"].(string); ok {
-				userID = sub
-			}
-		}
-	}
+	userID := claimString(c, "sub")
 	if userID == "" {
 		c.JSON(http.StatusUnauthorized, helper.UnauthorizedResponse())
 		return
 	}
 
-	var total float64
-	pivots := make([]entity.PivotItemsToTransaction, 0, len(req.Items))
-	for _, it := range req.Items {
-		item, err := h.itemsRepo.GetByID(it.IdItem)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, helper.BadRequestResponse("invalid item: "+it.IdItem))
-			return
-		}
-		qty := it.Quantity
-		if qty <= 0 {
-			qty = 1
-		}
-		pivots = append(pivots, entity.PivotItemsToTransaction{
-			IdItem:   item.IdItem,
-			Quantity: qty,
-			Price:    item.Price,
-		})
-		total += float64(qty) * item.Price
-	}
-
-	tx := &entity.Transactions{
-		IdTransaction: helper.Uuid(),
-		IdUser:        userID,
-		BuyerContact:  req.BuyerContact,
-		TotalPrice:    total,
-	}
+	lines := checkoutLines(req.Items)
 
-	saved, err := h.txSvc.Create(tx)
+	res, err := h.txSvc.Checkout(services.CheckoutRequest{
+		IdUser:       userID,
+		Role:         claimString(c, "role"),
+		BuyerContact: req.BuyerContact,
+		Customer:     checkoutCustomer(req.Customer),
+		Lines:        lines,
+		Discount:     manualDiscount(req.Discount),
+		Voucher:      req.VoucherCode,
+		Payments:     paymentInputs(req.Payments),
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, helper.InternalErrorResponse("failed to create transaction"))
+		writeCheckoutError(c, err, "failed to create transaction")
 		return
 	}
 
-	for i := range pivots {
-		pivots[i].IdTransaction = saved.IdTransaction
+	c.JSON(http.StatusCreated, helper.SuccessResponse("created", checkoutResponse(res)))
+}
+
+func paymentInputs(req []dto.PaymentRequest) []services.PaymentInput {
+	payments := make([]services.PaymentInput, 0, len(req))
+	for _, p := range req {
+		payments = append(payments, services.PaymentInput{Method: p.Method, Amount: p.Amount, Reference: p.Reference, Pin: p.Pin, Gateway: p.Gateway})
 	}
-	if err := h.pivotRepo.BulkCreate(pivots); err != nil {
-		c.JSON(http.StatusInternalServerError, helper.InternalErrorResponse("failed to save items"))
-		return
+	return payments
+}
+
+func checkoutResponse(res *services.CheckoutResult) gin.H {
+	return gin.H{"transaction": res.Transaction, "items": res.Lines, "payments": res.Payments, "payment_intents": res.Intents, "change": res.Change, "loyalty": res.Points, "gift_cards": res.GiftCards}
+}
+
+// writeCheckoutError maps what checkout refuses a sale for to a status.
+func writeCheckoutError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidItem), errors.Is(err, services.ErrInvalidModifier), errors.Is(err, services.ErrModifierSelection),
+		errors.Is(err, services.ErrInvalidPayment), errors.Is(err, services.ErrInsufficientPayment), errors.Is(err, services.ErrGatewayUnavailable),
+		errors.Is(err, services.ErrInvalidDiscount), errors.Is(err, services.ErrVoucherNotFound), errors.Is(err, services.ErrVoucherUnavailable),
+		errors.Is(err, services.ErrInvalidCustomer), errors.Is(err, services.ErrCustomerNotFound), errors.Is(err, services.ErrInsufficientPoints),
+		errors.Is(err, services.ErrInvalidGiftCard), errors.Is(err, services.ErrGiftCardUnavailable), errors.Is(err, services.ErrInsufficientBalance):
+		c.JSON(http.StatusBadRequest, helper.BadRequestResponse(err.Error()))
+	case errors.Is(err, services.ErrDiscountNotAllowed):
+		c.JSON(http.StatusForbidden, helper.ErrorResponse("FORBIDDEN", err.Error()))
+	case errors.Is(err, services.ErrNoOpenShift), errors.Is(err, services.ErrPeriodClosed), errors.Is(err, services.ErrHeldOrderChanged):
+		c.JSON(http.StatusConflict, helper.ErrorResponse("CONFLICT", err.Error()))
+	default:
+		c.JSON(http.StatusInternalServerError, helper.InternalErrorResponse(fallback))
+	}
+}
+
+func checkoutLines(items []dto.TransactionItemRequest) []services.CheckoutLine {
+	lines := make([]services.CheckoutLine, 0, len(items))
+	for _, it := range items {
+		lines = append(lines, services.CheckoutLine{IdItem: it.IdItem, Quantity: it.Quantity, Modifiers: it.Modifiers, Discount: manualDiscount(it.Discount), GiftCard: it.GiftCard})
 	}
+	return lines
+}
 
-	c.JSON(http.StatusCreated, helper.SuccessResponse("created", gin.H{"transaction": saved, "items": pivots}))
+func manualDiscount(d *dto.DiscountRequest) *services.ManualDiscount {
+	if d == nil {
+		return nil
+	}
+	return &services.ManualDiscount{Kind: d.Kind, Value: d.Value, Reason: d.Reason}
 }
 
 func (h *TransactionsHandler) update(c *gin.Context) {
@@ -164,26 +239,54 @@ func (h *TransactionsHandler) update(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, helper.BadRequestResponse(err.Error()))
 		return
 	}
-	t, err := h.txSvc.GetByID(id)
+	if req.BuyerContact == nil {
+		t, err := h.txSvc.GetByID(id)
+		if err != nil {
+			c.JSON(http.StatusNotFound, helper.NotFoundResponse("transaction not found"))
+			return
+		}
+		c.JSON(http.StatusOK, helper.SuccessResponse("updated", t))
+		return
+	}
+	updated, err := h.txSvc.SetBuyerContact(id, *req.BuyerContact)
 	if err != nil {
-		c.JSON(http.StatusNotFound, helper.NotFoundResponse("transaction not found"))
+		writeTransactionError(c, err, "failed to update transaction")
 		return
 	}
-	if req.BuyerContact != nil {
-		t.BuyerContact = *req.BuyerContact
+	c.JSON(http.StatusOK, helper.SuccessResponse("updated", updated))
+}
+
+func (h *TransactionsHandler) void(c *gin.Context) {
+	var req dto.VoidTransactionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, helper.BadRequestResponse(err.Error()))
+		return
 	}
-	updated, err := h.txSvc.Update(id, t)
+	t, err := h.txSvc.Void(c.Param("id"), req.Reason, claimString(c, "sub"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, helper.InternalErrorResponse("failed to update transaction"))
+		writeTransactionError(c, err, "failed to void transaction")
 		return
 	}
-	c.JSON(http.StatusOK, helper.SuccessResponse("updated", updated))
+	c.JSON(http.StatusOK, helper.SuccessResponse("voided", t))
+}
+
+func writeTransactionError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrTransactionNotFound):
+		c.JSON(http.StatusNotFound, helper.NotFoundResponse(err.Error()))
+	case errors.Is(err, services.ErrVoidReasonRequired):
+		c.JSON(http.StatusBadRequest, helper.BadRequestResponse(err.Error()))
+	case errors.Is(err, services.ErrInvalidTransition), errors.Is(err, services.ErrTransactionNotDeletable), errors.Is(err, services.ErrPeriodClosed):
+		c.JSON(http.StatusConflict, helper.ErrorResponse("CONFLICT", err.Error()))
+	default:
+		c.JSON(http.StatusInternalServerError, helper.InternalErrorResponse(fallback))
+	}
 }
 
 func (h *TransactionsHandler) delete(c *gin.Context) {
 	id := c.Param("id")
 	if err := h.txSvc.Delete(id); err != nil {
-		c.JSON(http.StatusInternalServerError, helper.InternalErrorResponse("failed to delete transaction"))
+		writeTransactionError(c, err, "failed to delete transaction")
 		return
 	}
 	_ = h.pivotRepo.DeleteByTransaction(id)
@@ -0,0 +1,277 @@
+package handler
+
+import (
+	"encoding/csv"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"faizalmaulana/lsp/conf"
+	"faizalmaulana/lsp/helper"
+	"faizalmaulana/lsp/http/dto"
+	"faizalmaulana/lsp/http/middleware"
+	"faizalmaulana/lsp/http/services"
+	"faizalmaulana/lsp/models/entity"
+
+	"github.com/gin-gonic/gin"
+)
+
+type VouchersHandler struct {
+	cfg        *conf.Config
+	vouchers   services.VouchersService
+	promotions services.PromotionsService
+	txSvc      services.TransactionsService
+}
+
+func NewVouchersHandler(cfg *conf.Config, vouchers services.VouchersService, promotions services.PromotionsService, tx services.TransactionsService) *VouchersHandler {
+	return &VouchersHandler{cfg: cfg, vouchers: vouchers, promotions: promotions, txSvc: tx}
+}
+
+// Register keeps voucher codes behind a manager login; cashiers only get to
+// validate the code a customer hands them.
+func (h *VouchersHandler) Register(rr *gin.RouterGroup) {
+	rg := rr.Group("/vouchers")
+	rg.POST("validate", middleware.JWTMiddleware(h.cfg), h.validate)
+	rg.GET("", middleware.JWTMiddleware(h.cfg), middleware.RequireRole(entity.RoleAdmin, entity.RoleManager), h.list)
+	rg.GET(":id", middleware.JWTMiddleware(h.cfg), middleware.RequireRole(entity.RoleAdmin, entity.RoleManager), h.get)
+	rg.POST("", middleware.JWTMiddleware(h.cfg), middleware.RequireRole(entity.RoleAdmin, entity.RoleManager), h.create)
+	rg.PUT(":id", middleware.JWTMiddleware(h.cfg), middleware.RequireRole(entity.RoleAdmin, entity.RoleManager), h.update)
+	rg.DELETE(":id", middleware.JWTMiddleware(h.cfg), middleware.RequireRole(entity.RoleAdmin, entity.RoleManager), h.delete)
+	rg.POST("batches", middleware.JWTMiddleware(h.cfg), middleware.RequireRole(entity.RoleAdmin, entity.RoleManager), h.generate)
+	rg.GET("batches/:batch/export", middleware.JWTMiddleware(h.cfg), middleware.RequireRole(entity.RoleAdmin, entity.RoleManager), h.export)
+}
+
+func (h *VouchersHandler) list(c *gin.Context) {
+	out, err := h.vouchers.GetAll(c.Query("batch"), c.Query("id_promotion"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, helper.InternalErrorResponse("failed to list vouchers"))
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("OK", out))
+}
+
+func (h *VouchersHandler) get(c *gin.Context) {
+	v, err := h.vouchers.GetByID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, helper.NotFoundResponse("voucher not found"))
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("OK", v))
+}
+
+func (h *VouchersHandler) create(c *gin.Context) {
+	var req dto.CreateVoucherRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, helper.BadRequestResponse(err.Error()))
+		return
+	}
+	v := &entity.Vouchers{
+		IdVoucher:          helper.Uuid(),
+		Code:               req.Code,
+		IdPromotion:        req.IdPromotion,
+		StartsAt:           req.StartsAt,
+		EndsAt:             req.EndsAt,
+		MaxUses:            req.MaxUses,
+		MaxUsesPerCustomer: req.MaxUsesPerCustomer,
+		IsActive:           true,
+	}
+	if req.IsActive != nil {
+		v.IsActive = *req.IsActive
+	}
+	saved, err := h.vouchers.Create(v)
+	if err != nil {
+		h.writeError(c, err, "failed to create voucher")
+		return
+	}
+	c.JSON(http.StatusCreated, helper.SuccessResponse("created", saved))
+}
+
+func (h *VouchersHandler) update(c *gin.Context) {
+	id := c.Param("id")
+	var req dto.UpdateVoucherRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, helper.BadRequestResponse(err.Error()))
+		return
+	}
+	v, err := h.vouchers.GetByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, helper.NotFoundResponse("voucher not found"))
+		return
+	}
+	if req.StartsAt != nil {
+		v.StartsAt = req.StartsAt
+	}
+	if req.ClearStartsAt {
+		v.StartsAt = nil
+	}
+	if req.EndsAt != nil {
+		v.EndsAt = req.EndsAt
+	}
+	if req.ClearEndsAt {
+		v.EndsAt = nil
+	}
+	if req.MaxUses != nil {
+		v.MaxUses = *req.MaxUses
+	}
+	if req.MaxUsesPerCustomer != nil {
+		v.MaxUsesPerCustomer = *req.MaxUsesPerCustomer
+	}
+	if req.IsActive != nil {
+		v.IsActive = *req.IsActive
+	}
+	updated, err := h.vouchers.Update(id, v)
+	if err != nil {
+		h.writeError(c, err, "failed to update voucher")
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("updated", updated))
+}
+
+func (h *VouchersHandler) delete(c *gin.Context) {
+	id := c.Param("id")
+	if _, err := h.vouchers.GetByID(id); err != nil {
+		c.JSON(http.StatusNotFound, helper.NotFoundResponse("voucher not found"))
+		return
+	}
+	if err := h.vouchers.Delete(id); err != nil {
+		h.writeError(c, err, "failed to delete voucher")
+		return
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("deleted", gin.H{"id": id}))
+}
+
+func (h *VouchersHandler) generate(c *gin.Context) {
+	var req dto.GenerateVouchersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, helper.BadRequestResponse(err.Error()))
+		return
+	}
+	out, err := h.vouchers.Generate(services.VoucherBatch{
+		IdPromotion:        req.IdPromotion,
+		Count:              req.Count,
+		Prefix:             req.Prefix,
+		Length:             req.Length,
+		StartsAt:           req.StartsAt,
+		EndsAt:             req.EndsAt,
+		MaxUsesPerCustomer: req.MaxUsesPerCustomer,
+	})
+	if err != nil {
+		h.writeError(c, err, "failed to generate vouchers")
+		return
+	}
+	c.JSON(http.StatusCreated, helper.SuccessResponse("created", gin.H{"batch": out[0].Batch, "count": len(out), "vouchers": out}))
+}
+
+// export downloads a batch as CSV for the print shop.
+func (h *VouchersHandler) export(c *gin.Context) {
+	batch := c.Param("batch")
+	list, err := h.vouchers.GetAll(batch, "")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, helper.InternalErrorResponse("failed to export vouchers"))
+		return
+	}
+	if len(list) == 0 {
+		c.JSON(http.StatusNotFound, helper.NotFoundResponse("batch not found"))
+		return
+	}
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="vouchers-`+batch+`.csv"`)
+	c.Status(http.StatusOK)
+	w := csv.NewWriter(c.Writer)
+	_ = w.Write([]string{"code", "id_promotion", "starts_at", "ends_at", "max_uses", "max_uses_per_customer", "used_count", "is_active"})
+	for _, v := range list {
+		_ = w.Write([]string{
+			v.Code,
+			v.IdPromotion,
+			csvTime(v.StartsAt),
+			csvTime(v.EndsAt),
+			strconv.Itoa(v.MaxUses),
+			strconv.Itoa(v.MaxUsesPerCustomer),
+			strconv.Itoa(v.UsedCount),
+			strconv.FormatBool(v.IsActive),
+		})
+	}
+	w.Flush()
+}
+
+func csvTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// validate tells a cashier whether a voucher can be redeemed and, when the
+// basket is sent along, what it takes off. Nothing is redeemed.
+func (h *VouchersHandler) validate(c *gin.Context) {
+	var req dto.ValidateVoucherRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, helper.BadRequestResponse(err.Error()))
+		return
+	}
+	v, err := h.vouchers.Check(req.Code, req.BuyerContact, time.Now())
+	if err != nil {
+		h.writeError(c, err, "failed to validate voucher")
+		return
+	}
+	resp := gin.H{"voucher": v, "uses_left": usesLeft(v)}
+	if p, err := h.promotions.GetByID(v.IdPromotion); err == nil {
+		resp["promotion"] = p
+	}
+	if len(req.Items) > 0 {
+		quote, err := h.txSvc.Quote(services.CheckoutRequest{
+			Role:         claimString(c, "role"),
+			BuyerContact: req.BuyerContact,
+			Lines:        checkoutLines(req.Items),
+			Voucher:      v.Code,
+		})
+		if err != nil {
+			h.writeError(c, err, "failed to price basket")
+			return
+		}
+		var discount entity.Money
+		details := make([]dto.TransactionItemDetail, 0, len(quote.Lines))
+		for _, l := range quote.Lines {
+			for _, d := range l.Discounts {
+				if d.IdVoucher == v.IdVoucher {
+					discount += d.Amount
+				}
+			}
+			details = append(details, transactionItemDetail(services.TransactionLine{PivotItemsToTransaction: l}))
+		}
+		t := quote.Transaction
+		resp["voucher_discount"] = discount
+		resp["discount_total"] = t.DiscountTotal
+		resp["subtotal"] = t.Subtotal
+		resp["service_charge"] = t.ServiceCharge
+		resp["tax_total"] = t.TaxTotal
+		resp["total"] = t.TotalPrice
+		resp["items"] = details
+	}
+	c.JSON(http.StatusOK, helper.SuccessResponse("OK", resp))
+}
+
+// usesLeft is nil for vouchers without a global limit.
+func usesLeft(v *entity.Vouchers) *int {
+	if v.MaxUses == 0 {
+		return nil
+	}
+	n := max(v.MaxUses-v.UsedCount, 0)
+	return &n
+}
+
+func (h *VouchersHandler) writeError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrVoucherNotFound):
+		c.JSON(http.StatusNotFound, helper.NotFoundResponse(err.Error()))
+	case errors.Is(err, services.ErrInvalidVoucher), errors.Is(err, services.ErrVoucherUnavailable),
+		errors.Is(err, services.ErrInvalidItem), errors.Is(err, services.ErrInvalidModifier), errors.Is(err, services.ErrModifierSelection),
+		errors.Is(err, services.ErrInvalidDiscount):
+		c.JSON(http.StatusBadRequest, helper.BadRequestResponse(err.Error()))
+	case errors.Is(err, services.ErrDiscountNotAllowed):
+		c.JSON(http.StatusForbidden, helper.ErrorResponse("FORBIDDEN", err.Error()))
+	default:
+		c.JSON(http.StatusInternalServerError, helper.InternalErrorResponse(fallback))
+	}
+}
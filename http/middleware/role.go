@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"faizalmaulana/lsp/helper"
+
+	"github.com/gin-gonic/gin"
+	jwt "github.com/golang-jwt/jwt/v5"
+)
+
+// RequireRole lets the request through only when the JWT role claim is one of
+// roles. It must run after JWTMiddleware.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := ""
+		if v, ok := c.Get("claims"); ok {
+			if claims, ok := v.(jwt.MapClaims); ok {
+				role, _ = claims["role"].(string)
+			}
+		}
+		for _, r := range roles {
+			if strings.EqualFold(role, r) {
+				c.Next()
+				return
+			}
+		}
+		c.JSON(http.StatusForbidden, helper.ErrorResponse("FORBIDDEN", "insufficient role"))
+		c.Abort()
+	}
+}
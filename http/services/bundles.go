@@ -0,0 +1,159 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"faizalmaulana/lsp/helper"
+	"faizalmaulana/lsp/models/entity"
+	"faizalmaulana/lsp/models/repo"
+)
+
+var ErrInvalidBundle = errors.New("invalid bundle")
+
+type BundleComponentInput struct {
+	IdItem   string
+	Quantity int
+}
+
+// BundleComponent is a component row joined with the item it refers to.
+type BundleComponent struct {
+	entity.BundleComponents
+	ItemName string
+	Price    entity.Money
+}
+
+type BundlesService interface {
+	GetComponents(idBundle string) ([]BundleComponent, error)
+	// SetComponents replaces the components of idBundle. An empty list turns
+	// the item back into a single item.
+	SetComponents(idBundle string, comps []BundleComponentInput) ([]BundleComponent, error)
+	// Expand returns what qty units of bundle are made of, with total, the
+	// line's total as sold, split over the components in proportion to their
+	// list price.
+	Expand(bundle *entity.Items, qty int, total entity.Money) ([]entity.PivotLineComponents, error)
+}
+
+type bundlesService struct {
+	repo  repo.BundlesRepo
+	items repo.ItemsRepo
+}
+
+func NewBundlesService(r repo.BundlesRepo, items repo.ItemsRepo) BundlesService {
+	return &bundlesService{repo: r, items: items}
+}
+
+func (s *bundlesService) GetComponents(idBundle string) ([]BundleComponent, error) {
+	list, err := s.repo.ListComponents(idBundle)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]BundleComponent, 0, len(list))
+	for _, c := range list {
+		bc := BundleComponent{BundleComponents: c}
+		if it, err := s.items.GetByID(c.IdItem); err == nil {
+			bc.ItemName = it.ItemName
+			bc.Price = it.Price
+		}
+		out = append(out, bc)
+	}
+	return out, nil
+}
+
+func (s *bundlesService) SetComponents(idBundle string, comps []BundleComponentInput) ([]BundleComponent, error) {
+	if len(comps) > 0 {
+		if it, err := s.items.GetByID(idBundle); err == nil && it.Kind == entity.ItemKindGiftCard {
+			return nil, fmt.Errorf("%w: a gift card cannot be a bundle", ErrInvalidBundle)
+		}
+		used, err := s.repo.CountBundlesUsing(idBundle)
+		if err != nil {
+			return nil, err
+		}
+		if used > 0 {
+			return nil, fmt.Errorf("%w: item is a component of another bundle", ErrInvalidBundle)
+		}
+	}
+
+	rows := make([]entity.BundleComponents, 0, len(comps))
+	index := map[string]int{}
+	for _, c := range comps {
+		if c.Quantity < 0 {
+			return nil, fmt.Errorf("%w: quantity must be positive", ErrInvalidBundle)
+		}
+		if c.Quantity == 0 {
+			c.Quantity = 1
+		}
+		if c.IdItem == idBundle {
+			return nil, fmt.Errorf("%w: a bundle cannot contain itself", ErrInvalidBundle)
+		}
+		if i, ok := index[c.IdItem]; ok {
+			rows[i].Quantity += c.Quantity
+			continue
+		}
+		it, err := s.items.GetByID(c.IdItem)
+		if err != nil {
+			return nil, fmt.Errorf("%w: unknown item %s", ErrInvalidBundle, c.IdItem)
+		}
+		if it.Kind == entity.ItemKindBundle {
+			return nil, fmt.Errorf("%w: %s is a bundle itself", ErrInvalidBundle, it.ItemName)
+		}
+		if it.Kind == entity.ItemKindGiftCard {
+			return nil, fmt.Errorf("%w: %s is a gift card", ErrInvalidBundle, it.ItemName)
+		}
+		index[c.IdItem] = len(rows)
+		rows = append(rows, entity.BundleComponents{
+			IdBundleComponent: helper.Uuid(),
+			IdBundle:          idBundle,
+			IdItem:            c.IdItem,
+			Quantity:          c.Quantity,
+			SortOrder:         len(rows),
+		})
+	}
+
+	if err := s.repo.ReplaceComponents(idBundle, rows); err != nil {
+		return nil, err
+	}
+	return s.GetComponents(idBundle)
+}
+
+func (s *bundlesService) Expand(bundle *entity.Items, qty int, total entity.Money) ([]entity.PivotLineComponents, error) {
+	if bundle.Kind != entity.ItemKindBundle {
+		return nil, nil
+	}
+	comps, err := s.GetComponents(bundle.IdItem)
+	if err != nil {
+		return nil, err
+	}
+
+	var weight entity.Money
+	for _, c := range comps {
+		if c.ItemName == "" {
+			return nil, fmt.Errorf("%w: %s contains an item that no longer exists", ErrInvalidItem, bundle.ItemName)
+		}
+		weight += c.Price.Mul(c.Quantity)
+	}
+
+	out := make([]entity.PivotLineComponents, 0, len(comps))
+	lineRevenue := total
+	var allocated entity.Money
+	for i, c := range comps {
+		revenue := lineRevenue / entity.Money(len(comps))
+		if weight > 0 {
+			revenue = entity.Money(math.Round(float64(lineRevenue) * float64(c.Price.Mul(c.Quantity)) / float64(weight)))
+		}
+		if i == len(comps)-1 {
+			revenue = lineRevenue - allocated
+		}
+		allocated += revenue
+		out = append(out, entity.PivotLineComponents{
+			IdPivotLineComponent: helper.Uuid(),
+			IdBundle:             bundle.IdItem,
+			IdItem:               c.IdItem,
+			ItemName:             c.ItemName,
+			Quantity:             c.Quantity * qty,
+			Revenue:              revenue,
+		})
+	}
+	return out, nil
+}
@@ -0,0 +1,144 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"faizalmaulana/lsp/models/entity"
+	"faizalmaulana/lsp/models/repo"
+)
+
+var (
+	ErrCategoryNotFound    = errors.New("category not found")
+	ErrCategoryCycle       = errors.New("category cannot be nested under itself")
+	ErrCategoryHasChildren = errors.New("category still has subcategories")
+	ErrCategoryNameEmpty   = errors.New("name required")
+	ErrCategoryInUse       = errors.New("category still has items")
+)
+
+type CategoriesService interface {
+	Create(c *entity.Categories) (*entity.Categories, error)
+	GetByID(id string) (*entity.Categories, error)
+	GetAll(activeOnly bool) ([]entity.Categories, error)
+	Update(id string, c *entity.Categories) (*entity.Categories, error)
+	Delete(id string) error
+	// Descendants returns id followed by the ids of every nested subcategory.
+	Descendants(id string) ([]string, error)
+}
+
+type categoriesService struct{ repo repo.CategoriesRepo }
+
+func NewCategoriesService(r repo.CategoriesRepo) CategoriesService {
+	return &categoriesService{repo: r}
+}
+
+func (s *categoriesService) Create(c *entity.Categories) (*entity.Categories, error) {
+	if c == nil {
+		return nil, errors.New("category nil")
+	}
+	c.Name = strings.TrimSpace(c.Name)
+	if c.Name == "" {
+		return nil, ErrCategoryNameEmpty
+	}
+	if c.IdParent != "" {
+		if _, err := s.repo.GetByID(c.IdParent); err != nil {
+			return nil, ErrCategoryNotFound
+		}
+	}
+	if err := s.repo.Create(c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (s *categoriesService) GetByID(id string) (*entity.Categories, error) {
+	c, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, ErrCategoryNotFound
+	}
+	return c, nil
+}
+
+func (s *categoriesService) GetAll(activeOnly bool) ([]entity.Categories, error) {
+	list, err := s.repo.List(activeOnly)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]entity.Categories, 0, len(list))
+	for _, c := range list {
+		out = append(out, *c)
+	}
+	return out, nil
+}
+
+func (s *categoriesService) Update(id string, c *entity.Categories) (*entity.Categories, error) {
+	if id == "" || c == nil {
+		return nil, errors.New("invalid input")
+	}
+	c.IdCategory = id
+	c.Name = strings.TrimSpace(c.Name)
+	if c.Name == "" {
+		return nil, ErrCategoryNameEmpty
+	}
+	if c.IdParent != "" {
+		if _, err := s.repo.GetByID(c.IdParent); err != nil {
+			return nil, ErrCategoryNotFound
+		}
+		below, err := s.Descendants(id)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range below {
+			if d == c.IdParent {
+				return nil, ErrCategoryCycle
+			}
+		}
+	}
+	if err := s.repo.Update(c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (s *categoriesService) Delete(id string) error {
+	if id == "" {
+		return errors.New("id required")
+	}
+	n, err := s.repo.CountChildren(id)
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return ErrCategoryHasChildren
+	}
+	if n, err = s.repo.CountItems(id); err != nil {
+		return err
+	}
+	if n > 0 {
+		return fmt.Errorf("%w: %d items use it", ErrCategoryInUse, n)
+	}
+	return s.repo.Delete(id)
+}
+
+func (s *categoriesService) Descendants(id string) ([]string, error) {
+	all, err := s.repo.List(false)
+	if err != nil {
+		return nil, err
+	}
+	children := map[string][]string{}
+	for _, c := range all {
+		children[c.IdParent] = append(children[c.IdParent], c.IdCategory)
+	}
+	out := []string{id}
+	seen := map[string]bool{id: true}
+	for i := 0; i < len(out); i++ {
+		for _, child := range children[out[i]] {
+			if !seen[child] {
+				seen[child] = true
+				out = append(out, child)
+			}
+		}
+	}
+	return out, nil
+}
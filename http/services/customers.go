@@ -0,0 +1,273 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+
+	"faizalmaulana/lsp/conf"
+	"faizalmaulana/lsp/helper"
+	"faizalmaulana/lsp/models/entity"
+	"faizalmaulana/lsp/models/repo"
+)
+
+var (
+	ErrInvalidCustomer  = errors.New("invalid customer")
+	ErrCustomerNotFound = errors.New("customer not found")
+)
+
+// CustomerInput names the buyer of a sale: an existing customer by id, or a
+// phone number that is looked up and registered at checkout when it is new.
+// Name and Email only fill in a new or incomplete customer.
+type CustomerInput struct {
+	IdCustomer string
+	Phone      string
+	Name       string
+	Email      string
+}
+
+// CustomerHistory is a customer with what they bought. Visits and
+// LifetimeValue count completed and refunded sales; LifetimeValue is net of
+// all refunds.
+type CustomerHistory struct {
+	Customer      *entity.Customers
+	Visits        int64
+	Sales         entity.Money
+	Refunded      entity.Money
+	LifetimeValue entity.Money
+	FirstVisit    *time.Time
+	LastVisit     *time.Time
+	Transactions  []entity.Transactions
+}
+
+type CustomersService interface {
+	Create(c *entity.Customers) (*entity.Customers, error)
+	GetByID(id string) (*entity.Customers, error)
+	// GetAll searches name, phone and email; a phone typed in local form is
+	// matched in E.164.
+	GetAll(search string, limit, page int) ([]entity.Customers, error)
+	Update(id string, c *entity.Customers) (*entity.Customers, error)
+	Delete(id string) error
+	// History pages through the customer's transactions, newest first.
+	History(id string, limit, page int) (*CustomerHistory, error)
+}
+
+type customersService struct {
+	repo         repo.CustomersRepo
+	transactions repo.TransactionsRepo
+	refunds      repo.RefundsRepo
+	cfg          *conf.Config
+}
+
+func NewCustomersService(r repo.CustomersRepo, transactions repo.TransactionsRepo, refunds repo.RefundsRepo, cfg *conf.Config) CustomersService {
+	return &customersService{repo: r, transactions: transactions, refunds: refunds, cfg: cfg}
+}
+
+func (s *customersService) Create(c *entity.Customers) (*entity.Customers, error) {
+	if c == nil {
+		return nil, errors.New("customer nil")
+	}
+	if err := s.validate(c); err != nil {
+		return nil, err
+	}
+	if _, err := s.repo.GetByPhone(c.Phone); err == nil {
+		return nil, fmt.Errorf("%w: phone %s is already registered", ErrInvalidCustomer, c.Phone)
+	}
+	c.IdCustomer = helper.Uuid()
+	c.IsDeleted = false
+	c.ConsentAt = nil
+	if c.MarketingConsent {
+		now := time.Now()
+		c.ConsentAt = &now
+	}
+	if err := s.repo.Create(c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (s *customersService) GetByID(id string) (*entity.Customers, error) {
+	c, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, ErrCustomerNotFound
+	}
+	return c, nil
+}
+
+func (s *customersService) GetAll(search string, limit, page int) ([]entity.Customers, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	if page <= 0 {
+		page = 1
+	}
+	search = strings.TrimSpace(search)
+	if phone, ok := helper.NormalizePhone(search, s.cfg.PhoneCountryCode); ok {
+		search = phone
+	}
+	list, err := s.repo.ListPage(search, limit, (page-1)*limit)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]entity.Customers, 0, len(list))
+	for _, c := range list {
+		out = append(out, *c)
+	}
+	return out, nil
+}
+
+func (s *customersService) Update(id string, c *entity.Customers) (*entity.Customers, error) {
+	if c == nil {
+		return nil, errors.New("customer nil")
+	}
+	existing, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, ErrCustomerNotFound
+	}
+	if err := s.validate(c); err != nil {
+		return nil, err
+	}
+	if other, err := s.repo.GetByPhone(c.Phone); err == nil && other.IdCustomer != id {
+		return nil, fmt.Errorf("%w: phone %s is already registered", ErrInvalidCustomer, c.Phone)
+	}
+	c.IdCustomer = id
+	c.ConsentAt = existing.ConsentAt
+	if c.MarketingConsent != existing.MarketingConsent {
+		now := time.Now()
+		c.ConsentAt = &now
+	}
+	if err := s.repo.Update(c); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(id)
+}
+
+func (s *customersService) Delete(id string) error {
+	if _, err := s.repo.GetByID(id); err != nil {
+		return ErrCustomerNotFound
+	}
+	return s.repo.Delete(id)
+}
+
+func (s *customersService) History(id string, limit, page int) (*CustomerHistory, error) {
+	c, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, ErrCustomerNotFound
+	}
+	if limit <= 0 {
+		limit = 10
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	if page <= 0 {
+		page = 1
+	}
+	stats, err := s.transactions.CustomerStats(id, entity.TransactionStatusCompleted, entity.TransactionStatusRefunded)
+	if err != nil {
+		return nil, err
+	}
+	refunded, err := s.refunds.SumByCustomer(id)
+	if err != nil {
+		return nil, err
+	}
+	list, err := s.transactions.ListByCustomer(id, limit, (page-1)*limit)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]entity.Transactions, 0, len(list))
+	for _, t := range list {
+		out = append(out, *t)
+	}
+	return &CustomerHistory{
+		Customer:      c,
+		Visits:        stats.Visits,
+		Sales:         stats.Total,
+		Refunded:      refunded,
+		LifetimeValue: stats.Total - refunded,
+		FirstVisit:    stats.FirstVisit,
+		LastVisit:     stats.LastVisit,
+		Transactions:  out,
+	}, nil
+}
+
+// validate normalizes the phone to E.164 and trims the other fields.
+func (s *customersService) validate(c *entity.Customers) error {
+	phone, ok := helper.NormalizePhone(c.Phone, s.cfg.PhoneCountryCode)
+	if !ok {
+		return fmt.Errorf("%w: phone %q is not a valid phone number", ErrInvalidCustomer, c.Phone)
+	}
+	c.Phone = phone
+	c.Name = truncate(strings.TrimSpace(c.Name), 100)
+	email, err := normalizeEmail(c.Email)
+	if err != nil {
+		return err
+	}
+	c.Email = email
+	c.Notes = strings.TrimSpace(c.Notes)
+	return nil
+}
+
+func normalizeEmail(email string) (string, error) {
+	email = strings.TrimSpace(email)
+	if email == "" {
+		return "", nil
+	}
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email || len(email) > 255 {
+		return "", fmt.Errorf("%w: email %q is not a valid address", ErrInvalidCustomer, email)
+	}
+	return strings.ToLower(email), nil
+}
+
+// buyerContact is contact in E.164 when it is a phone number and contact
+// as given otherwise, so a phone keys the same buyer however it was typed.
+func buyerContact(contact, countryCode string) string {
+	if phone, ok := helper.NormalizePhone(contact, countryCode); ok {
+		return phone
+	}
+	return contact
+}
+
+// resolveCustomer finds the customer with in.Phone, an E.164 number, or
+// registers them. A found customer without a name or email gets the ones
+// given. r must be bound to the checkout's database transaction.
+func resolveCustomer(r repo.CustomersRepo, in CustomerInput) (*entity.Customers, error) {
+	name := truncate(strings.TrimSpace(in.Name), 100)
+	email, err := normalizeEmail(in.Email)
+	if err != nil {
+		return nil, err
+	}
+	c, err := r.GetByPhone(in.Phone)
+	if err != nil {
+		c = &entity.Customers{IdCustomer: helper.Uuid(), Phone: in.Phone, Name: name, Email: email}
+		created, err := r.CreateIfAbsent(c)
+		if err != nil {
+			return nil, err
+		}
+		if created {
+			return c, nil
+		}
+		// Another checkout registered the phone in the meantime.
+		if c, err = r.GetByPhone(in.Phone); err != nil {
+			return nil, err
+		}
+	}
+	if (c.Name == "" && name != "") || (c.Email == "" && email != "") {
+		if c.Name == "" {
+			c.Name = name
+		}
+		if c.Email == "" {
+			c.Email = email
+		}
+		if err := r.Update(c); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
@@ -0,0 +1,175 @@
+package services
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"faizalmaulana/lsp/models/entity"
+)
+
+// DashboardEventSnapshot carries today's figures without anything that
+// changed them: on connecting, when missed events can no longer be
+// replayed, and when a new day starts.
+const DashboardEventSnapshot = "snapshot"
+
+const (
+	// dashboardBacklog is how many events are kept for dashboards that
+	// reconnect.
+	dashboardBacklog = 200
+	// dashboardLatest is how many of the latest sales the dashboard shows.
+	dashboardLatest = 10
+	// dashboardTopItems is how many top items the dashboard shows.
+	dashboardTopItems = 5
+)
+
+// Dashboard is today's summary as the live dashboards show it, with the
+// latest sales first.
+type Dashboard struct {
+	Date    string
+	Summary *ReportSummary
+	Latest  []entity.Transactions
+}
+
+// DashboardUpdate is what a dashboard event carries: the sale or refund
+// that changed the figures, if any, and the figures since.
+type DashboardUpdate struct {
+	Transaction *entity.Transactions
+	Refund      *entity.Refunds
+	Dashboard   *Dashboard
+}
+
+type DashboardService interface {
+	Today() (*Dashboard, error)
+	// Subscribe follows the dashboard after the event lastEventID. The
+	// events missed since then come first; when they are no longer all at
+	// hand, or lastEventID is empty, a snapshot comes first instead. The
+	// returned function ends the subscription.
+	Subscribe(lastEventID string) ([]Event, <-chan Event, func(), error)
+	// Run turns the sales published on the event bus into dashboard events
+	// until ctx is done.
+	Run(ctx context.Context)
+}
+
+type dashboardService struct {
+	reports ReportsService
+	bus     EventBus
+	feed    *hub
+
+	// boot tells the event ids of this process from those of an earlier
+	// one, whose events are gone.
+	boot   string
+	mu     sync.Mutex
+	seq    int64
+	recent []Event
+}
+
+func NewDashboardService(reports ReportsService, bus EventBus) DashboardService {
+	return &dashboardService{reports: reports, bus: bus, feed: newHub(), boot: strconv.FormatInt(time.Now().UnixNano(), 36)}
+}
+
+func (s *dashboardService) Today() (*Dashboard, error) {
+	from := startOfDay(time.Now())
+	sum, err := s.reports.Summarize(from, from.AddDate(0, 0, 1), dashboardTopItems)
+	if err != nil {
+		return nil, err
+	}
+	latest := make([]entity.Transactions, 0, dashboardLatest)
+	for i := len(sum.Transactions) - 1; i >= 0 && len(latest) < dashboardLatest; i-- {
+		latest = append(latest, sum.Transactions[i])
+	}
+	return &Dashboard{Date: from.Format("2006-01-02"), Summary: sum, Latest: latest}, nil
+}
+
+func (s *dashboardService) Subscribe(lastEventID string) ([]Event, <-chan Event, func(), error) {
+	// Taking the backlog and subscribing under one lock leaves no gap
+	// between them.
+	s.mu.Lock()
+	backlog, ok := s.since(lastEventID)
+	seq := s.seq
+	events, stop := s.feed.Subscribe(nil)
+	s.mu.Unlock()
+	if ok {
+		return backlog, events, stop, nil
+	}
+	today, err := s.Today()
+	if err != nil {
+		stop()
+		return nil, nil, nil, err
+	}
+	return []Event{{Id: s.eventID(seq), Type: DashboardEventSnapshot, Data: DashboardUpdate{Dashboard: today}}}, events, stop, nil
+}
+
+// since returns the events after the event id, or false when they are not
+// all kept any more. The caller holds s.mu.
+func (s *dashboardService) since(id string) ([]Event, bool) {
+	boot, n, found := strings.Cut(id, "-")
+	seq, err := strconv.ParseInt(n, 10, 64)
+	if !found || err != nil || boot != s.boot || seq > s.seq {
+		return nil, false
+	}
+	// recent holds the events first+1 to s.seq.
+	first := s.seq - int64(len(s.recent))
+	if seq < first {
+		return nil, false
+	}
+	return append([]Event(nil), s.recent[seq-first:]...), true
+}
+
+func (s *dashboardService) eventID(seq int64) string {
+	return s.boot + "-" + strconv.FormatInt(seq, 10)
+}
+
+func (s *dashboardService) Run(ctx context.Context) {
+	events, stop := s.bus.Subscribe(nil)
+	defer stop()
+	tick := time.NewTicker(time.Minute)
+	defer tick.Stop()
+	day := time.Now().Format("2006-01-02")
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e, ok := <-events:
+			if !ok {
+				return
+			}
+			s.update(e)
+		case now := <-tick.C:
+			// At midnight the dashboards start over from the new day.
+			if d := now.Format("2006-01-02"); d != day {
+				day = d
+				s.update(Event{Type: DashboardEventSnapshot})
+			}
+		}
+	}
+}
+
+// update works out today's figures after the sales event e and passes them
+// on to the dashboards.
+func (s *dashboardService) update(e Event) {
+	today, err := s.Today()
+	if err != nil {
+		log.Printf("dashboard update after %s: %v", e.Type, err)
+		return
+	}
+	u := DashboardUpdate{Dashboard: today}
+	switch v := e.Data.(type) {
+	case entity.Transactions:
+		u.Transaction = &v
+	case entity.Refunds:
+		u.Refund = &v
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seq++
+	out := Event{Id: s.eventID(s.seq), Type: e.Type, Data: u}
+	s.recent = append(s.recent, out)
+	if len(s.recent) > dashboardBacklog {
+		s.recent = s.recent[len(s.recent)-dashboardBacklog:]
+	}
+	s.feed.Publish(out)
+}
@@ -0,0 +1,334 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"faizalmaulana/lsp/helper"
+	"faizalmaulana/lsp/models/entity"
+	"faizalmaulana/lsp/models/repo"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidDayClose  = errors.New("invalid day close")
+	ErrDayCloseNotFound = errors.New("day close not found")
+	ErrDayClosed        = errors.New("day already closed")
+	ErrDayNotReady      = errors.New("day cannot be closed yet")
+	// ErrPeriodClosed rejects writes dated within a closed day.
+	ErrPeriodClosed = errors.New("period is closed")
+)
+
+// DayReport is the Z-report of a day close. It is stored as JSON with the
+// close and never recomputed, so it keeps showing the figures accountants
+// closed the day with.
+type DayReport struct {
+	Number        int                 `json:"number"`
+	BusinessDate  string              `json:"business_date"`
+	PeriodFrom    time.Time           `json:"period_from"`
+	PeriodTo      time.Time           `json:"period_to"`
+	Sales         int                 `json:"sales"`
+	SalesTotal    entity.Money        `json:"sales_total"`
+	Subtotal      entity.Money        `json:"subtotal"`
+	DiscountTotal entity.Money        `json:"discount_total"`
+	ServiceCharge entity.Money        `json:"service_charge_total"`
+	TaxTotal      entity.Money        `json:"tax_total"`
+	GiftCardSales entity.Money        `json:"gift_card_sales"`
+	CashRounding  entity.Money        `json:"cash_rounding"`
+	Payments      []DayReportPayment  `json:"payment_methods"`
+	Discounts     []DayReportDiscount `json:"discounts"`
+	RefundCount   int                 `json:"refund_count"`
+	RefundTotal   entity.Money        `json:"refund_total"`
+	NetSales      entity.Money        `json:"net_sales"`
+	Refunds       []DayReportRefund   `json:"refunds"`
+	VoidedCount   int                 `json:"voided_transactions"`
+	VoidedTotal   entity.Money        `json:"voided_total"`
+	Voids         []DayReportVoid     `json:"voids"`
+	Shifts        []DayReportShift    `json:"shifts"`
+	// Cash adds up the drawer counts of the shifts.
+	Cash DayReportCash `json:"cash"`
+}
+
+type DayReportPayment struct {
+	Method   string       `json:"method"`
+	Count    int          `json:"count"`
+	Amount   entity.Money `json:"amount"`
+	Refunded entity.Money `json:"refunded"`
+}
+
+type DayReportDiscount struct {
+	IdPromotion string       `json:"id_promotion,omitempty"`
+	Name        string       `json:"name"`
+	Source      string       `json:"source"`
+	Count       int          `json:"count"`
+	Amount      entity.Money `json:"amount"`
+}
+
+type DayReportRefund struct {
+	IdRefund      string       `json:"id_refund"`
+	IdTransaction string       `json:"id_transaction"`
+	Method        string       `json:"method"`
+	Amount        entity.Money `json:"amount"`
+	Timestamp     time.Time    `json:"timestamp"`
+}
+
+type DayReportVoid struct {
+	IdTransaction string       `json:"id_transaction"`
+	TotalPrice    entity.Money `json:"total_price"`
+	Reason        string       `json:"reason"`
+	VoidedBy      string       `json:"voided_by"`
+}
+
+type DayReportShift struct {
+	IdShift      string       `json:"id_shift"`
+	IdUser       string       `json:"id_user"`
+	OpenedAt     time.Time    `json:"opened_at"`
+	ClosedAt     *time.Time   `json:"closed_at"`
+	OpeningFloat entity.Money `json:"opening_float"`
+	ExpectedCash entity.Money `json:"expected_cash"`
+	CountedCash  entity.Money `json:"counted_cash"`
+	Difference   entity.Money `json:"difference"`
+}
+
+type DayReportCash struct {
+	Expected   entity.Money `json:"expected"`
+	Counted    entity.Money `json:"counted"`
+	Difference entity.Money `json:"difference"`
+}
+
+type DayCloseDetail struct {
+	Close  *entity.DayCloses
+	Report *DayReport
+}
+
+type DayClosesService interface {
+	// Preview builds the report closing date would produce, without
+	// closing it.
+	Preview(date time.Time) (*DayReport, error)
+	// Close closes the day, and any earlier days not closed yet, and locks
+	// the period.
+	Close(date time.Time, idUser string) (*DayCloseDetail, error)
+	Get(id string) (*DayCloseDetail, error)
+	GetAll(limit, page int) ([]entity.DayCloses, error)
+}
+
+type dayClosesService struct {
+	repo    repo.DayClosesRepo
+	uow     repo.UnitOfWork
+	reports ReportsService
+	txs     repo.TransactionsRepo
+	shifts  repo.ShiftsRepo
+}
+
+func NewDayClosesService(r repo.DayClosesRepo, uow repo.UnitOfWork, reports ReportsService, txs repo.TransactionsRepo, shifts repo.ShiftsRepo) DayClosesService {
+	return &dayClosesService{repo: r, uow: uow, reports: reports, txs: txs, shifts: shifts}
+}
+
+func (s *dayClosesService) Preview(date time.Time) (*DayReport, error) {
+	from, to, number, err := s.period(s.repo, date)
+	if err != nil {
+		return nil, err
+	}
+	return s.build(s.reports, s.shifts, number, date, from, to)
+}
+
+func (s *dayClosesService) Close(date time.Time, idUser string) (*DayCloseDetail, error) {
+	var (
+		out    *DayCloseDetail
+		number int
+	)
+	err := s.uow.Do(func(db *gorm.DB) error {
+		r := s.repo.WithTx(db)
+		// Wait for the writes that found the period open, and keep new ones
+		// out until the close is committed.
+		if err := r.LockPeriod(true); err != nil {
+			return err
+		}
+		var (
+			from, to time.Time
+			err      error
+		)
+		if from, to, number, err = s.period(r, date); err != nil {
+			return err
+		}
+		open, err := s.shifts.WithTx(db).CountOpenBefore(to)
+		if err != nil {
+			return err
+		}
+		if open > 0 {
+			return fmt.Errorf("%w: %d shift(s) of the day are still open", ErrDayNotReady, open)
+		}
+		pending, err := s.txs.WithTx(db).ListBetween(from, to, entity.TransactionStatusPending)
+		if err != nil {
+			return err
+		}
+		if len(pending) > 0 {
+			return fmt.Errorf("%w: %d sale(s) are still waiting for payment", ErrDayNotReady, len(pending))
+		}
+		report, err := s.build(s.reports.WithTx(db), s.shifts.WithTx(db), number, date, from, to)
+		if err != nil {
+			return err
+		}
+		body, err := json.Marshal(report)
+		if err != nil {
+			return err
+		}
+		d := &entity.DayCloses{
+			IdDayClose:     helper.Uuid(),
+			Number:         number,
+			BusinessDate:   report.BusinessDate,
+			PeriodFrom:     from,
+			PeriodTo:       to,
+			ClosedBy:       idUser,
+			Sales:          report.Sales,
+			SalesTotal:     report.SalesTotal,
+			TaxTotal:       report.TaxTotal,
+			RefundTotal:    report.RefundTotal,
+			NetSales:       report.NetSales,
+			CashDifference: report.Cash.Difference,
+			Report:         string(body),
+		}
+		if err := r.Create(d); err != nil {
+			return err
+		}
+		out = &DayCloseDetail{Close: d, Report: report}
+		return nil
+	})
+	if err != nil {
+		// The unique number and date reject a close made meanwhile.
+		if _, found := s.repo.GetByNumber(number); number > 0 && found == nil && !errors.Is(err, ErrDayClosed) {
+			return nil, fmt.Errorf("%w: another close was made meanwhile", ErrDayClosed)
+		}
+		return nil, err
+	}
+	return out, nil
+}
+
+func (s *dayClosesService) Get(id string) (*DayCloseDetail, error) {
+	d, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, ErrDayCloseNotFound
+	}
+	var report DayReport
+	if err := json.Unmarshal([]byte(d.Report), &report); err != nil {
+		return nil, err
+	}
+	return &DayCloseDetail{Close: d, Report: &report}, nil
+}
+
+func (s *dayClosesService) GetAll(limit, page int) ([]entity.DayCloses, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	if page <= 0 {
+		page = 1
+	}
+	return s.repo.ListPage(limit, (page-1)*limit)
+}
+
+// period works out what closing date covers: from the end of the previous
+// close, or the start of date for the first one, to the end of date. Days
+// are closed in order and never in advance.
+func (s *dayClosesService) period(r repo.DayClosesRepo, date time.Time) (from, to time.Time, number int, err error) {
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+	to = day.AddDate(0, 0, 1)
+	if day.After(time.Now()) {
+		return from, to, 0, fmt.Errorf("%w: %s has not started yet", ErrInvalidDayClose, day.Format("2006-01-02"))
+	}
+	from, number = day, 1
+	if last, err := r.GetLatest(); err == nil {
+		if !last.PeriodTo.Before(to) {
+			return from, to, 0, fmt.Errorf("%w: closed up to %s by Z-report #%d", ErrDayClosed, last.BusinessDate, last.Number)
+		}
+		from, number = last.PeriodTo, last.Number+1
+	}
+	return from, to, number, nil
+}
+
+// build sums up the period from the sales reports and the shifts opened in
+// it, read through reports and shifts.
+func (s *dayClosesService) build(reports ReportsService, shifts repo.ShiftsRepo, number int, date time.Time, from, to time.Time) (*DayReport, error) {
+	sum, err := reports.Summarize(from, to, 0)
+	if err != nil {
+		return nil, err
+	}
+	out := &DayReport{
+		Number:        number,
+		BusinessDate:  date.Format("2006-01-02"),
+		PeriodFrom:    from,
+		PeriodTo:      to,
+		Sales:         sum.TotalTransactions,
+		SalesTotal:    sum.SumTotalPrice,
+		Subtotal:      sum.Subtotal,
+		DiscountTotal: sum.DiscountGiven,
+		ServiceCharge: sum.ServiceChargeTotal,
+		TaxTotal:      sum.TaxTotal,
+		GiftCardSales: sum.GiftCardSales,
+		CashRounding:  sum.CashRounding,
+		RefundCount:   sum.RefundCount,
+		RefundTotal:   sum.RefundTotal,
+		NetSales:      sum.NetSales,
+		VoidedCount:   sum.VoidedCount,
+		VoidedTotal:   sum.VoidedTotal,
+	}
+	out.Payments = make([]DayReportPayment, 0, len(sum.PaymentMethods))
+	for _, m := range sum.PaymentMethods {
+		out.Payments = append(out.Payments, DayReportPayment{Method: m.Method, Count: m.Count, Amount: m.Amount, Refunded: m.Refunded})
+	}
+	out.Discounts = make([]DayReportDiscount, 0, len(sum.Discounts))
+	for _, d := range sum.Discounts {
+		out.Discounts = append(out.Discounts, DayReportDiscount{IdPromotion: d.IdPromotion, Name: d.Name, Source: d.Source, Count: d.Count, Amount: d.Amount})
+	}
+	out.Refunds = make([]DayReportRefund, 0, len(sum.Refunds))
+	for _, r := range sum.Refunds {
+		out.Refunds = append(out.Refunds, DayReportRefund{IdRefund: r.IdRefund, IdTransaction: r.IdTransaction, Method: r.Method, Amount: r.Amount, Timestamp: r.Timestamp})
+	}
+	out.Voids = make([]DayReportVoid, 0, len(sum.Voids))
+	for _, t := range sum.Voids {
+		out.Voids = append(out.Voids, DayReportVoid{IdTransaction: t.IdTransaction, TotalPrice: t.TotalPrice, Reason: t.VoidReason, VoidedBy: t.VoidedBy})
+	}
+	opened, err := shifts.ListOpenedBetween(from, to)
+	if err != nil {
+		return nil, err
+	}
+	out.Shifts = make([]DayReportShift, 0, len(opened))
+	for _, sh := range opened {
+		out.Shifts = append(out.Shifts, DayReportShift{
+			IdShift:      sh.IdShift,
+			IdUser:       sh.IdUser,
+			OpenedAt:     sh.Timestamp,
+			ClosedAt:     sh.ClosedAt,
+			OpeningFloat: sh.OpeningFloat,
+			ExpectedCash: sh.ExpectedCash,
+			CountedCash:  sh.CountedCash,
+			Difference:   sh.Difference,
+		})
+		out.Cash.Expected += sh.ExpectedCash
+		out.Cash.Counted += sh.CountedCash
+		out.Cash.Difference += sh.Difference
+	}
+	return out, nil
+}
+
+// checkPeriodOpen rejects a write dated at when it falls within a closed
+// day. r must be bound to the database transaction of the write, which then
+// holds the period lock so no close can commit before it does.
+func checkPeriodOpen(r repo.DayClosesRepo, at time.Time) error {
+	if err := r.LockPeriod(false); err != nil {
+		return err
+	}
+	until, err := r.LockedUntil()
+	if err != nil {
+		return err
+	}
+	if at.Before(until) {
+		return fmt.Errorf("%w: the books are closed through %s", ErrPeriodClosed, until.Add(-time.Nanosecond).Format("2006-01-02"))
+	}
+	return nil
+}
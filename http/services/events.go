@@ -0,0 +1,77 @@
+package services
+
+import "sync"
+
+// Events of the sales published on the EventBus. Transaction events carry
+// the entity.Transactions, transaction.refunded the entity.Refunds.
+const (
+	EventTransactionCreated  = "transaction.created"
+	EventTransactionVoided   = "transaction.voided"
+	EventTransactionRefunded = "transaction.refunded"
+)
+
+// Event is a message pushed to the subscribers of a hub, e.g. to the
+// screens following a server-sent event stream. Id is set on events a
+// stream can be resumed from.
+type Event struct {
+	Id   string      `json:"id,omitempty"`
+	Type string      `json:"type"`
+	Data interface{} `json:"data"`
+}
+
+// EventBus passes what happens to the sales on to whoever follows them
+// within the process, e.g. the live dashboard. Publishing never waits for
+// the subscribers.
+type EventBus interface {
+	Publish(e Event)
+	// Subscribe returns the events matching match, nil for all of them,
+	// until the returned function is called.
+	Subscribe(match func(Event) bool) (<-chan Event, func())
+}
+
+func NewEventBus() EventBus {
+	return newHub()
+}
+
+// hub fans events out to its subscribers, each of which only gets the
+// events it matches. Subscribers that do not keep up miss events rather
+// than hold up the publisher; screens reload what they show when they
+// reconnect.
+type hub struct {
+	mu   sync.Mutex
+	subs map[chan Event]func(Event) bool
+}
+
+func newHub() *hub {
+	return &hub{subs: map[chan Event]func(Event) bool{}}
+}
+
+func (h *hub) Subscribe(match func(Event) bool) (<-chan Event, func()) {
+	ch := make(chan Event, 32)
+	h.mu.Lock()
+	h.subs[ch] = match
+	h.mu.Unlock()
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			h.mu.Lock()
+			delete(h.subs, ch)
+			h.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+func (h *hub) Publish(e Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch, match := range h.subs {
+		if match != nil && !match(e) {
+			continue
+		}
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
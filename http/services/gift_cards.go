@@ -0,0 +1,486 @@
+package services
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"faizalmaulana/lsp/helper"
+	"faizalmaulana/lsp/models/entity"
+	"faizalmaulana/lsp/models/repo"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidGiftCard     = errors.New("invalid gift card")
+	ErrGiftCardNotFound    = errors.New("gift card not found")
+	ErrGiftCardUnavailable = errors.New("gift card cannot be used")
+	ErrInsufficientBalance = errors.New("insufficient gift card balance")
+)
+
+const (
+	giftCardCodeLength = 16
+	giftCardPinLength  = 6
+)
+
+// IssuedGiftCard is a newly issued account. Pin is only ever shown here;
+// the account keeps a hash of it.
+type IssuedGiftCard struct {
+	Card *entity.GiftCards `json:"card"`
+	Pin  string            `json:"pin"`
+}
+
+// GiftCardIssue asks for an account outside a sale, e.g. store credit given
+// as a goodwill gesture or a paper gift card carried over from another
+// system. Amount is the opening balance.
+type GiftCardIssue struct {
+	Kind       string
+	Amount     entity.Money
+	IdCustomer string
+	IdUser     string
+	Note       string
+}
+
+type GiftCardDetail struct {
+	Card   *entity.GiftCards       `json:"card"`
+	Ledger []entity.GiftCardLedger `json:"ledger"`
+}
+
+type GiftCardsService interface {
+	Issue(in GiftCardIssue) (*IssuedGiftCard, error)
+	// GetByID returns the account with a page of its ledger, newest first.
+	GetByID(id string, limit, page int) (*GiftCardDetail, error)
+	GetAll(kind, idCustomer string, limit, page int) ([]entity.GiftCards, error)
+	// Balance looks an account up by its code and PIN.
+	Balance(code, pin string) (*entity.GiftCards, error)
+	// Adjust corrects a balance by amount, which may be negative.
+	Adjust(id string, amount entity.Money, idUser, note string) (*entity.GiftCards, error)
+	// SetStatus disables a lost or stolen card, or enables it again.
+	SetStatus(id, status string) (*entity.GiftCards, error)
+	// Liability is what is owed on the accounts at at, per kind.
+	Liability(at time.Time) ([]repo.GiftCardLiability, error)
+}
+
+type giftCardsService struct {
+	repo      repo.GiftCardsRepo
+	uow       repo.UnitOfWork
+	customers repo.CustomersRepo
+}
+
+func NewGiftCardsService(r repo.GiftCardsRepo, uow repo.UnitOfWork, customers repo.CustomersRepo) GiftCardsService {
+	return &giftCardsService{repo: r, uow: uow, customers: customers}
+}
+
+func (s *giftCardsService) Issue(in GiftCardIssue) (*IssuedGiftCard, error) {
+	if in.Kind != entity.GiftCardKindGiftCard && in.Kind != entity.GiftCardKindStoreCredit {
+		return nil, fmt.Errorf("%w: kind must be %s or %s", ErrInvalidGiftCard, entity.GiftCardKindGiftCard, entity.GiftCardKindStoreCredit)
+	}
+	if in.Amount <= 0 {
+		return nil, fmt.Errorf("%w: amount must be positive", ErrInvalidGiftCard)
+	}
+	if in.IdCustomer != "" {
+		if _, err := s.customers.GetByID(in.IdCustomer); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrCustomerNotFound, in.IdCustomer)
+		}
+	}
+	var out *IssuedGiftCard
+	err := s.uow.Do(func(db *gorm.DB) error {
+		r := s.repo.WithTx(db)
+		var err error
+		if out, err = newGiftCard(r, in.Kind, in.IdCustomer); err != nil {
+			return err
+		}
+		return postGiftCard(r, out.Card, entity.GiftCardLedger{Kind: entity.GiftCardEntryIssue, Amount: in.Amount, IdUser: in.IdUser, Note: in.Note})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (s *giftCardsService) GetByID(id string, limit, page int) (*GiftCardDetail, error) {
+	g, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, ErrGiftCardNotFound
+	}
+	if limit <= 0 {
+		limit = 10
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	if page <= 0 {
+		page = 1
+	}
+	ledger, err := s.repo.ListEntries(id, limit, (page-1)*limit)
+	if err != nil {
+		return nil, err
+	}
+	return &GiftCardDetail{Card: g, Ledger: ledger}, nil
+}
+
+func (s *giftCardsService) GetAll(kind, idCustomer string, limit, page int) ([]entity.GiftCards, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	if page <= 0 {
+		page = 1
+	}
+	return s.repo.ListPage(kind, idCustomer, limit, (page-1)*limit)
+}
+
+func (s *giftCardsService) Balance(code, pin string) (*entity.GiftCards, error) {
+	return unlockGiftCard(s.repo, code, pin)
+}
+
+func (s *giftCardsService) Adjust(id string, amount entity.Money, idUser, note string) (*entity.GiftCards, error) {
+	if amount == 0 {
+		return nil, fmt.Errorf("%w: amount must not be zero", ErrInvalidGiftCard)
+	}
+	if strings.TrimSpace(note) == "" {
+		return nil, fmt.Errorf("%w: an adjustment needs a note", ErrInvalidGiftCard)
+	}
+	var g *entity.GiftCards
+	err := s.uow.Do(func(db *gorm.DB) error {
+		r := s.repo.WithTx(db)
+		var err error
+		if g, err = r.GetForUpdate(id); err != nil {
+			return ErrGiftCardNotFound
+		}
+		return postGiftCard(r, g, entity.GiftCardLedger{Kind: entity.GiftCardEntryAdjust, Amount: amount, IdUser: idUser, Note: note})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+func (s *giftCardsService) SetStatus(id, status string) (*entity.GiftCards, error) {
+	if status != entity.GiftCardStatusActive && status != entity.GiftCardStatusDisabled {
+		return nil, fmt.Errorf("%w: status must be %s or %s", ErrInvalidGiftCard, entity.GiftCardStatusActive, entity.GiftCardStatusDisabled)
+	}
+	if _, err := s.repo.GetByID(id); err != nil {
+		return nil, ErrGiftCardNotFound
+	}
+	if err := s.repo.SetStatus(id, status); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(id)
+}
+
+func (s *giftCardsService) Liability(at time.Time) ([]repo.GiftCardLiability, error) {
+	return s.repo.Liability(at)
+}
+
+func normalizeGiftCardCode(code string) string {
+	return strings.ToUpper(strings.NewReplacer(" ", "", "-", "").Replace(strings.TrimSpace(code)))
+}
+
+// maskGiftCardCode keeps the last four characters, which is what receipts
+// and payment references show of a card.
+func maskGiftCardCode(code string) string {
+	if len(code) <= 4 {
+		return code
+	}
+	return "****" + code[len(code)-4:]
+}
+
+func randomPin(n int) string {
+	ten := big.NewInt(10)
+	b := make([]byte, n)
+	for i := range b {
+		d, err := rand.Int(rand.Reader, ten)
+		if err != nil {
+			panic(err)
+		}
+		b[i] = byte('0' + d.Int64())
+	}
+	return string(b)
+}
+
+// unlockGiftCard finds the account with code and checks pin against it.
+// Both a wrong code and a wrong PIN give the same error.
+func unlockGiftCard(r repo.GiftCardsRepo, code, pin string) (*entity.GiftCards, error) {
+	g, err := r.GetByCode(normalizeGiftCardCode(code))
+	if err != nil {
+		return nil, fmt.Errorf("%w: wrong code or PIN", ErrGiftCardUnavailable)
+	}
+	if bcrypt.CompareHashAndPassword([]byte(g.PinHash), []byte(strings.TrimSpace(pin))) != nil {
+		return nil, fmt.Errorf("%w: wrong code or PIN", ErrGiftCardUnavailable)
+	}
+	return g, nil
+}
+
+// newGiftCard creates an empty account with a random code and PIN. r must
+// be bound to the database transaction that funds it.
+func newGiftCard(r repo.GiftCardsRepo, kind, idCustomer string) (*IssuedGiftCard, error) {
+	code := randomVoucherCode(giftCardCodeLength)
+	for tries := 0; tries < 3; tries++ {
+		if _, err := r.GetByCode(code); err != nil {
+			break
+		}
+		code = randomVoucherCode(giftCardCodeLength)
+	}
+	pin := randomPin(giftCardPinLength)
+	hash, err := bcrypt.GenerateFromPassword([]byte(pin), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+	g := &entity.GiftCards{
+		IdGiftCard: helper.Uuid(),
+		Kind:       kind,
+		Code:       code,
+		PinHash:    string(hash),
+		IdCustomer: idCustomer,
+		Status:     entity.GiftCardStatusActive,
+	}
+	if err := r.Create(g); err != nil {
+		return nil, err
+	}
+	return &IssuedGiftCard{Card: g, Pin: pin}, nil
+}
+
+// postGiftCard appends e to the ledger of g and moves the balance with it.
+// g must have been locked with GetForUpdate, or just created, in the
+// database transaction r is bound to. A balance never goes below zero.
+func postGiftCard(r repo.GiftCardsRepo, g *entity.GiftCards, e entity.GiftCardLedger) error {
+	balance := g.Balance + e.Amount
+	if balance < 0 {
+		return fmt.Errorf("%w: %s left on %s", ErrInsufficientBalance, formatMoney(g.Balance), maskGiftCardCode(g.Code))
+	}
+	e.IdGiftCardEntry = helper.Uuid()
+	e.IdGiftCard = g.IdGiftCard
+	e.Balance = balance
+	e.Note = truncate(e.Note, 255)
+	if err := r.CreateEntry(&e); err != nil {
+		return err
+	}
+	if err := r.SetBalance(g.IdGiftCard, balance); err != nil {
+		return err
+	}
+	g.Balance = balance
+	return nil
+}
+
+func storedValueMethod(method string) bool {
+	return method == entity.PaymentMethodGiftCard || method == entity.PaymentMethodStoreCredit
+}
+
+// resolveStoredValueTenders finds the account each gift card and store
+// credit tender pays from, and replaces its Reference with the account id.
+// A gift card needs its code and PIN; store credit is taken from the
+// buyer's account when no code is given.
+func resolveStoredValueTenders(r repo.GiftCardsRepo, customers repo.CustomersRepo, in []PaymentInput, buyer *CustomerInput) error {
+	for i := range in {
+		p := &in[i]
+		method := strings.ToLower(strings.TrimSpace(p.Method))
+		if !storedValueMethod(method) {
+			continue
+		}
+		if p.Gateway {
+			return fmt.Errorf("%w: %s cannot be paid through the gateway", ErrInvalidPayment, method)
+		}
+		var g *entity.GiftCards
+		switch {
+		case strings.TrimSpace(p.Reference) != "":
+			var err error
+			if g, err = unlockGiftCard(r, p.Reference, p.Pin); err != nil {
+				return err
+			}
+		case method == entity.PaymentMethodStoreCredit && buyer != nil:
+			id := buyer.IdCustomer
+			if id == "" {
+				c, err := customers.GetByPhone(buyer.Phone)
+				if err != nil {
+					return fmt.Errorf("%w: the customer has no store credit", ErrGiftCardUnavailable)
+				}
+				id = c.IdCustomer
+			}
+			var err error
+			if g, err = r.GetStoreCredit(id); err != nil {
+				return fmt.Errorf("%w: the customer has no store credit", ErrGiftCardUnavailable)
+			}
+		default:
+			return fmt.Errorf("%w: %s needs the card's code as reference", ErrInvalidPayment, method)
+		}
+		if g.Kind != method {
+			return fmt.Errorf("%w: %s is not %s", ErrGiftCardUnavailable, maskGiftCardCode(g.Code), strings.ReplaceAll(method, "_", " "))
+		}
+		if g.Status != entity.GiftCardStatusActive {
+			return fmt.Errorf("%w: %s is %s", ErrGiftCardUnavailable, maskGiftCardCode(g.Code), g.Status)
+		}
+		p.Reference = g.IdGiftCard
+	}
+	return nil
+}
+
+// redeemGiftCards debits the accounts of the stored value payments, whose
+// Reference resolveStoredValueTenders set to the account id, and replaces
+// it with the masked code. The accounts are locked in id order, so sales
+// paying with the same cards lock them in the same order. r must be bound to
+// the checkout's database transaction.
+func redeemGiftCards(r repo.GiftCardsRepo, payments []entity.Payments, idUser string) error {
+	var order []int
+	for i := range payments {
+		if storedValueMethod(payments[i].Method) {
+			order = append(order, i)
+		}
+	}
+	sort.SliceStable(order, func(i, j int) bool { return payments[order[i]].Reference < payments[order[j]].Reference })
+	for _, i := range order {
+		p := &payments[i]
+		g, err := r.GetForUpdate(p.Reference)
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrGiftCardNotFound, p.Reference)
+		}
+		if g.Status != entity.GiftCardStatusActive {
+			return fmt.Errorf("%w: %s is %s", ErrGiftCardUnavailable, maskGiftCardCode(g.Code), g.Status)
+		}
+		if err := postGiftCard(r, g, entity.GiftCardLedger{Kind: entity.GiftCardEntryRedeem, Amount: -p.Amount, IdTransaction: p.IdTransaction, IdUser: idUser}); err != nil {
+			return err
+		}
+		p.Reference = maskGiftCardCode(g.Code)
+	}
+	return nil
+}
+
+// giftCardSale is a gift card line of a sale: Quantity new cards of Value
+// each, or Value × Quantity loaded onto the card with Code.
+type giftCardSale struct {
+	Code     string
+	Value    entity.Money
+	Quantity int
+}
+
+// sellGiftCards issues and tops up the gift cards bought on a sale. r must
+// be bound to the checkout's database transaction.
+func sellGiftCards(r repo.GiftCardsRepo, sales []giftCardSale, idTransaction, idUser string) ([]IssuedGiftCard, error) {
+	var out []IssuedGiftCard
+	for _, s := range sales {
+		if s.Code != "" {
+			found, err := r.GetByCode(s.Code)
+			if err != nil {
+				return nil, fmt.Errorf("%w: no card %s", ErrInvalidGiftCard, maskGiftCardCode(s.Code))
+			}
+			g, err := r.GetForUpdate(found.IdGiftCard)
+			if err != nil {
+				return nil, err
+			}
+			if g.Kind != entity.GiftCardKindGiftCard || g.Status != entity.GiftCardStatusActive {
+				return nil, fmt.Errorf("%w: %s cannot be topped up", ErrInvalidGiftCard, maskGiftCardCode(g.Code))
+			}
+			e := entity.GiftCardLedger{Kind: entity.GiftCardEntryTopUp, Amount: s.Value.Mul(s.Quantity), IdTransaction: idTransaction, IdUser: idUser}
+			if err := postGiftCard(r, g, e); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		for i := 0; i < s.Quantity; i++ {
+			issued, err := newGiftCard(r, entity.GiftCardKindGiftCard, "")
+			if err != nil {
+				return nil, err
+			}
+			e := entity.GiftCardLedger{Kind: entity.GiftCardEntryIssue, Amount: s.Value, IdTransaction: idTransaction, IdUser: idUser}
+			if err := postGiftCard(r, issued.Card, e); err != nil {
+				return nil, err
+			}
+			out = append(out, *issued)
+		}
+	}
+	return out, nil
+}
+
+// reverseGiftCards undoes what a voided or cancelled sale did to stored
+// value accounts: payments are credited back and cards sold are emptied of
+// what they were loaded with. Refunds of the sale stand. It fails when a card
+// sold on the sale has been spent since. r must be bound to the database
+// transaction changing the sale's status.
+func reverseGiftCards(r repo.GiftCardsRepo, idTransaction, idUser, note string) error {
+	entries, err := r.ListByTransaction(idTransaction)
+	if err != nil {
+		return err
+	}
+	net := map[string]entity.Money{}
+	for _, e := range entries {
+		if e.IdRefund == "" {
+			net[e.IdGiftCard] += e.Amount
+		}
+	}
+	// Lock in a fixed order so two reversals cannot deadlock.
+	ids := make([]string, 0, len(net))
+	for id, amount := range net {
+		if amount != 0 {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		g, err := r.GetForUpdate(id)
+		if err != nil {
+			return err
+		}
+		e := entity.GiftCardLedger{Kind: entity.GiftCardEntryReverse, Amount: -net[id], IdTransaction: idTransaction, IdUser: idUser, Note: note}
+		if err := postGiftCard(r, g, e); err != nil {
+			if errors.Is(err, ErrInsufficientBalance) {
+				return fmt.Errorf("%w: gift card %s sold on this sale has been used", ErrInvalidTransition, maskGiftCardCode(g.Code))
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// refundToGiftCard pays a refund in store credit or onto a gift card. Store
+// credit goes to the customer's account, which is opened when needed, or to
+// a new account for a sale without a customer; a reference names another
+// account to credit instead. The new account, if any, is returned with its
+// PIN. r must be bound to the refund's database transaction.
+func refundToGiftCard(r repo.GiftCardsRepo, t *entity.Transactions, refund *entity.Refunds) (*IssuedGiftCard, error) {
+	if !storedValueMethod(refund.Method) {
+		return nil, nil
+	}
+	var (
+		g      *entity.GiftCards
+		issued *IssuedGiftCard
+	)
+	switch {
+	case refund.Reference != "":
+		found, err := r.GetByCode(normalizeGiftCardCode(refund.Reference))
+		if err != nil || found.Kind != refund.Method {
+			return nil, fmt.Errorf("%w: no %s %s", ErrInvalidRefund, strings.ReplaceAll(refund.Method, "_", " "), refund.Reference)
+		}
+		if g, err = r.GetForUpdate(found.IdGiftCard); err != nil {
+			return nil, err
+		}
+	case refund.Method == entity.PaymentMethodGiftCard:
+		return nil, fmt.Errorf("%w: a refund onto a gift card needs the card's code as reference", ErrInvalidRefund)
+	case t.IdCustomer != "":
+		if found, err := r.GetStoreCredit(t.IdCustomer); err == nil {
+			if g, err = r.GetForUpdate(found.IdGiftCard); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if g == nil {
+		var err error
+		if issued, err = newGiftCard(r, entity.GiftCardKindStoreCredit, t.IdCustomer); err != nil {
+			return nil, err
+		}
+		g = issued.Card
+	}
+	e := entity.GiftCardLedger{Kind: entity.GiftCardEntryRefund, Amount: refund.Amount, IdTransaction: t.IdTransaction, IdRefund: refund.IdRefund, IdUser: refund.IdUser, Note: refund.Reason}
+	if err := postGiftCard(r, g, e); err != nil {
+		return nil, err
+	}
+	refund.Reference = maskGiftCardCode(g.Code)
+	return issued, nil
+}
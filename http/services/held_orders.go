@@ -0,0 +1,765 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"faizalmaulana/lsp/conf"
+	"faizalmaulana/lsp/helper"
+	"faizalmaulana/lsp/models/entity"
+	"faizalmaulana/lsp/models/repo"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidHeldOrder  = errors.New("invalid held order")
+	ErrHeldOrderNotFound = errors.New("held order not found")
+	ErrHeldOrderClosed   = errors.New("held order is closed")
+	ErrHeldOrderChanged  = errors.New("held order changed")
+)
+
+const (
+	// HeldOrderPricingLock charges the lines of a held order at the price
+	// they were added at.
+	HeldOrderPricingLock = "lock"
+	// HeldOrderPricingRecalculate charges them at the prices current at
+	// checkout.
+	HeldOrderPricingRecalculate = "recalculate"
+)
+
+// HeldOrderInput is what a held order is parked with. IdCustomer and
+// BuyerContact are who the sale will be for, as at checkout. IdTable opens
+// the order on a free table, for Guests guests.
+type HeldOrderInput struct {
+	Terminal     string
+	Label        string
+	Note         string
+	IdCustomer   string
+	BuyerContact string
+	IdTable      string
+	Guests       int
+}
+
+// HeldOrderUpdate changes the fields that are set.
+type HeldOrderUpdate struct {
+	Terminal     *string
+	Label        *string
+	Note         *string
+	Guests       *int
+	IdCustomer   *string
+	BuyerContact *string
+}
+
+// SplitLine takes Quantity units of a line of a held order to another
+// order; 0 takes the whole line.
+type SplitLine struct {
+	IdHeldOrderLine string
+	Quantity        int
+}
+
+// HeldOrderCheckout pays for a held order. The customer and buyer contact
+// of the order are used unless others are given.
+type HeldOrderCheckout struct {
+	IdUser       string
+	Role         string
+	BuyerContact string
+	Customer     *CustomerInput
+	Discount     *ManualDiscount
+	Voucher      string
+	Payments     []PaymentInput
+}
+
+type HeldOrdersService interface {
+	Create(idUser string, in HeldOrderInput, lines []CheckoutLine) (*entity.HeldOrders, error)
+	// Get returns the order with its lines.
+	Get(id string) (*entity.HeldOrders, error)
+	// GetAll filters by terminal and status when they are set, oldest
+	// first.
+	GetAll(terminal, status string, limit, page int) ([]entity.HeldOrders, error)
+	Update(id string, in HeldOrderUpdate) (*entity.HeldOrders, error)
+	AddLine(id string, line CheckoutLine) (*entity.HeldOrders, error)
+	// UpdateLine replaces a line of the order.
+	UpdateLine(id, idLine string, line CheckoutLine) (*entity.HeldOrders, error)
+	RemoveLine(id, idLine string) (*entity.HeldOrders, error)
+	// Quote prices the order as checking it out now would, without saving
+	// anything.
+	Quote(id string, req HeldOrderCheckout) (*CheckoutResult, error)
+	// Checkout rings the order up through the normal checkout and closes
+	// it.
+	Checkout(id string, req HeldOrderCheckout) (*CheckoutResult, error)
+	// Cancel discards an open order.
+	Cancel(id, by string) (*entity.HeldOrders, error)
+
+	// SplitEqually splits the bill of an open order into parts shares, one
+	// per guest when parts is 0. The order becomes share 1 and the other
+	// shares are parked next to it; each is paid separately and charged an
+	// equal share of the lines.
+	SplitEqually(id string, parts int) ([]entity.HeldOrders, error)
+	// SplitItems moves the lines of each part to a new order on the same
+	// table and terminal. The order keeps what no part takes.
+	SplitItems(id string, parts [][]SplitLine) ([]entity.HeldOrders, error)
+	// MoveTable moves the orders open on table from to the free table to.
+	MoveTable(from, to string) ([]entity.HeldOrders, error)
+	// MergeTables moves the lines of the orders open on table from to the
+	// oldest order on table into, which is then served on both tables.
+	MergeTables(from, into, by string) (*entity.HeldOrders, error)
+}
+
+type heldOrdersService struct {
+	repo      repo.HeldOrdersRepo
+	uow       repo.UnitOfWork
+	items     repo.ItemsRepo
+	modifiers ModifiersService
+	txs       TransactionsService
+	tables    repo.TablesRepo
+	cfg       *conf.Config
+}
+
+func NewHeldOrdersService(r repo.HeldOrdersRepo, uow repo.UnitOfWork, items repo.ItemsRepo, modifiers ModifiersService, txs TransactionsService, tables repo.TablesRepo, cfg *conf.Config) HeldOrdersService {
+	return &heldOrdersService{repo: r, uow: uow, items: items, modifiers: modifiers, txs: txs, tables: tables, cfg: cfg}
+}
+
+func (s *heldOrdersService) Create(idUser string, in HeldOrderInput, lines []CheckoutLine) (*entity.HeldOrders, error) {
+	o := &entity.HeldOrders{
+		IdHeldOrder:  helper.Uuid(),
+		Terminal:     truncate(strings.TrimSpace(in.Terminal), 50),
+		Label:        truncate(strings.TrimSpace(in.Label), 100),
+		Note:         truncate(strings.TrimSpace(in.Note), 255),
+		IdUser:       idUser,
+		IdCustomer:   strings.TrimSpace(in.IdCustomer),
+		BuyerContact: truncate(strings.TrimSpace(in.BuyerContact), 120),
+		Status:       entity.HeldOrderStatusOpen,
+		IdTable:      strings.TrimSpace(in.IdTable),
+		Guests:       max(in.Guests, 0),
+	}
+	rows := make([]entity.HeldOrderLines, 0, len(lines))
+	for _, in := range lines {
+		l, err := s.line(o.IdHeldOrder, in)
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, *l)
+	}
+	err := s.uow.Do(func(db *gorm.DB) error {
+		r := s.repo.WithTx(db)
+		if o.IdTable != "" {
+			t, err := lockTables(s.tables.WithTx(db), o.IdTable)
+			if err != nil {
+				return err
+			}
+			if !t[0].IsActive {
+				return fmt.Errorf("%w: %s takes no orders", ErrInvalidTable, t[0].Name)
+			}
+			if err := tableFree(r, t[0]); err != nil {
+				return err
+			}
+			if o.Label == "" {
+				o.Label = t[0].Name
+			}
+		}
+		if err := r.Create(o); err != nil {
+			return err
+		}
+		return createLines(r, rows)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.Get(o.IdHeldOrder)
+}
+
+func createLines(r repo.HeldOrdersRepo, rows []entity.HeldOrderLines) error {
+	for i := range rows {
+		if err := r.CreateLine(&rows[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *heldOrdersService) Get(id string) (*entity.HeldOrders, error) {
+	o, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, ErrHeldOrderNotFound
+	}
+	if o.Lines, err = s.repo.ListLines(id); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+func (s *heldOrdersService) GetAll(terminal, status string, limit, page int) ([]entity.HeldOrders, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	if page <= 0 {
+		page = 1
+	}
+	return s.repo.ListPage(strings.TrimSpace(terminal), strings.TrimSpace(status), limit, (page-1)*limit)
+}
+
+func (s *heldOrdersService) Update(id string, in HeldOrderUpdate) (*entity.HeldOrders, error) {
+	err := s.change(id, func(r repo.HeldOrdersRepo, o *entity.HeldOrders) error {
+		if in.Terminal != nil {
+			o.Terminal = truncate(strings.TrimSpace(*in.Terminal), 50)
+		}
+		if in.Label != nil {
+			o.Label = truncate(strings.TrimSpace(*in.Label), 100)
+		}
+		if in.Note != nil {
+			o.Note = truncate(strings.TrimSpace(*in.Note), 255)
+		}
+		if in.Guests != nil {
+			o.Guests = max(*in.Guests, 0)
+		}
+		if in.IdCustomer != nil {
+			o.IdCustomer = strings.TrimSpace(*in.IdCustomer)
+		}
+		if in.BuyerContact != nil {
+			o.BuyerContact = truncate(strings.TrimSpace(*in.BuyerContact), 120)
+		}
+		return r.Update(o)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.Get(id)
+}
+
+func (s *heldOrdersService) AddLine(id string, in CheckoutLine) (*entity.HeldOrders, error) {
+	l, err := s.line(id, in)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.change(id, func(r repo.HeldOrdersRepo, o *entity.HeldOrders) error {
+		if err := editable(o); err != nil {
+			return err
+		}
+		return r.CreateLine(l)
+	}); err != nil {
+		return nil, err
+	}
+	return s.Get(id)
+}
+
+func (s *heldOrdersService) UpdateLine(id, idLine string, in CheckoutLine) (*entity.HeldOrders, error) {
+	l, err := s.line(id, in)
+	if err != nil {
+		return nil, err
+	}
+	l.IdHeldOrderLine = idLine
+	err = s.change(id, func(r repo.HeldOrdersRepo, o *entity.HeldOrders) error {
+		if err := editable(o); err != nil {
+			return err
+		}
+		old, err := r.GetLine(id, idLine)
+		if err != nil {
+			return fmt.Errorf("%w: no line %s", ErrHeldOrderNotFound, idLine)
+		}
+		if old.IdItem != l.IdItem {
+			return fmt.Errorf("%w: a line cannot change its item; remove it and add another", ErrInvalidHeldOrder)
+		}
+		return r.UpdateLine(l)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.Get(id)
+}
+
+func (s *heldOrdersService) RemoveLine(id, idLine string) (*entity.HeldOrders, error) {
+	err := s.change(id, func(r repo.HeldOrdersRepo, o *entity.HeldOrders) error {
+		if err := editable(o); err != nil {
+			return err
+		}
+		if _, err := r.GetLine(id, idLine); err != nil {
+			return fmt.Errorf("%w: no line %s", ErrHeldOrderNotFound, idLine)
+		}
+		return r.DeleteLine(idLine)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.Get(id)
+}
+
+func (s *heldOrdersService) Quote(id string, req HeldOrderCheckout) (*CheckoutResult, error) {
+	checkout, err := s.checkout(id, req)
+	if err != nil {
+		return nil, err
+	}
+	return s.txs.Quote(*checkout)
+}
+
+func (s *heldOrdersService) Checkout(id string, req HeldOrderCheckout) (*CheckoutResult, error) {
+	checkout, err := s.checkout(id, req)
+	if err != nil {
+		return nil, err
+	}
+	return s.txs.Checkout(*checkout)
+}
+
+func (s *heldOrdersService) Cancel(id, by string) (*entity.HeldOrders, error) {
+	o, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, ErrHeldOrderNotFound
+	}
+	if o.Status != entity.HeldOrderStatusOpen {
+		return nil, fmt.Errorf("%w: the order is %s", ErrHeldOrderClosed, o.Status)
+	}
+	ok, err := s.repo.Close(id, entity.HeldOrderStatusCancelled, "", by, time.Now(), time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: the order was closed meanwhile", ErrHeldOrderClosed)
+	}
+	return s.Get(id)
+}
+
+func (s *heldOrdersService) SplitEqually(id string, parts int) ([]entity.HeldOrders, error) {
+	var ids []string
+	err := s.change(id, func(r repo.HeldOrdersRepo, o *entity.HeldOrders) error {
+		if err := editable(o); err != nil {
+			return err
+		}
+		if parts == 0 {
+			parts = o.Guests
+		}
+		if parts < 2 || parts > 50 {
+			return fmt.Errorf("%w: a bill is split into 2 to 50 shares", ErrInvalidHeldOrder)
+		}
+		lines, err := r.ListLines(id)
+		if err != nil {
+			return err
+		}
+		if len(lines) == 0 {
+			return fmt.Errorf("%w: the order has no lines", ErrInvalidHeldOrder)
+		}
+		for _, l := range lines {
+			if l.GiftCard != "" || s.isGiftCard(l.IdItem) {
+				return fmt.Errorf("%w: a bill with gift cards cannot be split equally", ErrInvalidHeldOrder)
+			}
+		}
+		o.SplitOf, o.SplitPart, o.SplitParts = id, 1, parts
+		if err := r.SetSplit(o); err != nil {
+			return err
+		}
+		ids = append(ids, id)
+		for part := 2; part <= parts; part++ {
+			share := &entity.HeldOrders{
+				IdHeldOrder: helper.Uuid(),
+				Terminal:    o.Terminal,
+				Label:       o.Label,
+				Note:        o.Note,
+				IdUser:      o.IdUser,
+				Status:      entity.HeldOrderStatusOpen,
+				IdTable:     o.IdTable,
+				SplitOf:     id,
+				SplitPart:   part,
+				SplitParts:  parts,
+			}
+			if err := r.Create(share); err != nil {
+				return err
+			}
+			// The table's other tables stay with the first share.
+			rows := make([]entity.HeldOrderLines, 0, len(lines))
+			for _, l := range lines {
+				l.IdHeldOrderLine, l.IdHeldOrder = helper.Uuid(), share.IdHeldOrder
+				rows = append(rows, l)
+			}
+			if err := createLines(r, rows); err != nil {
+				return err
+			}
+			ids = append(ids, share.IdHeldOrder)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.getAll(ids)
+}
+
+func (s *heldOrdersService) SplitItems(id string, parts [][]SplitLine) ([]entity.HeldOrders, error) {
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("%w: no parts to split off", ErrInvalidHeldOrder)
+	}
+	ids := []string{id}
+	err := s.change(id, func(r repo.HeldOrdersRepo, o *entity.HeldOrders) error {
+		if err := editable(o); err != nil {
+			return err
+		}
+		lines, err := r.ListLines(id)
+		if err != nil {
+			return err
+		}
+		left := map[string]*entity.HeldOrderLines{}
+		units := 0
+		for i := range lines {
+			left[lines[i].IdHeldOrderLine] = &lines[i]
+			units += lines[i].Quantity
+		}
+		for _, part := range parts {
+			if len(part) == 0 {
+				return fmt.Errorf("%w: a part has no lines", ErrInvalidHeldOrder)
+			}
+			to := &entity.HeldOrders{
+				IdHeldOrder: helper.Uuid(),
+				Terminal:    o.Terminal,
+				Label:       o.Label,
+				IdUser:      o.IdUser,
+				Status:      entity.HeldOrderStatusOpen,
+				IdTable:     o.IdTable,
+			}
+			if err := r.Create(to); err != nil {
+				return err
+			}
+			for _, in := range part {
+				l := left[in.IdHeldOrderLine]
+				if l == nil {
+					return fmt.Errorf("%w: no line %s", ErrHeldOrderNotFound, in.IdHeldOrderLine)
+				}
+				qty := in.Quantity
+				if qty == 0 {
+					qty = l.Quantity
+				}
+				if qty < 0 || qty > l.Quantity {
+					return fmt.Errorf("%w: only %d of line %s are left to split off", ErrInvalidHeldOrder, l.Quantity, l.IdHeldOrderLine)
+				}
+				units -= qty
+				if qty == l.Quantity {
+					if err := r.MoveLine(l.IdHeldOrderLine, to.IdHeldOrder); err != nil {
+						return err
+					}
+					delete(left, l.IdHeldOrderLine)
+					continue
+				}
+				moved := *l
+				moved.IdHeldOrderLine, moved.IdHeldOrder, moved.Quantity = helper.Uuid(), to.IdHeldOrder, qty
+				if err := r.CreateLine(&moved); err != nil {
+					return err
+				}
+				l.Quantity -= qty
+				if err := r.UpdateLine(l); err != nil {
+					return err
+				}
+			}
+			ids = append(ids, to.IdHeldOrder)
+		}
+		if units <= 0 {
+			return fmt.Errorf("%w: the order has to keep at least one line", ErrInvalidHeldOrder)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.getAll(ids)
+}
+
+func (s *heldOrdersService) MoveTable(from, to string) ([]entity.HeldOrders, error) {
+	if from == to {
+		return nil, fmt.Errorf("%w: the orders are on that table already", ErrInvalidTable)
+	}
+	var ids []string
+	err := s.uow.Do(func(db *gorm.DB) error {
+		r := s.repo.WithTx(db)
+		t, err := lockTables(s.tables.WithTx(db), from, to)
+		if err != nil {
+			return err
+		}
+		target := t[1]
+		if !target.IsActive {
+			return fmt.Errorf("%w: %s takes no orders", ErrInvalidTable, target.Name)
+		}
+		if err := tableFree(r, target); err != nil {
+			return err
+		}
+		orders, err := r.ListOpenOnTable(from)
+		if err != nil {
+			return err
+		}
+		if len(orders) == 0 {
+			return fmt.Errorf("%w: %s", ErrTableFree, t[0].Name)
+		}
+		for i := range orders {
+			o := &orders[i]
+			if o.IdTable == from {
+				o.IdTable = to
+			}
+			for j, joined := range o.JoinedTables {
+				if joined == from {
+					o.JoinedTables[j] = to
+				}
+			}
+			if err := r.SetTables(o); err != nil {
+				return err
+			}
+			if err := r.Touch(o.IdHeldOrder); err != nil {
+				return err
+			}
+			ids = append(ids, o.IdHeldOrder)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.getAll(ids)
+}
+
+func (s *heldOrdersService) MergeTables(from, into, by string) (*entity.HeldOrders, error) {
+	if from == into {
+		return nil, fmt.Errorf("%w: a table cannot be merged into itself", ErrInvalidTable)
+	}
+	var id string
+	err := s.uow.Do(func(db *gorm.DB) error {
+		r := s.repo.WithTx(db)
+		t, err := lockTables(s.tables.WithTx(db), from, into)
+		if err != nil {
+			return err
+		}
+		targets, err := r.ListOpenOnTable(into)
+		if err != nil {
+			return err
+		}
+		if len(targets) == 0 {
+			return fmt.Errorf("%w: %s", ErrTableFree, t[1].Name)
+		}
+		sources, err := r.ListOpenOnTable(from)
+		if err != nil {
+			return err
+		}
+		if len(sources) == 0 {
+			return fmt.Errorf("%w: %s", ErrTableFree, t[0].Name)
+		}
+		target, err := r.GetForUpdate(targets[0].IdHeldOrder)
+		if err != nil {
+			return err
+		}
+		if err := editable(target); err != nil {
+			return err
+		}
+		now := time.Now()
+		for _, src := range sources {
+			if src.IdHeldOrder == target.IdHeldOrder {
+				return fmt.Errorf("%w: %s and %s are served together already", ErrInvalidTable, t[0].Name, t[1].Name)
+			}
+			if err := editable(&src); err != nil {
+				return err
+			}
+			lines, err := r.ListLines(src.IdHeldOrder)
+			if err != nil {
+				return err
+			}
+			for _, l := range lines {
+				if err := r.MoveLine(l.IdHeldOrderLine, target.IdHeldOrder); err != nil {
+					return err
+				}
+			}
+			ok, err := r.Merge(src.IdHeldOrder, target.IdHeldOrder, by, now)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return fmt.Errorf("%w: the order was closed meanwhile", ErrHeldOrderChanged)
+			}
+			target.JoinedTables = joinTables(target, append([]string{src.IdTable}, src.JoinedTables...)...)
+			target.Guests += src.Guests
+		}
+		if err := r.SetTables(target); err != nil {
+			return err
+		}
+		id = target.IdHeldOrder
+		return r.Touch(id)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.Get(id)
+}
+
+// getAll returns the orders ids with their lines.
+func (s *heldOrdersService) getAll(ids []string) ([]entity.HeldOrders, error) {
+	out := make([]entity.HeldOrders, 0, len(ids))
+	for _, id := range ids {
+		o, err := s.Get(id)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *o)
+	}
+	return out, nil
+}
+
+func (s *heldOrdersService) isGiftCard(idItem string) bool {
+	item, err := s.items.GetByID(idItem)
+	return err == nil && item.Kind == entity.ItemKindGiftCard
+}
+
+// editable refuses changes to the lines of a share of a split bill, which
+// has to keep the lines of the bill it is a share of.
+func editable(o *entity.HeldOrders) error {
+	if o.SplitParts > 0 {
+		return fmt.Errorf("%w: the order is share %d of %d of a split bill and cannot be changed", ErrInvalidHeldOrder, o.SplitPart, o.SplitParts)
+	}
+	return nil
+}
+
+// lockTables locks the tables ids in a fixed order, so that two moves
+// between the same tables cannot deadlock, and returns them in the order
+// asked for.
+func lockTables(r repo.TablesRepo, ids ...string) ([]*entity.DiningTables, error) {
+	sorted := append([]string{}, ids...)
+	sort.Strings(sorted)
+	locked := map[string]*entity.DiningTables{}
+	for _, id := range sorted {
+		t, err := r.GetForUpdate(id)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrTableNotFound, id)
+		}
+		locked[id] = t
+	}
+	out := make([]*entity.DiningTables, 0, len(ids))
+	for _, id := range ids {
+		out = append(out, locked[id])
+	}
+	return out, nil
+}
+
+// tableFree refuses a table some open order is on; the table must be
+// locked.
+func tableFree(r repo.HeldOrdersRepo, t *entity.DiningTables) error {
+	open, err := r.ListOpenOnTable(t.IdTable)
+	if err != nil {
+		return err
+	}
+	if len(open) > 0 {
+		return fmt.Errorf("%w: %s has an open order", ErrTableOccupied, t.Name)
+	}
+	return nil
+}
+
+// joinTables adds tables to the tables o is served on, once each.
+func joinTables(o *entity.HeldOrders, tables ...string) []string {
+	out := append([]string{}, o.JoinedTables...)
+	for _, t := range tables {
+		if t == "" || t == o.IdTable || slices.Contains(out, t) {
+			continue
+		}
+		out = append(out, t)
+	}
+	return out
+}
+
+// change runs fn on the open order id, locked, and marks the order changed.
+func (s *heldOrdersService) change(id string, fn func(r repo.HeldOrdersRepo, o *entity.HeldOrders) error) error {
+	return s.uow.Do(func(db *gorm.DB) error {
+		r := s.repo.WithTx(db)
+		o, err := r.GetForUpdate(id)
+		if err != nil {
+			return ErrHeldOrderNotFound
+		}
+		if o.Status != entity.HeldOrderStatusOpen {
+			return fmt.Errorf("%w: the order is %s", ErrHeldOrderClosed, o.Status)
+		}
+		if err := fn(r, o); err != nil {
+			return err
+		}
+		return r.Touch(id)
+	})
+}
+
+// line checks a line against the catalog and records what it costs now.
+// Discounts are checked at checkout, against the role of the cashier
+// ringing the order up.
+func (s *heldOrdersService) line(idHeldOrder string, in CheckoutLine) (*entity.HeldOrderLines, error) {
+	item, err := s.items.GetByID(in.IdItem)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidItem, in.IdItem)
+	}
+	if in.Quantity <= 0 {
+		in.Quantity = 1
+	}
+	if in.GiftCard != "" && item.Kind != entity.ItemKindGiftCard {
+		return nil, fmt.Errorf("%w: %s is not a gift card", ErrInvalidItem, item.ItemName)
+	}
+	// Only the options picked are kept; defaults are picked again at
+	// checkout from the item's groups as they are then.
+	_, delta, err := s.modifiers.Resolve(item.IdItem, in.Modifiers)
+	if err != nil {
+		return nil, err
+	}
+	l := &entity.HeldOrderLines{
+		IdHeldOrderLine: helper.Uuid(),
+		IdHeldOrder:     idHeldOrder,
+		IdItem:          item.IdItem,
+		ItemName:        item.ItemName,
+		Quantity:        in.Quantity,
+		Modifiers:       append([]string{}, in.Modifiers...),
+		BasePrice:       item.Price,
+		Price:           item.Price + delta,
+		GiftCard:        normalizeGiftCardCode(in.GiftCard),
+	}
+	if d := in.Discount; d != nil {
+		l.DiscountKind, l.DiscountValue, l.DiscountReason = d.Kind, d.Value, truncate(strings.TrimSpace(d.Reason), 255)
+	}
+	return l, nil
+}
+
+// checkout turns the open order id into the checkout request that rings it
+// up, with its lines at their kept prices when held orders lock prices.
+func (s *heldOrdersService) checkout(id string, req HeldOrderCheckout) (*CheckoutRequest, error) {
+	o, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if o.Status != entity.HeldOrderStatusOpen {
+		return nil, fmt.Errorf("%w: the order is %s", ErrHeldOrderClosed, o.Status)
+	}
+	if len(o.Lines) == 0 {
+		return nil, fmt.Errorf("%w: the order has no lines", ErrInvalidHeldOrder)
+	}
+	lock := s.cfg.HeldOrderPricing == HeldOrderPricingLock
+	lines := make([]CheckoutLine, 0, len(o.Lines))
+	for _, l := range o.Lines {
+		line := CheckoutLine{IdItem: l.IdItem, Quantity: l.Quantity, Modifiers: l.Modifiers, GiftCard: l.GiftCard}
+		if l.DiscountKind != "" {
+			line.Discount = &ManualDiscount{Kind: l.DiscountKind, Value: l.DiscountValue, Reason: l.DiscountReason}
+		}
+		if lock {
+			line.Locked = &LockedPrice{BasePrice: l.BasePrice, Price: l.Price}
+		}
+		lines = append(lines, line)
+	}
+	out := &CheckoutRequest{
+		IdUser:       req.IdUser,
+		Role:         req.Role,
+		BuyerContact: req.BuyerContact,
+		Customer:     req.Customer,
+		Lines:        lines,
+		Discount:     req.Discount,
+		Voucher:      req.Voucher,
+		Payments:     req.Payments,
+		HeldOrder:    o,
+	}
+	if o.SplitParts > 0 {
+		out.Share = &BillShare{Of: o.SplitOf, Part: o.SplitPart, Parts: o.SplitParts}
+	}
+	if out.Customer == nil && out.BuyerContact == "" {
+		out.BuyerContact = o.BuyerContact
+		if o.IdCustomer != "" {
+			out.Customer = &CustomerInput{IdCustomer: o.IdCustomer}
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,421 @@
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+)
+
+var (
+	ErrInvalidImage    = errors.New("unsupported or invalid image")
+	ErrImageTooLarge   = errors.New("image exceeds maximum file size")
+	ErrImageDimensions = errors.New("image exceeds maximum dimension")
+)
+
+// Image variant names accepted by GET /api/images/:id?size=...
+const (
+	VariantOriginal = "original"
+	VariantMedium   = "medium"
+	VariantThumb    = "thumb"
+)
+
+var variantMaxEdge = map[string]int{
+	VariantThumb:  160,
+	VariantMedium: 640,
+}
+
+// processedImage is the sanitized upload together with the decoded pixels
+// used to render the smaller variants. Decoded is nil for formats the
+// standard library cannot decode (webp), in which case no variants are made.
+type processedImage struct {
+	ContentType string
+	Ext         string
+	Data        []byte
+	Width       int
+	Height      int
+	Decoded     image.Image
+}
+
+type renderedVariant struct {
+	Name        string
+	ContentType string
+	Ext         string
+	Data        []byte
+	Width       int
+	Height      int
+}
+
+// processImage sniffs the real format from the magic bytes, enforces the
+// dimension limit and strips EXIF/XMP/comment metadata from the bytes that
+// will be stored as the original. JPEGs turned by their EXIF orientation are
+// re-encoded upright, since the tag goes with the rest of the EXIF, and GIFs
+// are re-encoded, which leaves out their comment and application
+// extensions.
+func processImage(data []byte, maxDimension int) (*processedImage, error) {
+	ct := http.DetectContentType(data)
+	switch ct {
+	case "image/jpeg", "image/png", "image/gif":
+		cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			return nil, ErrInvalidImage
+		}
+		if cfg.Width <= 0 || cfg.Height <= 0 {
+			return nil, ErrInvalidImage
+		}
+		if maxDimension > 0 && (cfg.Width > maxDimension || cfg.Height > maxDimension) {
+			return nil, ErrImageDimensions
+		}
+		img, _, err := image.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, ErrInvalidImage
+		}
+		out := &processedImage{ContentType: ct, Width: cfg.Width, Height: cfg.Height, Decoded: img}
+		switch ct {
+		case "image/jpeg":
+			out.Ext = ".jpg"
+			if o := jpegOrientation(data); o > 1 {
+				out.Decoded = orient(img, o)
+				out.Width, out.Height = out.Decoded.Bounds().Dx(), out.Decoded.Bounds().Dy()
+				var buf bytes.Buffer
+				if err := jpeg.Encode(&buf, out.Decoded, &jpeg.Options{Quality: 92}); err != nil {
+					return nil, err
+				}
+				out.Data = buf.Bytes()
+			} else {
+				out.Data = stripJPEGMetadata(data)
+			}
+		case "image/png":
+			out.Ext = ".png"
+			out.Data = stripPNGMetadata(data)
+		default:
+			out.Ext = ".gif"
+			anim, err := gif.DecodeAll(bytes.NewReader(data))
+			if err != nil {
+				return nil, ErrInvalidImage
+			}
+			var buf bytes.Buffer
+			if err := gif.EncodeAll(&buf, anim); err != nil {
+				return nil, err
+			}
+			out.Data = buf.Bytes()
+		}
+		return out, nil
+	case "image/webp":
+		w, h, err := webpDimensions(data)
+		if err != nil {
+			return nil, ErrInvalidImage
+		}
+		if maxDimension > 0 && (w > maxDimension || h > maxDimension) {
+			return nil, ErrImageDimensions
+		}
+		stripped, err := stripWebPMetadata(data)
+		if err != nil {
+			return nil, ErrInvalidImage
+		}
+		return &processedImage{ContentType: ct, Ext: ".webp", Data: stripped, Width: w, Height: h}, nil
+	}
+	return nil, ErrInvalidImage
+}
+
+// renderVariants produces the thumb/medium renditions. Images already smaller
+// than a variant's edge are skipped; the handler falls back to the next size.
+func renderVariants(p *processedImage) ([]renderedVariant, error) {
+	if p.Decoded == nil {
+		return nil, nil
+	}
+	out := make([]renderedVariant, 0, len(variantMaxEdge))
+	for _, name := range []string{VariantMedium, VariantThumb} {
+		edge := variantMaxEdge[name]
+		if p.Width <= edge && p.Height <= edge {
+			continue
+		}
+		resized := resizeToFit(p.Decoded, edge)
+		var buf bytes.Buffer
+		v := renderedVariant{Name: name, Width: resized.Bounds().Dx(), Height: resized.Bounds().Dy()}
+		if p.ContentType == "image/jpeg" || resized.Opaque() {
+			if err := jpeg.Encode(&buf, resized, &jpeg.Options{Quality: 82}); err != nil {
+				return nil, err
+			}
+			v.ContentType, v.Ext = "image/jpeg", ".jpg"
+		} else {
+			if err := png.Encode(&buf, resized); err != nil {
+				return nil, err
+			}
+			v.ContentType, v.Ext = "image/png", ".png"
+		}
+		v.Data = buf.Bytes()
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+// resizeToFit scales src down so its longest edge equals maxEdge, averaging
+// every source pixel that falls into a destination pixel (box filter).
+func resizeToFit(src image.Image, maxEdge int) *image.NRGBA {
+	b := src.Bounds()
+	sw, sh := b.Dx(), b.Dy()
+	dw, dh := sw, sh
+	if sw >= sh && sw > maxEdge {
+		dw, dh = maxEdge, sh*maxEdge/sw
+	} else if sh > sw && sh > maxEdge {
+		dw, dh = sw*maxEdge/sh, maxEdge
+	}
+	if dw < 1 {
+		dw = 1
+	}
+	if dh < 1 {
+		dh = 1
+	}
+
+	full := image.NewNRGBA(image.Rect(0, 0, sw, sh))
+	draw.Draw(full, full.Bounds(), src, b.Min, draw.Src)
+
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		y0, y1 := y*sh/dh, (y+1)*sh/dh
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < dw; x++ {
+			x0, x1 := x*sw/dw, (x+1)*sw/dw
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				off := full.PixOffset(x0, sy)
+				for sx := x0; sx < x1; sx++ {
+					pa := uint64(full.Pix[off+3])
+					r += uint64(full.Pix[off]) * pa
+					g += uint64(full.Pix[off+1]) * pa
+					bl += uint64(full.Pix[off+2]) * pa
+					a += pa
+					n++
+					off += 4
+				}
+			}
+			c := color.NRGBA{}
+			if a > 0 {
+				c = color.NRGBA{R: uint8(r / a), G: uint8(g / a), B: uint8(bl / a), A: uint8(a / n)}
+			}
+			dst.SetNRGBA(x, y, c)
+		}
+	}
+	return dst
+}
+
+// jpegOrientation reads the EXIF orientation tag (1 to 8) of a JPEG, or
+// returns 0 when it has none.
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 0
+	}
+	i := 2
+	for i+4 <= len(data) && data[i] == 0xFF && data[i+1] != 0xDA {
+		segLen := int(binary.BigEndian.Uint16(data[i+2 : i+4]))
+		end := i + 2 + segLen
+		if segLen < 2 || end > len(data) {
+			return 0
+		}
+		if seg := data[i+4 : end]; data[i+1] == 0xE1 && len(seg) >= 14 && string(seg[:6]) == "Exif\x00\x00" {
+			return exifOrientation(seg[6:])
+		}
+		i = end
+	}
+	return 0
+}
+
+// exifOrientation finds the orientation tag in IFD0 of the TIFF structure
+// an EXIF segment holds.
+func exifOrientation(tiff []byte) int {
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0
+	}
+	ifd := int(order.Uint32(tiff[4:8]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 0
+	}
+	n := int(order.Uint16(tiff[ifd : ifd+2]))
+	for e := ifd + 2; n > 0 && e+12 <= len(tiff); e, n = e+12, n-1 {
+		if order.Uint16(tiff[e:e+2]) == 0x0112 {
+			if o := int(order.Uint16(tiff[e+8 : e+10])); o >= 1 && o <= 8 {
+				return o
+			}
+			return 0
+		}
+	}
+	return 0
+}
+
+// orient turns src upright according to the EXIF orientation o; 5 to 8
+// swap width and height.
+func orient(src image.Image, o int) *image.NRGBA {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	full := image.NewNRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(full, full.Bounds(), src, b.Min, draw.Src)
+	dw, dh := w, h
+	if o >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch o {
+			case 2:
+				dx, dy = w-1-x, y
+			case 3:
+				dx, dy = w-1-x, h-1-y
+			case 4:
+				dx, dy = x, h-1-y
+			case 5:
+				dx, dy = y, x
+			case 6:
+				dx, dy = h-1-y, x
+			case 7:
+				dx, dy = h-1-y, w-1-x
+			case 8:
+				dx, dy = y, w-1-x
+			default:
+				dx, dy = x, y
+			}
+			dst.SetNRGBA(dx, dy, full.NRGBAAt(x, y))
+		}
+	}
+	return dst
+}
+
+// stripJPEGMetadata drops APP1 (EXIF/XMP), APP13 (IPTC) and COM segments
+// while keeping everything needed to decode the image unchanged.
+func stripJPEGMetadata(data []byte) []byte {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return data
+	}
+	out := make([]byte, 0, len(data))
+	out = append(out, 0xFF, 0xD8)
+	i := 2
+	for i+4 <= len(data) {
+		if data[i] != 0xFF {
+			return data
+		}
+		marker := data[i+1]
+		if marker == 0xDA {
+			// start of scan: the rest is entropy coded data
+			return append(out, data[i:]...)
+		}
+		segLen := int(binary.BigEndian.Uint16(data[i+2 : i+4]))
+		end := i + 2 + segLen
+		if segLen < 2 || end > len(data) {
+			return data
+		}
+		if marker != 0xE1 && marker != 0xED && marker != 0xFE {
+			out = append(out, data[i:end]...)
+		}
+		i = end
+	}
+	return data
+}
+
+// stripPNGMetadata removes textual and EXIF chunks. Dropped chunks carry
+// their own CRC so the remaining stream stays valid.
+func stripPNGMetadata(data []byte) []byte {
+	const sigLen = 8
+	if len(data) < sigLen {
+		return data
+	}
+	out := make([]byte, 0, len(data))
+	out = append(out, data[:sigLen]...)
+	i := sigLen
+	for i+12 <= len(data) {
+		n := int(binary.BigEndian.Uint32(data[i : i+4]))
+		end := i + 12 + n
+		if n < 0 || end > len(data) {
+			return data
+		}
+		switch string(data[i+4 : i+8]) {
+		case "eXIf", "tEXt", "iTXt", "zTXt", "tIME":
+		default:
+			out = append(out, data[i:end]...)
+		}
+		i = end
+	}
+	return out
+}
+
+// stripWebPMetadata removes EXIF and XMP chunks from a RIFF/WEBP container
+// and clears the matching VP8X feature flags.
+func stripWebPMetadata(data []byte) ([]byte, error) {
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil, ErrInvalidImage
+	}
+	out := make([]byte, 12, len(data))
+	copy(out, data[:12])
+	i := 12
+	for i+8 <= len(data) {
+		fourcc := string(data[i : i+4])
+		n := int(binary.LittleEndian.Uint32(data[i+4 : i+8]))
+		end := i + 8 + n + n%2
+		if end > len(data) {
+			return nil, ErrInvalidImage
+		}
+		switch fourcc {
+		case "EXIF", "XMP ":
+		case "VP8X":
+			start := len(out)
+			out = append(out, data[i:end]...)
+			if n >= 1 {
+				out[start+8] &^= 0x04 | 0x08
+			}
+		default:
+			out = append(out, data[i:end]...)
+		}
+		i = end
+	}
+	binary.LittleEndian.PutUint32(out[4:8], uint32(len(out)-8))
+	return out, nil
+}
+
+// webpDimensions reads the canvas size from the first VP8X, VP8 or VP8L
+// chunk without decoding the bitstream.
+func webpDimensions(data []byte) (int, int, error) {
+	if len(data) < 30 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return 0, 0, ErrInvalidImage
+	}
+	chunk := data[12:]
+	switch string(chunk[0:4]) {
+	case "VP8X":
+		w := int(chunk[12]) | int(chunk[13])<<8 | int(chunk[14])<<16
+		h := int(chunk[15]) | int(chunk[16])<<8 | int(chunk[17])<<16
+		return w + 1, h + 1, nil
+	case "VP8 ":
+		if chunk[11] != 0x9d || chunk[12] != 0x01 || chunk[13] != 0x2a {
+			return 0, 0, ErrInvalidImage
+		}
+		w := int(binary.LittleEndian.Uint16(chunk[14:16]) & 0x3fff)
+		h := int(binary.LittleEndian.Uint16(chunk[16:18]) & 0x3fff)
+		return w, h, nil
+	case "VP8L":
+		if chunk[8] != 0x2f {
+			return 0, 0, ErrInvalidImage
+		}
+		bits := binary.LittleEndian.Uint32(chunk[9:13])
+		w := int(bits&0x3fff) + 1
+		h := int((bits>>14)&0x3fff) + 1
+		return w, h, nil
+	}
+	return 0, 0, ErrInvalidImage
+}
@@ -3,6 +3,7 @@ package services
 import (
 	"encoding/base64"
 	"errors"
+	"faizalmaulana/lsp/conf"
 	"faizalmaulana/lsp/helper"
 	"faizalmaulana/lsp/models/entity"
 	"faizalmaulana/lsp/models/repo"
@@ -17,30 +18,87 @@ type ImagesService interface {
 	UploadBlob(fileName, contentType string, data []byte) (string, string, error) 
 	UploadBase64(fileName, contentType, b64 string) (string, string, error)      
 	GetBlob(id string) (*entity.Images, error)
+	GetBlobVariant(id, variant string) (*entity.Images, error)
 	GetBase64(id string) (string, string, string, error) 
 	Delete(id string) error
 }
 
-type imagesService struct{ repo repo.ImagesRepo }
+type imagesService struct {
+	repo repo.ImagesRepo
+	cfg  *conf.Config
+}
 
-func NewImagesService(r repo.ImagesRepo) ImagesService { return &imagesService{repo: r} }
+func NewImagesService(r repo.ImagesRepo, cfg *conf.Config) ImagesService {
+	return &imagesService{repo: r, cfg: cfg}
+}
 
 func (s *imagesService) UploadBlob(fileName, contentType string, data []byte) (string, string, error) {
 	if len(data) == 0 {
 		return "", "", errors.New("empty data")
 	}
-	storedName := generateFileName(fileName, contentType)
+	if s.cfg != nil && s.cfg.ImageMaxBytes > 0 && int64(len(data)) > s.cfg.ImageMaxBytes {
+		return "", "", ErrImageTooLarge
+	}
+	maxDim := 0
+	if s.cfg != nil {
+		maxDim = s.cfg.ImageMaxDimension
+	}
+	processed, err := processImage(data, maxDim)
+	if err != nil {
+		return "", "", err
+	}
+	rendered, err := renderVariants(processed)
+	if err != nil {
+		return "", "", err
+	}
+
+	storedName := generateFileName(fileName, processed.ContentType)
 	fullPath, err := ensureStoragePath(storedName)
 	if err != nil {
 		return "", "", err
 	}
-	if err := os.WriteFile(fullPath, data, fs.FileMode(0644)); err != nil {
+	if err := os.WriteFile(fullPath, processed.Data, fs.FileMode(0644)); err != nil {
 		return "", "", err
 	}
-	img := &entity.Images{IdImage: helper.Uuid(), FileName: storedName, ContentType: contentType, Size: int64(len(data))}
+	img := &entity.Images{
+		IdImage:     helper.Uuid(),
+		FileName:    storedName,
+		ContentType: processed.ContentType,
+		Size:        int64(len(processed.Data)),
+		Width:       processed.Width,
+		Height:      processed.Height,
+		Checksum:    helper.Checksum(processed.Data),
+	}
 	if err := s.repo.Create(img); err != nil {
 		return "", "", err
 	}
+
+	base := strings.TrimSuffix(storedName, filepath.Ext(storedName))
+	variants := make([]entity.ImageVariants, 0, len(rendered))
+	for _, v := range rendered {
+		name := base + "_" + v.Name + v.Ext
+		path, err := ensureStoragePath(name)
+		if err != nil {
+			return "", "", err
+		}
+		if err := os.WriteFile(path, v.Data, fs.FileMode(0644)); err != nil {
+			return "", "", err
+		}
+		variants = append(variants, entity.ImageVariants{
+			IdImageVariant: helper.Uuid(),
+			IdImage:        img.IdImage,
+			Variant:        v.Name,
+			FileName:       name,
+			ContentType:    v.ContentType,
+			Size:           int64(len(v.Data)),
+			Width:          v.Width,
+			Height:         v.Height,
+			Checksum:       helper.Checksum(v.Data),
+		})
+	}
+	if err := s.repo.CreateVariants(variants); err != nil {
+		return "", "", err
+	}
 	return img.IdImage, storedName, nil
 }
 
@@ -64,16 +122,62 @@ func (s *imagesService) GetBlob(id string) (*entity.Images, error) {
 
 	fmt.Println(meta.FileName)
 
-	fullPath := filepath.Join("storages", "images", id)
+	fullPath := filepath.Join("storages", "images", filepath.Base(meta.FileName))
 	data, err := os.ReadFile(fullPath)
 	if err != nil {
 		return nil, err
 	}
 	meta.Size = int64(len(data))
 	meta.Data = data
+	if meta.Checksum == "" {
+		meta.Checksum = helper.Checksum(data)
+	}
 	return meta, nil
 }
 
+// GetBlobVariant returns the requested rendition with its bytes loaded. When
+// the variant was never generated (small or webp uploads) it falls back to
+// the next larger size and finally the original.
+func (s *imagesService) GetBlobVariant(id, variant string) (*entity.Images, error) {
+	var chain []string
+	switch variant {
+	case "", VariantOriginal:
+		return s.GetBlob(id)
+	case VariantThumb:
+		chain = []string{VariantThumb, VariantMedium}
+	case VariantMedium:
+		chain = []string{VariantMedium}
+	default:
+		return nil, errors.New("unknown image size")
+	}
+	meta, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range chain {
+		v, err := s.repo.GetVariant(id, name)
+		if err != nil {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join("storages", "images", filepath.Base(v.FileName)))
+		if err != nil {
+			continue
+		}
+		meta.FileName = v.FileName
+		meta.ContentType = v.ContentType
+		meta.Width = v.Width
+		meta.Height = v.Height
+		meta.Checksum = v.Checksum
+		meta.Size = int64(len(data))
+		meta.Data = data
+		if meta.Checksum == "" {
+			meta.Checksum = helper.Checksum(data)
+		}
+		return meta, nil
+	}
+	return s.GetBlob(id)
+}
+
 func (s *imagesService) GetBase64(id string) (string, string, string, error) {
 	img, err := s.GetBlob(id)
 	if err != nil {
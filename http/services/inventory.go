@@ -0,0 +1,254 @@
+package services
+
+import (
+	"errors"
+	"sort"
+
+	"faizalmaulana/lsp/helper"
+	"faizalmaulana/lsp/models/entity"
+	"faizalmaulana/lsp/models/repo"
+)
+
+var ErrStockNotFound = errors.New("item not found")
+
+// StockCard is an item's stock: what is on hand, what it last cost, and
+// the latest movements of its stock and cost.
+type StockCard struct {
+	IdItem   string               `json:"id_item"`
+	ItemName string               `json:"item_name"`
+	OnHand   int                  `json:"on_hand"`
+	LastCost entity.Money         `json:"last_cost"`
+	Ledger   []entity.StockLedger `json:"ledger"`
+	Costs    []entity.ItemCosts   `json:"costs"`
+}
+
+type InventoryService interface {
+	// Levels pages through the items that have stock, by name.
+	Levels(limit, page int) ([]repo.StockLevel, error)
+	// Get shows an item's stock with its ten latest movements and costs.
+	Get(idItem string) (*StockCard, error)
+	// Ledger pages through an item's stock movements, newest first.
+	Ledger(idItem string, limit, page int) ([]entity.StockLedger, error)
+	// Costs pages through what an item cost on each delivery, newest first.
+	Costs(idItem string, limit, page int) ([]entity.ItemCosts, error)
+}
+
+type inventoryService struct {
+	repo  repo.InventoryRepo
+	items repo.ItemsRepo
+}
+
+func NewInventoryService(r repo.InventoryRepo, items repo.ItemsRepo) InventoryService {
+	return &inventoryService{repo: r, items: items}
+}
+
+func (s *inventoryService) Levels(limit, page int) ([]repo.StockLevel, error) {
+	limit, offset := itemsPage(limit, page)
+	return s.repo.ListLevels(limit, offset)
+}
+
+func (s *inventoryService) Get(idItem string) (*StockCard, error) {
+	item, err := s.items.GetByID(idItem)
+	if err != nil {
+		return nil, ErrStockNotFound
+	}
+	card := &StockCard{IdItem: item.IdItem, ItemName: item.ItemName}
+	if st, err := s.repo.GetStock(idItem); err == nil {
+		card.OnHand, card.LastCost = st.OnHand, st.LastCost
+	}
+	if card.Ledger, err = s.repo.ListEntries(idItem, 10, 0); err != nil {
+		return nil, err
+	}
+	if card.Costs, err = s.repo.ListCosts(idItem, 10, 0); err != nil {
+		return nil, err
+	}
+	return card, nil
+}
+
+func (s *inventoryService) Ledger(idItem string, limit, page int) ([]entity.StockLedger, error) {
+	if _, err := s.items.GetByID(idItem); err != nil {
+		return nil, ErrStockNotFound
+	}
+	limit, offset := itemsPage(limit, page)
+	return s.repo.ListEntries(idItem, limit, offset)
+}
+
+func (s *inventoryService) Costs(idItem string, limit, page int) ([]entity.ItemCosts, error) {
+	if _, err := s.items.GetByID(idItem); err != nil {
+		return nil, ErrStockNotFound
+	}
+	limit, offset := itemsPage(limit, page)
+	return s.repo.ListCosts(idItem, limit, offset)
+}
+
+// receiveStock adds the goods of a receipt line to the item's stock, with
+// the ledger entry and cost history entry that go with it. r must be bound
+// to the receipt's database transaction.
+func receiveStock(r repo.InventoryRepo, g *entity.GoodsReceipts, l entity.GoodsReceiptLines, idSupplier string) error {
+	st, err := r.GetStockForUpdate(l.IdItem)
+	if err != nil {
+		return err
+	}
+	st.OnHand += l.Quantity
+	st.LastCost = l.UnitCost
+	if err := r.SetStock(st); err != nil {
+		return err
+	}
+	if err := r.CreateEntry(&entity.StockLedger{
+		IdStockEntry:    helper.Uuid(),
+		IdItem:          l.IdItem,
+		Kind:            entity.StockEntryReceipt,
+		Quantity:        l.Quantity,
+		Balance:         st.OnHand,
+		UnitCost:        l.UnitCost,
+		IdPurchaseOrder: g.IdPurchaseOrder,
+		IdGoodsReceipt:  g.IdGoodsReceipt,
+		IdUser:          g.IdUser,
+		Note:            g.Note,
+	}); err != nil {
+		return err
+	}
+	return r.CreateCost(&entity.ItemCosts{
+		IdItemCost:     helper.Uuid(),
+		IdItem:         l.IdItem,
+		UnitCost:       l.UnitCost,
+		IdSupplier:     idSupplier,
+		IdGoodsReceipt: g.IdGoodsReceipt,
+	})
+}
+
+// stockMove changes the stock of IdItem by Quantity units for the sale line
+// IdPivot.
+type stockMove struct {
+	IdItem   string
+	IdPivot  string
+	Quantity int
+}
+
+// moveStock posts moves to the stock of the items that are stocked, each
+// with a ledger entry like e; items without stock are left alone. Moves are
+// posted in item order, so sales touching the same items lock them in the
+// same order. r must be bound to the caller's database transaction.
+func moveStock(r repo.InventoryRepo, moves []stockMove, e entity.StockLedger) error {
+	sort.Slice(moves, func(i, j int) bool {
+		if moves[i].IdItem != moves[j].IdItem {
+			return moves[i].IdItem < moves[j].IdItem
+		}
+		return moves[i].IdPivot < moves[j].IdPivot
+	})
+	for _, m := range moves {
+		if m.Quantity == 0 {
+			continue
+		}
+		st, err := r.LockStock(m.IdItem)
+		if err != nil {
+			return err
+		}
+		if st == nil {
+			continue
+		}
+		st.OnHand += m.Quantity
+		if err := r.SetStock(st); err != nil {
+			return err
+		}
+		entry := e
+		entry.IdStockEntry = helper.Uuid()
+		entry.IdItem, entry.IdPivot = m.IdItem, m.IdPivot
+		entry.Quantity, entry.Balance, entry.UnitCost = m.Quantity, st.OnHand, st.LastCost
+		if err := r.CreateEntry(&entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// takeStock takes the goods of a sale's lines off stock, a bundle by its
+// components. A share of a split bill carries all of the bill's lines and
+// takes its part of their units, split between the shares as shareOf splits
+// amounts. r must be bound to the checkout's database transaction.
+func takeStock(r repo.InventoryRepo, t *entity.Transactions, lines []entity.PivotItemsToTransaction) error {
+	units := func(n, i int) int { return int(shareOf(entity.Money(n), t.SplitPart, t.SplitParts, i)) }
+	var moves []stockMove
+	for i, l := range lines {
+		if len(l.Components) == 0 {
+			moves = append(moves, stockMove{IdItem: l.IdItem, IdPivot: l.IdPivot, Quantity: -units(l.Quantity, i)})
+			continue
+		}
+		for _, c := range l.Components {
+			moves = append(moves, stockMove{IdItem: c.IdItem, IdPivot: l.IdPivot, Quantity: -units(c.Quantity, i)})
+		}
+	}
+	return moveStock(r, moves, entity.StockLedger{Kind: entity.StockEntrySale, IdTransaction: t.IdTransaction, IdUser: t.IdUser})
+}
+
+// returnSaleStock puts back what a voided or cancelled sale still has off
+// stock. r must be bound to the caller's database transaction.
+func returnSaleStock(r repo.InventoryRepo, idTransaction, kind, idUser, note string) error {
+	entries, err := r.ListEntriesByTransaction(idTransaction)
+	if err != nil {
+		return err
+	}
+	out := map[stockMove]int{}
+	for _, e := range entries {
+		out[stockMove{IdItem: e.IdItem, IdPivot: e.IdPivot}] -= e.Quantity
+	}
+	var moves []stockMove
+	for m, n := range out {
+		if n > 0 {
+			m.Quantity = n
+			moves = append(moves, m)
+		}
+	}
+	return moveStock(r, moves, entity.StockLedger{Kind: kind, IdTransaction: idTransaction, IdUser: idUser, Note: note})
+}
+
+// restockRefund puts the units of a refund's restocked lines back on stock,
+// in proportion to what their sale took off it: of a line of Q units that
+// took n, restocking q puts back n*q/Q, counting the units restocked by the
+// prior refunds of the sale so the rounding never loses or adds a unit. r
+// must be bound to the refund's database transaction.
+func restockRefund(r repo.InventoryRepo, refund *entity.Refunds, sold map[string]entity.PivotItemsToTransaction, prior []entity.RefundLines) error {
+	entries, err := r.ListEntriesByTransaction(refund.IdTransaction)
+	if err != nil {
+		return err
+	}
+	taken := map[string]map[string]int{}
+	for _, e := range entries {
+		if e.Kind != entity.StockEntrySale {
+			continue
+		}
+		if taken[e.IdPivot] == nil {
+			taken[e.IdPivot] = map[string]int{}
+		}
+		taken[e.IdPivot][e.IdItem] -= e.Quantity
+	}
+	before := map[string]int{}
+	for _, l := range prior {
+		if l.Restock {
+			before[l.IdPivot] += l.Quantity
+		}
+	}
+	restocked := map[string]int{}
+	var pivots []string
+	for _, l := range refund.Lines {
+		if !l.Restock {
+			continue
+		}
+		if _, ok := restocked[l.IdPivot]; !ok {
+			pivots = append(pivots, l.IdPivot)
+		}
+		restocked[l.IdPivot] += l.Quantity
+	}
+	var moves []stockMove
+	for _, idPivot := range pivots {
+		p, ok := sold[idPivot]
+		if !ok || p.Quantity <= 0 {
+			continue
+		}
+		after := before[idPivot] + restocked[idPivot]
+		for idItem, n := range taken[idPivot] {
+			moves = append(moves, stockMove{IdItem: idItem, IdPivot: idPivot, Quantity: n*after/p.Quantity - n*before[idPivot]/p.Quantity})
+		}
+	}
+	return moveStock(r, moves, entity.StockLedger{Kind: entity.StockEntryReturn, IdTransaction: refund.IdTransaction, IdRefund: refund.IdRefund, IdUser: refund.IdUser, Note: refund.Reason})
+}
@@ -4,14 +4,18 @@ import (
 	"errors"
 	"faizalmaulana/lsp/models/entity"
 	"faizalmaulana/lsp/models/repo"
+	"time"
 )
 
 type ItemsService interface {
 	Create(i *entity.Items) (*entity.Items, error)
 	GetByID(id string) (*entity.Items, error)
 	GetAll(limit, page int) ([]entity.Items, error)
+	GetAllByType(limit, page int, itemType string) ([]entity.Items, error)
+	GetAllByCategories(limit, page int, categoryIDs []string) ([]entity.Items, error)
 	Update(id string, i *entity.Items) (*entity.Items, error)
 	Delete(id string) error
+	CatalogVersion() (time.Time, int64, error)
 }
 
 type itemsService struct{ repo repo.ItemsRepo }
@@ -52,6 +56,45 @@ func (s *itemsService) GetAll(limit, page int) ([]entity.Items, error) {
 	return out, nil
 }
 
+func (s *itemsService) GetAllByType(limit, page int, itemType string) ([]entity.Items, error) {
+	limit, offset := itemsPage(limit, page)
+	list, err := s.repo.ListPageByType(limit, offset, itemType)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]entity.Items, 0, len(list))
+	for _, it := range list {
+		out = append(out, *it)
+	}
+	return out, nil
+}
+
+func (s *itemsService) GetAllByCategories(limit, page int, categoryIDs []string) ([]entity.Items, error) {
+	limit, offset := itemsPage(limit, page)
+	list, err := s.repo.ListPageByCategories(limit, offset, categoryIDs)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]entity.Items, 0, len(list))
+	for _, it := range list {
+		out = append(out, *it)
+	}
+	return out, nil
+}
+
+func itemsPage(limit, page int) (int, int) {
+	if limit <= 0 {
+		limit = 10
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	if page <= 0 {
+		page = 1
+	}
+	return limit, (page - 1) * limit
+}
+
 func (s *itemsService) Update(id string, i *entity.Items) (*entity.Items, error) {
 	if id == "" || i == nil {
 		return nil, errors.New("invalid input")
@@ -69,3 +112,5 @@ func (s *itemsService) Delete(id string) error {
 	}
 	return s.repo.Delete(id)
 }
+
+func (s *itemsService) CatalogVersion() (time.Time, int64, error) { return s.repo.CatalogVersion() }
@@ -0,0 +1,485 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"faizalmaulana/lsp/helper"
+	"faizalmaulana/lsp/models/entity"
+	"faizalmaulana/lsp/models/repo"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidStation  = errors.New("invalid kitchen station")
+	ErrStationNotFound = errors.New("kitchen station not found")
+	ErrTicketNotFound  = errors.New("kitchen ticket not found")
+	ErrTicketStatus    = errors.New("kitchen ticket cannot change")
+)
+
+// Events of the kitchen feed. Ticket events carry the ticket with its
+// items, order.ready the KitchenOrder whose tickets are all ready.
+const (
+	KitchenEventTicketNew       = "ticket.new"
+	KitchenEventTicketReady     = "ticket.ready"
+	KitchenEventTicketRecalled  = "ticket.recalled"
+	KitchenEventTicketCancelled = "ticket.cancelled"
+	KitchenEventOrderReady      = "order.ready"
+)
+
+// KitchenOrder is how far the kitchen is with a sale: Ready once every
+// ticket that was not cancelled is.
+type KitchenOrder struct {
+	IdTransaction string                  `json:"id_transaction"`
+	Label         string                  `json:"label"`
+	QueueNumber   int                     `json:"queue_number,omitempty"`
+	Ready         bool                    `json:"ready"`
+	Tickets       []entity.KitchenTickets `json:"tickets"`
+}
+
+type KitchenService interface {
+	CreateStation(st *entity.KitchenStations) (*entity.KitchenStations, error)
+	GetStation(id string) (*entity.KitchenStations, error)
+	GetStations() ([]entity.KitchenStations, error)
+	UpdateStation(id string, st *entity.KitchenStations) (*entity.KitchenStations, error)
+	DeleteStation(id string) error
+
+	// Send routes the lines of the completed sale idTransaction to the
+	// stations preparing them and opens a ticket on each. Sales that are
+	// not completed, or were sent already, are left alone, so it is safe
+	// to call again.
+	Send(idTransaction string) ([]entity.KitchenTickets, error)
+	// Cancel withdraws the tickets of a sale that was voided.
+	Cancel(idTransaction string) error
+	// GetTickets filters by station, status and sale when they are set,
+	// oldest first, with their items.
+	GetTickets(idStation, status, idTransaction string, limit, page int) ([]entity.KitchenTickets, error)
+	GetTicket(id string) (*entity.KitchenTickets, error)
+	Order(idTransaction string) (*KitchenOrder, error)
+	// Bump marks a new ticket ready; Recall brings a ready one back.
+	Bump(id, by string) (*entity.KitchenTickets, error)
+	Recall(id string) (*entity.KitchenTickets, error)
+	// Subscribe follows the events of the station idStation, or of the
+	// whole kitchen when it is empty, until the returned function is
+	// called.
+	Subscribe(idStation string) (<-chan Event, func())
+}
+
+type kitchenService struct {
+	repo       repo.KitchenRepo
+	uow        repo.UnitOfWork
+	txs        repo.TransactionsRepo
+	pivots     repo.PivotItemsToTransactionsRepo
+	lineMods   repo.PivotLineModifiersRepo
+	lineComps  repo.PivotLineComponentsRepo
+	items      repo.ItemsRepo
+	categories repo.CategoriesRepo
+	held       repo.HeldOrdersRepo
+	feed       *hub
+}
+
+func NewKitchenService(r repo.KitchenRepo, uow repo.UnitOfWork, txs repo.TransactionsRepo, pivots repo.PivotItemsToTransactionsRepo, lineMods repo.PivotLineModifiersRepo, lineComps repo.PivotLineComponentsRepo, items repo.ItemsRepo, categories repo.CategoriesRepo, held repo.HeldOrdersRepo) KitchenService {
+	return &kitchenService{repo: r, uow: uow, txs: txs, pivots: pivots, lineMods: lineMods, lineComps: lineComps, items: items, categories: categories, held: held, feed: newHub()}
+}
+
+func (s *kitchenService) CreateStation(st *entity.KitchenStations) (*entity.KitchenStations, error) {
+	if st == nil {
+		return nil, errors.New("invalid input")
+	}
+	if st.IdStation == "" {
+		st.IdStation = helper.Uuid()
+	}
+	if err := s.checkStation(st); err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreateStation(st); err != nil {
+		return nil, err
+	}
+	return st, nil
+}
+
+func (s *kitchenService) GetStation(id string) (*entity.KitchenStations, error) {
+	st, err := s.repo.GetStation(id)
+	if err != nil {
+		return nil, ErrStationNotFound
+	}
+	return st, nil
+}
+
+func (s *kitchenService) GetStations() ([]entity.KitchenStations, error) {
+	return s.repo.ListStations()
+}
+
+func (s *kitchenService) UpdateStation(id string, st *entity.KitchenStations) (*entity.KitchenStations, error) {
+	if st == nil {
+		return nil, errors.New("invalid input")
+	}
+	if _, err := s.repo.GetStation(id); err != nil {
+		return nil, ErrStationNotFound
+	}
+	st.IdStation = id
+	if err := s.checkStation(st); err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateStation(st); err != nil {
+		return nil, err
+	}
+	return s.repo.GetStation(id)
+}
+
+func (s *kitchenService) DeleteStation(id string) error {
+	if _, err := s.repo.GetStation(id); err != nil {
+		return ErrStationNotFound
+	}
+	return s.repo.DeleteStation(id)
+}
+
+func (s *kitchenService) checkStation(st *entity.KitchenStations) error {
+	st.Name = truncate(strings.TrimSpace(st.Name), 100)
+	if st.Name == "" {
+		return fmt.Errorf("%w: name required", ErrInvalidStation)
+	}
+	categories := make([]string, 0, len(st.Categories))
+	for _, id := range st.Categories {
+		id = strings.TrimSpace(id)
+		if id == "" || slices.Contains(categories, id) {
+			continue
+		}
+		if _, err := s.categories.GetByID(id); err != nil {
+			return fmt.Errorf("%w: category %s not found", ErrInvalidStation, id)
+		}
+		categories = append(categories, id)
+	}
+	st.Categories = categories
+	return nil
+}
+
+func (s *kitchenService) Send(idTransaction string) ([]entity.KitchenTickets, error) {
+	stations, err := s.repo.ListStations()
+	if err != nil || len(stations) == 0 {
+		return nil, err
+	}
+	parent, err := s.categoryParents()
+	if err != nil {
+		return nil, err
+	}
+	var tickets []entity.KitchenTickets
+	err = s.uow.Do(func(db *gorm.DB) error {
+		// Holding the sale keeps a second send from opening the tickets
+		// twice.
+		t, err := s.txs.WithTx(db).GetByIDForUpdate(idTransaction)
+		if err != nil {
+			return ErrTransactionNotFound
+		}
+		// The shares of a split bill carry the same lines; the kitchen
+		// makes them once.
+		if t.Status != entity.TransactionStatusCompleted || t.SplitPart > 1 {
+			return nil
+		}
+		r := s.repo.WithTx(db)
+		sent, err := r.ListByTransaction(idTransaction)
+		if err != nil || len(sent) > 0 {
+			return err
+		}
+		if tickets, err = s.tickets(t, stations, parent); err != nil {
+			return err
+		}
+		for i := range tickets {
+			if err := r.CreateTicket(&tickets[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i := range tickets {
+		s.feed.Publish(Event{Type: KitchenEventTicketNew, Data: tickets[i]})
+	}
+	return tickets, nil
+}
+
+// sendToKitchen opens the kitchen tickets of a sale that was just paid. The
+// sale stands either way: a failure is logged, and the tickets can be sent
+// again.
+func sendToKitchen(k KitchenService, idTransaction string) {
+	if _, err := k.Send(idTransaction); err != nil {
+		log.Printf("kitchen tickets for %s: %v", idTransaction, err)
+	}
+}
+
+// tickets splits the lines of the sale t into a ticket per station. A
+// bundle is sent as its components; gift cards and items no station
+// prepares are left out.
+func (s *kitchenService) tickets(t *entity.Transactions, stations []entity.KitchenStations, parent map[string]string) ([]entity.KitchenTickets, error) {
+	lines, err := s.pivots.ListByTransaction(t.IdTransaction)
+	if err != nil {
+		return nil, err
+	}
+	mods, err := s.lineMods.ListByTransaction(t.IdTransaction)
+	if err != nil {
+		return nil, err
+	}
+	comps, err := s.lineComps.ListByTransaction(t.IdTransaction)
+	if err != nil {
+		return nil, err
+	}
+	options := map[string][]string{}
+	for _, m := range mods {
+		options[m.IdPivot] = append(options[m.IdPivot], m.OptionName)
+	}
+	parts := map[string][]entity.PivotLineComponents{}
+	for _, c := range comps {
+		parts[c.IdPivot] = append(parts[c.IdPivot], c)
+	}
+	label := ""
+	if o, err := s.held.GetByTransaction(t.IdTransaction); err == nil {
+		label = o.Label
+	}
+
+	catalog := map[string]*entity.Items{}
+	item := func(id string) *entity.Items {
+		if _, ok := catalog[id]; !ok {
+			it, err := s.items.GetByID(id)
+			if err != nil {
+				it = nil
+			}
+			catalog[id] = it
+		}
+		return catalog[id]
+	}
+	byStation := map[string]*entity.KitchenTickets{}
+	add := func(idCategory string, row entity.KitchenTicketItems) {
+		for _, id := range kitchenRoute(stations, parent, idCategory) {
+			tk := byStation[id]
+			if tk == nil {
+				tk = &entity.KitchenTickets{
+					IdTicket:      helper.Uuid(),
+					IdTransaction: t.IdTransaction,
+					IdStation:     id,
+					Label:         label,
+					QueueNumber:   t.QueueNumber,
+					Status:        entity.KitchenTicketNew,
+				}
+				byStation[id] = tk
+			}
+			row.IdTicketItem, row.IdTicket, row.Position = helper.Uuid(), tk.IdTicket, len(tk.Items)
+			tk.Items = append(tk.Items, row)
+		}
+	}
+	for _, l := range lines {
+		it := item(l.IdItem)
+		if it == nil || it.Kind == entity.ItemKindGiftCard {
+			continue
+		}
+		picked := truncate(strings.Join(options[l.IdPivot], ", "), 500)
+		if len(parts[l.IdPivot]) == 0 {
+			add(it.IdCategory, entity.KitchenTicketItems{IdPivot: l.IdPivot, IdItem: l.IdItem, ItemName: it.ItemName, Quantity: l.Quantity, Modifiers: picked})
+			continue
+		}
+		for _, c := range parts[l.IdPivot] {
+			category := ""
+			if ci := item(c.IdItem); ci != nil {
+				category = ci.IdCategory
+			}
+			add(category, entity.KitchenTicketItems{IdPivot: l.IdPivot, IdItem: c.IdItem, ItemName: c.ItemName, Quantity: c.Quantity, Modifiers: picked, Bundle: it.ItemName})
+		}
+	}
+	out := make([]entity.KitchenTickets, 0, len(byStation))
+	for _, st := range stations {
+		if tk := byStation[st.IdStation]; tk != nil {
+			out = append(out, *tk)
+		}
+	}
+	return out, nil
+}
+
+func (s *kitchenService) categoryParents() (map[string]string, error) {
+	list, err := s.categories.List(false)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]string, len(list))
+	for _, c := range list {
+		out[c.IdCategory] = c.IdParent
+	}
+	return out, nil
+}
+
+// kitchenRoute returns the stations preparing the items of the category
+// idCategory: the ones claiming it or, failing that, its nearest parent
+// some station claims, and the default stations when none does.
+func kitchenRoute(stations []entity.KitchenStations, parent map[string]string, idCategory string) []string {
+	seen := map[string]bool{}
+	for c := idCategory; c != "" && !seen[c]; c = parent[c] {
+		seen[c] = true
+		var out []string
+		for _, st := range stations {
+			if slices.Contains(st.Categories, c) {
+				out = append(out, st.IdStation)
+			}
+		}
+		if len(out) > 0 {
+			return out
+		}
+	}
+	var out []string
+	for _, st := range stations {
+		if st.IsDefault {
+			out = append(out, st.IdStation)
+		}
+	}
+	return out
+}
+
+func (s *kitchenService) Cancel(idTransaction string) error {
+	tickets, err := s.repo.ListByTransaction(idTransaction)
+	if err != nil {
+		return err
+	}
+	if _, err := s.repo.CancelByTransaction(idTransaction); err != nil {
+		return err
+	}
+	for _, t := range tickets {
+		if t.Status == entity.KitchenTicketCancelled {
+			continue
+		}
+		t.Status = entity.KitchenTicketCancelled
+		s.feed.Publish(Event{Type: KitchenEventTicketCancelled, Data: t})
+	}
+	return nil
+}
+
+func (s *kitchenService) GetTickets(idStation, status, idTransaction string, limit, page int) ([]entity.KitchenTickets, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	if limit > 200 {
+		limit = 200
+	}
+	if page <= 0 {
+		page = 1
+	}
+	var statuses []string
+	if status != "" {
+		statuses = strings.Split(status, ",")
+	}
+	list, err := s.repo.ListTickets(strings.TrimSpace(idStation), statuses, strings.TrimSpace(idTransaction), limit, (page-1)*limit)
+	if err != nil {
+		return nil, err
+	}
+	return s.withItems(list)
+}
+
+func (s *kitchenService) withItems(list []entity.KitchenTickets) ([]entity.KitchenTickets, error) {
+	ids := make([]string, 0, len(list))
+	for _, t := range list {
+		ids = append(ids, t.IdTicket)
+	}
+	items, err := s.repo.ListItems(ids)
+	if err != nil {
+		return nil, err
+	}
+	byTicket := map[string][]entity.KitchenTicketItems{}
+	for _, it := range items {
+		byTicket[it.IdTicket] = append(byTicket[it.IdTicket], it)
+	}
+	for i := range list {
+		list[i].Items = byTicket[list[i].IdTicket]
+	}
+	return list, nil
+}
+
+func (s *kitchenService) GetTicket(id string) (*entity.KitchenTickets, error) {
+	t, err := s.repo.GetTicket(id)
+	if err != nil {
+		return nil, ErrTicketNotFound
+	}
+	list, err := s.withItems([]entity.KitchenTickets{*t})
+	if err != nil {
+		return nil, err
+	}
+	return &list[0], nil
+}
+
+func (s *kitchenService) Order(idTransaction string) (*KitchenOrder, error) {
+	list, err := s.repo.ListByTransaction(idTransaction)
+	if err != nil {
+		return nil, err
+	}
+	if list, err = s.withItems(list); err != nil {
+		return nil, err
+	}
+	out := &KitchenOrder{IdTransaction: idTransaction, Tickets: list}
+	live := 0
+	ready := 0
+	for _, t := range list {
+		out.Label, out.QueueNumber = t.Label, t.QueueNumber
+		if t.Status == entity.KitchenTicketCancelled {
+			continue
+		}
+		live++
+		if t.Status == entity.KitchenTicketReady {
+			ready++
+		}
+	}
+	out.Ready = live > 0 && ready == live
+	return out, nil
+}
+
+func (s *kitchenService) Bump(id, by string) (*entity.KitchenTickets, error) {
+	now := time.Now()
+	t, err := s.move(id, entity.KitchenTicketNew, entity.KitchenTicketReady, map[string]interface{}{"bumped_by": by, "bumped_at": now})
+	if err != nil {
+		return nil, err
+	}
+	s.feed.Publish(Event{Type: KitchenEventTicketReady, Data: *t})
+	if order, err := s.Order(t.IdTransaction); err == nil && order.Ready {
+		s.feed.Publish(Event{Type: KitchenEventOrderReady, Data: *order})
+	}
+	return t, nil
+}
+
+func (s *kitchenService) Recall(id string) (*entity.KitchenTickets, error) {
+	t, err := s.move(id, entity.KitchenTicketReady, entity.KitchenTicketNew, map[string]interface{}{"bumped_by": "", "bumped_at": nil, "recalls": gorm.Expr("recalls + 1")})
+	if err != nil {
+		return nil, err
+	}
+	s.feed.Publish(Event{Type: KitchenEventTicketRecalled, Data: *t})
+	return t, nil
+}
+
+// move changes the ticket id from status from to status to.
+func (s *kitchenService) move(id, from, to string, fields map[string]interface{}) (*entity.KitchenTickets, error) {
+	t, err := s.repo.GetTicket(id)
+	if err != nil {
+		return nil, ErrTicketNotFound
+	}
+	ok, err := s.repo.SetStatus(id, []string{from}, to, fields)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		if t, err = s.repo.GetTicket(id); err != nil {
+			return nil, ErrTicketNotFound
+		}
+		return nil, fmt.Errorf("%w: the ticket is %s", ErrTicketStatus, t.Status)
+	}
+	return s.GetTicket(id)
+}
+
+func (s *kitchenService) Subscribe(idStation string) (<-chan Event, func()) {
+	if idStation == "" {
+		return s.feed.Subscribe(nil)
+	}
+	return s.feed.Subscribe(func(e Event) bool {
+		t, ok := e.Data.(entity.KitchenTickets)
+		return ok && t.IdStation == idStation
+	})
+}
@@ -0,0 +1,656 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"faizalmaulana/lsp/conf"
+	"faizalmaulana/lsp/helper"
+	"faizalmaulana/lsp/models/entity"
+	"faizalmaulana/lsp/models/repo"
+)
+
+var (
+	ErrInvalidLoyalty     = errors.New("invalid loyalty setting")
+	ErrLoyaltyNotFound    = errors.New("loyalty setting not found")
+	ErrInsufficientPoints = errors.New("insufficient points")
+)
+
+// expiringWindow is how far ahead a balance lookup warns about points
+// about to expire.
+const expiringWindow = 30 * 24 * time.Hour
+
+// LoyaltyPolicy is the store-wide part of the loyalty program: what a point
+// is worth when paid with, after how many days earned points expire (0
+// keeps them) and over how many days tiers are qualified for.
+type LoyaltyPolicy struct {
+	PointValue     entity.Money
+	ExpiryDays     int
+	TierWindowDays int
+}
+
+func loyaltyPolicy(cfg *conf.Config) LoyaltyPolicy {
+	return LoyaltyPolicy{PointValue: cfg.LoyaltyPointValue, ExpiryDays: cfg.LoyaltyExpiryDays, TierWindowDays: cfg.LoyaltyTierWindowDays}
+}
+
+// expiry is when points credited at at expire, nil when they do not.
+func (p LoyaltyPolicy) expiry(at time.Time) *time.Time {
+	if p.ExpiryDays <= 0 {
+		return nil
+	}
+	t := at.AddDate(0, 0, p.ExpiryDays)
+	return &t
+}
+
+func (p LoyaltyPolicy) tierSince(at time.Time) time.Time {
+	return at.AddDate(0, 0, -p.TierWindowDays)
+}
+
+// LoyaltyBalance is a customer's points as of now. Spend is what counts
+// towards the tiers; SpendToNextTier is what is missing for NextTier.
+// ExpiringPoints expire within 30 days, the first of them at ExpiringAt.
+type LoyaltyBalance struct {
+	Customer        *entity.Customers
+	Points          int
+	Value           entity.Money
+	Tier            *entity.LoyaltyTiers
+	NextTier        *entity.LoyaltyTiers
+	Spend           entity.Money
+	SpendToNextTier entity.Money
+	ExpiringPoints  int
+	ExpiringAt      *time.Time
+}
+
+// SalePoints is what a sale did to the customer's points.
+type SalePoints struct {
+	Earned   int    `json:"earned"`
+	Redeemed int    `json:"redeemed"`
+	Balance  int    `json:"balance"`
+	Tier     string `json:"tier"`
+}
+
+type LoyaltyService interface {
+	CreateRule(r *entity.LoyaltyRules) (*entity.LoyaltyRules, error)
+	GetRule(id string) (*entity.LoyaltyRules, error)
+	GetRules() ([]entity.LoyaltyRules, error)
+	UpdateRule(id string, r *entity.LoyaltyRules) (*entity.LoyaltyRules, error)
+	DeleteRule(id string) error
+
+	CreateTier(t *entity.LoyaltyTiers) (*entity.LoyaltyTiers, error)
+	GetTier(id string) (*entity.LoyaltyTiers, error)
+	GetTiers() ([]entity.LoyaltyTiers, error)
+	UpdateTier(id string, t *entity.LoyaltyTiers) (*entity.LoyaltyTiers, error)
+	DeleteTier(id string) error
+
+	Balance(idCustomer string) (*LoyaltyBalance, error)
+	// BalanceByPhone looks the customer up by a phone number in any format.
+	BalanceByPhone(phone string) (*LoyaltyBalance, error)
+	// Ledger pages through the customer's point movements, newest first.
+	Ledger(idCustomer string, limit, page int) ([]entity.LoyaltyLedger, error)
+}
+
+type loyaltyService struct {
+	repo      repo.LoyaltyRepo
+	customers repo.CustomersRepo
+	items     repo.ItemsRepo
+	cfg       *conf.Config
+}
+
+func NewLoyaltyService(r repo.LoyaltyRepo, customers repo.CustomersRepo, items repo.ItemsRepo, cfg *conf.Config) LoyaltyService {
+	return &loyaltyService{repo: r, customers: customers, items: items, cfg: cfg}
+}
+
+func (s *loyaltyService) CreateRule(r *entity.LoyaltyRules) (*entity.LoyaltyRules, error) {
+	if r == nil {
+		return nil, errors.New("rule nil")
+	}
+	if err := s.validateRule(r); err != nil {
+		return nil, err
+	}
+	r.IdLoyaltyRule = helper.Uuid()
+	r.IsDeleted = false
+	if err := s.repo.CreateRule(r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (s *loyaltyService) GetRule(id string) (*entity.LoyaltyRules, error) {
+	r, err := s.repo.GetRule(id)
+	if err != nil {
+		return nil, fmt.Errorf("%w: rule %s", ErrLoyaltyNotFound, id)
+	}
+	return r, nil
+}
+
+func (s *loyaltyService) GetRules() ([]entity.LoyaltyRules, error) {
+	return s.repo.ListRules(false)
+}
+
+func (s *loyaltyService) UpdateRule(id string, r *entity.LoyaltyRules) (*entity.LoyaltyRules, error) {
+	if r == nil {
+		return nil, errors.New("rule nil")
+	}
+	if _, err := s.GetRule(id); err != nil {
+		return nil, err
+	}
+	if err := s.validateRule(r); err != nil {
+		return nil, err
+	}
+	r.IdLoyaltyRule = id
+	if err := s.repo.UpdateRule(r); err != nil {
+		return nil, err
+	}
+	return s.repo.GetRule(id)
+}
+
+func (s *loyaltyService) DeleteRule(id string) error {
+	if _, err := s.GetRule(id); err != nil {
+		return err
+	}
+	return s.repo.DeleteRule(id)
+}
+
+func (s *loyaltyService) validateRule(r *entity.LoyaltyRules) error {
+	r.Name = strings.TrimSpace(r.Name)
+	if r.Name == "" || len(r.Name) > 100 {
+		return fmt.Errorf("%w: name must be 1-100 characters", ErrInvalidLoyalty)
+	}
+	if r.Points <= 0 {
+		return fmt.Errorf("%w: points must be positive", ErrInvalidLoyalty)
+	}
+	switch r.Kind {
+	case entity.LoyaltyRuleSpend:
+		if r.Step <= 0 {
+			return fmt.Errorf("%w: a spend rule needs a positive step", ErrInvalidLoyalty)
+		}
+		r.IdItem = ""
+	case entity.LoyaltyRuleItem:
+		if _, err := s.items.GetByID(r.IdItem); err != nil {
+			return fmt.Errorf("%w: unknown item %s", ErrInvalidLoyalty, r.IdItem)
+		}
+		r.Step = 0
+	default:
+		return fmt.Errorf("%w: kind must be %s or %s", ErrInvalidLoyalty, entity.LoyaltyRuleSpend, entity.LoyaltyRuleItem)
+	}
+	return nil
+}
+
+func (s *loyaltyService) CreateTier(t *entity.LoyaltyTiers) (*entity.LoyaltyTiers, error) {
+	if t == nil {
+		return nil, errors.New("tier nil")
+	}
+	if err := validateTier(t); err != nil {
+		return nil, err
+	}
+	t.IdLoyaltyTier = helper.Uuid()
+	t.IsDeleted = false
+	if err := s.repo.CreateTier(t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (s *loyaltyService) GetTier(id string) (*entity.LoyaltyTiers, error) {
+	t, err := s.repo.GetTier(id)
+	if err != nil {
+		return nil, fmt.Errorf("%w: tier %s", ErrLoyaltyNotFound, id)
+	}
+	return t, nil
+}
+
+func (s *loyaltyService) GetTiers() ([]entity.LoyaltyTiers, error) {
+	return s.repo.ListTiers()
+}
+
+func (s *loyaltyService) UpdateTier(id string, t *entity.LoyaltyTiers) (*entity.LoyaltyTiers, error) {
+	if t == nil {
+		return nil, errors.New("tier nil")
+	}
+	if _, err := s.GetTier(id); err != nil {
+		return nil, err
+	}
+	if err := validateTier(t); err != nil {
+		return nil, err
+	}
+	t.IdLoyaltyTier = id
+	if err := s.repo.UpdateTier(t); err != nil {
+		return nil, err
+	}
+	return s.repo.GetTier(id)
+}
+
+func (s *loyaltyService) DeleteTier(id string) error {
+	if _, err := s.GetTier(id); err != nil {
+		return err
+	}
+	return s.repo.DeleteTier(id)
+}
+
+func validateTier(t *entity.LoyaltyTiers) error {
+	t.Name = strings.TrimSpace(t.Name)
+	if t.Name == "" || len(t.Name) > 50 {
+		return fmt.Errorf("%w: name must be 1-50 characters", ErrInvalidLoyalty)
+	}
+	if t.MinSpend < 0 {
+		return fmt.Errorf("%w: min_spend cannot be negative", ErrInvalidLoyalty)
+	}
+	if t.Multiplier <= 0 || t.Multiplier > 100 {
+		return fmt.Errorf("%w: multiplier must be above 0 and at most 100", ErrInvalidLoyalty)
+	}
+	return nil
+}
+
+func (s *loyaltyService) Balance(idCustomer string) (*LoyaltyBalance, error) {
+	c, err := s.customers.GetByID(idCustomer)
+	if err != nil {
+		return nil, ErrCustomerNotFound
+	}
+	return s.balance(c, time.Now())
+}
+
+func (s *loyaltyService) BalanceByPhone(phone string) (*LoyaltyBalance, error) {
+	normalized, ok := helper.NormalizePhone(phone, s.cfg.PhoneCountryCode)
+	if !ok {
+		return nil, fmt.Errorf("%w: phone %q is not a valid phone number", ErrInvalidCustomer, phone)
+	}
+	c, err := s.customers.GetByPhone(normalized)
+	if err != nil {
+		return nil, ErrCustomerNotFound
+	}
+	return s.balance(c, time.Now())
+}
+
+// balance reads the lots without locking them; points past their expiry are
+// left out here and written off by the next sale, refund or void.
+func (s *loyaltyService) balance(c *entity.Customers, at time.Time) (*LoyaltyBalance, error) {
+	lots, err := s.repo.OpenLots(c.IdCustomer)
+	if err != nil {
+		return nil, err
+	}
+	out := &LoyaltyBalance{Customer: c}
+	for _, l := range lots {
+		if l.Remaining > 0 && lotExpired(l, at) {
+			continue
+		}
+		out.Points += l.Remaining
+		if l.Remaining > 0 && l.ExpiresAt != nil && l.ExpiresAt.Sub(at) <= expiringWindow {
+			out.ExpiringPoints += l.Remaining
+			if out.ExpiringAt == nil {
+				out.ExpiringAt = l.ExpiresAt
+			}
+		}
+	}
+	policy := loyaltyPolicy(s.cfg)
+	out.Value = policy.PointValue.Mul(max(out.Points, 0))
+
+	tiers, err := s.repo.ListTiers()
+	if err != nil {
+		return nil, err
+	}
+	spend, err := s.repo.QualifyingSpend(c.IdCustomer, policy.tierSince(at))
+	if err != nil {
+		return nil, err
+	}
+	out.Spend = spend
+	out.Tier = tierFor(tiers, spend)
+	for i := range tiers {
+		if tiers[i].MinSpend > spend {
+			out.NextTier = &tiers[i]
+			out.SpendToNextTier = tiers[i].MinSpend - spend
+			break
+		}
+	}
+	return out, nil
+}
+
+func (s *loyaltyService) Ledger(idCustomer string, limit, page int) ([]entity.LoyaltyLedger, error) {
+	if _, err := s.customers.GetByID(idCustomer); err != nil {
+		return nil, ErrCustomerNotFound
+	}
+	if limit <= 0 {
+		limit = 10
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	if page <= 0 {
+		page = 1
+	}
+	return s.repo.ListByCustomer(idCustomer, limit, (page-1)*limit)
+}
+
+// tierFor is the highest of tiers, sorted by MinSpend, that spend reaches.
+func tierFor(tiers []entity.LoyaltyTiers, spend entity.Money) *entity.LoyaltyTiers {
+	var out *entity.LoyaltyTiers
+	for i := range tiers {
+		if tiers[i].MinSpend <= spend {
+			out = &tiers[i]
+		}
+	}
+	return out
+}
+
+// earnedPoints is what the rules award on a sale before the tier
+// multiplier. paid is what the customer paid other than with points.
+func earnedPoints(rules []entity.LoyaltyRules, lines []entity.PivotItemsToTransaction, paid entity.Money) int {
+	n := 0
+	for _, r := range rules {
+		switch r.Kind {
+		case entity.LoyaltyRuleSpend:
+			if r.Step > 0 && paid > 0 {
+				n += int(paid/r.Step) * r.Points
+			}
+		case entity.LoyaltyRuleItem:
+			for _, l := range lines {
+				if l.IdItem == r.IdItem {
+					n += l.Quantity * r.Points
+				}
+			}
+		}
+	}
+	return n
+}
+
+func lotExpired(l entity.LoyaltyLedger, at time.Time) bool {
+	return l.ExpiresAt != nil && !at.Before(*l.ExpiresAt)
+}
+
+// pointsBook is a customer's open lots, locked for the sale, refund or void
+// that changes them. Its repo is bound to that database transaction.
+type pointsBook struct {
+	r          repo.LoyaltyRepo
+	idCustomer string
+	at         time.Time
+	lots       []entity.LoyaltyLedger
+}
+
+// openPoints locks the customer's points and writes off the lots that have
+// expired.
+func openPoints(r repo.LoyaltyRepo, idCustomer string, at time.Time) (*pointsBook, error) {
+	if err := r.LockCustomer(idCustomer); err != nil {
+		return nil, err
+	}
+	lots, err := r.OpenLots(idCustomer)
+	if err != nil {
+		return nil, err
+	}
+	b := &pointsBook{r: r, idCustomer: idCustomer, at: at}
+	for _, l := range lots {
+		if l.Remaining > 0 && lotExpired(l, at) {
+			if _, err := b.entry(entity.LoyaltyEntryExpire, l.IdTransaction, "", -l.Remaining, 0, nil, ""); err != nil {
+				return nil, err
+			}
+			if err := r.SetRemaining(l.IdLoyaltyEntry, 0); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		b.lots = append(b.lots, l)
+	}
+	return b, nil
+}
+
+func (b *pointsBook) balance() int {
+	n := 0
+	for _, l := range b.lots {
+		n += l.Remaining
+	}
+	return n
+}
+
+func (b *pointsBook) entry(kind, idTransaction, idRefund string, points, remaining int, expires *time.Time, note string) (*entity.LoyaltyLedger, error) {
+	e := &entity.LoyaltyLedger{
+		IdLoyaltyEntry: helper.Uuid(),
+		IdCustomer:     b.idCustomer,
+		IdTransaction:  idTransaction,
+		IdRefund:       idRefund,
+		Kind:           kind,
+		Points:         points,
+		Remaining:      remaining,
+		ExpiresAt:      expires,
+		Note:           truncate(note, 255),
+	}
+	if err := b.r.CreateEntry(e); err != nil {
+		return nil, err
+	}
+	if remaining != 0 {
+		b.lots = append(b.lots, *e)
+	}
+	return e, nil
+}
+
+// take spends up to points from the lots, the lot prefer first, and
+// returns how many it found.
+func (b *pointsBook) take(points int, prefer string) (int, error) {
+	order := make([]int, 0, len(b.lots))
+	for i, l := range b.lots {
+		if l.IdLoyaltyEntry == prefer {
+			order = append([]int{i}, order...)
+		} else {
+			order = append(order, i)
+		}
+	}
+	taken := 0
+	for _, i := range order {
+		l := &b.lots[i]
+		if taken == points {
+			break
+		}
+		if l.Remaining <= 0 {
+			continue
+		}
+		n := min(l.Remaining, points-taken)
+		l.Remaining -= n
+		taken += n
+		if err := b.r.SetRemaining(l.IdLoyaltyEntry, l.Remaining); err != nil {
+			return 0, err
+		}
+	}
+	return taken, nil
+}
+
+// credit adds points as a new lot after paying off the debts.
+func (b *pointsBook) credit(kind, idTransaction, idRefund string, points int, expires *time.Time, note string) error {
+	left := points
+	for i := range b.lots {
+		l := &b.lots[i]
+		if left == 0 {
+			break
+		}
+		if l.Remaining >= 0 {
+			continue
+		}
+		n := min(-l.Remaining, left)
+		l.Remaining += n
+		left -= n
+		if err := b.r.SetRemaining(l.IdLoyaltyEntry, l.Remaining); err != nil {
+			return err
+		}
+	}
+	_, err := b.entry(kind, idTransaction, idRefund, points, left, expires, note)
+	return err
+}
+
+func (b *pointsBook) redeem(idTransaction string, points int) error {
+	if have := b.balance(); have < points {
+		return fmt.Errorf("%w: %d points wanted, %d available", ErrInsufficientPoints, points, max(have, 0))
+	}
+	if _, err := b.take(points, ""); err != nil {
+		return err
+	}
+	_, err := b.entry(entity.LoyaltyEntryRedeem, idTransaction, "", -points, 0, nil, "")
+	return err
+}
+
+// reverse takes back points, from the lot prefer first. What was already
+// spent is owed as a debt.
+func (b *pointsBook) reverse(idTransaction, idRefund string, points int, prefer, note string) error {
+	taken, err := b.take(points, prefer)
+	if err != nil {
+		return err
+	}
+	_, err = b.entry(entity.LoyaltyEntryReverse, idTransaction, idRefund, -points, taken-points, nil, note)
+	return err
+}
+
+// salePoints sums up the ledger entries of one sale.
+type salePoints struct {
+	idCustomer string
+	earnLot    string
+	earned     int
+	reversed   int
+	redeemed   int
+	restored   int
+}
+
+func sumSalePoints(entries []entity.LoyaltyLedger) salePoints {
+	var out salePoints
+	for _, e := range entries {
+		out.idCustomer = e.IdCustomer
+		switch e.Kind {
+		case entity.LoyaltyEntryEarn:
+			out.earned += e.Points
+			out.earnLot = e.IdLoyaltyEntry
+		case entity.LoyaltyEntryReverse:
+			out.reversed -= e.Points
+		case entity.LoyaltyEntryRedeem:
+			out.redeemed -= e.Points
+		case entity.LoyaltyEntryRestore:
+			out.restored += e.Points
+		}
+	}
+	return out
+}
+
+// pointsTendered is the value paid with points. Only a sale with a
+// customer can be paid with points, and only in whole points.
+func pointsTendered(payments []entity.Payments, hasCustomer bool, p LoyaltyPolicy) (entity.Money, error) {
+	var paid entity.Money
+	for _, pay := range payments {
+		if pay.Method == entity.PaymentMethodPoints {
+			paid += pay.Amount
+		}
+	}
+	if paid > 0 && !hasCustomer {
+		return 0, fmt.Errorf("%w: paying with points needs a customer", ErrInvalidPayment)
+	}
+	if paid%p.PointValue != 0 {
+		return 0, fmt.Errorf("%w: points pay in steps of %s", ErrInvalidPayment, formatMoney(p.PointValue))
+	}
+	return paid, nil
+}
+
+// postSalePoints redeems the points tendered on a sale and credits what the
+// sale earns at the customer's tier. r must be bound to the checkout's
+// database transaction. Only sales with a customer take part.
+func postSalePoints(r repo.LoyaltyRepo, p LoyaltyPolicy, tx *entity.Transactions, lines []entity.PivotItemsToTransaction, payments []entity.Payments, at time.Time) (*SalePoints, error) {
+	paid, err := pointsTendered(payments, tx.IdCustomer != "", p)
+	if err != nil || tx.IdCustomer == "" {
+		return nil, err
+	}
+	book, err := openPoints(r, tx.IdCustomer, at)
+	if err != nil {
+		return nil, err
+	}
+	out := &SalePoints{Redeemed: int(paid / p.PointValue)}
+	if out.Redeemed > 0 {
+		if err := book.redeem(tx.IdTransaction, out.Redeemed); err != nil {
+			return nil, err
+		}
+	}
+
+	rules, err := r.ListRules(true)
+	if err != nil {
+		return nil, err
+	}
+	tiers, err := r.ListTiers()
+	if err != nil {
+		return nil, err
+	}
+	spend, err := r.QualifyingSpend(tx.IdCustomer, p.tierSince(at))
+	if err != nil {
+		return nil, err
+	}
+	out.Earned = earnedPoints(rules, lines, tx.TotalPrice-paid)
+	note := ""
+	if tier := tierFor(tiers, spend); tier != nil {
+		out.Tier = tier.Name
+		out.Earned = int(math.Floor(float64(out.Earned) * tier.Multiplier))
+		note = fmt.Sprintf("%s x%g", tier.Name, tier.Multiplier)
+	}
+	if out.Earned > 0 {
+		if err := book.credit(entity.LoyaltyEntryEarn, tx.IdTransaction, "", out.Earned, p.expiry(at), note); err != nil {
+			return nil, err
+		}
+	}
+	out.Balance = book.balance()
+	return out, nil
+}
+
+// reverseSalePoints undoes a sale that was voided or never paid: the points
+// it earned are taken back and those paid with are given back. r must be
+// bound to the database transaction changing the sale's status.
+func reverseSalePoints(r repo.LoyaltyRepo, p LoyaltyPolicy, idTransaction, note string, at time.Time) error {
+	entries, err := r.ListByTransaction(idTransaction)
+	if err != nil || len(entries) == 0 {
+		return err
+	}
+	sum := sumSalePoints(entries)
+	book, err := openPoints(r, sum.idCustomer, at)
+	if err != nil {
+		return err
+	}
+	if n := sum.earned - sum.reversed; n > 0 {
+		if err := book.reverse(idTransaction, "", n, sum.earnLot, note); err != nil {
+			return err
+		}
+	}
+	if n := sum.redeemed - sum.restored; n > 0 {
+		return book.credit(entity.LoyaltyEntryRestore, idTransaction, "", n, p.expiry(at), note)
+	}
+	return nil
+}
+
+// refundSalePoints takes back the points earned on the part of a sale being
+// refunded, all that is left of them once the sale is refunded in full. A
+// refund paid in points credits them, rounded down to whole points. r must
+// be bound to the refund's database transaction.
+func refundSalePoints(r repo.LoyaltyRepo, p LoyaltyPolicy, t *entity.Transactions, refund *entity.Refunds, refundedBefore entity.Money, full bool, at time.Time) error {
+	inPoints := refund.Method == entity.PaymentMethodPoints
+	if t.IdCustomer == "" {
+		if inPoints {
+			return fmt.Errorf("%w: refunding in points needs a customer", ErrInvalidRefund)
+		}
+		return nil
+	}
+	entries, err := r.ListByTransaction(t.IdTransaction)
+	if err != nil {
+		return err
+	}
+	sum := sumSalePoints(entries)
+	target := sum.earned
+	if !full && t.TotalPrice > 0 {
+		target = int(int64(sum.earned) * int64(refundedBefore+refund.Amount) / int64(t.TotalPrice))
+	}
+	take := target - sum.reversed
+	if take <= 0 && !inPoints {
+		return nil
+	}
+	book, err := openPoints(r, t.IdCustomer, at)
+	if err != nil {
+		return err
+	}
+	if take > 0 {
+		if err := book.reverse(t.IdTransaction, refund.IdRefund, take, sum.earnLot, "refund"); err != nil {
+			return err
+		}
+	}
+	if n := int(refund.Amount / p.PointValue); inPoints && n > 0 {
+		return book.credit(entity.LoyaltyEntryRestore, t.IdTransaction, refund.IdRefund, n, p.expiry(at), "refund")
+	}
+	return nil
+}
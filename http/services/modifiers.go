@@ -0,0 +1,259 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"faizalmaulana/lsp/helper"
+	"faizalmaulana/lsp/models/entity"
+	"faizalmaulana/lsp/models/repo"
+)
+
+var (
+	ErrModifierGroupNotFound  = errors.New("modifier group not found")
+	ErrModifierOptionNotFound = errors.New("modifier option not found")
+	ErrInvalidModifierGroup   = errors.New("invalid modifier group")
+	ErrInvalidModifier        = errors.New("invalid modifier")
+	ErrModifierSelection      = errors.New("invalid modifier selection")
+)
+
+type ModifiersService interface {
+	CreateGroup(g *entity.ModifierGroups) (*entity.ModifierGroups, error)
+	GetGroup(id string) (*entity.ModifierGroups, error)
+	ListByItem(idItem string) ([]entity.ModifierGroups, error)
+	UpdateGroup(id string, g *entity.ModifierGroups) (*entity.ModifierGroups, error)
+	DeleteGroup(id string) error
+
+	CreateOption(o *entity.ModifierOptions) (*entity.ModifierOptions, error)
+	GetOption(id string) (*entity.ModifierOptions, error)
+	UpdateOption(id string, o *entity.ModifierOptions) (*entity.ModifierOptions, error)
+	DeleteOption(id string) error
+
+	// Resolve validates the options picked for one line of idItem against the
+	// item's groups and returns the snapshot rows plus the unit price delta.
+	// Groups with nothing picked fall back to their default options.
+	Resolve(idItem string, optionIDs []string) ([]entity.PivotLineModifiers, entity.Money, error)
+}
+
+type modifiersService struct{ repo repo.ModifiersRepo }
+
+func NewModifiersService(r repo.ModifiersRepo) ModifiersService {
+	return &modifiersService{repo: r}
+}
+
+func normalizeGroup(g *entity.ModifierGroups) error {
+	g.Name = strings.TrimSpace(g.Name)
+	if g.Name == "" {
+		return fmt.Errorf("%w: name required", ErrInvalidModifierGroup)
+	}
+	switch g.Kind {
+	case "":
+		g.Kind = entity.ModifierKindModifier
+	case entity.ModifierKindVariant, entity.ModifierKindModifier:
+	default:
+		return fmt.Errorf("%w: kind must be variant or modifier", ErrInvalidModifierGroup)
+	}
+	if g.Kind == entity.ModifierKindVariant {
+		g.IsRequired, g.MinSelect, g.MaxSelect = true, 1, 1
+		return nil
+	}
+	if g.MinSelect < 0 || g.MaxSelect < 0 {
+		return fmt.Errorf("%w: min_select and max_select must not be negative", ErrInvalidModifierGroup)
+	}
+	if g.IsRequired && g.MinSelect == 0 {
+		g.MinSelect = 1
+	}
+	if g.MinSelect > 0 {
+		g.IsRequired = true
+	}
+	if g.MaxSelect > 0 && g.MaxSelect < g.MinSelect {
+		return fmt.Errorf("%w: max_select is lower than min_select", ErrInvalidModifierGroup)
+	}
+	return nil
+}
+
+func (s *modifiersService) CreateGroup(g *entity.ModifierGroups) (*entity.ModifierGroups, error) {
+	if g == nil {
+		return nil, errors.New("group nil")
+	}
+	if err := normalizeGroup(g); err != nil {
+		return nil, err
+	}
+	for i := range g.Options {
+		g.Options[i].IdModifierGroup = g.IdModifierGroup
+		if strings.TrimSpace(g.Options[i].Name) == "" {
+			return nil, fmt.Errorf("%w: option name required", ErrInvalidModifierGroup)
+		}
+	}
+	if err := s.repo.CreateGroup(g); err != nil {
+		return nil, err
+	}
+	for i := range g.Options {
+		if err := s.repo.CreateOption(&g.Options[i]); err != nil {
+			return nil, err
+		}
+	}
+	return s.GetGroup(g.IdModifierGroup)
+}
+
+func (s *modifiersService) GetGroup(id string) (*entity.ModifierGroups, error) {
+	g, err := s.repo.GetGroup(id)
+	if err != nil {
+		return nil, ErrModifierGroupNotFound
+	}
+	return g, nil
+}
+
+func (s *modifiersService) ListByItem(idItem string) ([]entity.ModifierGroups, error) {
+	list, err := s.repo.ListGroupsByItem(idItem)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]entity.ModifierGroups, 0, len(list))
+	for _, g := range list {
+		out = append(out, *g)
+	}
+	return out, nil
+}
+
+func (s *modifiersService) UpdateGroup(id string, g *entity.ModifierGroups) (*entity.ModifierGroups, error) {
+	if id == "" || g == nil {
+		return nil, errors.New("invalid input")
+	}
+	g.IdModifierGroup = id
+	if err := normalizeGroup(g); err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateGroup(g); err != nil {
+		return nil, err
+	}
+	return s.GetGroup(id)
+}
+
+func (s *modifiersService) DeleteGroup(id string) error {
+	if id == "" {
+		return errors.New("id required")
+	}
+	return s.repo.DeleteGroup(id)
+}
+
+func (s *modifiersService) CreateOption(o *entity.ModifierOptions) (*entity.ModifierOptions, error) {
+	if o == nil {
+		return nil, errors.New("option nil")
+	}
+	o.Name = strings.TrimSpace(o.Name)
+	if o.Name == "" {
+		return nil, fmt.Errorf("%w: option name required", ErrInvalidModifierGroup)
+	}
+	if _, err := s.repo.GetGroup(o.IdModifierGroup); err != nil {
+		return nil, ErrModifierGroupNotFound
+	}
+	if err := s.repo.CreateOption(o); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+func (s *modifiersService) GetOption(id string) (*entity.ModifierOptions, error) {
+	o, err := s.repo.GetOption(id)
+	if err != nil {
+		return nil, ErrModifierOptionNotFound
+	}
+	return o, nil
+}
+
+func (s *modifiersService) UpdateOption(id string, o *entity.ModifierOptions) (*entity.ModifierOptions, error) {
+	if id == "" || o == nil {
+		return nil, errors.New("invalid input")
+	}
+	o.IdModifierOption = id
+	o.Name = strings.TrimSpace(o.Name)
+	if o.Name == "" {
+		return nil, fmt.Errorf("%w: option name required", ErrInvalidModifierGroup)
+	}
+	if err := s.repo.UpdateOption(o); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+func (s *modifiersService) DeleteOption(id string) error {
+	if id == "" {
+		return errors.New("id required")
+	}
+	return s.repo.DeleteOption(id)
+}
+
+func (s *modifiersService) Resolve(idItem string, optionIDs []string) ([]entity.PivotLineModifiers, entity.Money, error) {
+	groups, err := s.repo.ListGroupsByItem(idItem)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(groups) == 0 && len(optionIDs) == 0 {
+		return nil, 0, nil
+	}
+
+	type pick struct {
+		group  *entity.ModifierGroups
+		option entity.ModifierOptions
+	}
+	index := map[string]pick{}
+	for _, g := range groups {
+		for _, o := range g.Options {
+			index[o.IdModifierOption] = pick{group: g, option: o}
+		}
+	}
+
+	chosen := map[string]map[string]bool{}
+	for _, id := range optionIDs {
+		p, ok := index[id]
+		if !ok {
+			return nil, 0, fmt.Errorf("%w: %s", ErrInvalidModifier, id)
+		}
+		if !p.option.IsAvailable {
+			return nil, 0, fmt.Errorf("%w: %s is not available", ErrInvalidModifier, p.option.Name)
+		}
+		if chosen[p.group.IdModifierGroup] == nil {
+			chosen[p.group.IdModifierGroup] = map[string]bool{}
+		}
+		chosen[p.group.IdModifierGroup][id] = true
+	}
+
+	var (
+		out   []entity.PivotLineModifiers
+		delta entity.Money
+	)
+	for _, g := range groups {
+		picked := chosen[g.IdModifierGroup]
+		if len(picked) == 0 {
+			picked = map[string]bool{}
+			for _, o := range g.Options {
+				if o.IsDefault && o.IsAvailable {
+					picked[o.IdModifierOption] = true
+				}
+			}
+		}
+		if len(picked) < g.MinSelect {
+			return nil, 0, fmt.Errorf("%w: %s requires at least %d selection(s)", ErrModifierSelection, g.Name, g.MinSelect)
+		}
+		if g.MaxSelect > 0 && len(picked) > g.MaxSelect {
+			return nil, 0, fmt.Errorf("%w: %s allows at most %d selection(s)", ErrModifierSelection, g.Name, g.MaxSelect)
+		}
+		for _, o := range g.Options {
+			if !picked[o.IdModifierOption] {
+				continue
+			}
+			out = append(out, entity.PivotLineModifiers{
+				IdPivotLineModifier: helper.Uuid(),
+				IdModifierGroup:     g.IdModifierGroup,
+				IdModifierOption:    o.IdModifierOption,
+				GroupName:           g.Name,
+				OptionName:          o.Name,
+				PriceDelta:          o.PriceDelta,
+			})
+			delta += o.PriceDelta
+		}
+	}
+	return out, delta, nil
+}
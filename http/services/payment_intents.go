@@ -0,0 +1,369 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"faizalmaulana/lsp/conf"
+	"faizalmaulana/lsp/helper"
+	"faizalmaulana/lsp/models/entity"
+	"faizalmaulana/lsp/models/repo"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrPaymentIntentNotFound = errors.New("payment intent not found")
+	ErrGatewayUnavailable    = errors.New("payment gateway unavailable")
+	ErrWebhookMismatch       = errors.New("webhook does not match the payment intent")
+)
+
+// WebhookResult describes what a gateway callback did. Duplicate is set when
+// the event had already been processed; nothing was changed then. Completed
+// is set when the callback paid the last open intent of the sale.
+// RefundNeeded is set when the customer paid but no sale took the money.
+type WebhookResult struct {
+	Duplicate    bool
+	Completed    bool
+	RefundNeeded bool
+	Intent       *entity.PaymentIntents
+	Outcome      string
+}
+
+type PaymentIntentsService interface {
+	// GatewayName is the configured gateway, empty when there is none.
+	GatewayName() string
+	// Open requests an intent from the gateway for every gateway payment. The
+	// returned intents still have to be stored by the caller.
+	Open(payments []entity.Payments) ([]entity.PaymentIntents, error)
+	Get(id string) (*entity.PaymentIntents, error)
+	ListByTransaction(idTransaction string) ([]entity.PaymentIntents, error)
+	HandleWebhook(gateway string, header http.Header, body []byte) (*WebhookResult, error)
+	// Simulate makes a simulator gateway send a signed callback for intent id.
+	Simulate(id, status string) (*WebhookResult, error)
+	ExpireStale(now time.Time) (int, error)
+	// Callbacks lists the gateway callbacks newest first, only those whose
+	// payment has to be refunded when refundNeeded is set.
+	Callbacks(refundNeeded bool, limit, page int) ([]entity.GatewayCallbacks, error)
+	RunExpiry(ctx context.Context, every time.Duration)
+}
+
+type paymentIntentsService struct {
+	gateway   PaymentGateway
+	uow       repo.UnitOfWork
+	intents   repo.PaymentIntentsRepo
+	callbacks repo.GatewayCallbacksRepo
+	txs       repo.TransactionsRepo
+	payments  repo.PaymentsRepo
+	vouchers  repo.VouchersRepo
+	loyalty   repo.LoyaltyRepo
+	giftCards repo.GiftCardsRepo
+	closes    repo.DayClosesRepo
+	inventory repo.InventoryRepo
+	kitchen   KitchenService
+	events    EventBus
+	cfg       *conf.Config
+}
+
+func NewPaymentIntentsService(gateway PaymentGateway, uow repo.UnitOfWork, intents repo.PaymentIntentsRepo, callbacks repo.GatewayCallbacksRepo, txs repo.TransactionsRepo, payments repo.PaymentsRepo, vouchers repo.VouchersRepo, loyalty repo.LoyaltyRepo, giftCards repo.GiftCardsRepo, closes repo.DayClosesRepo, inventory repo.InventoryRepo, kitchen KitchenService, events EventBus, cfg *conf.Config) PaymentIntentsService {
+	return &paymentIntentsService{gateway: gateway, uow: uow, intents: intents, callbacks: callbacks, txs: txs, payments: payments, vouchers: vouchers, loyalty: loyalty, giftCards: giftCards, closes: closes, inventory: inventory, kitchen: kitchen, events: events, cfg: cfg}
+}
+
+func (s *paymentIntentsService) GatewayName() string {
+	if s.gateway == nil {
+		return ""
+	}
+	return s.gateway.Name()
+}
+
+func (s *paymentIntentsService) Open(payments []entity.Payments) ([]entity.PaymentIntents, error) {
+	var out []entity.PaymentIntents
+	for _, p := range payments {
+		if p.Gateway == "" {
+			continue
+		}
+		if s.gateway == nil || p.Gateway != s.gateway.Name() {
+			return nil, ErrGatewayUnavailable
+		}
+		id := helper.Uuid()
+		gi, err := s.gateway.CreateIntent(id, p.Amount)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, entity.PaymentIntents{
+			IdPaymentIntent: id,
+			IdTransaction:   p.IdTransaction,
+			IdPayment:       p.IdPayment,
+			Gateway:         p.Gateway,
+			ExternalID:      gi.ExternalID,
+			Method:          p.Method,
+			Amount:          p.Amount,
+			QRPayload:       gi.QRPayload,
+			Status:          entity.PaymentIntentPending,
+			ExpiresAt:       gi.ExpiresAt,
+		})
+	}
+	return out, nil
+}
+
+// Get returns an intent, expiring it first when it is overdue so polling
+// clients never see a stale pending state.
+func (s *paymentIntentsService) Get(id string) (*entity.PaymentIntents, error) {
+	i, err := s.intents.GetByID(id)
+	if err != nil {
+		return nil, ErrPaymentIntentNotFound
+	}
+	if i.Status == entity.PaymentIntentPending && time.Now().After(i.ExpiresAt) {
+		if err := s.expire(i.Gateway, i.ExternalID); err != nil {
+			return nil, err
+		}
+		return s.intents.GetByID(id)
+	}
+	return i, nil
+}
+
+func (s *paymentIntentsService) ListByTransaction(idTransaction string) ([]entity.PaymentIntents, error) {
+	return s.intents.ListByTransaction(idTransaction)
+}
+
+func (s *paymentIntentsService) HandleWebhook(gateway string, header http.Header, body []byte) (*WebhookResult, error) {
+	if s.gateway == nil || gateway != s.gateway.Name() {
+		return nil, ErrGatewayUnavailable
+	}
+	ev, err := s.gateway.VerifyWebhook(header, body)
+	if err != nil {
+		return nil, err
+	}
+	if ev.EventID == "" || ev.ExternalID == "" {
+		return nil, fmt.Errorf("%w: event_id and external_id required", ErrWebhookMismatch)
+	}
+
+	res := &WebhookResult{}
+	err = s.uow.Do(func(db *gorm.DB) error {
+		intent, err := s.intents.WithTx(db).GetByExternalForUpdate(gateway, ev.ExternalID)
+		if err != nil {
+			return ErrPaymentIntentNotFound
+		}
+		res.Intent = intent
+
+		cb := &entity.GatewayCallbacks{
+			IdGatewayCallback: helper.Uuid(),
+			Gateway:           gateway,
+			EventID:           ev.EventID,
+			IdPaymentIntent:   intent.IdPaymentIntent,
+			Status:            ev.Status,
+			Amount:            ev.Amount,
+			Payload:           string(body),
+		}
+		fresh, err := s.callbacks.WithTx(db).Record(cb)
+		if err != nil {
+			return err
+		}
+		if !fresh {
+			res.Duplicate = true
+			res.Outcome = "duplicate"
+			return nil
+		}
+
+		res.Outcome, res.RefundNeeded, err = s.apply(db, intent, ev)
+		if err != nil {
+			return err
+		}
+		res.Completed = res.Outcome == outcomeCompleted
+		return s.callbacks.WithTx(db).SetOutcome(cb.IdGatewayCallback, res.Outcome, res.RefundNeeded)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if res.Completed {
+		sendToKitchen(s.kitchen, res.Intent.IdTransaction)
+		if t, err := s.txs.GetByID(res.Intent.IdTransaction); err == nil {
+			s.events.Publish(Event{Type: EventTransactionCreated, Data: *t})
+		}
+	}
+	return res, nil
+}
+
+// outcomeCompleted is the outcome of the callback that completes a sale.
+const outcomeCompleted = "paid, transaction completed"
+
+// apply moves a locked intent to the state reported by the gateway and
+// reports whether the customer's payment has to be refunded. Only pending
+// intents change; other late callbacks are recorded but ignored. A payment
+// for an intent that expired or failed, of the wrong amount, or for a sale
+// dated in a closed day, is kept out of the books and flagged for refund
+// instead.
+func (s *paymentIntentsService) apply(db *gorm.DB, intent *entity.PaymentIntents, ev *GatewayEvent) (string, bool, error) {
+	if intent.Status != entity.PaymentIntentPending {
+		if ev.Status == entity.PaymentIntentPaid && intent.Status != entity.PaymentIntentPaid {
+			return "refund needed: paid after the intent " + intent.Status, true, nil
+		}
+		return "ignored: intent already " + intent.Status, false, nil
+	}
+	switch ev.Status {
+	case entity.PaymentIntentPaid:
+		// A payment of the wrong amount cannot settle the intent, but the
+		// customer's money came in: the sale is cancelled and the payment
+		// flagged, rather than the callback refused and retried for ever.
+		if ev.Amount != intent.Amount {
+			if err := s.cancel(db, intent, entity.PaymentIntentFailed); err != nil {
+				return "", false, err
+			}
+			return fmt.Sprintf("refund needed: amount %s, expected %s", formatMoney(ev.Amount), formatMoney(intent.Amount)), true, nil
+		}
+		paidAt := ev.OccurredAt
+		if paidAt.IsZero() {
+			paidAt = time.Now()
+		}
+		intent.Status = entity.PaymentIntentPaid
+		intent.PaidAt = &paidAt
+		if err := s.intents.WithTx(db).UpdateStatus(intent); err != nil {
+			return "", false, err
+		}
+		if err := s.payments.WithTx(db).SetReference(intent.IdPayment, intent.ExternalID); err != nil {
+			return "", false, err
+		}
+		siblings, err := s.intents.WithTx(db).ListByTransaction(intent.IdTransaction)
+		if err != nil {
+			return "", false, err
+		}
+		for _, o := range siblings {
+			if o.IdPaymentIntent != intent.IdPaymentIntent && o.Status != entity.PaymentIntentPaid {
+				return "paid", false, nil
+			}
+		}
+		t, err := s.txs.WithTx(db).GetByID(intent.IdTransaction)
+		if err != nil {
+			return "", false, err
+		}
+		if err := checkPeriodOpen(s.closes.WithTx(db), t.Timestamp); err != nil {
+			if !errors.Is(err, ErrPeriodClosed) {
+				return "", false, err
+			}
+			if err := s.cancel(db, intent, entity.PaymentIntentFailed); err != nil {
+				return "", false, err
+			}
+			intent.Status = entity.PaymentIntentPaid
+			return "refund needed: paid, but the day of the sale is closed", true, nil
+		}
+		pending := []string{entity.TransactionStatusPending}
+		if _, err := s.txs.WithTx(db).Transition(intent.IdTransaction, pending, entity.TransactionStatusCompleted, nil); err != nil {
+			return "", false, err
+		}
+		return outcomeCompleted, false, nil
+	case entity.PaymentIntentFailed, entity.PaymentIntentExpired:
+		if err := s.cancel(db, intent, ev.Status); err != nil {
+			return "", false, err
+		}
+		return ev.Status + ", transaction cancelled", false, nil
+	default:
+		return "", false, fmt.Errorf("%w: unknown status %q", ErrWebhookMismatch, ev.Status)
+	}
+}
+
+// cancel closes every pending intent of the intent's transaction with status
+// and cancels the transaction, since it can no longer be paid in full. The
+// sale's voucher is given back, and its goods are put back on stock.
+func (s *paymentIntentsService) cancel(db *gorm.DB, intent *entity.PaymentIntents, status string) error {
+	siblings, err := s.intents.WithTx(db).ListByTransaction(intent.IdTransaction)
+	if err != nil {
+		return err
+	}
+	for _, o := range siblings {
+		if o.Status != entity.PaymentIntentPending {
+			continue
+		}
+		o.Status = status
+		if err := s.intents.WithTx(db).UpdateStatus(&o); err != nil {
+			return err
+		}
+	}
+	intent.Status = status
+	pending := []string{entity.TransactionStatusPending}
+	ok, err := s.txs.WithTx(db).Transition(intent.IdTransaction, pending, entity.TransactionStatusCancelled, nil)
+	if err != nil || !ok {
+		return err
+	}
+	if err := s.vouchers.WithTx(db).ReleaseByTransaction(intent.IdTransaction); err != nil {
+		return err
+	}
+	if err := returnSaleStock(s.inventory.WithTx(db), intent.IdTransaction, entity.StockEntryCancel, "", "cancelled"); err != nil {
+		return err
+	}
+	if err := reverseSalePoints(s.loyalty.WithTx(db), loyaltyPolicy(s.cfg), intent.IdTransaction, "cancelled", time.Now()); err != nil {
+		return err
+	}
+	return reverseGiftCards(s.giftCards.WithTx(db), intent.IdTransaction, "", "cancelled")
+}
+
+func (s *paymentIntentsService) expire(gateway, externalID string) error {
+	return s.uow.Do(func(db *gorm.DB) error {
+		intent, err := s.intents.WithTx(db).GetByExternalForUpdate(gateway, externalID)
+		if err != nil {
+			return err
+		}
+		if intent.Status != entity.PaymentIntentPending || time.Now().Before(intent.ExpiresAt) {
+			return nil
+		}
+		return s.cancel(db, intent, entity.PaymentIntentExpired)
+	})
+}
+
+func (s *paymentIntentsService) Simulate(id, status string) (*WebhookResult, error) {
+	sim, ok := s.gateway.(gatewaySimulator)
+	if !ok {
+		return nil, ErrGatewayUnavailable
+	}
+	intent, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if status == "" {
+		status = entity.PaymentIntentPaid
+	}
+	header, body, err := sim.SimulateEvent(GatewayEvent{ExternalID: intent.ExternalID, Status: status, Amount: intent.Amount})
+	if err != nil {
+		return nil, err
+	}
+	return s.HandleWebhook(intent.Gateway, header, body)
+}
+
+func (s *paymentIntentsService) ExpireStale(now time.Time) (int, error) {
+	list, err := s.intents.ListExpired(now, 100)
+	if err != nil {
+		return 0, err
+	}
+	for _, i := range list {
+		if err := s.expire(i.Gateway, i.ExternalID); err != nil {
+			return 0, err
+		}
+	}
+	return len(list), nil
+}
+
+func (s *paymentIntentsService) Callbacks(refundNeeded bool, limit, page int) ([]entity.GatewayCallbacks, error) {
+	limit, offset := itemsPage(limit, page)
+	return s.callbacks.List(refundNeeded, limit, offset)
+}
+
+// RunExpiry expires overdue intents every interval until ctx is done.
+func (s *paymentIntentsService) RunExpiry(ctx context.Context, every time.Duration) {
+	t := time.NewTicker(every)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-t.C:
+			if n, err := s.ExpireStale(now); err != nil {
+				log.Printf("payment intent expiry failed: %v", err)
+			} else if n > 0 {
+				log.Printf("expired %d payment intent(s)", n)
+			}
+		}
+	}
+}
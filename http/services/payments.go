@@ -0,0 +1,169 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"faizalmaulana/lsp/helper"
+	"faizalmaulana/lsp/models/entity"
+)
+
+var (
+	ErrInvalidPayment      = errors.New("invalid payment")
+	ErrInsufficientPayment = errors.New("insufficient payment")
+)
+
+// PaymentMethods lists the accepted tender types in display order.
+var PaymentMethods = []string{
+	entity.PaymentMethodCash,
+	entity.PaymentMethodCard,
+	entity.PaymentMethodQRIS,
+	entity.PaymentMethodEWallet,
+	entity.PaymentMethodTransfer,
+	entity.PaymentMethodPoints,
+	entity.PaymentMethodGiftCard,
+	entity.PaymentMethodStoreCredit,
+}
+
+// PaymentInput is one tender as entered by the cashier. For cash Amount is
+// the money handed over; for the other methods it is the amount charged,
+// for points the value of the points redeemed. A gift card is named by its
+// code in Reference and unlocked with Pin; store credit likewise, or taken
+// from the customer's account when Reference is empty.
+// Gateway tenders are settled later through the payment gateway.
+type PaymentInput struct {
+	Method    string
+	Amount    entity.Money
+	Reference string
+	Pin       string
+	Gateway   bool
+}
+
+const (
+	CashRoundingNearest = "nearest"
+	CashRoundingUp      = "up"
+	CashRoundingDown    = "down"
+)
+
+// CashRounding rounds what is paid in cash to Step, the smallest amount the
+// drawer can pay out. A zero Step leaves amounts alone.
+type CashRounding struct {
+	Step entity.Money
+	Mode string
+}
+
+// Round rounds v to a multiple of Step as Mode says; nearest rounds halves
+// up.
+func (r CashRounding) Round(v entity.Money) entity.Money {
+	if r.Step <= 0 || v <= 0 {
+		return v
+	}
+	rem := v % r.Step
+	if rem == 0 {
+		return v
+	}
+	down := v - rem
+	switch r.Mode {
+	case CashRoundingUp:
+		return down + r.Step
+	case CashRoundingDown:
+		return down
+	}
+	if rem*2 >= r.Step {
+		return down + r.Step
+	}
+	return down
+}
+
+func validPaymentMethod(m string) bool {
+	for _, pm := range PaymentMethods {
+		if pm == m {
+			return true
+		}
+	}
+	return false
+}
+
+// settlePayments applies the tenders to total. Non-cash tenders are applied
+// first and may not exceed what is still due, because only cash can give
+// change. What is left for cash is rounded first; the rounding adjustment
+// comes back alongside the payments. Without any tender the total is taken
+// as paid in exact cash. gateway names the configured payment gateway, empty
+// when there is none.
+func settlePayments(idTransaction string, total entity.Money, in []PaymentInput, gateway string, rounding CashRounding) ([]entity.Payments, entity.Money, error) {
+	if len(in) == 0 {
+		in = []PaymentInput{{Method: entity.PaymentMethodCash, Amount: rounding.Round(total)}}
+	}
+	tenders := make([]PaymentInput, 0, len(in))
+	for _, p := range in {
+		p.Method = strings.ToLower(strings.TrimSpace(p.Method))
+		if !validPaymentMethod(p.Method) {
+			return nil, 0, fmt.Errorf("%w: unknown method %q", ErrInvalidPayment, p.Method)
+		}
+		if p.Amount < 0 || (p.Amount == 0 && total > 0) {
+			return nil, 0, fmt.Errorf("%w: amount must be positive", ErrInvalidPayment)
+		}
+		if p.Gateway && !gatewayMethods[p.Method] {
+			return nil, 0, fmt.Errorf("%w: %s cannot be paid through the gateway", ErrInvalidPayment, p.Method)
+		}
+		if p.Gateway && gateway == "" {
+			return nil, 0, fmt.Errorf("%w: no payment gateway configured", ErrInvalidPayment)
+		}
+		tenders = append(tenders, p)
+	}
+	sort.SliceStable(tenders, func(i, j int) bool {
+		return tenders[i].Method != entity.PaymentMethodCash && tenders[j].Method == entity.PaymentMethodCash
+	})
+
+	due := total
+	var adjustment entity.Money
+	rounded := false
+	out := make([]entity.Payments, 0, len(tenders))
+	for _, p := range tenders {
+		given := p.Amount
+		if due == 0 && given > 0 {
+			return nil, 0, fmt.Errorf("%w: the total is already covered before the %s payment", ErrInvalidPayment, p.Method)
+		}
+		if p.Method == entity.PaymentMethodCash && !rounded {
+			rounded = true
+			adjustment = rounding.Round(due) - due
+			due += adjustment
+		}
+		applied := given
+		if given > due {
+			if p.Method != entity.PaymentMethodCash {
+				return nil, 0, fmt.Errorf("%w: %s payment exceeds the amount due", ErrInvalidPayment, p.Method)
+			}
+			applied = due
+		}
+		due -= applied
+		pay := entity.Payments{
+			IdPayment:     helper.Uuid(),
+			IdTransaction: idTransaction,
+			Method:        p.Method,
+			Amount:        applied,
+			Tendered:      given,
+			Change:        given - applied,
+			Reference:     strings.TrimSpace(p.Reference),
+		}
+		if p.Gateway {
+			pay.Gateway = gateway
+		}
+		out = append(out, pay)
+	}
+	if due > 0 {
+		return nil, 0, fmt.Errorf("%w: %s still due", ErrInsufficientPayment, formatMoney(due))
+	}
+	return out, adjustment, nil
+}
+
+// totalChange returns the change handed back over all payments.
+func totalChange(payments []entity.Payments) entity.Money {
+	var c entity.Money
+	for _, p := range payments {
+		c += p.Change
+	}
+	return c
+}
@@ -0,0 +1,557 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"faizalmaulana/lsp/conf"
+	"faizalmaulana/lsp/helper"
+	"faizalmaulana/lsp/models/entity"
+	"faizalmaulana/lsp/models/repo"
+)
+
+var (
+	ErrInvalidPromotion   = errors.New("invalid promotion")
+	ErrPromotionNotFound  = errors.New("promotion not found")
+	ErrInvalidDiscount    = errors.New("invalid discount")
+	ErrDiscountNotAllowed = errors.New("discount exceeds the cap for this role")
+)
+
+const (
+	DiscountKindPercent = "percent"
+	DiscountKindFixed   = "fixed"
+)
+
+// ManualDiscount is a discount keyed in by the cashier. Value is a percentage
+// or an amount depending on Kind.
+type ManualDiscount struct {
+	Kind   string
+	Value  float64
+	Reason string
+}
+
+// PricedLine is a basket line as priced at checkout, before any discount.
+type PricedLine struct {
+	IdPivot    string
+	IdItem     string
+	IdCategory string
+	Quantity   int
+	Price      entity.Money
+	Discount   *ManualDiscount
+}
+
+type PricingRequest struct {
+	Lines    []PricedLine
+	Discount *ManualDiscount
+	// Role is the role of the user giving the manual discounts.
+	Role string
+	At   time.Time
+	// Unlocked is the voucher-only promotion unlocked by the sale's voucher.
+	Unlocked string
+}
+
+type PromotionsService interface {
+	Create(p *entity.Promotions) (*entity.Promotions, error)
+	GetByID(id string) (*entity.Promotions, error)
+	GetAll(activeOnly bool) ([]entity.Promotions, error)
+	Update(id string, p *entity.Promotions) (*entity.Promotions, error)
+	Delete(id string) error
+	// Apply works out the discounts of a basket: first the promotions running
+	// at req.At, then the manual discounts on what is left. The discounts
+	// come back without a transaction id.
+	Apply(req PricingRequest) ([]entity.PivotLineDiscounts, error)
+}
+
+type promotionsService struct {
+	repo       repo.PromotionsRepo
+	items      repo.ItemsRepo
+	categories CategoriesService
+	cfg        *conf.Config
+}
+
+func NewPromotionsService(r repo.PromotionsRepo, items repo.ItemsRepo, categories CategoriesService, cfg *conf.Config) PromotionsService {
+	return &promotionsService{repo: r, items: items, categories: categories, cfg: cfg}
+}
+
+func (s *promotionsService) Create(p *entity.Promotions) (*entity.Promotions, error) {
+	if p == nil {
+		return nil, errors.New("promotion nil")
+	}
+	if err := s.validate(p); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Create(p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (s *promotionsService) GetByID(id string) (*entity.Promotions, error) {
+	p, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, ErrPromotionNotFound
+	}
+	return p, nil
+}
+
+func (s *promotionsService) GetAll(activeOnly bool) ([]entity.Promotions, error) {
+	list, err := s.repo.List(activeOnly)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]entity.Promotions, 0, len(list))
+	for _, p := range list {
+		out = append(out, *p)
+	}
+	return out, nil
+}
+
+func (s *promotionsService) Update(id string, p *entity.Promotions) (*entity.Promotions, error) {
+	if id == "" || p == nil {
+		return nil, errors.New("invalid input")
+	}
+	p.IdPromotion = id
+	if err := s.validate(p); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Update(p); err != nil {
+		return nil, err
+	}
+	return s.GetByID(id)
+}
+
+func (s *promotionsService) Delete(id string) error {
+	if id == "" {
+		return errors.New("id required")
+	}
+	return s.repo.Delete(id)
+}
+
+func (s *promotionsService) validate(p *entity.Promotions) error {
+	p.Name = strings.TrimSpace(p.Name)
+	if p.Name == "" {
+		return fmt.Errorf("%w: name required", ErrInvalidPromotion)
+	}
+	switch p.Scope {
+	case entity.PromotionScopeItem:
+		if _, err := s.items.GetByID(p.IdTarget); err != nil {
+			return fmt.Errorf("%w: unknown item %q", ErrInvalidPromotion, p.IdTarget)
+		}
+	case entity.PromotionScopeCategory:
+		if _, err := s.categories.GetByID(p.IdTarget); err != nil {
+			return fmt.Errorf("%w: unknown category %q", ErrInvalidPromotion, p.IdTarget)
+		}
+	case entity.PromotionScopeBasket:
+		p.IdTarget = ""
+	default:
+		return fmt.Errorf("%w: unknown scope %q", ErrInvalidPromotion, p.Scope)
+	}
+	switch p.Kind {
+	case entity.PromotionKindPercent:
+		if p.Value <= 0 || p.Value > 100 {
+			return fmt.Errorf("%w: percentage must be between 0 and 100", ErrInvalidPromotion)
+		}
+	case entity.PromotionKindFixed:
+		if p.Value <= 0 {
+			return fmt.Errorf("%w: value must be positive", ErrInvalidPromotion)
+		}
+	case entity.PromotionKindBuyXGetY:
+		if p.Scope == entity.PromotionScopeBasket {
+			return fmt.Errorf("%w: buy_x_get_y needs an item or category scope", ErrInvalidPromotion)
+		}
+		if p.BuyQuantity < 1 || p.GetQuantity < 1 {
+			return fmt.Errorf("%w: buy_quantity and get_quantity must be at least 1", ErrInvalidPromotion)
+		}
+		if p.Value < 0 || p.Value > 100 {
+			return fmt.Errorf("%w: percentage must be between 0 and 100", ErrInvalidPromotion)
+		}
+	default:
+		return fmt.Errorf("%w: unknown kind %q", ErrInvalidPromotion, p.Kind)
+	}
+	if p.MinSpend < 0 {
+		return fmt.Errorf("%w: min_spend cannot be negative", ErrInvalidPromotion)
+	}
+	if p.StartsAt != nil && p.EndsAt != nil && !p.EndsAt.After(*p.StartsAt) {
+		return fmt.Errorf("%w: ends_at must be after starts_at", ErrInvalidPromotion)
+	}
+	days, err := parseDaysOfWeek(p.DaysOfWeek)
+	if err != nil {
+		return err
+	}
+	p.DaysOfWeek = formatDaysOfWeek(days)
+	if (p.StartTime == "") != (p.EndTime == "") {
+		return fmt.Errorf("%w: start_time and end_time go together", ErrInvalidPromotion)
+	}
+	if p.StartTime != "" {
+		if _, err := parseClock(p.StartTime); err != nil {
+			return err
+		}
+		if _, err := parseClock(p.EndTime); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseDaysOfWeek reads "1,3,5" (0 is Sunday) into a set of weekdays.
+func parseDaysOfWeek(v string) (map[time.Weekday]bool, error) {
+	days := map[time.Weekday]bool{}
+	for _, part := range strings.Split(v, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		d, err := strconv.Atoi(part)
+		if err != nil || d < 0 || d > 6 {
+			return nil, fmt.Errorf("%w: days_of_week must list days 0 (Sunday) to 6", ErrInvalidPromotion)
+		}
+		days[time.Weekday(d)] = true
+	}
+	return days, nil
+}
+
+func formatDaysOfWeek(days map[time.Weekday]bool) string {
+	var parts []string
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if days[d] {
+			parts = append(parts, strconv.Itoa(int(d)))
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// parseClock returns the minutes since midnight of a "15:04" time.
+func parseClock(v string) (int, error) {
+	t, err := time.Parse("15:04", v)
+	if err != nil {
+		return 0, fmt.Errorf("%w: time %q must be HH:MM", ErrInvalidPromotion, v)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// promotionRunning tells whether p applies to a sale made at `at`. A daily
+// window whose end is before its start runs past midnight.
+func promotionRunning(p *entity.Promotions, at time.Time) bool {
+	if !p.IsActive {
+		return false
+	}
+	if p.StartsAt != nil && at.Before(*p.StartsAt) {
+		return false
+	}
+	if p.EndsAt != nil && !at.Before(*p.EndsAt) {
+		return false
+	}
+	if p.DaysOfWeek != "" {
+		days, err := parseDaysOfWeek(p.DaysOfWeek)
+		if err != nil || !days[at.Weekday()] {
+			return false
+		}
+	}
+	if p.StartTime != "" && p.EndTime != "" {
+		from, err1 := parseClock(p.StartTime)
+		to, err2 := parseClock(p.EndTime)
+		if err1 != nil || err2 != nil {
+			return false
+		}
+		now := at.Hour()*60 + at.Minute()
+		if from <= to {
+			return now >= from && now < to
+		}
+		return now >= from || now < to
+	}
+	return true
+}
+
+func (s *promotionsService) Apply(req PricingRequest) ([]entity.PivotLineDiscounts, error) {
+	lines := make([]*basketLine, 0, len(req.Lines))
+	for _, l := range req.Lines {
+		lines = append(lines, &basketLine{PricedLine: l, net: l.Price.Mul(l.Quantity)})
+	}
+
+	list, err := s.repo.List(true)
+	if err != nil {
+		return nil, err
+	}
+	running := make([]entity.Promotions, 0, len(list))
+	categories := map[string]map[string]bool{}
+	for _, p := range list {
+		if !promotionRunning(p, req.At) || (p.VoucherOnly && p.IdPromotion != req.Unlocked) {
+			continue
+		}
+		if p.Scope == entity.PromotionScopeCategory {
+			ids, err := s.categories.Descendants(p.IdTarget)
+			if err != nil {
+				continue
+			}
+			set := map[string]bool{}
+			for _, id := range ids {
+				set[id] = true
+			}
+			categories[p.IdPromotion] = set
+		}
+		running = append(running, *p)
+	}
+
+	out := applyPromotions(running, lines, categories)
+	manual, err := applyManualDiscounts(lines, req.Discount, s.discountCap(req.Role), req.Role)
+	if err != nil {
+		return nil, err
+	}
+	return append(out, manual...), nil
+}
+
+// discountCap returns the largest manual discount role may give, in percent.
+func (s *promotionsService) discountCap(role string) float64 {
+	switch strings.ToLower(role) {
+	case entity.RoleAdmin:
+		return 100
+	case entity.RoleManager:
+		return s.cfg.DiscountCapManager
+	}
+	return s.cfg.DiscountCapCashier
+}
+
+// basketLine tracks what is left to pay on a line while the discounts are
+// applied.
+type basketLine struct {
+	PricedLine
+	net        entity.Money
+	discounted bool
+	locked     bool
+}
+
+// applyPromotions applies promos in the given order. categories maps the id
+// of every category promotion to the categories it covers.
+func applyPromotions(promos []entity.Promotions, lines []*basketLine, categories map[string]map[string]bool) []entity.PivotLineDiscounts {
+	var subtotal entity.Money
+	for _, l := range lines {
+		subtotal += l.net
+	}
+	var out []entity.PivotLineDiscounts
+	for i := range promos {
+		p := &promos[i]
+		if subtotal < p.MinSpend {
+			continue
+		}
+		eligible := make([]*basketLine, 0, len(lines))
+		for _, l := range lines {
+			if l.locked || l.net <= 0 || l.Quantity <= 0 || (!p.Stackable && l.discounted) {
+				continue
+			}
+			switch p.Scope {
+			case entity.PromotionScopeItem:
+				if l.IdItem != p.IdTarget {
+					continue
+				}
+			case entity.PromotionScopeCategory:
+				if !categories[p.IdPromotion][l.IdCategory] {
+					continue
+				}
+			}
+			eligible = append(eligible, l)
+		}
+		for j, amount := range promotionDiscounts(p, eligible) {
+			if amount <= 0 {
+				continue
+			}
+			l := eligible[j]
+			l.net -= amount
+			l.discounted = true
+			if !p.Stackable {
+				l.locked = true
+			}
+			out = append(out, entity.PivotLineDiscounts{
+				IdPivotLineDiscount: helper.Uuid(),
+				IdPivot:             l.IdPivot,
+				IdPromotion:         p.IdPromotion,
+				Source:              entity.DiscountSourcePromotion,
+				Name:                p.Name,
+				Amount:              amount,
+			})
+		}
+	}
+	return out
+}
+
+// promotionDiscounts returns the discount of p on each line.
+func promotionDiscounts(p *entity.Promotions, lines []*basketLine) []entity.Money {
+	out := make([]entity.Money, len(lines))
+	switch p.Kind {
+	case entity.PromotionKindPercent:
+		for i, l := range lines {
+			out[i] = percentOf(l.net, p.Value)
+		}
+	case entity.PromotionKindFixed:
+		if p.Scope == entity.PromotionScopeBasket {
+			nets := make([]entity.Money, len(lines))
+			var total entity.Money
+			for i, l := range lines {
+				nets[i] = l.net
+				total += l.net
+			}
+			return allocateCents(min(total, entity.NewMoney(p.Value)), nets)
+		}
+		for i, l := range lines {
+			out[i] = min(l.net, entity.NewMoney(p.Value).Mul(l.Quantity))
+		}
+	case entity.PromotionKindBuyXGetY:
+		// The cheapest units are the ones given away.
+		type unit struct {
+			line  int
+			price float64
+		}
+		var units []unit
+		for i, l := range lines {
+			per := float64(l.net) / float64(l.Quantity)
+			for k := 0; k < l.Quantity; k++ {
+				units = append(units, unit{line: i, price: per})
+			}
+		}
+		sort.SliceStable(units, func(i, j int) bool { return units[i].price < units[j].price })
+		free := len(units) / (p.BuyQuantity + p.GetQuantity) * p.GetQuantity
+		pct := p.Value
+		if pct == 0 {
+			pct = 100
+		}
+		counts := make([]int, len(lines))
+		for _, u := range units[:free] {
+			counts[u.line]++
+		}
+		for i, l := range lines {
+			if counts[i] == 0 {
+				continue
+			}
+			value := float64(l.net) * float64(counts[i]) / float64(l.Quantity)
+			out[i] = min(l.net, entity.Money(math.Round(value*pct/100)))
+		}
+	}
+	return out
+}
+
+func percentOf(v entity.Money, pct float64) entity.Money {
+	return min(v, v.Percent(pct))
+}
+
+// allocateCents splits total over weights in proportion, handing the cents
+// lost to rounding to the first lines with room left. total must not exceed
+// the sum of weights.
+func allocateCents(total entity.Money, weights []entity.Money) []entity.Money {
+	out := make([]entity.Money, len(weights))
+	var sum entity.Money
+	for _, w := range weights {
+		sum += w
+	}
+	if sum <= 0 || total <= 0 {
+		return out
+	}
+	var given entity.Money
+	for i, w := range weights {
+		out[i] = total * w / sum
+		given += out[i]
+	}
+	for i := 0; given < total; i = (i + 1) % len(weights) {
+		if out[i] < weights[i] {
+			out[i]++
+			given++
+		}
+	}
+	return out
+}
+
+// applyManualDiscounts applies the line discounts and then the basket
+// discount on what is left. Each of them, and all of them together, must
+// stay within capPct percent of the amount they apply to.
+func applyManualDiscounts(lines []*basketLine, basket *ManualDiscount, capPct float64, role string) ([]entity.PivotLineDiscounts, error) {
+	var before entity.Money
+	for _, l := range lines {
+		before += l.net
+	}
+	var (
+		out   []entity.PivotLineDiscounts
+		given entity.Money
+	)
+	add := func(l *basketLine, amount entity.Money, d *ManualDiscount) {
+		l.net -= amount
+		given += amount
+		out = append(out, entity.PivotLineDiscounts{
+			IdPivotLineDiscount: helper.Uuid(),
+			IdPivot:             l.IdPivot,
+			Source:              entity.DiscountSourceManual,
+			Name:                "Manual discount",
+			Reason:              truncate(strings.TrimSpace(d.Reason), 255),
+			Amount:              amount,
+		})
+	}
+	exceeds := func(amount, base entity.Money) error {
+		if amount > percentOf(base, capPct) {
+			return fmt.Errorf("%w: %s may give up to %g%%", ErrDiscountNotAllowed, role, capPct)
+		}
+		return nil
+	}
+
+	for _, l := range lines {
+		if l.Discount == nil {
+			continue
+		}
+		amount, err := manualAmount(l.Discount, l.net)
+		if err != nil {
+			return nil, err
+		}
+		if err := exceeds(amount, l.net); err != nil {
+			return nil, err
+		}
+		if amount > 0 {
+			add(l, amount, l.Discount)
+		}
+	}
+	if basket != nil {
+		nets := make([]entity.Money, len(lines))
+		var left entity.Money
+		for i, l := range lines {
+			nets[i] = l.net
+			left += l.net
+		}
+		amount, err := manualAmount(basket, left)
+		if err != nil {
+			return nil, err
+		}
+		if err := exceeds(amount, left); err != nil {
+			return nil, err
+		}
+		for i, a := range allocateCents(amount, nets) {
+			if a > 0 {
+				add(lines[i], a, basket)
+			}
+		}
+	}
+	if err := exceeds(given, before); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// manualAmount returns the discount d gives on base.
+func manualAmount(d *ManualDiscount, base entity.Money) (entity.Money, error) {
+	switch strings.ToLower(strings.TrimSpace(d.Kind)) {
+	case DiscountKindPercent:
+		if d.Value <= 0 || d.Value > 100 {
+			return 0, fmt.Errorf("%w: percentage must be between 0 and 100", ErrInvalidDiscount)
+		}
+		return percentOf(base, d.Value), nil
+	case DiscountKindFixed:
+		if d.Value <= 0 {
+			return 0, fmt.Errorf("%w: amount must be positive", ErrInvalidDiscount)
+		}
+		amount := entity.NewMoney(d.Value)
+		if amount > base {
+			return 0, fmt.Errorf("%w: discount is more than the %s left to pay", ErrInvalidDiscount, formatMoney(base))
+		}
+		return amount, nil
+	}
+	return 0, fmt.Errorf("%w: unknown kind %q", ErrInvalidDiscount, d.Kind)
+}
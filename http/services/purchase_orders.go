@@ -0,0 +1,381 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"faizalmaulana/lsp/helper"
+	"faizalmaulana/lsp/models/entity"
+	"faizalmaulana/lsp/models/repo"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidPurchaseOrder  = errors.New("invalid purchase order")
+	ErrPurchaseOrderNotFound = errors.New("purchase order not found")
+	// ErrPurchaseOrderStatus refuses what the order's status does not
+	// allow, e.g. changing a sent order or receiving on a draft.
+	ErrPurchaseOrderStatus = errors.New("purchase order cannot change")
+)
+
+// PurchaseOrderInput is a purchase order as it is drafted.
+type PurchaseOrderInput struct {
+	IdSupplier string
+	Note       string
+	ExpectedAt *time.Time
+	Lines      []PurchaseOrderLineInput
+}
+
+// PurchaseOrderLineInput orders Quantity units of an item at UnitCost each.
+type PurchaseOrderLineInput struct {
+	IdItem   string
+	Quantity int
+	UnitCost entity.Money
+}
+
+// PurchaseOrderUpdate changes the fields of a draft that are set. Lines,
+// when set, replace the draft's lines.
+type PurchaseOrderUpdate struct {
+	IdSupplier *string
+	Note       *string
+	ExpectedAt *time.Time
+	Lines      []PurchaseOrderLineInput
+}
+
+// GoodsReceiptInput is a delivery received against a purchase order.
+type GoodsReceiptInput struct {
+	Note  string
+	Lines []GoodsReceiptLineInput
+}
+
+// GoodsReceiptLineInput receives Quantity units of an order line. UnitCost
+// is what they were invoiced at; nil takes the cost they were ordered at.
+type GoodsReceiptLineInput struct {
+	IdPurchaseOrderLine string
+	Quantity            int
+	UnitCost            *entity.Money
+}
+
+type PurchaseOrdersService interface {
+	Create(idUser string, in PurchaseOrderInput) (*entity.PurchaseOrders, error)
+	// Get returns the order with its lines and receipts.
+	Get(id string) (*entity.PurchaseOrders, error)
+	// GetAll filters by supplier and status when they are set, newest
+	// first. Lines and receipts are not included.
+	GetAll(idSupplier, status string, limit, page int) ([]entity.PurchaseOrders, error)
+	// Update changes a draft.
+	Update(id string, in PurchaseOrderUpdate) (*entity.PurchaseOrders, error)
+	// Send marks a draft as placed with the supplier; goods can be received
+	// on it from then on.
+	Send(id string) (*entity.PurchaseOrders, error)
+	// Receive records a delivery against a sent order and adds it to
+	// stock. The order is closed once everything ordered has come in.
+	Receive(id, idUser string, in GoodsReceiptInput) (*entity.PurchaseOrders, error)
+	// Close closes an order with whatever has not come in yet.
+	Close(id, by string) (*entity.PurchaseOrders, error)
+}
+
+type purchaseOrdersService struct {
+	repo      repo.PurchaseOrdersRepo
+	uow       repo.UnitOfWork
+	suppliers repo.SuppliersRepo
+	items     repo.ItemsRepo
+	inventory repo.InventoryRepo
+}
+
+func NewPurchaseOrdersService(r repo.PurchaseOrdersRepo, uow repo.UnitOfWork, suppliers repo.SuppliersRepo, items repo.ItemsRepo, inventory repo.InventoryRepo) PurchaseOrdersService {
+	return &purchaseOrdersService{repo: r, uow: uow, suppliers: suppliers, items: items, inventory: inventory}
+}
+
+func (s *purchaseOrdersService) Create(idUser string, in PurchaseOrderInput) (*entity.PurchaseOrders, error) {
+	if _, err := s.suppliers.GetByID(in.IdSupplier); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrSupplierNotFound, in.IdSupplier)
+	}
+	o := &entity.PurchaseOrders{
+		IdPurchaseOrder: helper.Uuid(),
+		IdSupplier:      in.IdSupplier,
+		Status:          entity.PurchaseOrderStatusDraft,
+		Note:            truncate(strings.TrimSpace(in.Note), 255),
+		ExpectedAt:      in.ExpectedAt,
+		IdUser:          idUser,
+	}
+	lines, total, err := s.lines(o.IdPurchaseOrder, in.Lines)
+	if err != nil {
+		return nil, err
+	}
+	o.Total = total
+	err = s.uow.Do(func(db *gorm.DB) error {
+		r := s.repo.WithTx(db)
+		if err := r.Create(o); err != nil {
+			return err
+		}
+		return r.CreateLines(lines)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.Get(o.IdPurchaseOrder)
+}
+
+func (s *purchaseOrdersService) Get(id string) (*entity.PurchaseOrders, error) {
+	o, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, ErrPurchaseOrderNotFound
+	}
+	if o.Lines, err = s.repo.ListLines(id); err != nil {
+		return nil, err
+	}
+	if o.Receipts, err = s.repo.ListReceipts(id); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+func (s *purchaseOrdersService) GetAll(idSupplier, status string, limit, page int) ([]entity.PurchaseOrders, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	if page <= 0 {
+		page = 1
+	}
+	return s.repo.ListPage(idSupplier, status, limit, (page-1)*limit)
+}
+
+func (s *purchaseOrdersService) Update(id string, in PurchaseOrderUpdate) (*entity.PurchaseOrders, error) {
+	if in.IdSupplier != nil {
+		if _, err := s.suppliers.GetByID(*in.IdSupplier); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrSupplierNotFound, *in.IdSupplier)
+		}
+	}
+	var lines []entity.PurchaseOrderLines
+	var total entity.Money
+	if in.Lines != nil {
+		var err error
+		if lines, total, err = s.lines(id, in.Lines); err != nil {
+			return nil, err
+		}
+	}
+	err := s.change(id, func(r repo.PurchaseOrdersRepo, o *entity.PurchaseOrders) error {
+		if o.Status != entity.PurchaseOrderStatusDraft {
+			return fmt.Errorf("%w: the order is %s", ErrPurchaseOrderStatus, o.Status)
+		}
+		if in.IdSupplier != nil {
+			o.IdSupplier = *in.IdSupplier
+		}
+		if in.Note != nil {
+			o.Note = truncate(strings.TrimSpace(*in.Note), 255)
+		}
+		if in.ExpectedAt != nil {
+			o.ExpectedAt = in.ExpectedAt
+		}
+		if in.Lines != nil {
+			if err := r.DeleteLines(id); err != nil {
+				return err
+			}
+			if err := r.CreateLines(lines); err != nil {
+				return err
+			}
+			o.Total = total
+		}
+		return r.Update(o)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.Get(id)
+}
+
+func (s *purchaseOrdersService) Send(id string) (*entity.PurchaseOrders, error) {
+	err := s.change(id, func(r repo.PurchaseOrdersRepo, o *entity.PurchaseOrders) error {
+		if o.Status != entity.PurchaseOrderStatusDraft {
+			return fmt.Errorf("%w: the order is %s", ErrPurchaseOrderStatus, o.Status)
+		}
+		lines, err := r.ListLines(id)
+		if err != nil {
+			return err
+		}
+		if len(lines) == 0 {
+			return fmt.Errorf("%w: the order has no lines", ErrInvalidPurchaseOrder)
+		}
+		now := time.Now()
+		o.Status, o.SentAt = entity.PurchaseOrderStatusSent, &now
+		return r.SetStatus(o)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.Get(id)
+}
+
+func (s *purchaseOrdersService) Receive(id, idUser string, in GoodsReceiptInput) (*entity.PurchaseOrders, error) {
+	if len(in.Lines) == 0 {
+		return nil, fmt.Errorf("%w: nothing received", ErrInvalidPurchaseOrder)
+	}
+	err := s.uow.Do(func(db *gorm.DB) error {
+		r := s.repo.WithTx(db)
+		o, err := r.GetForUpdate(id)
+		if err != nil {
+			return ErrPurchaseOrderNotFound
+		}
+		if o.Status != entity.PurchaseOrderStatusSent && o.Status != entity.PurchaseOrderStatusPartiallyReceived {
+			return fmt.Errorf("%w: the order is %s", ErrPurchaseOrderStatus, o.Status)
+		}
+		lines, err := r.ListLines(id)
+		if err != nil {
+			return err
+		}
+		index := make(map[string]int, len(lines))
+		for i, l := range lines {
+			index[l.IdPurchaseOrderLine] = i
+		}
+
+		g := &entity.GoodsReceipts{
+			IdGoodsReceipt:  helper.Uuid(),
+			IdPurchaseOrder: id,
+			IdUser:          idUser,
+			Note:            truncate(strings.TrimSpace(in.Note), 255),
+		}
+		received := make([]entity.GoodsReceiptLines, 0, len(in.Lines))
+		seen := make(map[string]bool, len(in.Lines))
+		for _, l := range in.Lines {
+			i, ok := index[l.IdPurchaseOrderLine]
+			if !ok {
+				return fmt.Errorf("%w: no line %s", ErrInvalidPurchaseOrder, l.IdPurchaseOrderLine)
+			}
+			if seen[l.IdPurchaseOrderLine] {
+				return fmt.Errorf("%w: line %s is received twice", ErrInvalidPurchaseOrder, l.IdPurchaseOrderLine)
+			}
+			seen[l.IdPurchaseOrderLine] = true
+			line := &lines[i]
+			if left := line.Quantity - line.Received; l.Quantity <= 0 || l.Quantity > left {
+				return fmt.Errorf("%w: %d of %s are left to receive", ErrInvalidPurchaseOrder, left, line.ItemName)
+			}
+			cost := line.UnitCost
+			if l.UnitCost != nil {
+				if *l.UnitCost < 0 {
+					return fmt.Errorf("%w: the cost of %s cannot be negative", ErrInvalidPurchaseOrder, line.ItemName)
+				}
+				cost = *l.UnitCost
+			}
+			line.Received += l.Quantity
+			g.Total += cost.Mul(l.Quantity)
+			received = append(received, entity.GoodsReceiptLines{
+				IdGoodsReceiptLine:  helper.Uuid(),
+				IdGoodsReceipt:      g.IdGoodsReceipt,
+				IdPurchaseOrderLine: line.IdPurchaseOrderLine,
+				IdItem:              line.IdItem,
+				Quantity:            l.Quantity,
+				UnitCost:            cost,
+			})
+		}
+
+		if err := r.CreateReceipt(g); err != nil {
+			return err
+		}
+		if err := r.CreateReceiptLines(received); err != nil {
+			return err
+		}
+		// Stock is locked in the order of the items, so receipts taking the
+		// same items cannot deadlock.
+		sort.Slice(received, func(i, j int) bool { return received[i].IdItem < received[j].IdItem })
+		inv := s.inventory.WithTx(db)
+		for _, l := range received {
+			if err := r.SetReceived(l.IdPurchaseOrderLine, lines[index[l.IdPurchaseOrderLine]].Received); err != nil {
+				return err
+			}
+			if err := receiveStock(inv, g, l, o.IdSupplier); err != nil {
+				return err
+			}
+		}
+
+		o.Status = entity.PurchaseOrderStatusClosed
+		for _, l := range lines {
+			if l.Received < l.Quantity {
+				o.Status = entity.PurchaseOrderStatusPartiallyReceived
+				break
+			}
+		}
+		if o.Status == entity.PurchaseOrderStatusClosed {
+			now := time.Now()
+			o.ClosedBy, o.ClosedAt = idUser, &now
+		}
+		return r.SetStatus(o)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.Get(id)
+}
+
+func (s *purchaseOrdersService) Close(id, by string) (*entity.PurchaseOrders, error) {
+	err := s.change(id, func(r repo.PurchaseOrdersRepo, o *entity.PurchaseOrders) error {
+		if o.Status == entity.PurchaseOrderStatusClosed {
+			return fmt.Errorf("%w: the order is %s", ErrPurchaseOrderStatus, o.Status)
+		}
+		now := time.Now()
+		o.Status, o.ClosedBy, o.ClosedAt = entity.PurchaseOrderStatusClosed, by, &now
+		return r.SetStatus(o)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.Get(id)
+}
+
+// change runs fn on the order id while it is locked.
+func (s *purchaseOrdersService) change(id string, fn func(r repo.PurchaseOrdersRepo, o *entity.PurchaseOrders) error) error {
+	return s.uow.Do(func(db *gorm.DB) error {
+		r := s.repo.WithTx(db)
+		o, err := r.GetForUpdate(id)
+		if err != nil {
+			return ErrPurchaseOrderNotFound
+		}
+		return fn(r, o)
+	})
+}
+
+// lines checks the lines of an order against the catalog and works out
+// what they come to. Only single items are stocked: bundles are stocked as
+// their components, and gift cards are not stocked.
+func (s *purchaseOrdersService) lines(idPurchaseOrder string, in []PurchaseOrderLineInput) ([]entity.PurchaseOrderLines, entity.Money, error) {
+	out := make([]entity.PurchaseOrderLines, 0, len(in))
+	var total entity.Money
+	seen := make(map[string]bool, len(in))
+	for i, l := range in {
+		item, err := s.items.GetByID(l.IdItem)
+		if err != nil {
+			return nil, 0, fmt.Errorf("%w: %s", ErrInvalidItem, l.IdItem)
+		}
+		if item.Kind != "" && item.Kind != entity.ItemKindSingle {
+			return nil, 0, fmt.Errorf("%w: %s is a %s and is not stocked", ErrInvalidItem, item.ItemName, strings.ReplaceAll(item.Kind, "_", " "))
+		}
+		if seen[item.IdItem] {
+			return nil, 0, fmt.Errorf("%w: %s is on the order twice", ErrInvalidPurchaseOrder, item.ItemName)
+		}
+		seen[item.IdItem] = true
+		if l.Quantity <= 0 {
+			return nil, 0, fmt.Errorf("%w: quantity of %s must be positive", ErrInvalidPurchaseOrder, item.ItemName)
+		}
+		if l.UnitCost < 0 {
+			return nil, 0, fmt.Errorf("%w: the cost of %s cannot be negative", ErrInvalidPurchaseOrder, item.ItemName)
+		}
+		out = append(out, entity.PurchaseOrderLines{
+			IdPurchaseOrderLine: helper.Uuid(),
+			IdPurchaseOrder:     idPurchaseOrder,
+			IdItem:              item.IdItem,
+			ItemName:            item.ItemName,
+			Quantity:            l.Quantity,
+			UnitCost:            l.UnitCost,
+			Position:            i,
+		})
+		total += l.UnitCost.Mul(l.Quantity)
+	}
+	return out, total, nil
+}
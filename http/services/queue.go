@@ -0,0 +1,125 @@
+package services
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"faizalmaulana/lsp/conf"
+	"faizalmaulana/lsp/models/entity"
+	"faizalmaulana/lsp/models/repo"
+)
+
+var (
+	ErrQueueDisplayOff     = errors.New("queue display is not set up")
+	ErrQueueDisplayToken   = errors.New("invalid display token")
+	ErrQueueNumberNotFound = errors.New("queue number not found")
+)
+
+// QueueDisplay is what the screen facing the customers shows: the numbers
+// the kitchen is preparing, oldest first, and the numbers ready to be
+// collected, the latest first.
+type QueueDisplay struct {
+	Date      string `json:"date"`
+	Preparing []int  `json:"preparing"`
+	Ready     []int  `json:"ready"`
+}
+
+type QueueService interface {
+	// Display shows today's queue to whoever has the store's display token.
+	Display(token string) (*QueueDisplay, error)
+	// Collect takes today's queue number off the display once the order
+	// has been handed over.
+	Collect(number int) error
+}
+
+type queueService struct {
+	kitchen repo.KitchenRepo
+	cfg     *conf.Config
+}
+
+func NewQueueService(kitchen repo.KitchenRepo, cfg *conf.Config) QueueService {
+	return &queueService{kitchen: kitchen, cfg: cfg}
+}
+
+func (s *queueService) Display(token string) (*QueueDisplay, error) {
+	if s.cfg.QueueDisplayToken == "" {
+		return nil, ErrQueueDisplayOff
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(s.cfg.QueueDisplayToken)) != 1 {
+		return nil, ErrQueueDisplayToken
+	}
+	now := time.Now()
+	from := startOfDay(now)
+	tickets, err := s.kitchen.ListQueued(from)
+	if err != nil {
+		return nil, err
+	}
+
+	// A sale is being prepared while one of its tickets is new, and ready
+	// from when its last ticket was bumped.
+	type order struct {
+		number    int
+		preparing bool
+		collected bool
+		readyAt   time.Time
+	}
+	orders := map[string]*order{}
+	var sales []string
+	for _, t := range tickets {
+		o := orders[t.IdTransaction]
+		if o == nil {
+			o = &order{number: t.QueueNumber}
+			orders[t.IdTransaction] = o
+			sales = append(sales, t.IdTransaction)
+		}
+		if t.Status == entity.KitchenTicketNew {
+			o.preparing = true
+		}
+		if t.CollectedAt != nil {
+			o.collected = true
+		}
+		if t.BumpedAt != nil && t.BumpedAt.After(o.readyAt) {
+			o.readyAt = *t.BumpedAt
+		}
+	}
+
+	out := &QueueDisplay{Date: from.Format("2006-01-02"), Preparing: []int{}, Ready: []int{}}
+	since := now.Add(-time.Duration(s.cfg.QueueReadyMinutes) * time.Minute)
+	var ready []*order
+	for _, id := range sales {
+		o := orders[id]
+		switch {
+		case o.collected:
+		case o.preparing:
+			out.Preparing = append(out.Preparing, o.number)
+		case o.readyAt.After(since):
+			ready = append(ready, o)
+		}
+	}
+	sort.SliceStable(ready, func(i, j int) bool { return ready[i].readyAt.After(ready[j].readyAt) })
+	for _, o := range ready {
+		out.Ready = append(out.Ready, o.number)
+	}
+	return out, nil
+}
+
+func (s *queueService) Collect(number int) error {
+	now := time.Now()
+	n, err := s.kitchen.Collect(startOfDay(now), number, now)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("%w: %d has no order waiting today", ErrQueueNumberNotFound, number)
+	}
+	return nil
+}
+
+// startOfDay is midnight of the day of t.
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
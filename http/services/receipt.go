@@ -0,0 +1,302 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"faizalmaulana/lsp/models/entity"
+)
+
+// ReceiptWidth is the number of characters per line of a 58mm thermal roll.
+const ReceiptWidth = 32
+
+// Receipt is the printable view of a sale (or any document derived from one).
+// It is rendered to plain text by RenderReceipt and also returned as JSON.
+type Receipt struct {
+	Title     string          `json:"title"`
+	Number    string          `json:"number"`
+	Queue     int             `json:"queue,omitempty"`
+	Timestamp time.Time       `json:"timestamp"`
+	Header    []string        `json:"header"`
+	Lines     []ReceiptLine   `json:"lines"`
+	Totals    []ReceiptAmount `json:"totals"`
+	Footer    []string        `json:"footer"`
+}
+
+type ReceiptLine struct {
+	Name      string       `json:"name"`
+	Quantity  int          `json:"quantity"`
+	UnitPrice entity.Money `json:"unit_price"`
+	Amount    entity.Money `json:"amount"`
+	Details   []string     `json:"details"`
+}
+
+type ReceiptAmount struct {
+	Label  string       `json:"label"`
+	Amount entity.Money `json:"amount"`
+}
+
+// transactionReceipt prints a sale. taxLabel names the tax, e.g. "PPN".
+func transactionReceipt(d *TransactionDetail, taxLabel string) *Receipt {
+	t := d.Transaction
+	r := &Receipt{
+		Title:     "SALES RECEIPT",
+		Number:    t.IdTransaction,
+		Queue:     t.QueueNumber,
+		Timestamp: t.Timestamp,
+	}
+	if t.BuyerContact != "" {
+		r.Header = append(r.Header, "Customer: "+t.BuyerContact)
+	}
+	if t.VoucherCode != "" {
+		r.Header = append(r.Header, "Voucher: "+t.VoucherCode)
+	}
+	switch t.Status {
+	case entity.TransactionStatusVoided:
+		r.Header = append(r.Header, "*** VOID ***", "Reason: "+t.VoidReason)
+	case entity.TransactionStatusPending, entity.TransactionStatusCancelled, entity.TransactionStatusRefunded:
+		r.Header = append(r.Header, "*** "+strings.ToUpper(t.Status)+" ***")
+	}
+	for _, l := range d.Lines {
+		line := ReceiptLine{
+			Name:      l.ItemName,
+			Quantity:  l.Quantity,
+			UnitPrice: l.Price,
+			Amount:    l.Price.Mul(l.Quantity),
+		}
+		if line.Name == "" {
+			line.Name = l.IdItem
+		}
+		for _, m := range l.Modifiers {
+			detail := m.GroupName + ": " + m.OptionName
+			if m.PriceDelta != 0 {
+				detail += " (" + signedMoney(m.PriceDelta) + ")"
+			}
+			line.Details = append(line.Details, detail)
+		}
+		for _, c := range l.Components {
+			line.Details = append(line.Details, fmt.Sprintf("- %d x %s", c.Quantity, c.ItemName))
+		}
+		for _, dc := range l.Discounts {
+			line.Details = append(line.Details, dc.Name+" ("+signedMoney(-dc.Amount)+")")
+		}
+		r.Lines = append(r.Lines, line)
+	}
+	var gross entity.Money
+	for _, l := range d.Lines {
+		gross += l.Price.Mul(l.Quantity)
+	}
+	addedTax := t.TaxTotal > 0 && !t.TaxInclusive
+	if t.DiscountTotal > 0 || t.ServiceCharge > 0 || addedTax {
+		r.Totals = append(r.Totals, ReceiptAmount{Label: "SUBTOTAL", Amount: gross})
+	}
+	if t.DiscountTotal > 0 {
+		r.Totals = append(r.Totals, ReceiptAmount{Label: "DISCOUNT", Amount: -t.DiscountTotal})
+	}
+	if t.ServiceCharge > 0 {
+		r.Totals = append(r.Totals, ReceiptAmount{Label: "SERVICE CHARGE", Amount: t.ServiceCharge})
+	}
+	taxes := taxByRate(d.Lines, taxLabel)
+	if addedTax {
+		r.Totals = append(r.Totals, taxes...)
+	}
+	r.Totals = append(r.Totals, ReceiptAmount{Label: "TOTAL", Amount: t.TotalPrice})
+	if t.TaxTotal > 0 && t.TaxInclusive {
+		for _, a := range taxes {
+			a.Label = "INCL. " + a.Label
+			r.Totals = append(r.Totals, a)
+		}
+	}
+	if t.CashRounding != 0 {
+		r.Totals = append(r.Totals, ReceiptAmount{Label: "ROUNDING", Amount: t.CashRounding})
+	}
+	for _, p := range d.Payments {
+		label := strings.ToUpper(p.Method)
+		if p.Reference != "" {
+			label += " " + p.Reference
+		}
+		r.Totals = append(r.Totals, ReceiptAmount{Label: label, Amount: p.Tendered})
+	}
+	if change := totalChange(d.Payments); change > 0 {
+		r.Totals = append(r.Totals, ReceiptAmount{Label: "CHANGE", Amount: change})
+	}
+	pts := sumSalePoints(d.Points)
+	if pts.redeemed > 0 {
+		r.Footer = append(r.Footer, fmt.Sprintf("Points redeemed: %d", pts.redeemed))
+	}
+	if pts.earned > 0 {
+		r.Footer = append(r.Footer, fmt.Sprintf("Points earned: %d", pts.earned))
+	}
+	r.Footer = append(r.Footer, "Thank you")
+	return r
+}
+
+// taxByRate sums the tax of the lines per rate, lowest rate first.
+func taxByRate(lines []TransactionLine, label string) []ReceiptAmount {
+	perRate := map[float64]entity.Money{}
+	var rates []float64
+	for _, l := range lines {
+		if l.Tax == 0 {
+			continue
+		}
+		if _, ok := perRate[l.TaxRate]; !ok {
+			rates = append(rates, l.TaxRate)
+		}
+		perRate[l.TaxRate] += l.Tax
+	}
+	sort.Float64s(rates)
+	out := make([]ReceiptAmount, 0, len(rates))
+	for _, rate := range rates {
+		out = append(out, ReceiptAmount{Label: fmt.Sprintf("%s %g%%", label, rate), Amount: perRate[rate]})
+	}
+	return out
+}
+
+// refundReceipt prints a refund with the same layout as the sale it refers to.
+func refundReceipt(d *RefundDetail) *Receipt {
+	rf := d.Refund
+	r := &Receipt{
+		Title:     "REFUND RECEIPT",
+		Number:    rf.IdRefund,
+		Timestamp: rf.Timestamp,
+		Header:    []string{"Sale: " + rf.IdTransaction},
+	}
+	if d.Transaction != nil && d.Transaction.BuyerContact != "" {
+		r.Header = append(r.Header, "Customer: "+d.Transaction.BuyerContact)
+	}
+	if rf.Reason != "" {
+		r.Header = append(r.Header, "Reason: "+rf.Reason)
+	}
+	for _, l := range d.Lines {
+		line := ReceiptLine{
+			Name:      l.ItemName,
+			Quantity:  l.Quantity,
+			UnitPrice: l.UnitPrice,
+			Amount:    l.Amount,
+		}
+		if line.Name == "" {
+			line.Name = l.IdItem
+		}
+		if l.Restock {
+			line.Details = append(line.Details, "returned to stock")
+		}
+		r.Lines = append(r.Lines, line)
+	}
+	label := strings.ToUpper(rf.Method)
+	if rf.Reference != "" {
+		label += " " + rf.Reference
+	}
+	r.Totals = append(r.Totals,
+		ReceiptAmount{Label: "REFUND TOTAL", Amount: rf.Amount},
+		ReceiptAmount{Label: label, Amount: rf.Amount},
+	)
+	r.Footer = append(r.Footer, "Refund processed")
+	return r
+}
+
+// RenderReceipt lays a receipt out as fixed-width text for thermal printers.
+func RenderReceipt(r *Receipt, width int) string {
+	if width <= 0 {
+		width = ReceiptWidth
+	}
+	var b strings.Builder
+	rule := strings.Repeat("-", width)
+
+	b.WriteString(center(r.Title, width) + "\n")
+	if r.Queue > 0 {
+		b.WriteString(center(fmt.Sprintf("QUEUE NO. %d", r.Queue), width) + "\n")
+	}
+	if r.Number != "" {
+		b.WriteString(truncate("No: "+r.Number, width) + "\n")
+	}
+	if !r.Timestamp.IsZero() {
+		b.WriteString(r.Timestamp.Format("02/01/2006 15:04") + "\n")
+	}
+	for _, h := range r.Header {
+		b.WriteString(truncate(h, width) + "\n")
+	}
+	b.WriteString(rule + "\n")
+	for _, l := range r.Lines {
+		b.WriteString(truncate(l.Name, width) + "\n")
+		for _, d := range l.Details {
+			b.WriteString(truncate("  "+d, width) + "\n")
+		}
+		left := fmt.Sprintf("  %d x %s", l.Quantity, formatMoney(l.UnitPrice))
+		b.WriteString(columns(left, formatMoney(l.Amount), width) + "\n")
+	}
+	b.WriteString(rule + "\n")
+	for _, t := range r.Totals {
+		b.WriteString(columns(t.Label, formatMoney(t.Amount), width) + "\n")
+	}
+	if len(r.Footer) > 0 {
+		b.WriteString(rule + "\n")
+		for _, f := range r.Footer {
+			b.WriteString(center(f, width) + "\n")
+		}
+	}
+	return b.String()
+}
+
+// formatMoney prints an amount the Indonesian way: dots between thousands and
+// a decimal comma only when there are cents.
+func formatMoney(v entity.Money) string {
+	neg := v < 0
+	cents := int64(v)
+	if neg {
+		cents = -cents
+	}
+	whole := strconv.FormatInt(cents/100, 10)
+	var parts []string
+	for len(whole) > 3 {
+		parts = append([]string{whole[len(whole)-3:]}, parts...)
+		whole = whole[:len(whole)-3]
+	}
+	parts = append([]string{whole}, parts...)
+	out := strings.Join(parts, ".")
+	if c := cents % 100; c != 0 {
+		out += fmt.Sprintf(",%02d", c)
+	}
+	if neg {
+		out = "-" + out
+	}
+	return out
+}
+
+func signedMoney(v entity.Money) string {
+	if v > 0 {
+		return "+" + formatMoney(v)
+	}
+	return formatMoney(v)
+}
+
+func columns(left, right string, width int) string {
+	space := width - utf8.RuneCountInString(right) - 1
+	if space < 1 {
+		space = 1
+	}
+	left = truncate(left, space)
+	gap := width - utf8.RuneCountInString(left) - utf8.RuneCountInString(right)
+	if gap < 1 {
+		gap = 1
+	}
+	return left + strings.Repeat(" ", gap) + right
+}
+
+func center(s string, width int) string {
+	s = truncate(s, width)
+	pad := (width - utf8.RuneCountInString(s)) / 2
+	return strings.Repeat(" ", pad) + s
+}
+
+// truncate cuts s to width characters. It counts runes, not bytes, so a
+// multi-byte character is never split; varchar columns count the same way.
+func truncate(s string, width int) string {
+	if utf8.RuneCountInString(s) <= width {
+		return s
+	}
+	return string([]rune(s)[:width])
+}
@@ -0,0 +1,317 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"faizalmaulana/lsp/conf"
+	"faizalmaulana/lsp/helper"
+	"faizalmaulana/lsp/models/entity"
+	"faizalmaulana/lsp/models/repo"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidRefund  = errors.New("invalid refund")
+	ErrRefundNotFound = errors.New("refund not found")
+)
+
+// RefundLineInput returns Quantity units of the transaction line IdPivot.
+type RefundLineInput struct {
+	IdPivot  string
+	Quantity int
+	Restock  bool
+}
+
+type RefundRequest struct {
+	IdTransaction string
+	IdUser        string
+	Reason        string
+	Method        string
+	Reference     string
+	Lines         []RefundLineInput
+}
+
+// RefundLine is a refund line joined with the item it refers to.
+type RefundLine struct {
+	entity.RefundLines
+	ItemName string
+}
+
+type RefundDetail struct {
+	Refund      *entity.Refunds
+	Transaction *entity.Transactions
+	Lines       []RefundLine
+	// Credit is the store credit account opened for the refund, with its
+	// PIN; nil when none was.
+	Credit *IssuedGiftCard
+}
+
+type RefundsService interface {
+	// Create refunds part of a completed transaction. Once every unit of the
+	// sale has been returned the transaction becomes refunded. The refund
+	// is booked on the refunding user's open shift, if any.
+	Create(req RefundRequest) (*RefundDetail, error)
+	Get(id string) (*RefundDetail, error)
+	ListByTransaction(idTransaction string) ([]RefundDetail, error)
+	Receipt(id string) (*Receipt, error)
+}
+
+type refundsService struct {
+	repo      repo.RefundsRepo
+	uow       repo.UnitOfWork
+	txs       repo.TransactionsRepo
+	pivots    repo.PivotItemsToTransactionsRepo
+	items     repo.ItemsRepo
+	loyalty   repo.LoyaltyRepo
+	giftCards repo.GiftCardsRepo
+	shifts    repo.ShiftsRepo
+	closes    repo.DayClosesRepo
+	inventory repo.InventoryRepo
+	events    EventBus
+	cfg       *conf.Config
+}
+
+func NewRefundsService(r repo.RefundsRepo, uow repo.UnitOfWork, txs repo.TransactionsRepo, pivots repo.PivotItemsToTransactionsRepo, items repo.ItemsRepo, loyalty repo.LoyaltyRepo, giftCards repo.GiftCardsRepo, shifts repo.ShiftsRepo, closes repo.DayClosesRepo, inventory repo.InventoryRepo, events EventBus, cfg *conf.Config) RefundsService {
+	return &refundsService{repo: r, uow: uow, txs: txs, pivots: pivots, items: items, loyalty: loyalty, giftCards: giftCards, shifts: shifts, closes: closes, inventory: inventory, events: events, cfg: cfg}
+}
+
+func (s *refundsService) Create(req RefundRequest) (*RefundDetail, error) {
+	method := strings.ToLower(strings.TrimSpace(req.Method))
+	if !validPaymentMethod(method) {
+		return nil, fmt.Errorf("%w: unknown method %q", ErrInvalidRefund, req.Method)
+	}
+	if len(req.Lines) == 0 {
+		return nil, fmt.Errorf("%w: lines required", ErrInvalidRefund)
+	}
+	reason := truncate(strings.TrimSpace(req.Reason), 255)
+
+	refund := &entity.Refunds{
+		IdRefund:      helper.Uuid(),
+		IdTransaction: req.IdTransaction,
+		IdUser:        req.IdUser,
+		Reason:        reason,
+		Method:        method,
+		Reference:     strings.TrimSpace(req.Reference),
+	}
+	var (
+		t      *entity.Transactions
+		credit *IssuedGiftCard
+	)
+	err := s.uow.Do(func(db *gorm.DB) error {
+		// A refund is booked on the day it is made, so refunding a sale of
+		// a closed day is fine; only the closed day itself takes no more.
+		if err := checkPeriodOpen(s.closes.WithTx(db), time.Now()); err != nil {
+			return err
+		}
+		var err error
+		t, err = s.txs.WithTx(db).GetByIDForUpdate(req.IdTransaction)
+		if err != nil {
+			return ErrTransactionNotFound
+		}
+		if t.Status != entity.TransactionStatusCompleted {
+			return fmt.Errorf("%w: a %s transaction cannot be refunded", ErrInvalidTransition, t.Status)
+		}
+		pivots, err := s.pivots.WithTx(db).ListByTransaction(t.IdTransaction)
+		if err != nil {
+			return err
+		}
+		prior, err := s.repo.WithTx(db).ListLinesByTransaction(t.IdTransaction)
+		if err != nil {
+			return err
+		}
+		ids := make([]string, 0, len(pivots))
+		for _, p := range pivots {
+			ids = append(ids, p.IdItem)
+		}
+		kinds, err := s.items.Kinds(ids)
+		if err != nil {
+			return err
+		}
+		// remaining counts the units and left the amount of each line that
+		// have not been refunded yet. Lines are refunded at what was paid
+		// for them: net of discounts, with their service charge and tax.
+		// Gift cards are not refunded, so their lines are left out, and so
+		// is what they cost from what can be refunded.
+		remaining := map[string]int{}
+		left := map[string]entity.Money{}
+		byPivot := map[string]entity.PivotItemsToTransaction{}
+		refundable := t.TotalPrice
+		for _, p := range pivots {
+			kind, ok := kinds[p.IdItem]
+			if !ok {
+				return fmt.Errorf("%w: line %s has no item", ErrInvalidRefund, p.IdPivot)
+			}
+			if kind == entity.ItemKindGiftCard {
+				refundable -= p.LineTotal
+				continue
+			}
+			remaining[p.IdPivot] += p.Quantity
+			left[p.IdPivot] += p.LineTotal
+			byPivot[p.IdPivot] = p
+		}
+		var refunded entity.Money
+		for _, l := range prior {
+			remaining[l.IdPivot] -= l.Quantity
+			left[l.IdPivot] -= l.Amount
+			refunded += l.Amount
+		}
+
+		var amount entity.Money
+		index := map[string]int{}
+		for _, in := range req.Lines {
+			p, ok := byPivot[in.IdPivot]
+			if !ok {
+				for _, g := range pivots {
+					if g.IdPivot == in.IdPivot {
+						return fmt.Errorf("%w: gift cards cannot be refunded", ErrInvalidRefund)
+					}
+				}
+				return fmt.Errorf("%w: line %s is not part of the transaction", ErrInvalidRefund, in.IdPivot)
+			}
+			if in.Quantity <= 0 {
+				return fmt.Errorf("%w: quantity must be positive", ErrInvalidRefund)
+			}
+			if in.Quantity > remaining[in.IdPivot] {
+				return fmt.Errorf("%w: only %d of line %s left to refund", ErrInvalidRefund, remaining[in.IdPivot], in.IdPivot)
+			}
+			// The last units take whatever rounding left over.
+			line := left[in.IdPivot]
+			if in.Quantity < remaining[in.IdPivot] {
+				line = min(line, entity.Money(math.Round(float64(p.LineTotal)*float64(in.Quantity)/float64(p.Quantity))))
+			}
+			remaining[in.IdPivot] -= in.Quantity
+			left[in.IdPivot] -= line
+			amount += line
+			if i, ok := index[in.IdPivot]; ok && refund.Lines[i].Restock == in.Restock {
+				refund.Lines[i].Quantity += in.Quantity
+				refund.Lines[i].Amount += line
+				continue
+			}
+			index[in.IdPivot] = len(refund.Lines)
+			refund.Lines = append(refund.Lines, entity.RefundLines{
+				IdRefundLine:  helper.Uuid(),
+				IdRefund:      refund.IdRefund,
+				IdTransaction: t.IdTransaction,
+				IdPivot:       p.IdPivot,
+				IdItem:        p.IdItem,
+				Quantity:      in.Quantity,
+				UnitPrice:     entity.Money(math.Round(float64(p.LineTotal) / float64(p.Quantity))),
+				Amount:        line,
+				Restock:       in.Restock,
+			})
+		}
+		if refunded+amount > refundable {
+			return fmt.Errorf("%w: only %s left to refund", ErrInvalidRefund, formatMoney(refundable-refunded))
+		}
+		refund.Amount = amount
+		if credit, err = refundToGiftCard(s.giftCards.WithTx(db), t, refund); err != nil {
+			return err
+		}
+		// A refund belongs to the open shift of whoever makes it, whose
+		// drawer pays the cash out.
+		if sh, err := s.shifts.WithTx(db).GetOpenForShare(refund.IdUser); err == nil {
+			refund.IdShift = sh.IdShift
+		}
+		if err := s.repo.WithTx(db).Create(refund); err != nil {
+			return err
+		}
+		if err := restockRefund(s.inventory.WithTx(db), refund, byPivot, prior); err != nil {
+			return err
+		}
+
+		full := true
+		for _, n := range remaining {
+			if n > 0 {
+				full = false
+			}
+		}
+		if err := refundSalePoints(s.loyalty.WithTx(db), loyaltyPolicy(s.cfg), t, refund, refunded, full, time.Now()); err != nil {
+			return err
+		}
+		if !full {
+			return nil
+		}
+		ok, err := s.txs.WithTx(db).Transition(t.IdTransaction, transitionSources(entity.TransactionStatusRefunded), entity.TransactionStatusRefunded, nil)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("%w: the transaction changed status meanwhile", ErrInvalidTransition)
+		}
+		t.Status = entity.TransactionStatusRefunded
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.events.Publish(Event{Type: EventTransactionRefunded, Data: *refund})
+	d := s.detail(refund, t, refund.Lines)
+	d.Credit = credit
+	return d, nil
+}
+
+func (s *refundsService) Get(id string) (*RefundDetail, error) {
+	refund, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, ErrRefundNotFound
+	}
+	t, err := s.txs.GetByID(refund.IdTransaction)
+	if err != nil {
+		return nil, err
+	}
+	lines, err := s.repo.ListLinesByRefunds([]string{refund.IdRefund})
+	if err != nil {
+		return nil, err
+	}
+	return s.detail(refund, t, lines), nil
+}
+
+func (s *refundsService) ListByTransaction(idTransaction string) ([]RefundDetail, error) {
+	t, err := s.txs.GetByID(idTransaction)
+	if err != nil {
+		return nil, ErrTransactionNotFound
+	}
+	refunds, err := s.repo.ListByTransaction(idTransaction)
+	if err != nil {
+		return nil, err
+	}
+	lines, err := s.repo.ListLinesByTransaction(idTransaction)
+	if err != nil {
+		return nil, err
+	}
+	byRefund := map[string][]entity.RefundLines{}
+	for _, l := range lines {
+		byRefund[l.IdRefund] = append(byRefund[l.IdRefund], l)
+	}
+	out := make([]RefundDetail, 0, len(refunds))
+	for i := range refunds {
+		out = append(out, *s.detail(&refunds[i], t, byRefund[refunds[i].IdRefund]))
+	}
+	return out, nil
+}
+
+func (s *refundsService) Receipt(id string) (*Receipt, error) {
+	d, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	return refundReceipt(d), nil
+}
+
+func (s *refundsService) detail(refund *entity.Refunds, t *entity.Transactions, lines []entity.RefundLines) *RefundDetail {
+	refund.Lines = lines
+	out := &RefundDetail{Refund: refund, Transaction: t, Lines: make([]RefundLine, 0, len(lines))}
+	for _, l := range lines {
+		line := RefundLine{RefundLines: l}
+		if it, err := s.items.GetByID(l.IdItem); err == nil {
+			line.ItemName = it.ItemName
+		}
+		out.Lines = append(out.Lines, line)
+	}
+	return out
+}
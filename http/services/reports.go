@@ -0,0 +1,451 @@
+package services
+
+import (
+	"sort"
+	"time"
+
+	"faizalmaulana/lsp/models/entity"
+	"faizalmaulana/lsp/models/repo"
+
+	"gorm.io/gorm"
+)
+
+// ReportSummary aggregates the sales of a period. It backs every
+// /api/reports endpoint so the figures stay consistent between them.
+type ReportSummary struct {
+	Transactions      []entity.Transactions
+	TotalTransactions int
+	SumTotalPrice     entity.Money
+	TotalProductsSold int
+	AverageOrderValue entity.Money
+	MinOrderValue     entity.Money
+	MaxOrderValue     entity.Money
+	AvgItemsPerTx     float64
+	TopItems          []ItemSales
+	PaymentMethods    []PaymentMethodSales
+
+	// DiscountGiven is what promotions and manual discounts took off the
+	// sales above; SumTotalPrice is already net of it. Discounts breaks it
+	// down by promotion.
+	DiscountGiven entity.Money
+	Discounts     []DiscountSales
+
+	// Subtotal, ServiceChargeTotal and TaxTotal split SumTotalPrice into what
+	// the sales came to before tax and service, the service charged and the
+	// tax collected, including the tax contained in inclusive prices.
+	Subtotal           entity.Money
+	ServiceChargeTotal entity.Money
+	TaxTotal           entity.Money
+
+	// GiftCardSales is what was paid for gift cards sold in the period and
+	// GiftCardsSold the number of cards. The cards are a liability until they
+	// are spent, so they are in none of the sales figures above.
+	GiftCardSales entity.Money
+	GiftCardsSold int
+
+	// CashRounding is what rounding cash payments added to the takings,
+	// negative when more was rounded down than up; the payments add up to
+	// SumTotalPrice + GiftCardSales + CashRounding. CashRoundingByDay splits it per day so
+	// the drawer can be reconciled with the books.
+	CashRounding      entity.Money
+	CashRoundingByDay []RoundingSales
+
+	// Voids are the sales of the period that were voided afterwards. They are
+	// not part of any figure above.
+	Voids       []entity.Transactions
+	VoidedCount int
+	VoidedTotal entity.Money
+
+	// Refunds are the refunds made during the period, whenever the sale took
+	// place. NetSales is SumTotalPrice less RefundTotal.
+	Refunds          []entity.Refunds
+	RefundCount      int
+	RefundTotal      entity.Money
+	RefundedProducts int
+	NetSales         entity.Money
+}
+
+// PaymentMethodSales is the revenue settled with one tender type and what was
+// refunded through it. Sales made before payments were recorded are reported
+// under PaymentMethodUnrecorded.
+type PaymentMethodSales struct {
+	Method   string
+	Count    int
+	Amount   entity.Money
+	Refunded entity.Money
+}
+
+const PaymentMethodUnrecorded = "unrecorded"
+
+// RoundingSales is the cash rounding of the sales of one day, Date being
+// formatted as 2006-01-02. Count is the number of rounded sales.
+type RoundingSales struct {
+	Date   string
+	Count  int
+	Amount entity.Money
+}
+
+// DiscountSales is the discount one promotion gave, or all manual discounts
+// together when Source is manual. Count is the number of discounted lines.
+type DiscountSales struct {
+	IdPromotion string
+	Name        string
+	Source      string
+	Count       int
+	Amount      entity.Money
+}
+
+// ItemSales holds the direct sales of an item; BundleQuantity and
+// BundleRevenue add what was sold of it as part of bundles. RefundedQuantity
+// and RefundedRevenue are what was returned of it during the period. Revenue
+// is before discounts, which are in Discount.
+type ItemSales struct {
+	IdItem           string
+	ItemName         string
+	ImageUrl         string
+	QuantitySold     int
+	Revenue          entity.Money
+	Discount         entity.Money
+	BundleQuantity   int
+	BundleRevenue    entity.Money
+	RefundedQuantity int
+	RefundedRevenue  entity.Money
+	Modifiers        []ModifierSales
+}
+
+// ModifierSales counts how often an option was sold on an item and the extra
+// revenue its price delta brought in.
+type ModifierSales struct {
+	GroupName    string
+	OptionName   string
+	QuantitySold int
+	Revenue      entity.Money
+}
+
+type ReportsService interface {
+	Summarize(from, to time.Time, topN int) (*ReportSummary, error)
+	// WithTx returns the service reading the sales through the database
+	// transaction tx.
+	WithTx(tx *gorm.DB) ReportsService
+}
+
+type reportsService struct {
+	txs       repo.TransactionsRepo
+	pivots    repo.PivotItemsToTransactionsRepo
+	lineMods  repo.PivotLineModifiersRepo
+	lineComps repo.PivotLineComponentsRepo
+	lineDisc  repo.PivotLineDiscountsRepo
+	payments  repo.PaymentsRepo
+	refunds   repo.RefundsRepo
+	items     repo.ItemsRepo
+}
+
+func NewReportsService(txs repo.TransactionsRepo, pivots repo.PivotItemsToTransactionsRepo, lineMods repo.PivotLineModifiersRepo, lineComps repo.PivotLineComponentsRepo, lineDisc repo.PivotLineDiscountsRepo, payments repo.PaymentsRepo, refunds repo.RefundsRepo, items repo.ItemsRepo) ReportsService {
+	return &reportsService{txs: txs, pivots: pivots, lineMods: lineMods, lineComps: lineComps, lineDisc: lineDisc, payments: payments, refunds: refunds, items: items}
+}
+
+func (s *reportsService) WithTx(tx *gorm.DB) ReportsService {
+	return &reportsService{txs: s.txs.WithTx(tx), pivots: s.pivots.WithTx(tx), lineMods: s.lineMods.WithTx(tx), lineComps: s.lineComps.WithTx(tx), lineDisc: s.lineDisc.WithTx(tx), payments: s.payments.WithTx(tx), refunds: s.refunds.WithTx(tx), items: s.items}
+}
+
+// Summarize aggregates the paid transactions with from <= timestamp < to,
+// including the ones refunded since, and nets out the refunds made in the
+// same period. topN limits the number of top items returned; 0 returns all
+// of them.
+func (s *reportsService) Summarize(from, to time.Time, topN int) (*ReportSummary, error) {
+	list, err := s.txs.ListBetween(from, to, entity.TransactionStatusCompleted, entity.TransactionStatusRefunded)
+	if err != nil {
+		return nil, err
+	}
+	out := &ReportSummary{Transactions: make([]entity.Transactions, 0, len(list))}
+	ids := make([]string, 0, len(list))
+	// The shares of a bill split equally each carry all of its lines; only
+	// the first one counts the units.
+	share := map[string]bool{}
+	for _, t := range list {
+		ids = append(ids, t.IdTransaction)
+		share[t.IdTransaction] = t.SplitPart > 1
+	}
+	pivots, err := s.pivots.ListByTransactions(ids)
+	if err != nil {
+		return nil, err
+	}
+	itemIds := make([]string, 0, len(pivots))
+	for _, p := range pivots {
+		itemIds = append(itemIds, p.IdItem)
+	}
+	kinds, err := s.items.Kinds(itemIds)
+	if err != nil {
+		return nil, err
+	}
+	// Gift card lines carry neither tax nor service charge, so their face
+	// value comes straight off the sale's total and subtotal.
+	giftCards := map[string]entity.Money{}
+	for _, p := range pivots {
+		if kinds[p.IdItem] != entity.ItemKindGiftCard {
+			continue
+		}
+		giftCards[p.IdTransaction] += p.LineTotal
+		out.GiftCardSales += p.LineTotal
+		if !share[p.IdTransaction] {
+			out.GiftCardsSold += p.Quantity
+		}
+	}
+	for _, t := range list {
+		out.Transactions = append(out.Transactions, *t)
+		value := t.TotalPrice - giftCards[t.IdTransaction]
+		out.TotalTransactions++
+		out.SumTotalPrice += value
+		out.DiscountGiven += t.DiscountTotal
+		out.Subtotal += t.Subtotal - giftCards[t.IdTransaction]
+		out.ServiceChargeTotal += t.ServiceCharge
+		out.TaxTotal += t.TaxTotal
+		if out.MinOrderValue == 0 || value < out.MinOrderValue {
+			out.MinOrderValue = value
+		}
+		if value > out.MaxOrderValue {
+			out.MaxOrderValue = value
+		}
+		out.CashRounding += t.CashRounding
+	}
+	out.CashRoundingByDay = roundingSales(list, from.Location())
+
+	mods, err := s.lineMods.ListByTransactions(ids)
+	if err != nil {
+		return nil, err
+	}
+	comps, err := s.lineComps.ListByTransactions(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	type modKey struct{ item, group, option string }
+	perItem := map[string]*ItemSales{}
+	perMod := map[modKey]*ModifierSales{}
+	lineItem := map[string]string{}
+	lineQty := map[string]int{}
+	sales := func(idItem string) *ItemSales {
+		a := perItem[idItem]
+		if a == nil {
+			a = &ItemSales{IdItem: idItem}
+			perItem[idItem] = a
+		}
+		return a
+	}
+	lineUnits := map[string]int{}
+	for _, p := range pivots {
+		if kinds[p.IdItem] == entity.ItemKindGiftCard {
+			continue
+		}
+		units := p.Quantity
+		if share[p.IdTransaction] {
+			units = 0
+		}
+		out.TotalProductsSold += units
+		a := sales(p.IdItem)
+		a.QuantitySold += units
+		a.Revenue += p.Price.Mul(p.Quantity)
+		a.Discount += p.Discount
+		lineItem[p.IdPivot] = p.IdItem
+		lineQty[p.IdPivot] = p.Quantity
+		lineUnits[p.IdPivot] = units
+	}
+	for _, m := range mods {
+		idItem, ok := lineItem[m.IdPivot]
+		if !ok {
+			continue
+		}
+		k := modKey{idItem, m.GroupName, m.OptionName}
+		ms := perMod[k]
+		if ms == nil {
+			ms = &ModifierSales{GroupName: m.GroupName, OptionName: m.OptionName}
+			perMod[k] = ms
+		}
+		ms.QuantitySold += lineUnits[m.IdPivot]
+		ms.Revenue += m.PriceDelta.Mul(lineQty[m.IdPivot])
+	}
+	for _, c := range comps {
+		a := sales(c.IdItem)
+		if !share[c.IdTransaction] {
+			a.BundleQuantity += c.Quantity
+		}
+		a.BundleRevenue += c.Revenue
+	}
+	discounts, err := s.lineDisc.ListByTransactions(ids)
+	if err != nil {
+		return nil, err
+	}
+	out.Discounts = discountSales(discounts)
+
+	refunds, err := s.refunds.ListBetween(from, to)
+	if err != nil {
+		return nil, err
+	}
+	refundIds := make([]string, 0, len(refunds))
+	for _, r := range refunds {
+		refundIds = append(refundIds, r.IdRefund)
+		out.RefundCount++
+		out.RefundTotal += r.Amount
+	}
+	out.Refunds = refunds
+	refundLines, err := s.refunds.ListLinesByRefunds(refundIds)
+	if err != nil {
+		return nil, err
+	}
+	for _, l := range refundLines {
+		out.RefundedProducts += l.Quantity
+		a := sales(l.IdItem)
+		a.RefundedQuantity += l.Quantity
+		a.RefundedRevenue += l.Amount
+	}
+	out.NetSales = out.SumTotalPrice - out.RefundTotal
+
+	for k, ms := range perMod {
+		perItem[k.item].Modifiers = append(perItem[k.item].Modifiers, *ms)
+	}
+
+	top := make([]ItemSales, 0, len(perItem))
+	for id, a := range perItem {
+		it, err := s.items.GetByID(id)
+		if err != nil {
+			continue
+		}
+		a.ItemName = it.ItemName
+		a.ImageUrl = it.ImageUrl
+		sort.Slice(a.Modifiers, func(i, j int) bool {
+			if a.Modifiers[i].QuantitySold != a.Modifiers[j].QuantitySold {
+				return a.Modifiers[i].QuantitySold > a.Modifiers[j].QuantitySold
+			}
+			return a.Modifiers[i].GroupName+a.Modifiers[i].OptionName < a.Modifiers[j].GroupName+a.Modifiers[j].OptionName
+		})
+		top = append(top, *a)
+	}
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].QuantitySold != top[j].QuantitySold {
+			return top[i].QuantitySold > top[j].QuantitySold
+		}
+		if top[i].Revenue != top[j].Revenue {
+			return top[i].Revenue > top[j].Revenue
+		}
+		return top[i].IdItem < top[j].IdItem
+	})
+	if topN > 0 && len(top) > topN {
+		top = top[:topN]
+	}
+	out.TopItems = top
+
+	payments, err := s.payments.ListByTransactions(ids)
+	if err != nil {
+		return nil, err
+	}
+	out.PaymentMethods = paymentMethodSales(list, payments, refunds)
+
+	voids, err := s.txs.ListBetween(from, to, entity.TransactionStatusVoided)
+	if err != nil {
+		return nil, err
+	}
+	out.Voids = make([]entity.Transactions, 0, len(voids))
+	for _, t := range voids {
+		out.Voids = append(out.Voids, *t)
+		out.VoidedCount++
+		out.VoidedTotal += t.TotalPrice
+	}
+
+	if out.TotalTransactions > 0 {
+		out.AverageOrderValue = entity.NewMoney(out.SumTotalPrice.Float64() / float64(out.TotalTransactions))
+		out.AvgItemsPerTx = float64(out.TotalProductsSold) / float64(out.TotalTransactions)
+	}
+	return out, nil
+}
+
+// roundingSales sums the cash rounding of txs per day in loc, oldest day
+// first. Days without rounding are left out.
+func roundingSales(txs []*entity.Transactions, loc *time.Location) []RoundingSales {
+	perDay := map[string]*RoundingSales{}
+	var days []string
+	for _, t := range txs {
+		if t.CashRounding == 0 {
+			continue
+		}
+		day := t.Timestamp.In(loc).Format("2006-01-02")
+		r := perDay[day]
+		if r == nil {
+			r = &RoundingSales{Date: day}
+			perDay[day] = r
+			days = append(days, day)
+		}
+		r.Count++
+		r.Amount += t.CashRounding
+	}
+	sort.Strings(days)
+	out := make([]RoundingSales, 0, len(days))
+	for _, day := range days {
+		out = append(out, *perDay[day])
+	}
+	return out
+}
+
+func discountSales(discounts []entity.PivotLineDiscounts) []DiscountSales {
+	perSource := map[string]*DiscountSales{}
+	var order []string
+	for _, d := range discounts {
+		key := d.IdPromotion
+		if d.Source == entity.DiscountSourceManual {
+			key = entity.DiscountSourceManual
+		}
+		ds := perSource[key]
+		if ds == nil {
+			ds = &DiscountSales{IdPromotion: d.IdPromotion, Name: d.Name, Source: d.Source}
+			perSource[key] = ds
+			order = append(order, key)
+		}
+		ds.Count++
+		ds.Amount += d.Amount
+	}
+	out := make([]DiscountSales, 0, len(order))
+	for _, key := range order {
+		out = append(out, *perSource[key])
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Amount > out[j].Amount })
+	return out
+}
+
+func paymentMethodSales(txs []*entity.Transactions, payments []entity.Payments, refunds []entity.Refunds) []PaymentMethodSales {
+	perMethod := map[string]*PaymentMethodSales{}
+	get := func(method string) *PaymentMethodSales {
+		m := perMethod[method]
+		if m == nil {
+			m = &PaymentMethodSales{Method: method}
+			perMethod[method] = m
+		}
+		return m
+	}
+	add := func(method string, amount entity.Money) {
+		m := get(method)
+		m.Count++
+		m.Amount += amount
+	}
+	for _, r := range refunds {
+		m := get(r.Method)
+		m.Refunded += r.Amount
+	}
+	paid := map[string]bool{}
+	for _, p := range payments {
+		paid[p.IdTransaction] = true
+		add(p.Method, p.Amount)
+	}
+	for _, t := range txs {
+		if !paid[t.IdTransaction] {
+			add(PaymentMethodUnrecorded, t.TotalPrice)
+		}
+	}
+
+	out := make([]PaymentMethodSales, 0, len(perMethod))
+	order := append(append([]string{}, PaymentMethods...), PaymentMethodUnrecorded)
+	for _, method := range order {
+		if m := perMethod[method]; m != nil {
+			out = append(out, *m)
+		}
+	}
+	return out
+}
@@ -0,0 +1,432 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"faizalmaulana/lsp/conf"
+	"faizalmaulana/lsp/helper"
+	"faizalmaulana/lsp/models/entity"
+	"faizalmaulana/lsp/models/repo"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidShift   = errors.New("invalid shift")
+	ErrShiftNotFound  = errors.New("shift not found")
+	ErrNoOpenShift    = errors.New("no open shift")
+	ErrShiftOpen      = errors.New("shift already open")
+	ErrShiftClosed    = errors.New("shift is closed")
+	ErrShiftForbidden = errors.New("shift belongs to another cashier")
+)
+
+const (
+	// ShiftReportX is the running report of an open shift, ShiftReportZ the
+	// final one of a closed shift.
+	ShiftReportX = "X"
+	ShiftReportZ = "Z"
+)
+
+// ShiftActor is the user acting on a shift. Cashiers may only act on their
+// own shifts; managers and admins on anyone's.
+type ShiftActor struct {
+	IdUser string
+	Role   string
+}
+
+type ShiftMovementInput struct {
+	Kind   string
+	Amount entity.Money
+	Reason string
+}
+
+// DenominationCount is the number of notes or coins of one denomination
+// counted in the drawer.
+type DenominationCount struct {
+	Denomination entity.Money
+	Quantity     int
+}
+
+type ShiftDetail struct {
+	Shift     *entity.Shifts
+	Movements []entity.ShiftMovements
+	Counts    []entity.ShiftCounts
+}
+
+// ShiftCash is how much cash should be in the drawer: the opening float,
+// plus cash taken on the shift's sales and put in, less cash refunds paid
+// out, petty cash and drops. Counted and Difference are only known once
+// the shift is closed.
+type ShiftCash struct {
+	OpeningFloat entity.Money
+	Sales        entity.Money
+	Refunds      entity.Money
+	CashIn       entity.Money
+	CashOut      entity.Money
+	Drops        entity.Money
+	Expected     entity.Money
+	Counted      entity.Money
+	Difference   entity.Money
+}
+
+// ShiftReport sums up the sales of a shift: an X report while it is open,
+// the Z report once it is closed. Sales counts the completed sales,
+// including the ones refunded since; voided sales are only in Voided*.
+type ShiftReport struct {
+	Kind          string
+	Shift         *entity.Shifts
+	GeneratedAt   time.Time
+	Sales         int
+	SalesTotal    entity.Money
+	Subtotal      entity.Money
+	DiscountTotal entity.Money
+	ServiceCharge entity.Money
+	TaxTotal      entity.Money
+	CashRounding  entity.Money
+	Payments      []PaymentMethodSales
+	RefundCount   int
+	RefundTotal   entity.Money
+	NetSales      entity.Money
+	VoidedCount   int
+	VoidedTotal   entity.Money
+	Movements     []entity.ShiftMovements
+	Counts        []entity.ShiftCounts
+	Cash          ShiftCash
+}
+
+type ShiftsService interface {
+	// Open starts a shift for the user with the cash put in the drawer.
+	Open(idUser string, openingFloat entity.Money, note string) (*entity.Shifts, error)
+	// Current returns the X report of the user's open shift.
+	Current(idUser string) (*ShiftReport, error)
+	Get(id string, by ShiftActor) (*ShiftDetail, error)
+	GetAll(status, idUser string, limit, page int) ([]entity.Shifts, error)
+	AddMovement(id string, by ShiftActor, in ShiftMovementInput) (*entity.ShiftMovements, error)
+	// Close records the cash counted in the drawer, works out what should
+	// have been in it and returns the Z report.
+	Close(id string, by ShiftActor, counts []DenominationCount, note string) (*ShiftReport, error)
+	Report(id string, by ShiftActor) (*ShiftReport, error)
+}
+
+type shiftsService struct {
+	repo     repo.ShiftsRepo
+	uow      repo.UnitOfWork
+	txs      repo.TransactionsRepo
+	payments repo.PaymentsRepo
+	refunds  repo.RefundsRepo
+	closes   repo.DayClosesRepo
+	cfg      *conf.Config
+}
+
+func NewShiftsService(r repo.ShiftsRepo, uow repo.UnitOfWork, txs repo.TransactionsRepo, payments repo.PaymentsRepo, refunds repo.RefundsRepo, closes repo.DayClosesRepo, cfg *conf.Config) ShiftsService {
+	return &shiftsService{repo: r, uow: uow, txs: txs, payments: payments, refunds: refunds, closes: closes, cfg: cfg}
+}
+
+func (s *shiftsService) Open(idUser string, openingFloat entity.Money, note string) (*entity.Shifts, error) {
+	if openingFloat < 0 {
+		return nil, fmt.Errorf("%w: opening float must not be negative", ErrInvalidShift)
+	}
+	if _, err := s.repo.GetOpenByUser(idUser); err == nil {
+		return nil, ErrShiftOpen
+	}
+	sh := &entity.Shifts{
+		IdShift:      helper.Uuid(),
+		IdUser:       idUser,
+		Status:       entity.ShiftStatusOpen,
+		OpeningFloat: openingFloat,
+		OpenNote:     truncate(strings.TrimSpace(note), 255),
+	}
+	err := s.uow.Do(func(db *gorm.DB) error {
+		// A shift belongs to the day it opens on, which must not be closed.
+		if err := checkPeriodOpen(s.closes.WithTx(db), time.Now()); err != nil {
+			return err
+		}
+		return s.repo.WithTx(db).Create(sh)
+	})
+	if err != nil {
+		// The partial unique index on open shifts rejects a second one
+		// opened at the same time.
+		if _, found := s.repo.GetOpenByUser(idUser); found == nil {
+			return nil, ErrShiftOpen
+		}
+		return nil, err
+	}
+	return sh, nil
+}
+
+func (s *shiftsService) Current(idUser string) (*ShiftReport, error) {
+	sh, err := s.repo.GetOpenByUser(idUser)
+	if err != nil {
+		return nil, ErrNoOpenShift
+	}
+	return shiftReport(sh, s.repo, s.txs, s.payments, s.refunds)
+}
+
+func (s *shiftsService) Get(id string, by ShiftActor) (*ShiftDetail, error) {
+	sh, err := s.get(id, by)
+	if err != nil {
+		return nil, err
+	}
+	movements, err := s.repo.ListMovements(id)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := s.repo.ListCounts(id)
+	if err != nil {
+		return nil, err
+	}
+	return &ShiftDetail{Shift: sh, Movements: movements, Counts: counts}, nil
+}
+
+func (s *shiftsService) GetAll(status, idUser string, limit, page int) ([]entity.Shifts, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	if page <= 0 {
+		page = 1
+	}
+	return s.repo.ListPage(status, idUser, limit, (page-1)*limit)
+}
+
+func (s *shiftsService) AddMovement(id string, by ShiftActor, in ShiftMovementInput) (*entity.ShiftMovements, error) {
+	kind := strings.ToLower(strings.TrimSpace(in.Kind))
+	switch kind {
+	case entity.ShiftMovementCashIn, entity.ShiftMovementCashOut, entity.ShiftMovementDrop:
+	default:
+		return nil, fmt.Errorf("%w: kind must be %s, %s or %s", ErrInvalidShift, entity.ShiftMovementCashIn, entity.ShiftMovementCashOut, entity.ShiftMovementDrop)
+	}
+	if in.Amount <= 0 {
+		return nil, fmt.Errorf("%w: amount must be positive", ErrInvalidShift)
+	}
+	reason := strings.TrimSpace(in.Reason)
+	if kind == entity.ShiftMovementCashOut && reason == "" {
+		return nil, fmt.Errorf("%w: paying out petty cash needs a reason", ErrInvalidShift)
+	}
+	m := &entity.ShiftMovements{
+		IdShiftMovement: helper.Uuid(),
+		IdShift:         id,
+		IdUser:          by.IdUser,
+		Kind:            kind,
+		Amount:          in.Amount,
+		Reason:          truncate(reason, 255),
+	}
+	err := s.uow.Do(func(db *gorm.DB) error {
+		r := s.repo.WithTx(db)
+		sh, err := r.GetForUpdate(id)
+		if err != nil {
+			return ErrShiftNotFound
+		}
+		if err := checkShiftActor(sh, by); err != nil {
+			return err
+		}
+		if sh.Status != entity.ShiftStatusOpen {
+			return ErrShiftClosed
+		}
+		return r.CreateMovement(m)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (s *shiftsService) Close(id string, by ShiftActor, counts []DenominationCount, note string) (*ShiftReport, error) {
+	rows, counted, err := s.countRows(id, counts)
+	if err != nil {
+		return nil, err
+	}
+	err = s.uow.Do(func(db *gorm.DB) error {
+		r := s.repo.WithTx(db)
+		// The lock waits for the sales and refunds still being written to
+		// the shift, which share it, so the expected cash includes them.
+		sh, err := r.GetForUpdate(id)
+		if err != nil {
+			return ErrShiftNotFound
+		}
+		if err := checkShiftActor(sh, by); err != nil {
+			return err
+		}
+		if sh.Status != entity.ShiftStatusOpen {
+			return ErrShiftClosed
+		}
+		report, err := shiftReport(sh, r, s.txs.WithTx(db), s.payments.WithTx(db), s.refunds.WithTx(db))
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		sh.ClosedAt = &now
+		sh.ClosedBy = by.IdUser
+		sh.ExpectedCash = report.Cash.Expected
+		sh.CountedCash = counted
+		sh.Difference = counted - report.Cash.Expected
+		sh.CloseNote = truncate(strings.TrimSpace(note), 255)
+		if err := r.Close(sh); err != nil {
+			return err
+		}
+		return r.BulkCreateCounts(rows)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.Report(id, by)
+}
+
+// countRows turns the counts entered at close into rows, merging repeated
+// denominations and leaving out empty ones. Denominations must be among
+// CASH_DENOMINATIONS.
+func (s *shiftsService) countRows(id string, counts []DenominationCount) ([]entity.ShiftCounts, entity.Money, error) {
+	known := map[entity.Money]bool{}
+	for _, d := range s.cfg.CashDenominations {
+		known[d] = true
+	}
+	perDenomination := map[entity.Money]int{}
+	for _, c := range counts {
+		if c.Quantity < 0 {
+			return nil, 0, fmt.Errorf("%w: quantity of %s must not be negative", ErrInvalidShift, formatMoney(c.Denomination))
+		}
+		if !known[c.Denomination] {
+			return nil, 0, fmt.Errorf("%w: %s is not a denomination", ErrInvalidShift, formatMoney(c.Denomination))
+		}
+		perDenomination[c.Denomination] += c.Quantity
+	}
+	var (
+		rows    []entity.ShiftCounts
+		counted entity.Money
+	)
+	for _, d := range s.cfg.CashDenominations {
+		qty := perDenomination[d]
+		if qty == 0 {
+			continue
+		}
+		rows = append(rows, entity.ShiftCounts{IdShiftCount: helper.Uuid(), IdShift: id, Denomination: d, Quantity: qty, Amount: d.Mul(qty)})
+		counted += d.Mul(qty)
+	}
+	return rows, counted, nil
+}
+
+func (s *shiftsService) Report(id string, by ShiftActor) (*ShiftReport, error) {
+	sh, err := s.get(id, by)
+	if err != nil {
+		return nil, err
+	}
+	return shiftReport(sh, s.repo, s.txs, s.payments, s.refunds)
+}
+
+func (s *shiftsService) get(id string, by ShiftActor) (*entity.Shifts, error) {
+	sh, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, ErrShiftNotFound
+	}
+	if err := checkShiftActor(sh, by); err != nil {
+		return nil, err
+	}
+	return sh, nil
+}
+
+func checkShiftActor(sh *entity.Shifts, by ShiftActor) error {
+	if sh.IdUser == by.IdUser || by.Role == entity.RoleAdmin || by.Role == entity.RoleManager {
+		return nil
+	}
+	return ErrShiftForbidden
+}
+
+// currentShift returns the user's open shift, or nil when they have none
+// and shifts are not required.
+func currentShift(r repo.ShiftsRepo, idUser string, required bool) (*entity.Shifts, error) {
+	sh, err := r.GetOpenByUser(idUser)
+	if err != nil {
+		if required {
+			return nil, fmt.Errorf("%w: open a shift before ringing up sales", ErrNoOpenShift)
+		}
+		return nil, nil
+	}
+	return sh, nil
+}
+
+// shiftReport adds up what happened on the shift. For an open shift the
+// figures are a snapshot; the repos may be bound to a database transaction.
+func shiftReport(sh *entity.Shifts, shifts repo.ShiftsRepo, txs repo.TransactionsRepo, payments repo.PaymentsRepo, refunds repo.RefundsRepo) (*ShiftReport, error) {
+	out := &ShiftReport{Kind: ShiftReportX, Shift: sh, GeneratedAt: time.Now()}
+	if sh.Status == entity.ShiftStatusClosed {
+		out.Kind = ShiftReportZ
+	}
+	list, err := txs.ListByShift(sh.IdShift)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		sold []*entity.Transactions
+		ids  []string
+	)
+	for _, t := range list {
+		switch t.Status {
+		case entity.TransactionStatusCompleted, entity.TransactionStatusRefunded:
+			sold = append(sold, t)
+			ids = append(ids, t.IdTransaction)
+			out.Sales++
+			out.SalesTotal += t.TotalPrice
+			out.Subtotal += t.Subtotal
+			out.DiscountTotal += t.DiscountTotal
+			out.ServiceCharge += t.ServiceCharge
+			out.TaxTotal += t.TaxTotal
+			out.CashRounding += t.CashRounding
+		case entity.TransactionStatusVoided:
+			out.VoidedCount++
+			out.VoidedTotal += t.TotalPrice
+		}
+	}
+	paid, err := payments.ListByTransactions(ids)
+	if err != nil {
+		return nil, err
+	}
+	back, err := refunds.ListByShift(sh.IdShift)
+	if err != nil {
+		return nil, err
+	}
+	out.Payments = paymentMethodSales(sold, paid, back)
+	if out.Movements, err = shifts.ListMovements(sh.IdShift); err != nil {
+		return nil, err
+	}
+	if out.Counts, err = shifts.ListCounts(sh.IdShift); err != nil {
+		return nil, err
+	}
+
+	cash := ShiftCash{OpeningFloat: sh.OpeningFloat}
+	for _, p := range paid {
+		if p.Method == entity.PaymentMethodCash {
+			cash.Sales += p.Amount
+		}
+	}
+	for _, r := range back {
+		out.RefundCount++
+		out.RefundTotal += r.Amount
+		if r.Method == entity.PaymentMethodCash {
+			cash.Refunds += r.Amount
+		}
+	}
+	for _, m := range out.Movements {
+		switch m.Kind {
+		case entity.ShiftMovementCashIn:
+			cash.CashIn += m.Amount
+		case entity.ShiftMovementCashOut:
+			cash.CashOut += m.Amount
+		case entity.ShiftMovementDrop:
+			cash.Drops += m.Amount
+		}
+	}
+	cash.Expected = cash.OpeningFloat + cash.Sales + cash.CashIn - cash.Refunds - cash.CashOut - cash.Drops
+	if out.Kind == ShiftReportZ {
+		cash.Expected = sh.ExpectedCash
+		cash.Counted = sh.CountedCash
+		cash.Difference = sh.Difference
+	}
+	out.Cash = cash
+	out.NetSales = out.SalesTotal - out.RefundTotal
+	return out, nil
+}
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+
+	"faizalmaulana/lsp/helper"
+	"faizalmaulana/lsp/models/entity"
+	"faizalmaulana/lsp/models/repo"
+)
+
+var (
+	ErrInvalidSupplier  = errors.New("invalid supplier")
+	ErrSupplierNotFound = errors.New("supplier not found")
+)
+
+type SuppliersService interface {
+	Create(s *entity.Suppliers) (*entity.Suppliers, error)
+	GetByID(id string) (*entity.Suppliers, error)
+	// GetAll returns the suppliers by name; search matches the name or the
+	// contact name.
+	GetAll(search string) ([]entity.Suppliers, error)
+	Update(id string, s *entity.Suppliers) (*entity.Suppliers, error)
+	// Delete keeps the supplier's purchase orders; new ones cannot be
+	// placed with it.
+	Delete(id string) error
+}
+
+type suppliersService struct {
+	repo repo.SuppliersRepo
+}
+
+func NewSuppliersService(r repo.SuppliersRepo) SuppliersService {
+	return &suppliersService{repo: r}
+}
+
+func (s *suppliersService) Create(sup *entity.Suppliers) (*entity.Suppliers, error) {
+	if sup == nil {
+		return nil, errors.New("invalid input")
+	}
+	if err := validateSupplier(sup); err != nil {
+		return nil, err
+	}
+	if sup.IdSupplier == "" {
+		sup.IdSupplier = helper.Uuid()
+	}
+	if err := s.repo.Create(sup); err != nil {
+		return nil, err
+	}
+	return sup, nil
+}
+
+func (s *suppliersService) GetByID(id string) (*entity.Suppliers, error) {
+	sup, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, ErrSupplierNotFound
+	}
+	return sup, nil
+}
+
+func (s *suppliersService) GetAll(search string) ([]entity.Suppliers, error) {
+	return s.repo.List(strings.TrimSpace(search))
+}
+
+func (s *suppliersService) Update(id string, sup *entity.Suppliers) (*entity.Suppliers, error) {
+	if sup == nil {
+		return nil, errors.New("invalid input")
+	}
+	if _, err := s.repo.GetByID(id); err != nil {
+		return nil, ErrSupplierNotFound
+	}
+	sup.IdSupplier = id
+	if err := validateSupplier(sup); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Update(sup); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(id)
+}
+
+func (s *suppliersService) Delete(id string) error {
+	if _, err := s.repo.GetByID(id); err != nil {
+		return ErrSupplierNotFound
+	}
+	return s.repo.Delete(id)
+}
+
+func validateSupplier(sup *entity.Suppliers) error {
+	sup.Name = truncate(strings.TrimSpace(sup.Name), 150)
+	if sup.Name == "" {
+		return fmt.Errorf("%w: name required", ErrInvalidSupplier)
+	}
+	sup.ContactName = truncate(strings.TrimSpace(sup.ContactName), 100)
+	sup.Phone = truncate(strings.TrimSpace(sup.Phone), 30)
+	sup.Address = truncate(strings.TrimSpace(sup.Address), 255)
+	sup.Note = truncate(strings.TrimSpace(sup.Note), 255)
+	sup.Email = strings.TrimSpace(sup.Email)
+	if sup.Email != "" {
+		addr, err := mail.ParseAddress(sup.Email)
+		if err != nil || addr.Address != sup.Email || len(sup.Email) > 100 {
+			return fmt.Errorf("%w: email %q is not a valid address", ErrInvalidSupplier, sup.Email)
+		}
+		sup.Email = strings.ToLower(sup.Email)
+	}
+	return nil
+}
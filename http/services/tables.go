@@ -0,0 +1,322 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"faizalmaulana/lsp/helper"
+	"faizalmaulana/lsp/models/entity"
+	"faizalmaulana/lsp/models/repo"
+)
+
+var (
+	ErrInvalidTable      = errors.New("invalid table")
+	ErrTableNotFound     = errors.New("table not found")
+	ErrTableAreaNotFound = errors.New("table area not found")
+	ErrTableAreaInUse    = errors.New("table area still has tables")
+	ErrTableOccupied     = errors.New("table is occupied")
+	ErrTableFree         = errors.New("table has no open order")
+)
+
+const (
+	TableStatusFree     = "free"
+	TableStatusOccupied = "occupied"
+	TableStatusInactive = "inactive"
+)
+
+// FloorArea is an area of the floor with its tables as they are now.
+type FloorArea struct {
+	Area   entity.TableAreas `json:"area"`
+	Tables []FloorTable      `json:"tables"`
+}
+
+// FloorTable is a table and what is open on it. JoinedTo is the table whose
+// order a merged table is served on.
+type FloorTable struct {
+	entity.DiningTables
+	Status   string       `json:"status"`
+	Guests   int          `json:"guests"`
+	JoinedTo string       `json:"joined_to,omitempty"`
+	Since    *time.Time   `json:"since,omitempty"`
+	Orders   []FloorOrder `json:"orders"`
+}
+
+// FloorOrder sums up an open order on a table. Estimate is what its lines
+// come to at the prices they were entered at, before discounts, service
+// charge and tax.
+type FloorOrder struct {
+	IdHeldOrder string       `json:"id_held_order"`
+	Label       string       `json:"label"`
+	Guests      int          `json:"guests"`
+	Lines       int          `json:"lines"`
+	Items       int          `json:"items"`
+	Estimate    entity.Money `json:"estimate"`
+	SplitPart   int          `json:"split_part,omitempty"`
+	SplitParts  int          `json:"split_parts,omitempty"`
+	Timestamp   time.Time    `json:"timestamp"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+}
+
+type TablesService interface {
+	CreateArea(a *entity.TableAreas) (*entity.TableAreas, error)
+	GetArea(id string) (*entity.TableAreas, error)
+	GetAreas() ([]entity.TableAreas, error)
+	UpdateArea(id string, a *entity.TableAreas) (*entity.TableAreas, error)
+	// DeleteArea refuses areas that still have tables.
+	DeleteArea(id string) error
+
+	Create(t *entity.DiningTables) (*entity.DiningTables, error)
+	GetByID(id string) (*entity.DiningTables, error)
+	// GetAll returns the tables of an area, or of every area when it is
+	// empty.
+	GetAll(idTableArea string) ([]entity.DiningTables, error)
+	Update(id string, t *entity.DiningTables) (*entity.DiningTables, error)
+	// Delete refuses tables with an open order.
+	Delete(id string) error
+
+	// Floor shows every area with its tables, whether they are free, and
+	// the orders open on them.
+	Floor() ([]FloorArea, error)
+}
+
+type tablesService struct {
+	repo repo.TablesRepo
+	held repo.HeldOrdersRepo
+}
+
+func NewTablesService(r repo.TablesRepo, held repo.HeldOrdersRepo) TablesService {
+	return &tablesService{repo: r, held: held}
+}
+
+func (s *tablesService) CreateArea(a *entity.TableAreas) (*entity.TableAreas, error) {
+	if a == nil {
+		return nil, errors.New("invalid input")
+	}
+	a.Name = truncate(strings.TrimSpace(a.Name), 100)
+	if a.Name == "" {
+		return nil, fmt.Errorf("%w: name required", ErrInvalidTable)
+	}
+	if a.IdTableArea == "" {
+		a.IdTableArea = helper.Uuid()
+	}
+	if err := s.repo.CreateArea(a); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func (s *tablesService) GetArea(id string) (*entity.TableAreas, error) {
+	a, err := s.repo.GetArea(id)
+	if err != nil {
+		return nil, ErrTableAreaNotFound
+	}
+	return a, nil
+}
+
+func (s *tablesService) GetAreas() ([]entity.TableAreas, error) {
+	return s.repo.ListAreas()
+}
+
+func (s *tablesService) UpdateArea(id string, a *entity.TableAreas) (*entity.TableAreas, error) {
+	if a == nil {
+		return nil, errors.New("invalid input")
+	}
+	if _, err := s.repo.GetArea(id); err != nil {
+		return nil, ErrTableAreaNotFound
+	}
+	a.IdTableArea = id
+	a.Name = truncate(strings.TrimSpace(a.Name), 100)
+	if a.Name == "" {
+		return nil, fmt.Errorf("%w: name required", ErrInvalidTable)
+	}
+	if err := s.repo.UpdateArea(a); err != nil {
+		return nil, err
+	}
+	return s.repo.GetArea(id)
+}
+
+func (s *tablesService) DeleteArea(id string) error {
+	if _, err := s.repo.GetArea(id); err != nil {
+		return ErrTableAreaNotFound
+	}
+	n, err := s.repo.CountInArea(id)
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return fmt.Errorf("%w: %d table(s)", ErrTableAreaInUse, n)
+	}
+	return s.repo.DeleteArea(id)
+}
+
+func (s *tablesService) Create(t *entity.DiningTables) (*entity.DiningTables, error) {
+	if t == nil {
+		return nil, errors.New("invalid input")
+	}
+	if t.IdTable == "" {
+		t.IdTable = helper.Uuid()
+	}
+	if err := s.check(t); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Create(t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (s *tablesService) GetByID(id string) (*entity.DiningTables, error) {
+	t, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, ErrTableNotFound
+	}
+	return t, nil
+}
+
+func (s *tablesService) GetAll(idTableArea string) ([]entity.DiningTables, error) {
+	return s.repo.List(strings.TrimSpace(idTableArea))
+}
+
+func (s *tablesService) Update(id string, t *entity.DiningTables) (*entity.DiningTables, error) {
+	if t == nil {
+		return nil, errors.New("invalid input")
+	}
+	if _, err := s.repo.GetByID(id); err != nil {
+		return nil, ErrTableNotFound
+	}
+	t.IdTable = id
+	if err := s.check(t); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Update(t); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(id)
+}
+
+func (s *tablesService) Delete(id string) error {
+	if _, err := s.repo.GetByID(id); err != nil {
+		return ErrTableNotFound
+	}
+	open, err := s.held.ListOpenOnTable(id)
+	if err != nil {
+		return err
+	}
+	if len(open) > 0 {
+		return fmt.Errorf("%w: %d order(s) are open on it", ErrTableOccupied, len(open))
+	}
+	return s.repo.Delete(id)
+}
+
+// check validates t and fills in the defaults of its layout.
+func (s *tablesService) check(t *entity.DiningTables) error {
+	t.Name = truncate(strings.TrimSpace(t.Name), 30)
+	if t.Name == "" {
+		return fmt.Errorf("%w: name required", ErrInvalidTable)
+	}
+	if _, err := s.repo.GetArea(t.IdTableArea); err != nil {
+		return ErrTableAreaNotFound
+	}
+	switch t.Shape {
+	case "":
+		t.Shape = entity.TableShapeSquare
+	case entity.TableShapeSquare, entity.TableShapeRound, entity.TableShapeRect:
+	default:
+		return fmt.Errorf("%w: shape must be square, round or rect", ErrInvalidTable)
+	}
+	if t.Seats < 0 || t.PosX < 0 || t.PosY < 0 {
+		return fmt.Errorf("%w: seats and position cannot be negative", ErrInvalidTable)
+	}
+	if t.Width <= 0 {
+		t.Width = 1
+	}
+	if t.Height <= 0 {
+		t.Height = 1
+	}
+	return nil
+}
+
+func (s *tablesService) Floor() ([]FloorArea, error) {
+	areas, err := s.repo.ListAreas()
+	if err != nil {
+		return nil, err
+	}
+	tables, err := s.repo.List("")
+	if err != nil {
+		return nil, err
+	}
+	orders, err := s.held.ListOpenOnTables()
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(orders))
+	for _, o := range orders {
+		ids = append(ids, o.IdHeldOrder)
+	}
+	lines, err := s.held.ListLinesByOrders(ids)
+	if err != nil {
+		return nil, err
+	}
+	perOrder := map[string]*FloorOrder{}
+	onTable := map[string][]*FloorOrder{}
+	joinedTo := map[string]string{}
+	for _, o := range orders {
+		fo := &FloorOrder{
+			IdHeldOrder: o.IdHeldOrder,
+			Label:       o.Label,
+			Guests:      o.Guests,
+			SplitPart:   o.SplitPart,
+			SplitParts:  o.SplitParts,
+			Timestamp:   o.Timestamp,
+			UpdatedAt:   o.UpdatedAt,
+		}
+		perOrder[o.IdHeldOrder] = fo
+		onTable[o.IdTable] = append(onTable[o.IdTable], fo)
+		for _, t := range o.JoinedTables {
+			joinedTo[t] = o.IdTable
+		}
+	}
+	for _, l := range lines {
+		fo := perOrder[l.IdHeldOrder]
+		fo.Lines++
+		fo.Items += l.Quantity
+		// Each share of a split bill is charged its part of the lines.
+		est := l.Price.Mul(l.Quantity)
+		if fo.SplitParts > 0 {
+			est = shareOf(est, fo.SplitPart, fo.SplitParts, 0)
+		}
+		fo.Estimate += est
+	}
+
+	byArea := map[string][]FloorTable{}
+	for _, t := range tables {
+		ft := FloorTable{DiningTables: t, Status: TableStatusFree, Orders: []FloorOrder{}}
+		for _, fo := range onTable[t.IdTable] {
+			ft.Orders = append(ft.Orders, *fo)
+			ft.Guests += fo.Guests
+			if ft.Since == nil || fo.Timestamp.Before(*ft.Since) {
+				since := fo.Timestamp
+				ft.Since = &since
+			}
+		}
+		ft.JoinedTo = joinedTo[t.IdTable]
+		switch {
+		case len(ft.Orders) > 0 || ft.JoinedTo != "":
+			ft.Status = TableStatusOccupied
+		case !t.IsActive:
+			ft.Status = TableStatusInactive
+		}
+		byArea[t.IdTableArea] = append(byArea[t.IdTableArea], ft)
+	}
+	out := make([]FloorArea, 0, len(areas))
+	for _, a := range areas {
+		ft := byArea[a.IdTableArea]
+		if ft == nil {
+			ft = []FloorTable{}
+		}
+		out = append(out, FloorArea{Area: a, Tables: ft})
+	}
+	return out, nil
+}
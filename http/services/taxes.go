@@ -0,0 +1,211 @@
+package services
+
+import (
+	"math"
+	"sort"
+
+	"faizalmaulana/lsp/conf"
+	"faizalmaulana/lsp/models/entity"
+	"faizalmaulana/lsp/models/repo"
+)
+
+// TaxPolicy is how the store charges tax and service, from the config.
+type TaxPolicy struct {
+	Inclusive bool
+	// PerLine rounds the tax and service charge of every line; otherwise
+	// they are rounded once per invoice (per rate for tax) and spread over
+	// the lines.
+	PerLine       bool
+	ServiceCharge float64
+	Label         string
+}
+
+// TaxLine is a line to tax: Net is what the line comes to after discounts,
+// at the prices charged.
+type TaxLine struct {
+	Net  entity.Money
+	Rate float64
+}
+
+// TaxedLine is a line's share of the invoice. Base excludes tax.
+type TaxedLine struct {
+	Base    entity.Money
+	Service entity.Money
+	Tax     entity.Money
+	Total   entity.Money
+}
+
+// TaxBreakdown is a taxed invoice.
+type TaxBreakdown struct {
+	Lines    []TaxedLine
+	Subtotal entity.Money
+	Service  entity.Money
+	Tax      entity.Money
+	Total    entity.Money
+}
+
+type TaxesService interface {
+	Policy() TaxPolicy
+	// Rates returns the tax rate of every item by id: its own rate, else
+	// that of the nearest category up the tree with one, else the store's.
+	Rates(items []*entity.Items) (map[string]float64, error)
+}
+
+type taxesService struct {
+	categories repo.CategoriesRepo
+	cfg        *conf.Config
+}
+
+func NewTaxesService(categories repo.CategoriesRepo, cfg *conf.Config) TaxesService {
+	return &taxesService{categories: categories, cfg: cfg}
+}
+
+func (s *taxesService) Policy() TaxPolicy {
+	return TaxPolicy{
+		Inclusive:     s.cfg.TaxInclusive,
+		PerLine:       s.cfg.TaxRounding == "line",
+		ServiceCharge: s.cfg.ServiceCharge,
+		Label:         s.cfg.TaxLabel,
+	}
+}
+
+func (s *taxesService) Rates(items []*entity.Items) (map[string]float64, error) {
+	out := make(map[string]float64, len(items))
+	var byID map[string]*entity.Categories
+	for _, it := range items {
+		if it.TaxRate != nil {
+			out[it.IdItem] = *it.TaxRate
+			continue
+		}
+		if byID == nil {
+			all, err := s.categories.List(false)
+			if err != nil {
+				return nil, err
+			}
+			byID = make(map[string]*entity.Categories, len(all))
+			for _, c := range all {
+				byID[c.IdCategory] = c
+			}
+		}
+		rate := s.cfg.TaxRate
+		seen := map[string]bool{}
+		for id := it.IdCategory; id != "" && !seen[id]; {
+			seen[id] = true
+			c := byID[id]
+			if c == nil {
+				break
+			}
+			if c.TaxRate != nil {
+				rate = *c.TaxRate
+				break
+			}
+			id = c.IdParent
+		}
+		out[it.IdItem] = rate
+	}
+	return out, nil
+}
+
+// computeTax works out the service charge and tax of an invoice. Service is
+// charged on the lines without tax, and is itself taxed at the rate of the
+// line it is charged on.
+func computeTax(lines []TaxLine, p TaxPolicy) TaxBreakdown {
+	n := len(lines)
+	nets := make([]entity.Money, n)
+	rates := make([]float64, n)
+	for i, l := range lines {
+		nets[i] = l.Net
+		rates[i] = l.Rate
+	}
+
+	// Tax contained in inclusive prices.
+	contained := make([]entity.Money, n)
+	if p.Inclusive {
+		contained = taxShares(nets, rates, p.PerLine, func(v, r float64) float64 { return v * r / (100 + r) })
+	}
+	bases := make([]entity.Money, n)
+	for i := range lines {
+		bases[i] = nets[i] - contained[i]
+	}
+
+	service := make([]entity.Money, n)
+	if p.ServiceCharge > 0 {
+		if p.PerLine {
+			for i, b := range bases {
+				service[i] = percentOf(b, p.ServiceCharge)
+			}
+		} else {
+			var sum entity.Money
+			for _, b := range bases {
+				sum += b
+			}
+			service = allocateCents(percentOf(sum, p.ServiceCharge), bases)
+		}
+	}
+
+	// Tax added on top: on the service charge only when prices include tax,
+	// on the whole line otherwise.
+	taxable := make([]entity.Money, n)
+	for i := range lines {
+		taxable[i] = service[i]
+		if !p.Inclusive {
+			taxable[i] += bases[i]
+		}
+	}
+	added := taxShares(taxable, rates, p.PerLine, func(v, r float64) float64 { return v * r / 100 })
+
+	out := TaxBreakdown{Lines: make([]TaxedLine, n)}
+	for i := range lines {
+		l := TaxedLine{
+			Base:    bases[i],
+			Service: service[i],
+			Tax:     contained[i] + added[i],
+			Total:   nets[i] + service[i] + added[i],
+		}
+		out.Lines[i] = l
+		out.Subtotal += l.Base
+		out.Service += l.Service
+		out.Tax += l.Tax
+		out.Total += l.Total
+	}
+	return out
+}
+
+// taxShares applies tax to every amount at its rate. Per line each result is
+// rounded; otherwise the amounts of a rate are taxed together, rounded once
+// and the tax spread back over them.
+func taxShares(amounts []entity.Money, rates []float64, perLine bool, tax func(v, rate float64) float64) []entity.Money {
+	out := make([]entity.Money, len(amounts))
+	if perLine {
+		for i, v := range amounts {
+			out[i] = entity.Money(math.Round(tax(float64(v), rates[i])))
+		}
+		return out
+	}
+	groups := map[float64][]int{}
+	var order []float64
+	for i, r := range rates {
+		if r <= 0 || amounts[i] <= 0 {
+			continue
+		}
+		if _, ok := groups[r]; !ok {
+			order = append(order, r)
+		}
+		groups[r] = append(groups[r], i)
+	}
+	sort.Float64s(order)
+	for _, r := range order {
+		idx := groups[r]
+		weights := make([]entity.Money, len(idx))
+		var sum entity.Money
+		for k, i := range idx {
+			weights[k] = amounts[i]
+			sum += amounts[i]
+		}
+		total := entity.Money(math.Round(tax(float64(sum), r)))
+		for k, v := range allocateCents(total, weights) {
+			out[idx[k]] = v
+		}
+	}
+	return out
+}
@@ -2,23 +2,185 @@ package services
 
 import (
 	"errors"
+	"faizalmaulana/lsp/conf"
+	"faizalmaulana/lsp/helper"
 	"faizalmaulana/lsp/models/entity"
 	"faizalmaulana/lsp/models/repo"
+	"fmt"
+	"log"
 	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidItem             = errors.New("invalid item")
+	ErrTransactionNotFound     = errors.New("transaction not found")
+	ErrInvalidTransition       = errors.New("invalid status transition")
+	ErrVoidReasonRequired      = errors.New("void reason required")
+	ErrTransactionNotDeletable = errors.New("transaction cannot be deleted")
 )
 
+// transactionTransitions lists the statuses each status may move to. Voided,
+// refunded and cancelled sales are final.
+var transactionTransitions = map[string][]string{
+	entity.TransactionStatusPending:   {entity.TransactionStatusCompleted, entity.TransactionStatusCancelled},
+	entity.TransactionStatusCompleted: {entity.TransactionStatusVoided, entity.TransactionStatusRefunded},
+}
+
+func canTransition(from, to string) bool {
+	for _, s := range transactionTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// transitionSources returns the statuses allowed to move to `to`.
+func transitionSources(to string) []string {
+	var out []string
+	for from := range transactionTransitions {
+		if canTransition(from, to) {
+			out = append(out, from)
+		}
+	}
+	return out
+}
+
 type TransactionsService interface {
 	Create(t *entity.Transactions) (*entity.Transactions, error)
+	Checkout(req CheckoutRequest) (*CheckoutResult, error)
+	// Quote prices a sale like Checkout would, without saving it or taking
+	// payments.
+	Quote(req CheckoutRequest) (*CheckoutResult, error)
 	GetByID(id string) (*entity.Transactions, error)
+	GetDetail(id string) (*TransactionDetail, error)
+	Receipt(id string) (*Receipt, error)
 	GetAll(limit, page int) ([]entity.Transactions, error)
-	Update(id string, t *entity.Transactions) (*entity.Transactions, error)
+	// SetBuyerContact, Void and Delete refuse sales dated within a closed
+	// day.
+	SetBuyerContact(id, contact string) (*entity.Transactions, error)
+	// Void annuls a completed sale; by is the approving manager.
+	Void(id, reason, by string) (*entity.Transactions, error)
+	// Delete hides a sale that never completed. Completed, voided and refunded
+	// sales stay on record and must be voided or refunded instead.
 	Delete(id string) error
 }
 
-type transactionsService struct{ repo repo.TransactionsRepo }
+// CheckoutLine is one item of a sale as requested by the cashier. GiftCard
+// is the code of the card a gift card item tops up; without it new cards
+// are issued.
+type CheckoutLine struct {
+	IdItem    string
+	Quantity  int
+	Modifiers []string
+	Discount  *ManualDiscount
+	GiftCard  string
+	// Locked, when set, is what the line is charged at instead of the
+	// current prices of the item and its modifiers.
+	Locked *LockedPrice
+}
+
+// LockedPrice is a line's unit price kept from earlier: BasePrice is the
+// item's, Price includes the modifiers.
+type LockedPrice struct {
+	BasePrice entity.Money
+	Price     entity.Money
+}
+
+// CheckoutRequest is a sale as entered by the cashier. Role is the cashier's
+// role, which caps the manual discounts. Voucher is a voucher code to redeem.
+// The sale is linked to Customer, or to the customer with BuyerContact's
+// phone when only a contact that is a phone number is given.
+type CheckoutRequest struct {
+	IdUser       string
+	Role         string
+	BuyerContact string
+	Customer     *CustomerInput
+	Lines        []CheckoutLine
+	Discount     *ManualDiscount
+	Voucher      string
+	Payments     []PaymentInput
+	// HeldOrder is the held order the sale pays for, as it was when its
+	// lines were read. It is checked out together with the sale, which fails
+	// when the order has changed since.
+	HeldOrder *entity.HeldOrders
+	// Share, when set, charges the sale one share of the lines of a bill
+	// split equally. It takes no voucher, order discount or gift cards.
+	Share *BillShare
+}
+
+// BillShare is the share Part of Parts of the bill Of, the held order that
+// was split.
+type BillShare struct {
+	Of    string
+	Part  int
+	Parts int
+}
+
+type CheckoutResult struct {
+	Transaction *entity.Transactions
+	Lines       []entity.PivotItemsToTransaction
+	Payments    []entity.Payments
+	Intents     []entity.PaymentIntents
+	Change      entity.Money
+	// Points is what the sale did to the customer's loyalty points, nil for
+	// sales without a customer.
+	Points *SalePoints
+	// GiftCards are the cards issued on the sale, with their PINs.
+	GiftCards []IssuedGiftCard
+}
+
+// TransactionLine is a pivot row joined with the item it refers to.
+type TransactionLine struct {
+	entity.PivotItemsToTransaction
+	ItemName string
+	ImageUrl string
+}
+
+type TransactionDetail struct {
+	Transaction *entity.Transactions
+	Lines       []TransactionLine
+	Payments    []entity.Payments
+	Intents     []entity.PaymentIntents
+	Points      []entity.LoyaltyLedger
+	GiftCards   []entity.GiftCardLedger
+}
 
-func NewTransactionsService(r repo.TransactionsRepo) TransactionsService {
-	return &transactionsService{repo: r}
+type transactionsService struct {
+	repo      repo.TransactionsRepo
+	uow       repo.UnitOfWork
+	items     repo.ItemsRepo
+	pivots    repo.PivotItemsToTransactionsRepo
+	lineMods  repo.PivotLineModifiersRepo
+	lineComps repo.PivotLineComponentsRepo
+	lineDisc  repo.PivotLineDiscountsRepo
+	payments  repo.PaymentsRepo
+	intents   repo.PaymentIntentsRepo
+	modifiers ModifiersService
+	bundles   BundlesService
+	promos    PromotionsService
+	vouchers  repo.VouchersRepo
+	taxes     TaxesService
+	gateway   PaymentIntentsService
+	customers repo.CustomersRepo
+	loyalty   repo.LoyaltyRepo
+	giftCards repo.GiftCardsRepo
+	shifts    repo.ShiftsRepo
+	closes    repo.DayClosesRepo
+	refunds   repo.RefundsRepo
+	inventory repo.InventoryRepo
+	held      repo.HeldOrdersRepo
+	queue     repo.QueueRepo
+	kitchen   KitchenService
+	events    EventBus
+	cfg       *conf.Config
+}
+
+func NewTransactionsService(r repo.TransactionsRepo, uow repo.UnitOfWork, items repo.ItemsRepo, pivots repo.PivotItemsToTransactionsRepo, lineMods repo.PivotLineModifiersRepo, lineComps repo.PivotLineComponentsRepo, lineDisc repo.PivotLineDiscountsRepo, payments repo.PaymentsRepo, intents repo.PaymentIntentsRepo, modifiers ModifiersService, bundles BundlesService, promos PromotionsService, vouchers repo.VouchersRepo, taxes TaxesService, gateway PaymentIntentsService, customers repo.CustomersRepo, loyalty repo.LoyaltyRepo, giftCards repo.GiftCardsRepo, shifts repo.ShiftsRepo, closes repo.DayClosesRepo, refunds repo.RefundsRepo, inventory repo.InventoryRepo, held repo.HeldOrdersRepo, queue repo.QueueRepo, kitchen KitchenService, events EventBus, cfg *conf.Config) TransactionsService {
+	return &transactionsService{repo: r, uow: uow, items: items, pivots: pivots, lineMods: lineMods, lineComps: lineComps, lineDisc: lineDisc, payments: payments, intents: intents, modifiers: modifiers, bundles: bundles, promos: promos, vouchers: vouchers, taxes: taxes, gateway: gateway, customers: customers, loyalty: loyalty, giftCards: giftCards, shifts: shifts, closes: closes, refunds: refunds, inventory: inventory, held: held, queue: queue, kitchen: kitchen, events: events, cfg: cfg}
 }
 
 func (s *transactionsService) Create(t *entity.Transactions) (*entity.Transactions, error) {
@@ -28,16 +190,531 @@ func (s *transactionsService) Create(t *entity.Transactions) (*entity.Transactio
 	if strings.TrimSpace(t.IdUser) == "" {
 		return nil, errors.New("id_user required")
 	}
-	if err := s.repo.Create(t); err != nil {
+	at := t.Timestamp
+	if at.IsZero() {
+		at = time.Now()
+	}
+	err := s.uow.Do(func(db *gorm.DB) error {
+		if err := checkPeriodOpen(s.closes.WithTx(db), at); err != nil {
+			return err
+		}
+		return s.repo.WithTx(db).Create(t)
+	})
+	if err != nil {
 		return nil, err
 	}
 	return t, nil
 }
 
+// Checkout prices every line from the current catalog, validates modifier
+// selections, expands bundles into their components, applies promotions,
+// the voucher and manual discounts, adds the service charge and tax, settles
+// the payments and stores the transaction with all its lines and payments in
+// a single database transaction. A sale with gateway payments stays pending until the gateway
+// confirms them. A buyer phone not in the customer directory yet is
+// registered along with the sale, and the customer's loyalty points are
+// redeemed and earned in the same database transaction, as are the gift
+// cards and store credit paid with and the gift cards sold, and the held
+// order paid for is checked out. The sale belongs to the cashier's open
+// shift and cannot be made once the day is closed.
+func (s *transactionsService) Checkout(req CheckoutRequest) (*CheckoutResult, error) {
+	if strings.TrimSpace(req.IdUser) == "" {
+		return nil, errors.New("id_user required")
+	}
+	buyer, err := s.buyer(&req)
+	if err != nil {
+		return nil, err
+	}
+	if err := resolveStoredValueTenders(s.giftCards, s.customers, req.Payments, buyer); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	sale, err := s.price(req, now)
+	if err != nil {
+		return nil, err
+	}
+	tx := sale.tx
+	shift, err := currentShift(s.shifts, tx.IdUser, s.cfg.ShiftRequired)
+	if err != nil {
+		return nil, err
+	}
+	if shift != nil {
+		tx.IdShift = shift.IdShift
+	}
+
+	payments, rounding, err := settlePayments(tx.IdTransaction, tx.TotalPrice, req.Payments, s.gateway.GatewayName(), s.cashRounding())
+	if err != nil {
+		return nil, err
+	}
+	tx.CashRounding = rounding
+	if _, err := pointsTendered(payments, buyer != nil, loyaltyPolicy(s.cfg)); err != nil {
+		return nil, err
+	}
+	if len(sale.giftCards) > 0 {
+		for _, p := range payments {
+			if p.Gateway != "" {
+				return nil, fmt.Errorf("%w: gift cards cannot be sold on a gateway payment", ErrInvalidPayment)
+			}
+		}
+	}
+	intents, err := s.gateway.Open(payments)
+	if err != nil {
+		return nil, err
+	}
+	if len(intents) > 0 {
+		tx.Status = entity.TransactionStatusPending
+	}
+
+	var (
+		points *SalePoints
+		cards  []IssuedGiftCard
+	)
+	err = s.uow.Do(func(db *gorm.DB) error {
+		if err := checkPeriodOpen(s.closes.WithTx(db), now); err != nil {
+			return err
+		}
+		if tx.IdShift != "" {
+			// Holding the shift keeps it from closing before the sale is in.
+			sh, err := s.shifts.WithTx(db).GetOpenForShare(tx.IdUser)
+			if err != nil || sh.IdShift != tx.IdShift {
+				return fmt.Errorf("%w: the shift was closed", ErrNoOpenShift)
+			}
+		}
+		if o := req.HeldOrder; o != nil {
+			ok, err := s.held.WithTx(db).Close(o.IdHeldOrder, entity.HeldOrderStatusCheckedOut, tx.IdTransaction, tx.IdUser, now, o.UpdatedAt)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return fmt.Errorf("%w: the order was changed or closed meanwhile", ErrHeldOrderChanged)
+			}
+		}
+		if buyer != nil && buyer.IdCustomer == "" {
+			c, err := resolveCustomer(s.customers.WithTx(db), *buyer)
+			if err != nil {
+				return err
+			}
+			tx.IdCustomer = c.IdCustomer
+		}
+		var err error
+		if points, err = postSalePoints(s.loyalty.WithTx(db), loyaltyPolicy(s.cfg), tx, sale.lines, payments, now); err != nil {
+			return err
+		}
+		if sale.voucher != nil {
+			if err := redeemVoucher(s.vouchers.WithTx(db), sale.voucher.IdVoucher, tx.IdTransaction, req.BuyerContact, now); err != nil {
+				return err
+			}
+		}
+		if err := redeemGiftCards(s.giftCards.WithTx(db), payments, tx.IdUser); err != nil {
+			return err
+		}
+		if cards, err = sellGiftCards(s.giftCards.WithTx(db), sale.giftCards, tx.IdTransaction, tx.IdUser); err != nil {
+			return err
+		}
+		if err := takeStock(s.inventory.WithTx(db), tx, sale.lines); err != nil {
+			return err
+		}
+		// Numbered last, so the counter is held for as short as can be.
+		if tx.SplitPart <= 1 {
+			if tx.QueueNumber, err = s.queue.WithTx(db).Next(now.Format("2006-01-02")); err != nil {
+				return err
+			}
+		}
+		if err := s.repo.WithTx(db).Create(tx); err != nil {
+			return err
+		}
+		if err := s.pivots.WithTx(db).BulkCreate(sale.lines); err != nil {
+			return err
+		}
+		if err := s.lineMods.WithTx(db).BulkCreate(sale.mods); err != nil {
+			return err
+		}
+		if err := s.lineComps.WithTx(db).BulkCreate(sale.comps); err != nil {
+			return err
+		}
+		if err := s.lineDisc.WithTx(db).BulkCreate(sale.discounts); err != nil {
+			return err
+		}
+		if err := s.payments.WithTx(db).BulkCreate(payments); err != nil {
+			return err
+		}
+		return s.intents.WithTx(db).BulkCreate(intents)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if tx.Status == entity.TransactionStatusCompleted {
+		sendToKitchen(s.kitchen, tx.IdTransaction)
+		s.events.Publish(Event{Type: EventTransactionCreated, Data: *tx})
+	}
+	return &CheckoutResult{Transaction: tx, Lines: sale.lines, Payments: payments, Intents: intents, Change: totalChange(payments), Points: points, GiftCards: cards}, nil
+}
+
+func (s *transactionsService) cashRounding() CashRounding {
+	return CashRounding{Step: s.cfg.CashRoundingStep, Mode: s.cfg.CashRoundingMode}
+}
+
+// buyer works out who req is for before it is priced. BuyerContact becomes
+// the customer's E.164 phone whenever there is one, which is also what
+// voucher limits are counted on. It returns nil for anonymous sales and
+// free-text contacts, and an input without IdCustomer for a phone that still
+// has to be looked up.
+func (s *transactionsService) buyer(req *CheckoutRequest) (*CustomerInput, error) {
+	in := CustomerInput{}
+	if req.Customer != nil {
+		in = *req.Customer
+	}
+	if id := strings.TrimSpace(in.IdCustomer); id != "" {
+		c, err := s.customers.GetByID(id)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrCustomerNotFound, id)
+		}
+		in.IdCustomer = c.IdCustomer
+		req.BuyerContact = c.Phone
+		return &in, nil
+	}
+	if strings.TrimSpace(in.Phone) != "" {
+		phone, ok := helper.NormalizePhone(in.Phone, s.cfg.PhoneCountryCode)
+		if !ok {
+			return nil, fmt.Errorf("%w: phone %q is not a valid phone number", ErrInvalidCustomer, in.Phone)
+		}
+		in.Phone = phone
+	} else if phone, ok := helper.NormalizePhone(req.BuyerContact, s.cfg.PhoneCountryCode); ok {
+		in.Phone = phone
+	} else {
+		return nil, nil
+	}
+	req.BuyerContact = in.Phone
+	return &in, nil
+}
+
+func (s *transactionsService) Quote(req CheckoutRequest) (*CheckoutResult, error) {
+	if _, err := s.buyer(&req); err != nil {
+		return nil, err
+	}
+	sale, err := s.price(req, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return &CheckoutResult{Transaction: sale.tx, Lines: sale.lines}, nil
+}
+
+// pricedSale is a sale priced by price and not saved yet.
+type pricedSale struct {
+	tx        *entity.Transactions
+	lines     []entity.PivotItemsToTransaction
+	mods      []entity.PivotLineModifiers
+	comps     []entity.PivotLineComponents
+	discounts []entity.PivotLineDiscounts
+	voucher   *entity.Vouchers
+	giftCards []giftCardSale
+}
+
+func (s *transactionsService) price(req CheckoutRequest, at time.Time) (*pricedSale, error) {
+	if len(req.Lines) == 0 {
+		return nil, errors.New("items required")
+	}
+
+	tx := &entity.Transactions{
+		IdTransaction: helper.Uuid(),
+		IdUser:        req.IdUser,
+		BuyerContact:  req.BuyerContact,
+		Status:        entity.TransactionStatusCompleted,
+	}
+	if req.Customer != nil {
+		tx.IdCustomer = strings.TrimSpace(req.Customer.IdCustomer)
+	}
+	sale := &pricedSale{tx: tx, lines: make([]entity.PivotItemsToTransaction, 0, len(req.Lines))}
+
+	var (
+		priced = make([]PricedLine, 0, len(req.Lines))
+		items  = make([]*entity.Items, 0, len(req.Lines))
+	)
+	for _, line := range req.Lines {
+		item, err := s.items.GetByID(line.IdItem)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidItem, line.IdItem)
+		}
+		qty := line.Quantity
+		if qty <= 0 {
+			qty = 1
+		}
+		giftCard := item.Kind == entity.ItemKindGiftCard
+		if line.GiftCard != "" && !giftCard {
+			return nil, fmt.Errorf("%w: %s is not a gift card", ErrInvalidItem, item.ItemName)
+		}
+		if giftCard && line.Discount != nil {
+			return nil, fmt.Errorf("%w: gift cards are sold at face value", ErrInvalidDiscount)
+		}
+		if giftCard && req.Share != nil {
+			return nil, fmt.Errorf("%w: gift cards cannot be sold on a split bill", ErrInvalidItem)
+		}
+		selected, delta, err := s.modifiers.Resolve(item.IdItem, line.Modifiers)
+		if err != nil {
+			return nil, err
+		}
+		pivot := entity.PivotItemsToTransaction{
+			IdPivot:       helper.Uuid(),
+			IdTransaction: tx.IdTransaction,
+			IdItem:        item.IdItem,
+			Quantity:      qty,
+			BasePrice:     item.Price,
+			Price:         item.Price + delta,
+		}
+		if line.Locked != nil {
+			pivot.BasePrice, pivot.Price = line.Locked.BasePrice, line.Locked.Price
+		}
+		for i := range selected {
+			selected[i].IdPivot = pivot.IdPivot
+			selected[i].IdTransaction = tx.IdTransaction
+		}
+		pivot.Modifiers = selected
+		sale.mods = append(sale.mods, selected...)
+
+		parts, err := s.bundles.Expand(item, qty, pivot.Price.Mul(qty))
+		if err != nil {
+			return nil, err
+		}
+		for i := range parts {
+			parts[i].IdPivot = pivot.IdPivot
+			parts[i].IdTransaction = tx.IdTransaction
+		}
+		pivot.Components = parts
+		sale.comps = append(sale.comps, parts...)
+		sale.lines = append(sale.lines, pivot)
+		items = append(items, item)
+		if giftCard {
+			sale.giftCards = append(sale.giftCards, giftCardSale{Code: normalizeGiftCardCode(line.GiftCard), Value: pivot.Price, Quantity: qty})
+			continue
+		}
+		priced = append(priced, PricedLine{
+			IdPivot:    pivot.IdPivot,
+			IdItem:     item.IdItem,
+			IdCategory: item.IdCategory,
+			Quantity:   qty,
+			Price:      pivot.Price,
+			Discount:   line.Discount,
+		})
+	}
+
+	if req.Share != nil && (req.Discount != nil || strings.TrimSpace(req.Voucher) != "") {
+		return nil, fmt.Errorf("%w: a share of a split bill takes no voucher or order discount", ErrInvalidDiscount)
+	}
+	pricing := PricingRequest{Lines: priced, Discount: req.Discount, Role: req.Role, At: at}
+	if code := strings.TrimSpace(req.Voucher); code != "" {
+		v, err := checkVoucher(s.vouchers, code, req.BuyerContact, at)
+		if err != nil {
+			return nil, err
+		}
+		sale.voucher = v
+		tx.VoucherCode = v.Code
+		pricing.Unlocked = v.IdPromotion
+	}
+	discounts, err := s.promos.Apply(pricing)
+	if err != nil {
+		return nil, err
+	}
+	var discounted entity.Money
+	applied := false
+	lineDiscount := map[string]entity.Money{}
+	byPivot := map[string][]entity.PivotLineDiscounts{}
+	for i := range discounts {
+		discounts[i].IdTransaction = tx.IdTransaction
+		if sale.voucher != nil && discounts[i].IdPromotion == sale.voucher.IdPromotion {
+			discounts[i].IdVoucher = sale.voucher.IdVoucher
+			applied = true
+		}
+		d := discounts[i]
+		lineDiscount[d.IdPivot] += d.Amount
+		byPivot[d.IdPivot] = append(byPivot[d.IdPivot], d)
+		discounted += d.Amount
+	}
+	if sale.voucher != nil && !applied {
+		return nil, fmt.Errorf("%w: %s does not apply to this sale", ErrVoucherUnavailable, sale.voucher.Code)
+	}
+	rates, err := s.taxes.Rates(items)
+	if err != nil {
+		return nil, err
+	}
+	policy := s.taxes.Policy()
+	// Gift cards are stored value, not a supply: they stay out of the
+	// service charge and tax and come to their face value.
+	var faceValue entity.Money
+	taxLines := make([]TaxLine, 0, len(sale.lines))
+	withTax := make([]int, 0, len(sale.lines))
+	for i := range sale.lines {
+		l := &sale.lines[i]
+		if items[i].Kind == entity.ItemKindGiftCard {
+			l.LineTotal = l.Price.Mul(l.Quantity)
+			faceValue += l.LineTotal
+			continue
+		}
+		l.Discount = lineDiscount[l.IdPivot]
+		l.Discounts = byPivot[l.IdPivot]
+		l.TaxRate = rates[l.IdItem]
+		taxLines = append(taxLines, TaxLine{Net: l.Price.Mul(l.Quantity) - lineDiscount[l.IdPivot], Rate: l.TaxRate})
+		withTax = append(withTax, i)
+	}
+	taxed := computeTax(taxLines, policy)
+	for j, t := range taxed.Lines {
+		i := withTax[j]
+		sale.lines[i].Tax = t.Tax
+		sale.lines[i].ServiceCharge = t.Service
+		sale.lines[i].LineTotal = t.Total
+	}
+	sale.discounts = discounts
+	tx.DiscountTotal = discounted
+	tx.Subtotal = taxed.Subtotal + faceValue
+	tx.ServiceCharge = taxed.Service
+	tx.TaxTotal = taxed.Tax
+	tx.TaxInclusive = policy.Inclusive
+	tx.TotalPrice = taxed.Total + faceValue
+	if req.Share != nil {
+		shareSale(sale, *req.Share)
+	}
+	return sale, nil
+}
+
+// shareSale charges sale its share of every amount of the bill, so that the
+// shares add up to the bill to the cent. Quantities stay whole; the odd
+// cents of each amount go to a different share from line to line.
+func shareSale(sale *pricedSale, sh BillShare) {
+	cut := func(v entity.Money, i int) entity.Money { return shareOf(v, sh.Part, sh.Parts, i) }
+	discounts := map[string][]entity.PivotLineDiscounts{}
+	for i := range sale.discounts {
+		d := &sale.discounts[i]
+		d.Amount = cut(d.Amount, i)
+		discounts[d.IdPivot] = append(discounts[d.IdPivot], *d)
+	}
+	mods := map[string][]entity.PivotLineModifiers{}
+	for i := range sale.mods {
+		m := &sale.mods[i]
+		m.PriceDelta = cut(m.PriceDelta, i)
+		mods[m.IdPivot] = append(mods[m.IdPivot], *m)
+	}
+	comps := map[string][]entity.PivotLineComponents{}
+	for i := range sale.comps {
+		c := &sale.comps[i]
+		c.Revenue = cut(c.Revenue, i)
+		comps[c.IdPivot] = append(comps[c.IdPivot], *c)
+	}
+
+	tx := sale.tx
+	tx.SplitOf, tx.SplitPart, tx.SplitParts = sh.Of, sh.Part, sh.Parts
+	tx.DiscountTotal, tx.ServiceCharge, tx.TaxTotal, tx.TotalPrice = 0, 0, 0, 0
+	for i := range sale.lines {
+		l := &sale.lines[i]
+		l.BasePrice, l.Price = cut(l.BasePrice, i), cut(l.Price, i)
+		l.Tax, l.ServiceCharge, l.LineTotal = cut(l.Tax, i), cut(l.ServiceCharge, i), cut(l.LineTotal, i)
+		l.Discounts, l.Modifiers, l.Components = discounts[l.IdPivot], mods[l.IdPivot], comps[l.IdPivot]
+		l.Discount = 0
+		for _, d := range l.Discounts {
+			l.Discount += d.Amount
+		}
+		tx.DiscountTotal += l.Discount
+		tx.ServiceCharge += l.ServiceCharge
+		tx.TaxTotal += l.Tax
+		tx.TotalPrice += l.LineTotal
+	}
+	tx.Subtotal = tx.TotalPrice - tx.ServiceCharge - tx.TaxTotal
+}
+
+// shareOf is the share part (1-based) of parts of v. The parts add up to v;
+// the cents that do not divide evenly go to the shares from the one at
+// offset onwards.
+func shareOf(v entity.Money, part, parts, offset int) entity.Money {
+	if parts <= 1 {
+		return v
+	}
+	n := entity.Money(parts)
+	out, rem := v/n, v%n
+	step := entity.Money(1)
+	if rem < 0 {
+		rem, step = -rem, -1
+	}
+	if entity.Money(((part-1-offset)%parts+parts)%parts) < rem {
+		out += step
+	}
+	return out
+}
+
 func (s *transactionsService) GetByID(id string) (*entity.Transactions, error) {
 	return s.repo.GetByID(id)
 }
 
+func (s *transactionsService) GetDetail(id string) (*TransactionDetail, error) {
+	t, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	pivots, err := s.pivots.ListByTransaction(id)
+	if err != nil {
+		return nil, err
+	}
+	mods, err := s.lineMods.ListByTransaction(id)
+	if err != nil {
+		return nil, err
+	}
+	comps, err := s.lineComps.ListByTransaction(id)
+	if err != nil {
+		return nil, err
+	}
+	discounts, err := s.lineDisc.ListByTransaction(id)
+	if err != nil {
+		return nil, err
+	}
+	payments, err := s.payments.ListByTransaction(id)
+	if err != nil {
+		return nil, err
+	}
+	intents, err := s.intents.ListByTransaction(id)
+	if err != nil {
+		return nil, err
+	}
+	points, err := s.loyalty.ListByTransaction(id)
+	if err != nil {
+		return nil, err
+	}
+	cards, err := s.giftCards.ListByTransaction(id)
+	if err != nil {
+		return nil, err
+	}
+	byPivot := map[string][]entity.PivotLineModifiers{}
+	for _, m := range mods {
+		byPivot[m.IdPivot] = append(byPivot[m.IdPivot], m)
+	}
+	compsByPivot := map[string][]entity.PivotLineComponents{}
+	for _, c := range comps {
+		compsByPivot[c.IdPivot] = append(compsByPivot[c.IdPivot], c)
+	}
+	discByPivot := map[string][]entity.PivotLineDiscounts{}
+	for _, d := range discounts {
+		discByPivot[d.IdPivot] = append(discByPivot[d.IdPivot], d)
+	}
+
+	lines := make([]TransactionLine, 0, len(pivots))
+	for _, p := range pivots {
+		p.Modifiers = byPivot[p.IdPivot]
+		p.Components = compsByPivot[p.IdPivot]
+		p.Discounts = discByPivot[p.IdPivot]
+		line := TransactionLine{PivotItemsToTransaction: p}
+		if it, err := s.items.GetByID(p.IdItem); err == nil {
+			line.ItemName = it.ItemName
+			line.ImageUrl = it.ImageUrl
+		}
+		lines = append(lines, line)
+	}
+	return &TransactionDetail{Transaction: t, Lines: lines, Payments: payments, Intents: intents, Points: points, GiftCards: cards}, nil
+}
+
+func (s *transactionsService) Receipt(id string) (*Receipt, error) {
+	d, err := s.GetDetail(id)
+	if err != nil {
+		return nil, err
+	}
+	return transactionReceipt(d, s.taxes.Policy().Label), nil
+}
+
 func (s *transactionsService) GetAll(limit, page int) ([]entity.Transactions, error) {
 	if limit <= 0 {
 		limit = 10
@@ -60,20 +737,105 @@ func (s *transactionsService) GetAll(limit, page int) ([]entity.Transactions, er
 	return out, nil
 }
 
-func (s *transactionsService) Update(id string, t *entity.Transactions) (*entity.Transactions, error) {
-	if id == "" || t == nil {
-		return nil, errors.New("invalid input")
-	}
-	t.IdTransaction = id
-	if err := s.repo.Update(t); err != nil {
+func (s *transactionsService) SetBuyerContact(id, contact string) (*entity.Transactions, error) {
+	var t *entity.Transactions
+	err := s.uow.Do(func(db *gorm.DB) error {
+		var err error
+		t, err = s.repo.WithTx(db).GetByIDForUpdate(id)
+		if err != nil {
+			return ErrTransactionNotFound
+		}
+		if err := checkPeriodOpen(s.closes.WithTx(db), t.Timestamp); err != nil {
+			return err
+		}
+		t.BuyerContact = contact
+		return s.repo.WithTx(db).SetBuyerContact(id, contact)
+	})
+	if err != nil {
 		return nil, err
 	}
 	return t, nil
 }
 
+func (s *transactionsService) Void(id, reason, by string) (*entity.Transactions, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, ErrVoidReasonRequired
+	}
+	reason = truncate(reason, 255)
+	err := s.uow.Do(func(db *gorm.DB) error {
+		t, err := s.repo.WithTx(db).GetByIDForUpdate(id)
+		if err != nil {
+			return ErrTransactionNotFound
+		}
+		if !canTransition(t.Status, entity.TransactionStatusVoided) {
+			return fmt.Errorf("%w: a %s transaction cannot be voided", ErrInvalidTransition, t.Status)
+		}
+		if err := checkPeriodOpen(s.closes.WithTx(db), t.Timestamp); err != nil {
+			return err
+		}
+		// Refunds already gave part of the sale back; voiding it on top
+		// would give that back a second time.
+		refunded, err := s.refunds.WithTx(db).ListByTransaction(id)
+		if err != nil {
+			return err
+		}
+		if len(refunded) > 0 {
+			return fmt.Errorf("%w: the transaction has been partly refunded", ErrInvalidTransition)
+		}
+		ok, err := s.repo.WithTx(db).Transition(id, transitionSources(entity.TransactionStatusVoided), entity.TransactionStatusVoided, map[string]interface{}{
+			"void_reason": reason,
+			"voided_by":   by,
+			"voided_at":   time.Now(),
+		})
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("%w: the transaction changed status meanwhile", ErrInvalidTransition)
+		}
+		// A voided sale gives its voucher back, its loyalty points, what
+		// was paid with gift cards and store credit, and its goods.
+		if err := s.vouchers.WithTx(db).ReleaseByTransaction(id); err != nil {
+			return err
+		}
+		if err := returnSaleStock(s.inventory.WithTx(db), id, entity.StockEntryVoid, by, reason); err != nil {
+			return err
+		}
+		if err := reverseSalePoints(s.loyalty.WithTx(db), loyaltyPolicy(s.cfg), id, "void", time.Now()); err != nil {
+			return err
+		}
+		return reverseGiftCards(s.giftCards.WithTx(db), id, by, "void")
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.kitchen.Cancel(id); err != nil {
+		log.Printf("kitchen tickets for %s: %v", id, err)
+	}
+	voided, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	s.events.Publish(Event{Type: EventTransactionVoided, Data: *voided})
+	return voided, nil
+}
+
 func (s *transactionsService) Delete(id string) error {
 	if id == "" {
 		return errors.New("id required")
 	}
-	return s.repo.Delete(id)
+	t, err := s.repo.GetByID(id)
+	if err != nil {
+		return ErrTransactionNotFound
+	}
+	if t.Status != entity.TransactionStatusCancelled {
+		return fmt.Errorf("%w: a %s transaction must be voided or refunded instead", ErrTransactionNotDeletable, t.Status)
+	}
+	return s.uow.Do(func(db *gorm.DB) error {
+		if err := checkPeriodOpen(s.closes.WithTx(db), t.Timestamp); err != nil {
+			return err
+		}
+		return s.repo.WithTx(db).Delete(id)
+	})
 }
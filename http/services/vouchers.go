@@ -0,0 +1,287 @@
+package services
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"faizalmaulana/lsp/conf"
+	"faizalmaulana/lsp/helper"
+	"faizalmaulana/lsp/models/entity"
+	"faizalmaulana/lsp/models/repo"
+)
+
+var (
+	ErrInvalidVoucher     = errors.New("invalid voucher")
+	ErrVoucherNotFound    = errors.New("voucher not found")
+	ErrVoucherUnavailable = errors.New("voucher cannot be used")
+)
+
+const (
+	maxVoucherBatch = 1000
+	// voucherAlphabet leaves out 0/O and 1/I, which are easy to misread on
+	// printed coupons. Its 32 letters keep byte%32 unbiased.
+	voucherAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+)
+
+var voucherCodePattern = regexp.MustCompile(`^[A-Z0-9-]{4,40}$`)
+
+// VoucherBatch asks for Count single-use vouchers of a promotion, coded
+// Prefix-XXXXXXXX with Length random characters.
+type VoucherBatch struct {
+	IdPromotion        string
+	Count              int
+	Prefix             string
+	Length             int
+	StartsAt           *time.Time
+	EndsAt             *time.Time
+	MaxUsesPerCustomer int
+}
+
+type VouchersService interface {
+	Create(v *entity.Vouchers) (*entity.Vouchers, error)
+	// Generate creates a batch of single-use vouchers with random codes.
+	Generate(b VoucherBatch) ([]entity.Vouchers, error)
+	GetByID(id string) (*entity.Vouchers, error)
+	GetAll(batch, idPromotion string) ([]entity.Vouchers, error)
+	Update(id string, v *entity.Vouchers) (*entity.Vouchers, error)
+	Delete(id string) error
+	// Check tells whether customer could redeem code at at, without
+	// redeeming it.
+	Check(code, customer string, at time.Time) (*entity.Vouchers, error)
+}
+
+type vouchersService struct {
+	repo       repo.VouchersRepo
+	promotions repo.PromotionsRepo
+	cfg        *conf.Config
+}
+
+func NewVouchersService(r repo.VouchersRepo, promotions repo.PromotionsRepo, cfg *conf.Config) VouchersService {
+	return &vouchersService{repo: r, promotions: promotions, cfg: cfg}
+}
+
+func (s *vouchersService) Create(v *entity.Vouchers) (*entity.Vouchers, error) {
+	if v == nil {
+		return nil, errors.New("voucher nil")
+	}
+	v.Code = normalizeVoucherCode(v.Code)
+	if !voucherCodePattern.MatchString(v.Code) {
+		return nil, fmt.Errorf("%w: code must be 4-40 letters, digits or dashes", ErrInvalidVoucher)
+	}
+	if err := s.validate(v); err != nil {
+		return nil, err
+	}
+	taken, err := s.repo.CodeExists(v.Code)
+	if err != nil {
+		return nil, err
+	}
+	if taken {
+		return nil, fmt.Errorf("%w: code %s already exists", ErrInvalidVoucher, v.Code)
+	}
+	if err := s.repo.Create(v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func (s *vouchersService) Generate(b VoucherBatch) ([]entity.Vouchers, error) {
+	if b.Count <= 0 || b.Count > maxVoucherBatch {
+		return nil, fmt.Errorf("%w: count must be between 1 and %d", ErrInvalidVoucher, maxVoucherBatch)
+	}
+	if b.Length == 0 {
+		b.Length = 8
+	}
+	if b.Length < 6 || b.Length > 16 {
+		return nil, fmt.Errorf("%w: length must be between 6 and 16", ErrInvalidVoucher)
+	}
+	prefix := normalizeVoucherCode(b.Prefix)
+	if prefix != "" {
+		prefix += "-"
+	}
+	if !voucherCodePattern.MatchString(prefix + strings.Repeat("A", b.Length)) {
+		return nil, fmt.Errorf("%w: prefix must be letters, digits or dashes and leave room for the code", ErrInvalidVoucher)
+	}
+
+	batch := helper.Uuid()
+	template := entity.Vouchers{
+		IdPromotion:        b.IdPromotion,
+		Batch:              batch,
+		StartsAt:           b.StartsAt,
+		EndsAt:             b.EndsAt,
+		MaxUses:            1,
+		MaxUsesPerCustomer: b.MaxUsesPerCustomer,
+		IsActive:           true,
+	}
+	if err := s.validate(&template); err != nil {
+		return nil, err
+	}
+	out := make([]entity.Vouchers, 0, b.Count)
+	seen := map[string]bool{}
+	for len(out) < b.Count {
+		code := prefix + randomVoucherCode(b.Length)
+		if seen[code] {
+			continue
+		}
+		seen[code] = true
+		taken, err := s.repo.CodeExists(code)
+		if err != nil {
+			return nil, err
+		}
+		if taken {
+			continue
+		}
+		v := template
+		v.IdVoucher = helper.Uuid()
+		v.Code = code
+		out = append(out, v)
+	}
+	if err := s.repo.BulkCreate(out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (s *vouchersService) GetByID(id string) (*entity.Vouchers, error) {
+	v, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, ErrVoucherNotFound
+	}
+	return v, nil
+}
+
+func (s *vouchersService) GetAll(batch, idPromotion string) ([]entity.Vouchers, error) {
+	list, err := s.repo.List(batch, idPromotion)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]entity.Vouchers, 0, len(list))
+	for _, v := range list {
+		out = append(out, *v)
+	}
+	return out, nil
+}
+
+func (s *vouchersService) Update(id string, v *entity.Vouchers) (*entity.Vouchers, error) {
+	if id == "" || v == nil {
+		return nil, errors.New("invalid input")
+	}
+	v.IdVoucher = id
+	if err := s.validate(v); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Update(v); err != nil {
+		return nil, err
+	}
+	return s.GetByID(id)
+}
+
+func (s *vouchersService) Delete(id string) error {
+	if id == "" {
+		return errors.New("id required")
+	}
+	return s.repo.Delete(id)
+}
+
+func (s *vouchersService) Check(code, customer string, at time.Time) (*entity.Vouchers, error) {
+	return checkVoucher(s.repo, code, buyerContact(customer, s.cfg.PhoneCountryCode), at)
+}
+
+func (s *vouchersService) validate(v *entity.Vouchers) error {
+	p, err := s.promotions.GetByID(v.IdPromotion)
+	if err != nil {
+		return fmt.Errorf("%w: unknown promotion %s", ErrInvalidVoucher, v.IdPromotion)
+	}
+	if !p.VoucherOnly {
+		return fmt.Errorf("%w: promotion %q is not voucher-only", ErrInvalidVoucher, p.Name)
+	}
+	if v.StartsAt != nil && v.EndsAt != nil && !v.EndsAt.After(*v.StartsAt) {
+		return fmt.Errorf("%w: ends_at must be after starts_at", ErrInvalidVoucher)
+	}
+	if v.MaxUses < 0 || v.MaxUsesPerCustomer < 0 {
+		return fmt.Errorf("%w: usage limits cannot be negative", ErrInvalidVoucher)
+	}
+	return nil
+}
+
+func normalizeVoucherCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// voucherCustomer is the key the per-customer limit is counted on. Phone
+// numbers reach it in E.164.
+func voucherCustomer(contact string) string {
+	return truncate(strings.ToLower(strings.TrimSpace(contact)), 120)
+}
+
+func randomVoucherCode(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	for i := range b {
+		b[i] = voucherAlphabet[int(b[i])%len(voucherAlphabet)]
+	}
+	return string(b)
+}
+
+// checkVoucher looks code up and tells whether customer may redeem it at at.
+func checkVoucher(r repo.VouchersRepo, code, customer string, at time.Time) (*entity.Vouchers, error) {
+	v, err := r.GetByCode(normalizeVoucherCode(code))
+	if err != nil {
+		return nil, ErrVoucherNotFound
+	}
+	if err := voucherUsable(r, v, voucherCustomer(customer), at); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func voucherUsable(r repo.VouchersRepo, v *entity.Vouchers, customer string, at time.Time) error {
+	switch {
+	case !v.IsActive:
+		return fmt.Errorf("%w: %s is inactive", ErrVoucherUnavailable, v.Code)
+	case v.StartsAt != nil && at.Before(*v.StartsAt):
+		return fmt.Errorf("%w: %s is not valid before %s", ErrVoucherUnavailable, v.Code, v.StartsAt.Format("02/01/2006 15:04"))
+	case v.EndsAt != nil && !at.Before(*v.EndsAt):
+		return fmt.Errorf("%w: %s has expired", ErrVoucherUnavailable, v.Code)
+	case v.MaxUses > 0 && v.UsedCount >= v.MaxUses:
+		return fmt.Errorf("%w: %s has been used up", ErrVoucherUnavailable, v.Code)
+	}
+	if v.MaxUsesPerCustomer > 0 {
+		if customer == "" {
+			return fmt.Errorf("%w: %s requires the buyer contact", ErrVoucherUnavailable, v.Code)
+		}
+		n, err := r.CountRedemptions(v.IdVoucher, customer)
+		if err != nil {
+			return err
+		}
+		if n >= int64(v.MaxUsesPerCustomer) {
+			return fmt.Errorf("%w: %s has already been used by this customer", ErrVoucherUnavailable, v.Code)
+		}
+	}
+	return nil
+}
+
+// redeemVoucher counts a use of the voucher for the sale. r must be bound to
+// the checkout's database transaction: the voucher row stays locked until it
+// ends, so concurrent checkouts cannot both take its last use.
+func redeemVoucher(r repo.VouchersRepo, idVoucher, idTransaction, customer string, at time.Time) error {
+	v, err := r.GetByIDForUpdate(idVoucher)
+	if err != nil {
+		return ErrVoucherNotFound
+	}
+	customer = voucherCustomer(customer)
+	if err := voucherUsable(r, v, customer, at); err != nil {
+		return err
+	}
+	return r.CreateRedemption(&entity.VoucherRedemptions{
+		IdVoucherRedemption: helper.Uuid(),
+		IdVoucher:           v.IdVoucher,
+		IdTransaction:       idTransaction,
+		Customer:            customer,
+	})
+}
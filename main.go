@@ -39,7 +39,13 @@ func main() {
 		return
 	}
 
-	srv := di.InitializeServer()
+	app := di.InitializeApp()
+	srv := app.Server
+
+	jobs, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	go app.PaymentIntents.RunExpiry(jobs, time.Minute)
+	go app.Dashboard.Run(jobs)
 
 	go func() {
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
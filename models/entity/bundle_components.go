@@ -0,0 +1,16 @@
+package entity
+
+import "time"
+
+// BundleComponents lists the items a bundle item is made of.
+type BundleComponents struct {
+	IdBundleComponent string `json:"id_bundle_component" gorm:"type:varchar(36);unique;primaryKey;not null"`
+	IdBundle          string `json:"id_bundle" gorm:"type:varchar(36);not null;index"`
+	IdItem            string `json:"id_item" gorm:"type:varchar(36);not null;index"`
+
+	Quantity  int `json:"quantity" gorm:"not null;default:1"`
+	SortOrder int `json:"sort_order" gorm:"default:0"`
+
+	IsDeleted bool      `json:"is_deleted" gorm:"type:boolean;default:false"`
+	Timestamp time.Time `json:"timestamp" gorm:"autoCreateTime"`
+}
@@ -0,0 +1,21 @@
+package entity
+
+import "time"
+
+type Categories struct {
+	IdCategory string `json:"id_category" gorm:"type:varchar(36);unique;primaryKey;not null"`
+	IdParent   string `json:"id_parent" gorm:"type:varchar(36);index"`
+
+	Name      string `json:"name" gorm:"type:varchar(100);not null"`
+	SortOrder int    `json:"sort_order" gorm:"default:0"`
+	Color     string `json:"color" gorm:"type:varchar(20)"`
+	IdImage   string `json:"id_image" gorm:"type:varchar(36)"`
+	IsActive  bool   `json:"is_active" gorm:"type:boolean;default:true"`
+	// TaxRate applies to the category's items and subcategories, in
+	// percent; nil inherits from the parent or the store default.
+	TaxRate *float64 `json:"tax_rate" gorm:"type:decimal(5,2)"`
+
+	IsDeleted bool      `json:"is_deleted" gorm:"type:boolean;default:false"`
+	Timestamp time.Time `json:"timestamp" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
@@ -0,0 +1,21 @@
+package entity
+
+import "time"
+
+// Customers is the buyer directory. Phone is stored in E.164 and is unique
+// among customers that are not deleted, so the till can look a customer up
+// by the number they give. ConsentAt is when MarketingConsent last changed.
+type Customers struct {
+	IdCustomer string `json:"id_customer" gorm:"type:varchar(36);unique;primaryKey;not null"`
+	Name       string `json:"name" gorm:"type:varchar(100)"`
+	Phone      string `json:"phone" gorm:"type:varchar(20);not null;uniqueIndex:idx_customers_phone,where:is_deleted = false"`
+	Email      string `json:"email" gorm:"type:varchar(255)"`
+	Notes      string `json:"notes" gorm:"type:text"`
+
+	MarketingConsent bool       `json:"marketing_consent" gorm:"type:boolean;default:false"`
+	ConsentAt        *time.Time `json:"consent_at"`
+
+	IsDeleted bool      `json:"is_deleted" gorm:"type:boolean;default:false"`
+	Timestamp time.Time `json:"timestamp" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
@@ -0,0 +1,28 @@
+package entity
+
+import "time"
+
+// DayCloses are the end-of-day closings (Z-reports). A close covers
+// PeriodFrom <= t < PeriodTo, which runs from the end of the previous close
+// to the end of BusinessDate, and locks it: sales, voids and refunds cannot
+// be made or changed in a closed period. Number is sequential. Closes are
+// never changed or removed; Report is the full report as it was at closing,
+// in JSON, and the other figures summarise it.
+type DayCloses struct {
+	IdDayClose   string    `json:"id_day_close" gorm:"type:varchar(36);unique;primaryKey;not null"`
+	Number       int       `json:"number" gorm:"not null;uniqueIndex"`
+	BusinessDate string    `json:"business_date" gorm:"type:varchar(10);not null;uniqueIndex"`
+	PeriodFrom   time.Time `json:"period_from" gorm:"not null"`
+	PeriodTo     time.Time `json:"period_to" gorm:"not null;index"`
+	ClosedBy     string    `json:"closed_by" gorm:"type:varchar(36);not null"`
+
+	Sales          int    `json:"sales" gorm:"not null;default:0"`
+	SalesTotal     Money  `json:"sales_total" gorm:"type:decimal(12,2);not null;default:0"`
+	TaxTotal       Money  `json:"tax_total" gorm:"type:decimal(12,2);not null;default:0"`
+	RefundTotal    Money  `json:"refund_total" gorm:"type:decimal(12,2);not null;default:0"`
+	NetSales       Money  `json:"net_sales" gorm:"type:decimal(12,2);not null;default:0"`
+	CashDifference Money  `json:"cash_difference" gorm:"type:decimal(12,2);not null;default:0"`
+	Report         string `json:"-" gorm:"type:text;not null"`
+
+	Timestamp time.Time `json:"timestamp" gorm:"autoCreateTime"`
+}
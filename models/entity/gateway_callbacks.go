@@ -0,0 +1,22 @@
+package entity
+
+import "time"
+
+// GatewayCallbacks stores every webhook accepted from a payment gateway. The
+// unique (gateway, event_id) pair makes callback processing idempotent.
+// RefundNeeded marks a payment the customer made that no sale took, such as
+// one arriving after its intent expired; it has to be refunded by hand.
+type GatewayCallbacks struct {
+	IdGatewayCallback string `json:"id_gateway_callback" gorm:"type:varchar(36);unique;primaryKey;not null"`
+	Gateway           string `json:"gateway" gorm:"type:varchar(30);not null;uniqueIndex:idx_gateway_callbacks_event"`
+	EventID           string `json:"event_id" gorm:"type:varchar(100);not null;uniqueIndex:idx_gateway_callbacks_event"`
+	IdPaymentIntent   string `json:"id_payment_intent" gorm:"type:varchar(36);index"`
+
+	Status       string `json:"status" gorm:"type:varchar(20)"`
+	Amount       Money  `json:"amount" gorm:"type:decimal(12,2);not null;default:0"`
+	Outcome      string `json:"outcome" gorm:"type:varchar(100)"`
+	RefundNeeded bool   `json:"refund_needed" gorm:"not null;default:false;index"`
+	Payload      string `json:"payload" gorm:"type:text"`
+
+	Timestamp time.Time `json:"timestamp" gorm:"autoCreateTime"`
+}
@@ -0,0 +1,54 @@
+package entity
+
+import "time"
+
+const (
+	GiftCardKindGiftCard    = "gift_card"
+	GiftCardKindStoreCredit = "store_credit"
+
+	GiftCardStatusActive   = "active"
+	GiftCardStatusDisabled = "disabled"
+
+	GiftCardEntryIssue  = "issue"
+	GiftCardEntryTopUp  = "topup"
+	GiftCardEntryRedeem = "redeem"
+	GiftCardEntryRefund = "refund"
+	// GiftCardEntryReverse undoes the entries of a voided or cancelled sale.
+	GiftCardEntryReverse = "reverse"
+	GiftCardEntryAdjust  = "adjust"
+)
+
+// GiftCards are stored value accounts: gift cards sold at the till and store
+// credit issued on refunds. Code and PIN pay with the account; store credit
+// that belongs to a customer can also be used by the customer's sales.
+// Balance always equals the sum of the account's ledger and is only written
+// together with a ledger entry while the row is locked.
+type GiftCards struct {
+	IdGiftCard string `json:"id_gift_card" gorm:"type:varchar(36);unique;primaryKey;not null"`
+	Kind       string `json:"kind" gorm:"type:varchar(20);not null;index"`
+	Code       string `json:"code" gorm:"type:varchar(32);not null;uniqueIndex"`
+	PinHash    string `json:"-" gorm:"type:varchar(100);not null"`
+	IdCustomer string `json:"id_customer,omitempty" gorm:"type:varchar(36);index"`
+	Balance    Money  `json:"balance" gorm:"type:decimal(12,2);not null;default:0"`
+	Status     string `json:"status" gorm:"type:varchar(10);not null;default:'active'"`
+
+	Timestamp time.Time `json:"timestamp" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// GiftCardLedger is one movement of a stored value account. Entries are
+// never changed or removed; Amount is signed and Balance is the account's
+// balance after it.
+type GiftCardLedger struct {
+	IdGiftCardEntry string `json:"id_gift_card_entry" gorm:"type:varchar(36);unique;primaryKey;not null"`
+	IdGiftCard      string `json:"id_gift_card" gorm:"type:varchar(36);not null;index"`
+	Kind            string `json:"kind" gorm:"type:varchar(10);not null"`
+	Amount          Money  `json:"amount" gorm:"type:decimal(12,2);not null"`
+	Balance         Money  `json:"balance" gorm:"type:decimal(12,2);not null"`
+	IdTransaction   string `json:"id_transaction,omitempty" gorm:"type:varchar(36);index"`
+	IdRefund        string `json:"id_refund,omitempty" gorm:"type:varchar(36)"`
+	IdUser          string `json:"id_user,omitempty" gorm:"type:varchar(36)"`
+	Note            string `json:"note" gorm:"type:varchar(255)"`
+
+	Timestamp time.Time `json:"timestamp" gorm:"autoCreateTime;index"`
+}
@@ -0,0 +1,73 @@
+package entity
+
+import "time"
+
+const (
+	HeldOrderStatusOpen       = "open"
+	HeldOrderStatusCheckedOut = "checked_out"
+	HeldOrderStatusCancelled  = "cancelled"
+	// HeldOrderStatusMerged had its lines moved to the order MergedInto.
+	HeldOrderStatusMerged = "merged"
+)
+
+// HeldOrders are orders put aside before they are paid: a basket parked
+// while the customer fetches their wallet, or a tab kept open for a table.
+// Lines can be added, changed and removed while the order is open. It is
+// paid through the normal checkout, which records the sale as IdTransaction
+// and closes the order. Terminal is the till the order was parked on.
+//
+// A dine-in order is open on IdTable, and on JoinedTables when other tables
+// were merged into it. An order split equally is replaced by SplitParts
+// orders, each the share SplitPart of the order SplitOf; they keep its
+// lines, and each is charged an equal share of them.
+type HeldOrders struct {
+	IdHeldOrder  string `json:"id_held_order" gorm:"type:varchar(36);unique;primaryKey;not null"`
+	Terminal     string `json:"terminal" gorm:"type:varchar(50);index"`
+	Label        string `json:"label" gorm:"type:varchar(100)"`
+	Note         string `json:"note" gorm:"type:varchar(255)"`
+	IdUser       string `json:"id_user" gorm:"type:varchar(36);not null"`
+	IdCustomer   string `json:"id_customer,omitempty" gorm:"type:varchar(36)"`
+	BuyerContact string `json:"buyer_contact" gorm:"type:varchar(120)"`
+	Status       string `json:"status" gorm:"type:varchar(15);not null;default:'open';index"`
+
+	IdTable      string   `json:"id_table,omitempty" gorm:"type:varchar(36);index"`
+	JoinedTables []string `json:"joined_tables,omitempty" gorm:"type:text;serializer:json"`
+	Guests       int      `json:"guests" gorm:"default:0"`
+	SplitOf      string   `json:"split_of,omitempty" gorm:"type:varchar(36);index"`
+	SplitPart    int      `json:"split_part,omitempty" gorm:"default:0"`
+	SplitParts   int      `json:"split_parts,omitempty" gorm:"default:0"`
+	MergedInto   string   `json:"merged_into,omitempty" gorm:"type:varchar(36)"`
+
+	IdTransaction string     `json:"id_transaction,omitempty" gorm:"type:varchar(36);index"`
+	ClosedBy      string     `json:"closed_by,omitempty" gorm:"type:varchar(36)"`
+	ClosedAt      *time.Time `json:"closed_at,omitempty"`
+
+	Lines []HeldOrderLines `json:"lines,omitempty" gorm:"-"`
+
+	Timestamp time.Time `json:"timestamp" gorm:"autoCreateTime;index"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// HeldOrderLines are the lines of a held order as the cashier entered them.
+// BasePrice and Price (with the modifiers) are the item's prices when the
+// line was added or last changed; they are what the line is charged at when
+// held orders keep their prices. GiftCard is the code of the card a gift
+// card item tops up.
+type HeldOrderLines struct {
+	IdHeldOrderLine string   `json:"id_held_order_line" gorm:"type:varchar(36);unique;primaryKey;not null"`
+	IdHeldOrder     string   `json:"id_held_order" gorm:"type:varchar(36);not null;index"`
+	IdItem          string   `json:"id_item" gorm:"type:varchar(36);not null"`
+	ItemName        string   `json:"item_name" gorm:"type:varchar(255)"`
+	Quantity        int      `json:"quantity" gorm:"not null"`
+	Modifiers       []string `json:"modifiers" gorm:"type:text;serializer:json"`
+	BasePrice       Money    `json:"base_price" gorm:"type:decimal(12,2);not null"`
+	Price           Money    `json:"price" gorm:"type:decimal(12,2);not null"`
+	GiftCard        string   `json:"gift_card,omitempty" gorm:"type:varchar(32)"`
+
+	DiscountKind   string  `json:"discount_kind,omitempty" gorm:"type:varchar(10)"`
+	DiscountValue  float64 `json:"discount_value,omitempty"`
+	DiscountReason string  `json:"discount_reason,omitempty" gorm:"type:varchar(255)"`
+
+	Timestamp time.Time `json:"timestamp" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
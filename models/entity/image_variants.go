@@ -0,0 +1,20 @@
+package entity
+
+import "time"
+
+// ImageVariants holds a resized rendition of an uploaded image (thumb, medium).
+type ImageVariants struct {
+	IdImageVariant string `json:"id_image_variant" gorm:"type:varchar(36);unique;primaryKey;not null"`
+	IdImage        string `json:"id_image" gorm:"type:varchar(36);not null;index"`
+
+	Variant     string `json:"variant" gorm:"type:varchar(20);not null"`
+	FileName    string `json:"file_name" gorm:"type:varchar(255)"`
+	ContentType string `json:"content_type" gorm:"type:varchar(120)"`
+	Size        int64  `json:"size"`
+	Width       int    `json:"width"`
+	Height      int    `json:"height"`
+	Checksum    string `json:"checksum" gorm:"type:varchar(64)"`
+
+	IsDeleted bool      `json:"is_deleted" gorm:"type:boolean;default:false"`
+	Timestamp time.Time `json:"timestamp" gorm:"autoCreateTime"`
+}
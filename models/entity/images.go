@@ -7,7 +7,12 @@ type Images struct {
 	FileName    string    `json:"file_name" gorm:"type:varchar(255)"`
 	ContentType string    `json:"content_type" gorm:"type:varchar(120)"`
 	Size        int64     `json:"size"`
+	Width       int       `json:"width"`
+	Height      int       `json:"height"`
+	Checksum    string    `json:"checksum" gorm:"type:varchar(64)"`
 	Data        []byte    `json:"-" gorm:"type:bytea"`
 	IsDeleted   bool      `json:"is_deleted" gorm:"type:boolean;default:false"`
 	Timestamp   time.Time `json:"timestamp" gorm:"autoCreateTime"`
+
+	Variants []ImageVariants `json:"variants" gorm:"foreignKey:IdImage;references:IdImage;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
 }
@@ -0,0 +1,59 @@
+package entity
+
+import "time"
+
+const (
+	// StockEntryReceipt is goods received against a purchase order.
+	StockEntryReceipt = "receipt"
+	// StockEntrySale is goods sold, a bundle by its components.
+	StockEntrySale = "sale"
+	// StockEntryVoid and StockEntryCancel put back what a voided sale or a
+	// cancelled gateway sale took.
+	StockEntryVoid   = "void"
+	StockEntryCancel = "cancel"
+	// StockEntryReturn is refunded goods put back on the shelf.
+	StockEntryReturn = "return"
+)
+
+// ItemStocks are what is on hand of an item and what it last cost. OnHand
+// always equals the sum of the item's stock ledger and is only written
+// together with a ledger entry while the row is locked. Items that were
+// never received have no row, and their stock is not tracked.
+type ItemStocks struct {
+	IdItem    string    `json:"id_item" gorm:"type:varchar(36);primaryKey;not null"`
+	OnHand    int       `json:"on_hand" gorm:"not null;default:0"`
+	LastCost  Money     `json:"last_cost" gorm:"type:decimal(12,2);not null;default:0"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// StockLedger is one movement of an item's stock. Entries are never changed
+// or removed; Quantity is signed and Balance is what is on hand after it.
+type StockLedger struct {
+	IdStockEntry    string `json:"id_stock_entry" gorm:"type:varchar(36);unique;primaryKey;not null"`
+	IdItem          string `json:"id_item" gorm:"type:varchar(36);not null;index"`
+	Kind            string `json:"kind" gorm:"type:varchar(10);not null"`
+	Quantity        int    `json:"quantity" gorm:"not null"`
+	Balance         int    `json:"balance" gorm:"not null"`
+	UnitCost        Money  `json:"unit_cost" gorm:"type:decimal(12,2);not null;default:0"`
+	IdPurchaseOrder string `json:"id_purchase_order,omitempty" gorm:"type:varchar(36)"`
+	IdGoodsReceipt  string `json:"id_goods_receipt,omitempty" gorm:"type:varchar(36);index"`
+	IdTransaction   string `json:"id_transaction,omitempty" gorm:"type:varchar(36);index"`
+	IdPivot         string `json:"id_pivot,omitempty" gorm:"type:varchar(36)"`
+	IdRefund        string `json:"id_refund,omitempty" gorm:"type:varchar(36)"`
+	IdUser          string `json:"id_user,omitempty" gorm:"type:varchar(36)"`
+	Note            string `json:"note" gorm:"type:varchar(255)"`
+
+	Timestamp time.Time `json:"timestamp" gorm:"autoCreateTime;index"`
+}
+
+// ItemCosts are the history of what an item cost: one entry for every
+// delivery it came in with, at the cost it was invoiced at.
+type ItemCosts struct {
+	IdItemCost     string `json:"id_item_cost" gorm:"type:varchar(36);unique;primaryKey;not null"`
+	IdItem         string `json:"id_item" gorm:"type:varchar(36);not null;index"`
+	UnitCost       Money  `json:"unit_cost" gorm:"type:decimal(12,2);not null"`
+	IdSupplier     string `json:"id_supplier" gorm:"type:varchar(36)"`
+	IdGoodsReceipt string `json:"id_goods_receipt" gorm:"type:varchar(36)"`
+
+	Timestamp time.Time `json:"timestamp" gorm:"autoCreateTime;index"`
+}
@@ -2,17 +2,32 @@ package entity
 
 import "time"
 
+const (
+	ItemKindSingle = "single"
+	// ItemKindBundle is sold as one unit at its own price but is made of the
+	// items listed in bundle_components.
+	ItemKindBundle = "bundle"
+	// ItemKindGiftCard sells a gift card loaded with the item's price. It is
+	// sold at face value: no discounts, service charge or tax.
+	ItemKindGiftCard = "gift_card"
+)
+
 type Items struct {
 	IdItem string `json:"id_item" gorm:"type:varchar(36);unique;primaryKey;not null"`
 
-	ItemName    string  `json:"item_name" gorm:"type:varchar(255);not null"`
-	ItemType    string  `json:"item_type" gorm:"type:varchar(50);index"`
-	IsAvailable bool    `json:"is_available" gorm:"type:boolean;default:true"`
-	Price       float64 `json:"price" gorm:"type:decimal(10,2);not null"`
-	Description string  `json:"description" gorm:"type:text"`
-	ImageUrl    string  `json:"image_url" gorm:"type:varchar(255)"`
+	ItemName    string `json:"item_name" gorm:"type:varchar(255);not null"`
+	ItemType    string `json:"item_type" gorm:"type:varchar(50);index"`
+	IdCategory  string `json:"id_category" gorm:"type:varchar(36);index"`
+	Kind        string `json:"kind" gorm:"type:varchar(20);not null;default:'single'"`
+	IsAvailable bool   `json:"is_available" gorm:"type:boolean;default:true"`
+	Price       Money  `json:"price" gorm:"type:decimal(12,2);not null"`
+	// TaxRate overrides the category's tax rate, in percent; nil inherits.
+	TaxRate     *float64 `json:"tax_rate" gorm:"type:decimal(5,2)"`
+	Description string   `json:"description" gorm:"type:text"`
+	ImageUrl    string   `json:"image_url" gorm:"type:varchar(255)"`
 
 	Timestamp time.Time `json:"timestamp" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime;index"`
 	IsDeleted bool      `json:"is_deleted" gorm:"type:boolean;default:false"`
 
 	PivotItemsToTransaction []PivotItemsToTransaction `json:"pivot_items_to_transaction" gorm:"foreignKey:IdItem;references:IdItem;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
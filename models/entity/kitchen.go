@@ -0,0 +1,63 @@
+package entity
+
+import "time"
+
+const (
+	KitchenTicketNew       = "new"
+	KitchenTicketReady     = "ready"
+	KitchenTicketCancelled = "cancelled"
+)
+
+// KitchenStations are the screens of the kitchen, e.g. "Grill" or "Bar".
+// A station gets the lines of the Categories it prepares, and of their
+// subcategories. A default station gets the lines no station claims; items
+// no station gets are not sent to the kitchen.
+type KitchenStations struct {
+	IdStation  string   `json:"id_station" gorm:"type:varchar(36);unique;primaryKey;not null"`
+	Name       string   `json:"name" gorm:"type:varchar(100);not null"`
+	Categories []string `json:"categories" gorm:"type:text;serializer:json"`
+	IsDefault  bool     `json:"is_default" gorm:"type:boolean;default:false"`
+	SortOrder  int      `json:"sort_order" gorm:"default:0"`
+
+	IsDeleted bool      `json:"is_deleted" gorm:"type:boolean;default:false"`
+	Timestamp time.Time `json:"timestamp" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// KitchenTickets are what a station has to prepare for one sale. A ticket
+// is new until the station bumps it as ready; recalling brings it back.
+// Label is the held order the sale paid for, e.g. its table, and
+// QueueNumber the sale's queue number. CollectedAt is set once the order
+// was handed over.
+type KitchenTickets struct {
+	IdTicket      string     `json:"id_ticket" gorm:"type:varchar(36);unique;primaryKey;not null"`
+	IdTransaction string     `json:"id_transaction" gorm:"type:varchar(36);not null;uniqueIndex:idx_kitchen_ticket_station"`
+	IdStation     string     `json:"id_station" gorm:"type:varchar(36);not null;uniqueIndex:idx_kitchen_ticket_station;index"`
+	Label         string     `json:"label" gorm:"type:varchar(100)"`
+	Status        string     `json:"status" gorm:"type:varchar(15);not null;default:'new';index"`
+	BumpedBy      string     `json:"bumped_by,omitempty" gorm:"type:varchar(36)"`
+	BumpedAt      *time.Time `json:"bumped_at,omitempty"`
+	Recalls       int        `json:"recalls" gorm:"default:0"`
+	QueueNumber   int        `json:"queue_number,omitempty" gorm:"default:0;index"`
+	CollectedAt   *time.Time `json:"collected_at,omitempty"`
+
+	Items []KitchenTicketItems `json:"items,omitempty" gorm:"-"`
+
+	Timestamp time.Time `json:"timestamp" gorm:"autoCreateTime;index"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// KitchenTicketItems are the lines of a ticket. A bundle is sent as its
+// components, each to its own station, with Bundle naming the bundle.
+// Modifiers lists the picked options as the kitchen reads them.
+type KitchenTicketItems struct {
+	IdTicketItem string `json:"id_ticket_item" gorm:"type:varchar(36);unique;primaryKey;not null"`
+	IdTicket     string `json:"id_ticket" gorm:"type:varchar(36);not null;index"`
+	IdPivot      string `json:"id_pivot" gorm:"type:varchar(36)"`
+	IdItem       string `json:"id_item" gorm:"type:varchar(36);not null"`
+	ItemName     string `json:"item_name" gorm:"type:varchar(255)"`
+	Quantity     int    `json:"quantity"`
+	Modifiers    string `json:"modifiers,omitempty" gorm:"type:varchar(500)"`
+	Bundle       string `json:"bundle,omitempty" gorm:"type:varchar(255)"`
+	Position     int    `json:"position"`
+}
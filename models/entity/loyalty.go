@@ -0,0 +1,64 @@
+package entity
+
+import "time"
+
+const (
+	LoyaltyRuleSpend = "spend"
+	LoyaltyRuleItem  = "item"
+
+	LoyaltyEntryEarn    = "earn"
+	LoyaltyEntryRedeem  = "redeem"
+	LoyaltyEntryReverse = "reverse"
+	LoyaltyEntryRestore = "restore"
+	LoyaltyEntryExpire  = "expire"
+)
+
+// LoyaltyRules award points on a sale. A spend rule gives Points for every
+// full Step of what the customer paid; an item rule gives Points for every
+// unit of IdItem bought.
+type LoyaltyRules struct {
+	IdLoyaltyRule string `json:"id_loyalty_rule" gorm:"type:varchar(36);unique;primaryKey;not null"`
+	Name          string `json:"name" gorm:"type:varchar(100);not null"`
+	Kind          string `json:"kind" gorm:"type:varchar(10);not null"`
+	Step          Money  `json:"step" gorm:"type:decimal(12,2);default:0"`
+	IdItem        string `json:"id_item" gorm:"type:varchar(36);index"`
+	Points        int    `json:"points" gorm:"not null"`
+
+	IsActive  bool      `json:"is_active" gorm:"type:boolean;default:true"`
+	IsDeleted bool      `json:"is_deleted" gorm:"type:boolean;default:false"`
+	Timestamp time.Time `json:"timestamp" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// LoyaltyTiers multiply the points a customer earns once what they spent
+// over the tier window reaches MinSpend. The highest tier reached applies.
+type LoyaltyTiers struct {
+	IdLoyaltyTier string  `json:"id_loyalty_tier" gorm:"type:varchar(36);unique;primaryKey;not null"`
+	Name          string  `json:"name" gorm:"type:varchar(50);not null"`
+	MinSpend      Money   `json:"min_spend" gorm:"type:decimal(12,2);not null"`
+	Multiplier    float64 `json:"multiplier" gorm:"type:decimal(5,2);not null"`
+
+	IsDeleted bool      `json:"is_deleted" gorm:"type:boolean;default:false"`
+	Timestamp time.Time `json:"timestamp" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// LoyaltyLedger is one movement of a customer's points; the balance is the
+// sum of Points. Earned and restored points form lots that are spent oldest
+// expiry first: Remaining is what is left of the lot until it expires. A
+// reversal of points already spent leaves a debt, a negative Remaining,
+// which the next points earned pay off.
+type LoyaltyLedger struct {
+	IdLoyaltyEntry string `json:"id_loyalty_entry" gorm:"type:varchar(36);unique;primaryKey;not null"`
+	IdCustomer     string `json:"id_customer" gorm:"type:varchar(36);not null;index"`
+	IdTransaction  string `json:"id_transaction,omitempty" gorm:"type:varchar(36);index"`
+	IdRefund       string `json:"id_refund,omitempty" gorm:"type:varchar(36)"`
+
+	Kind      string     `json:"kind" gorm:"type:varchar(10);not null"`
+	Points    int        `json:"points" gorm:"not null"`
+	Remaining int        `json:"remaining" gorm:"default:0;index"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	Note      string     `json:"note" gorm:"type:varchar(255)"`
+
+	Timestamp time.Time `json:"timestamp" gorm:"autoCreateTime"`
+}
@@ -0,0 +1,27 @@
+package entity
+
+import "time"
+
+// Modifier group kinds. A variant group picks exactly one option (size S/M/L),
+// a modifier group picks between MinSelect and MaxSelect options (toppings).
+const (
+	ModifierKindVariant  = "variant"
+	ModifierKindModifier = "modifier"
+)
+
+type ModifierGroups struct {
+	IdModifierGroup string `json:"id_modifier_group" gorm:"type:varchar(36);unique;primaryKey;not null"`
+	IdItem          string `json:"id_item" gorm:"type:varchar(36);not null;index"`
+
+	Name       string `json:"name" gorm:"type:varchar(100);not null"`
+	Kind       string `json:"kind" gorm:"type:varchar(20);not null;default:'modifier'"`
+	IsRequired bool   `json:"is_required" gorm:"type:boolean;default:false"`
+	MinSelect  int    `json:"min_select" gorm:"default:0"`
+	MaxSelect  int    `json:"max_select" gorm:"default:0"`
+	SortOrder  int    `json:"sort_order" gorm:"default:0"`
+
+	IsDeleted bool      `json:"is_deleted" gorm:"type:boolean;default:false"`
+	Timestamp time.Time `json:"timestamp" gorm:"autoCreateTime"`
+
+	Options []ModifierOptions `json:"options" gorm:"foreignKey:IdModifierGroup;references:IdModifierGroup;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+}
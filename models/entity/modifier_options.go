@@ -0,0 +1,17 @@
+package entity
+
+import "time"
+
+type ModifierOptions struct {
+	IdModifierOption string `json:"id_modifier_option" gorm:"type:varchar(36);unique;primaryKey;not null"`
+	IdModifierGroup  string `json:"id_modifier_group" gorm:"type:varchar(36);not null;index"`
+
+	Name        string `json:"name" gorm:"type:varchar(100);not null"`
+	PriceDelta  Money  `json:"price_delta" gorm:"type:decimal(10,2);default:0"`
+	IsDefault   bool   `json:"is_default" gorm:"type:boolean;default:false"`
+	IsAvailable bool   `json:"is_available" gorm:"type:boolean;default:true"`
+	SortOrder   int    `json:"sort_order" gorm:"default:0"`
+
+	IsDeleted bool      `json:"is_deleted" gorm:"type:boolean;default:false"`
+	Timestamp time.Time `json:"timestamp" gorm:"autoCreateTime"`
+}
@@ -0,0 +1,157 @@
+package entity
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Money is an amount in cents. Amounts are added up as integers so totals
+// never drift, and are read and written as decimals: decimal(12,2) columns in
+// the database and plain JSON numbers such as 29.99, the way float amounts
+// were served before.
+type Money int64
+
+// NewMoney rounds v to the nearest cent.
+func NewMoney(v float64) Money {
+	return Money(math.Round(v * 100))
+}
+
+// ParseMoney reads a decimal such as "1234.5" or "-0.75". Digits past the
+// cents are rounded half away from zero.
+func ParseMoney(s string) (Money, error) {
+	s = strings.TrimSpace(s)
+	if strings.ContainsAny(s, "eE") {
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid amount %q", s)
+		}
+		return NewMoney(v), nil
+	}
+	neg := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "-"), "+")
+	whole, frac, _ := strings.Cut(s, ".")
+	if whole == "" && frac == "" || !digits(whole) || !digits(frac) {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+	var c int64
+	for _, d := range whole {
+		c = c*10 + int64(d-'0')
+		if c > math.MaxInt64/1000 {
+			return 0, fmt.Errorf("amount %q out of range", s)
+		}
+	}
+	frac += "000"
+	c = c*100 + int64(frac[0]-'0')*10 + int64(frac[1]-'0')
+	if frac[2] >= '5' {
+		c++
+	}
+	if neg {
+		c = -c
+	}
+	return Money(c), nil
+}
+
+func digits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Float64 is for ratios and display only; never add amounts as floats.
+func (m Money) Float64() float64 {
+	return float64(m) / 100
+}
+
+// String formats m with two decimals, e.g. "1234.50".
+func (m Money) String() string {
+	sign := ""
+	c := int64(m)
+	if c < 0 {
+		sign, c = "-", -c
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, c/100, c%100)
+}
+
+// Mul is the amount of n units of m.
+func (m Money) Mul(n int) Money {
+	return m * Money(n)
+}
+
+// Percent is pct percent of m, rounded to the cent.
+func (m Money) Percent(pct float64) Money {
+	return Money(math.Round(float64(m) * pct / 100))
+}
+
+// Compact is the shortest decimal form of m: 1234.5 for 1234.50 and 1000
+// for 1000.00.
+func (m Money) Compact() string {
+	s := m.String()
+	if strings.HasSuffix(s, ".00") {
+		return strings.TrimSuffix(s, ".00")
+	}
+	return strings.TrimSuffix(s, "0")
+}
+
+// MarshalJSON writes m as a JSON number in compact form, the way the float
+// amounts were written.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.Compact()), nil
+}
+
+// UnmarshalJSON accepts a number or a numeric string.
+func (m *Money) UnmarshalJSON(b []byte) error {
+	s := string(b)
+	if s == "null" {
+		return nil
+	}
+	v, err := ParseMoney(strings.Trim(s, `"`))
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
+
+// Scan reads a numeric column, which the driver hands over as text.
+func (m *Money) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*m = 0
+	case []byte:
+		return m.scanString(string(v))
+	case string:
+		return m.scanString(v)
+	case int64:
+		*m = Money(v * 100)
+	case float64:
+		*m = NewMoney(v)
+	default:
+		return fmt.Errorf("cannot scan %T into Money", src)
+	}
+	return nil
+}
+
+func (m *Money) scanString(s string) error {
+	v, err := ParseMoney(s)
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
+
+// Value writes m as an exact decimal.
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
+
+// GormDataType is the column type of Money fields without a type tag.
+func (Money) GormDataType() string {
+	return "decimal(12,2)"
+}
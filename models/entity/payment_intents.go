@@ -0,0 +1,31 @@
+package entity
+
+import "time"
+
+const (
+	PaymentIntentPending = "pending"
+	PaymentIntentPaid    = "paid"
+	PaymentIntentFailed  = "failed"
+	PaymentIntentExpired = "expired"
+)
+
+// PaymentIntents tracks a payment requested from a payment gateway, e.g. a
+// dynamic QRIS code, until the gateway reports it paid or it expires.
+type PaymentIntents struct {
+	IdPaymentIntent string `json:"id_payment_intent" gorm:"type:varchar(36);unique;primaryKey;not null"`
+	IdTransaction   string `json:"id_transaction" gorm:"type:varchar(36);not null;index"`
+	IdPayment       string `json:"id_payment" gorm:"type:varchar(36);not null"`
+
+	Gateway    string `json:"gateway" gorm:"type:varchar(30);not null;uniqueIndex:idx_payment_intents_external"`
+	ExternalID string `json:"external_id" gorm:"type:varchar(100);not null;uniqueIndex:idx_payment_intents_external"`
+	Method     string `json:"method" gorm:"type:varchar(20);not null"`
+	Amount     Money  `json:"amount" gorm:"type:decimal(12,2);not null"`
+	QRPayload  string `json:"qr_payload" gorm:"type:text"`
+
+	Status    string     `json:"status" gorm:"type:varchar(20);not null;index"`
+	ExpiresAt time.Time  `json:"expires_at" gorm:"index"`
+	PaidAt    *time.Time `json:"paid_at"`
+
+	Timestamp time.Time `json:"timestamp" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
@@ -0,0 +1,36 @@
+package entity
+
+import "time"
+
+const (
+	PaymentMethodCash     = "cash"
+	PaymentMethodCard     = "card"
+	PaymentMethodQRIS     = "qris"
+	PaymentMethodEWallet  = "ewallet"
+	PaymentMethodTransfer = "transfer"
+	// PaymentMethodPoints pays with the customer's loyalty points.
+	PaymentMethodPoints = "points"
+	// PaymentMethodGiftCard and PaymentMethodStoreCredit pay from a stored
+	// value account, see GiftCards.
+	PaymentMethodGiftCard    = "gift_card"
+	PaymentMethodStoreCredit = "store_credit"
+)
+
+// Payments is one tender used to settle a transaction. Amount is the part of
+// the total it covers; Tendered and Change differ from it only for cash.
+type Payments struct {
+	IdPayment     string `json:"id_payment" gorm:"type:varchar(36);unique;primaryKey;not null"`
+	IdTransaction string `json:"id_transaction" gorm:"type:varchar(36);not null;index"`
+
+	Method    string `json:"method" gorm:"type:varchar(20);not null;index"`
+	Amount    Money  `json:"amount" gorm:"type:decimal(12,2);not null"`
+	Tendered  Money  `json:"tendered" gorm:"type:decimal(12,2)"`
+	Change    Money  `json:"change" gorm:"type:decimal(12,2)"`
+	Reference string `json:"reference" gorm:"type:varchar(100)"`
+	// Gateway names the payment gateway settling this tender; empty when the
+	// cashier recorded it directly.
+	Gateway string `json:"gateway" gorm:"type:varchar(30)"`
+
+	IsDeleted bool      `json:"is_deleted" gorm:"type:boolean;default:false"`
+	Timestamp time.Time `json:"timestamp" gorm:"autoCreateTime"`
+}
@@ -1,11 +1,30 @@
 package entity
 
 type PivotItemsToTransaction struct {
+	IdPivot       string `json:"id_pivot" gorm:"type:varchar(36);index"`
 	IdTransaction string `json:"id_transaction" gorm:"type:varchar(36);not null;index"`
 	IdItem        string `json:"id_item" gorm:"type:varchar(36);not null;index"`
 
-	IsDeleted bool      `json:"is_deleted" gorm:"type:boolean;default:false"`
+	IsDeleted bool `json:"is_deleted" gorm:"type:boolean;default:false"`
 
-	Quantity int     `json:"quantity"`
-	Price    float64 `json:"price"`
+	// Price is the unit price charged, BasePrice plus the selected modifiers.
+	// Discount is what promotions and manual discounts took off the whole
+	// line, so the line comes to Quantity*Price - Discount.
+	Quantity  int   `json:"quantity"`
+	BasePrice Money `json:"base_price" gorm:"type:decimal(12,2)"`
+	Price     Money `json:"price" gorm:"type:decimal(12,2)"`
+	Discount  Money `json:"discount" gorm:"type:decimal(12,2);default:0"`
+
+	// TaxRate is the rate the line was taxed at and Tax the tax on it,
+	// including the tax on its share of the service charge. LineTotal is
+	// what the customer paid for the line after discounts, service charge
+	// and tax.
+	TaxRate       float64 `json:"tax_rate" gorm:"type:decimal(5,2);default:0"`
+	Tax           Money   `json:"tax" gorm:"type:decimal(12,2);default:0"`
+	ServiceCharge Money   `json:"service_charge" gorm:"type:decimal(12,2);default:0"`
+	LineTotal     Money   `json:"line_total" gorm:"type:decimal(12,2);default:0"`
+
+	Modifiers  []PivotLineModifiers  `json:"modifiers,omitempty" gorm:"-"`
+	Components []PivotLineComponents `json:"components,omitempty" gorm:"-"`
+	Discounts  []PivotLineDiscounts  `json:"discounts,omitempty" gorm:"-"`
 }
@@ -0,0 +1,18 @@
+package entity
+
+// PivotLineComponents records what a bundle line was made of when it was sold.
+// Quantity is the number of component units consumed by the whole line and
+// Revenue the part of the line's bundle price attributed to the component.
+type PivotLineComponents struct {
+	IdPivotLineComponent string `json:"id_pivot_line_component" gorm:"type:varchar(36);unique;primaryKey;not null"`
+	IdPivot              string `json:"id_pivot" gorm:"type:varchar(36);not null;index"`
+	IdTransaction        string `json:"id_transaction" gorm:"type:varchar(36);not null;index"`
+	IdBundle             string `json:"id_bundle" gorm:"type:varchar(36);not null"`
+	IdItem               string `json:"id_item" gorm:"type:varchar(36);not null;index"`
+
+	ItemName string `json:"item_name" gorm:"type:varchar(255)"`
+	Quantity int    `json:"quantity"`
+	Revenue  Money  `json:"revenue" gorm:"type:decimal(12,2)"`
+
+	IsDeleted bool `json:"is_deleted" gorm:"type:boolean;default:false"`
+}
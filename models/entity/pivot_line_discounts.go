@@ -0,0 +1,24 @@
+package entity
+
+const (
+	DiscountSourcePromotion = "promotion"
+	DiscountSourceManual    = "manual"
+)
+
+// PivotLineDiscounts records a discount given on a transaction line, either
+// by a promotion or manually by the cashier. Name is the promotion name at the
+// time of sale; IdVoucher is set when a voucher unlocked the promotion.
+type PivotLineDiscounts struct {
+	IdPivotLineDiscount string `json:"id_pivot_line_discount" gorm:"type:varchar(36);unique;primaryKey;not null"`
+	IdPivot             string `json:"id_pivot" gorm:"type:varchar(36);not null;index"`
+	IdTransaction       string `json:"id_transaction" gorm:"type:varchar(36);not null;index"`
+	IdPromotion         string `json:"id_promotion" gorm:"type:varchar(36);index"`
+	IdVoucher           string `json:"id_voucher" gorm:"type:varchar(36);index"`
+
+	Source string `json:"source" gorm:"type:varchar(20);not null"`
+	Name   string `json:"name" gorm:"type:varchar(120)"`
+	Reason string `json:"reason" gorm:"type:varchar(255)"`
+	Amount Money  `json:"amount" gorm:"type:decimal(12,2);not null"`
+
+	IsDeleted bool `json:"is_deleted" gorm:"type:boolean;default:false"`
+}
@@ -0,0 +1,17 @@
+package entity
+
+// PivotLineModifiers snapshots the options chosen for one transaction line so
+// later edits to the item's groups do not change past sales.
+type PivotLineModifiers struct {
+	IdPivotLineModifier string `json:"id_pivot_line_modifier" gorm:"type:varchar(36);unique;primaryKey;not null"`
+	IdPivot             string `json:"id_pivot" gorm:"type:varchar(36);not null;index"`
+	IdTransaction       string `json:"id_transaction" gorm:"type:varchar(36);not null;index"`
+
+	IdModifierGroup  string `json:"id_modifier_group" gorm:"type:varchar(36)"`
+	IdModifierOption string `json:"id_modifier_option" gorm:"type:varchar(36);index"`
+	GroupName        string `json:"group_name" gorm:"type:varchar(100)"`
+	OptionName       string `json:"option_name" gorm:"type:varchar(100)"`
+	PriceDelta       Money  `json:"price_delta" gorm:"type:decimal(10,2)"`
+
+	IsDeleted bool `json:"is_deleted" gorm:"type:boolean;default:false"`
+}
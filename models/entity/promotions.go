@@ -0,0 +1,53 @@
+package entity
+
+import "time"
+
+const (
+	PromotionKindPercent  = "percent"
+	PromotionKindFixed    = "fixed"
+	PromotionKindBuyXGetY = "buy_x_get_y"
+
+	PromotionScopeItem     = "item"
+	PromotionScopeCategory = "category"
+	PromotionScopeBasket   = "basket"
+)
+
+// Promotions is a discount rule applied automatically at checkout. Value is a
+// percentage for percent and buy_x_get_y promotions (0 makes the "get" units
+// free) and an amount per unit, or per basket for basket scope, for fixed
+// ones. DaysOfWeek lists weekdays as "1,2,3" with 0 for Sunday; StartTime
+// and EndTime bound the hours of the day as "15:04". Empty bounds do not
+// restrict.
+type Promotions struct {
+	IdPromotion string `json:"id_promotion" gorm:"type:varchar(36);unique;primaryKey;not null"`
+
+	Name     string  `json:"name" gorm:"type:varchar(120);not null"`
+	Kind     string  `json:"kind" gorm:"type:varchar(20);not null"`
+	Scope    string  `json:"scope" gorm:"type:varchar(20);not null"`
+	IdTarget string  `json:"id_target" gorm:"type:varchar(36);index"`
+	Value    float64 `json:"value" gorm:"type:decimal(12,2)"`
+
+	BuyQuantity int   `json:"buy_quantity"`
+	GetQuantity int   `json:"get_quantity"`
+	MinSpend    Money `json:"min_spend" gorm:"type:decimal(12,2)"`
+
+	StartsAt   *time.Time `json:"starts_at"`
+	EndsAt     *time.Time `json:"ends_at"`
+	DaysOfWeek string     `json:"days_of_week" gorm:"type:varchar(20)"`
+	StartTime  string     `json:"start_time" gorm:"type:varchar(5)"`
+	EndTime    string     `json:"end_time" gorm:"type:varchar(5)"`
+
+	// Promotions apply in descending Priority. A promotion that is not
+	// Stackable only discounts lines no earlier promotion discounted, and no
+	// later promotion discounts the lines it did.
+	Priority  int  `json:"priority"`
+	Stackable bool `json:"stackable" gorm:"type:boolean;default:false"`
+	// VoucherOnly promotions apply only to sales redeeming one of their
+	// vouchers.
+	VoucherOnly bool `json:"voucher_only" gorm:"type:boolean;default:false"`
+	IsActive    bool `json:"is_active" gorm:"type:boolean;default:true"`
+
+	IsDeleted bool      `json:"is_deleted" gorm:"type:boolean;default:false"`
+	Timestamp time.Time `json:"timestamp" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
@@ -0,0 +1,78 @@
+package entity
+
+import "time"
+
+const (
+	PurchaseOrderStatusDraft = "draft"
+	PurchaseOrderStatusSent  = "sent"
+	// PurchaseOrderStatusPartiallyReceived has had goods received on it,
+	// but not all that was ordered.
+	PurchaseOrderStatusPartiallyReceived = "partially_received"
+	// PurchaseOrderStatusClosed was received in full, or closed with
+	// whatever was still missing.
+	PurchaseOrderStatusClosed = "closed"
+)
+
+// PurchaseOrders are orders placed with a supplier. A draft can be changed
+// until it is sent; goods are then received against it, in as many
+// deliveries as it takes, until everything ordered has come in or the
+// order is closed. Number is sequential but may skip. Total is what the
+// lines come to at their ordered cost.
+type PurchaseOrders struct {
+	IdPurchaseOrder string     `json:"id_purchase_order" gorm:"type:varchar(36);unique;primaryKey;not null"`
+	Number          int64      `json:"number" gorm:"autoIncrement;uniqueIndex"`
+	IdSupplier      string     `json:"id_supplier" gorm:"type:varchar(36);not null;index"`
+	Status          string     `json:"status" gorm:"type:varchar(20);not null;default:'draft';index"`
+	Note            string     `json:"note" gorm:"type:varchar(255)"`
+	ExpectedAt      *time.Time `json:"expected_at,omitempty"`
+	Total           Money      `json:"total" gorm:"type:decimal(12,2);not null;default:0"`
+	IdUser          string     `json:"id_user" gorm:"type:varchar(36);not null"`
+
+	SentAt   *time.Time `json:"sent_at,omitempty"`
+	ClosedBy string     `json:"closed_by,omitempty" gorm:"type:varchar(36)"`
+	ClosedAt *time.Time `json:"closed_at,omitempty"`
+
+	Lines    []PurchaseOrderLines `json:"lines,omitempty" gorm:"-"`
+	Receipts []GoodsReceipts      `json:"receipts,omitempty" gorm:"-"`
+
+	Timestamp time.Time `json:"timestamp" gorm:"autoCreateTime;index"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// PurchaseOrderLines are what is ordered of an item and at what cost per
+// unit. Received counts the units come in so far.
+type PurchaseOrderLines struct {
+	IdPurchaseOrderLine string `json:"id_purchase_order_line" gorm:"type:varchar(36);unique;primaryKey;not null"`
+	IdPurchaseOrder     string `json:"id_purchase_order" gorm:"type:varchar(36);not null;index"`
+	IdItem              string `json:"id_item" gorm:"type:varchar(36);not null;index"`
+	ItemName            string `json:"item_name" gorm:"type:varchar(255)"`
+	Quantity            int    `json:"quantity" gorm:"not null"`
+	Received            int    `json:"received" gorm:"not null;default:0"`
+	UnitCost            Money  `json:"unit_cost" gorm:"type:decimal(12,2);not null"`
+	Position            int    `json:"position" gorm:"default:0"`
+}
+
+// GoodsReceipts record a delivery received against a purchase order.
+// Receipts are never changed or removed.
+type GoodsReceipts struct {
+	IdGoodsReceipt  string `json:"id_goods_receipt" gorm:"type:varchar(36);unique;primaryKey;not null"`
+	IdPurchaseOrder string `json:"id_purchase_order" gorm:"type:varchar(36);not null;index"`
+	IdUser          string `json:"id_user" gorm:"type:varchar(36);not null"`
+	Note            string `json:"note" gorm:"type:varchar(255)"`
+	Total           Money  `json:"total" gorm:"type:decimal(12,2);not null;default:0"`
+
+	Lines []GoodsReceiptLines `json:"lines,omitempty" gorm:"-"`
+
+	Timestamp time.Time `json:"timestamp" gorm:"autoCreateTime;index"`
+}
+
+// GoodsReceiptLines are the units of an order line that came in with a
+// delivery, at the cost they were invoiced at.
+type GoodsReceiptLines struct {
+	IdGoodsReceiptLine  string `json:"id_goods_receipt_line" gorm:"type:varchar(36);unique;primaryKey;not null"`
+	IdGoodsReceipt      string `json:"id_goods_receipt" gorm:"type:varchar(36);not null;index"`
+	IdPurchaseOrderLine string `json:"id_purchase_order_line" gorm:"type:varchar(36);not null"`
+	IdItem              string `json:"id_item" gorm:"type:varchar(36);not null"`
+	Quantity            int    `json:"quantity" gorm:"not null"`
+	UnitCost            Money  `json:"unit_cost" gorm:"type:decimal(12,2);not null"`
+}
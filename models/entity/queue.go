@@ -0,0 +1,11 @@
+package entity
+
+import "time"
+
+// QueueCounters hand out the queue numbers of one day. LastNumber is the
+// number the latest sale of Day got; the first sale of a day gets 1.
+type QueueCounters struct {
+	Day        string    `json:"day" gorm:"type:varchar(10);primaryKey;not null"`
+	LastNumber int       `json:"last_number" gorm:"not null;default:0"`
+	UpdatedAt  time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
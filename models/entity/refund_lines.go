@@ -0,0 +1,19 @@
+package entity
+
+// RefundLines is the part of one transaction line that was returned. Amount
+// is Quantity times the unit price the line was sold at. Restock tells
+// whether the returned units went back on the shelf.
+type RefundLines struct {
+	IdRefundLine  string `json:"id_refund_line" gorm:"type:varchar(36);unique;primaryKey;not null"`
+	IdRefund      string `json:"id_refund" gorm:"type:varchar(36);not null;index"`
+	IdTransaction string `json:"id_transaction" gorm:"type:varchar(36);not null;index"`
+	IdPivot       string `json:"id_pivot" gorm:"type:varchar(36);not null;index"`
+	IdItem        string `json:"id_item" gorm:"type:varchar(36);not null;index"`
+
+	Quantity  int   `json:"quantity"`
+	UnitPrice Money `json:"unit_price" gorm:"type:decimal(12,2)"`
+	Amount    Money `json:"amount" gorm:"type:decimal(12,2)"`
+	Restock   bool  `json:"restock" gorm:"type:boolean;default:false"`
+
+	IsDeleted bool `json:"is_deleted" gorm:"type:boolean;default:false"`
+}
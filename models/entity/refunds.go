@@ -0,0 +1,24 @@
+package entity
+
+import "time"
+
+// Refunds is money paid back against a completed transaction. Its lines say
+// which of the sale's lines, and how many units of each, were returned.
+type Refunds struct {
+	IdRefund      string `json:"id_refund" gorm:"type:varchar(36);unique;primaryKey;not null"`
+	IdTransaction string `json:"id_transaction" gorm:"type:varchar(36);not null;index"`
+	IdUser        string `json:"id_user" gorm:"type:varchar(36);not null"`
+	// IdShift is the open shift of the refunding user, whose drawer paid
+	// a cash refund out; empty when they had none.
+	IdShift string `json:"id_shift,omitempty" gorm:"type:varchar(36);index"`
+
+	Reason    string `json:"reason" gorm:"type:varchar(255)"`
+	Method    string `json:"method" gorm:"type:varchar(20);not null;index"`
+	Amount    Money  `json:"amount" gorm:"type:decimal(12,2);not null"`
+	Reference string `json:"reference" gorm:"type:varchar(100)"`
+
+	IsDeleted bool      `json:"is_deleted" gorm:"type:boolean;default:false"`
+	Timestamp time.Time `json:"timestamp" gorm:"autoCreateTime;index"`
+
+	Lines []RefundLines `json:"lines,omitempty" gorm:"-"`
+}
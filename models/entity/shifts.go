@@ -0,0 +1,61 @@
+package entity
+
+import "time"
+
+const (
+	ShiftStatusOpen   = "open"
+	ShiftStatusClosed = "closed"
+
+	// ShiftMovementCashIn is money put into the drawer, e.g. more change.
+	ShiftMovementCashIn = "cash_in"
+	// ShiftMovementCashOut is petty cash paid out of the drawer.
+	ShiftMovementCashOut = "cash_out"
+	// ShiftMovementDrop is cash taken to the safe during the shift.
+	ShiftMovementDrop = "drop"
+)
+
+// Shifts are the periods a cashier works a cash drawer, from the opening
+// float to the count at close. A cashier has at most one open shift, and
+// every sale they ring up belongs to it. ExpectedCash, CountedCash and
+// Difference (counted minus expected: positive over, negative short) are
+// set when the shift is closed.
+type Shifts struct {
+	IdShift      string `json:"id_shift" gorm:"type:varchar(36);unique;primaryKey;not null"`
+	IdUser       string `json:"id_user" gorm:"type:varchar(36);not null;index;uniqueIndex:idx_shifts_open_user,where:status = 'open'"`
+	Status       string `json:"status" gorm:"type:varchar(10);not null;default:'open';index"`
+	OpeningFloat Money  `json:"opening_float" gorm:"type:decimal(12,2);not null;default:0"`
+	OpenNote     string `json:"open_note" gorm:"type:varchar(255)"`
+
+	ClosedAt     *time.Time `json:"closed_at,omitempty"`
+	ClosedBy     string     `json:"closed_by,omitempty" gorm:"type:varchar(36)"`
+	ExpectedCash Money      `json:"expected_cash" gorm:"type:decimal(12,2);default:0"`
+	CountedCash  Money      `json:"counted_cash" gorm:"type:decimal(12,2);default:0"`
+	Difference   Money      `json:"difference" gorm:"type:decimal(12,2);default:0"`
+	CloseNote    string     `json:"close_note" gorm:"type:varchar(255)"`
+
+	Timestamp time.Time `json:"timestamp" gorm:"autoCreateTime;index"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// ShiftMovements are cash put into or taken out of the drawer other than by
+// sales and refunds. Amount is always positive; Kind gives the direction.
+type ShiftMovements struct {
+	IdShiftMovement string `json:"id_shift_movement" gorm:"type:varchar(36);unique;primaryKey;not null"`
+	IdShift         string `json:"id_shift" gorm:"type:varchar(36);not null;index"`
+	IdUser          string `json:"id_user" gorm:"type:varchar(36);not null"`
+	Kind            string `json:"kind" gorm:"type:varchar(10);not null"`
+	Amount          Money  `json:"amount" gorm:"type:decimal(12,2);not null"`
+	Reason          string `json:"reason" gorm:"type:varchar(255)"`
+
+	Timestamp time.Time `json:"timestamp" gorm:"autoCreateTime"`
+}
+
+// ShiftCounts are the cash counted in the drawer at close, one row per
+// denomination.
+type ShiftCounts struct {
+	IdShiftCount string `json:"id_shift_count" gorm:"type:varchar(36);unique;primaryKey;not null"`
+	IdShift      string `json:"id_shift" gorm:"type:varchar(36);not null;index"`
+	Denomination Money  `json:"denomination" gorm:"type:decimal(12,2);not null"`
+	Quantity     int    `json:"quantity" gorm:"not null"`
+	Amount       Money  `json:"amount" gorm:"type:decimal(12,2);not null"`
+}
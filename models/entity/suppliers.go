@@ -0,0 +1,19 @@
+package entity
+
+import "time"
+
+// Suppliers are who the store buys its stock from. Purchase orders are
+// placed with a supplier; deleting one keeps its orders.
+type Suppliers struct {
+	IdSupplier  string `json:"id_supplier" gorm:"type:varchar(36);unique;primaryKey;not null"`
+	Name        string `json:"name" gorm:"type:varchar(150);not null"`
+	ContactName string `json:"contact_name" gorm:"type:varchar(100)"`
+	Phone       string `json:"phone" gorm:"type:varchar(30)"`
+	Email       string `json:"email" gorm:"type:varchar(100)"`
+	Address     string `json:"address" gorm:"type:varchar(255)"`
+	Note        string `json:"note" gorm:"type:varchar(255)"`
+
+	IsDeleted bool      `json:"is_deleted" gorm:"type:boolean;default:false"`
+	Timestamp time.Time `json:"timestamp" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
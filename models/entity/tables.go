@@ -0,0 +1,42 @@
+package entity
+
+import "time"
+
+const (
+	TableShapeSquare = "square"
+	TableShapeRound  = "round"
+	TableShapeRect   = "rect"
+)
+
+// TableAreas group the tables of the floor, e.g. "Indoor" or "Terrace".
+type TableAreas struct {
+	IdTableArea string `json:"id_table_area" gorm:"type:varchar(36);unique;primaryKey;not null"`
+	Name        string `json:"name" gorm:"type:varchar(100);not null"`
+	SortOrder   int    `json:"sort_order" gorm:"default:0"`
+
+	IsDeleted bool      `json:"is_deleted" gorm:"type:boolean;default:false"`
+	Timestamp time.Time `json:"timestamp" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// DiningTables are the tables guests are seated at. PosX, PosY, Width and
+// Height place the table on its area's floor plan, in the units the floor
+// plan is drawn in. A table is occupied while a held order is open on it;
+// inactive tables take no orders.
+type DiningTables struct {
+	IdTable     string `json:"id_table" gorm:"type:varchar(36);unique;primaryKey;not null"`
+	IdTableArea string `json:"id_table_area" gorm:"type:varchar(36);not null;index"`
+	Name        string `json:"name" gorm:"type:varchar(30);not null"`
+	Seats       int    `json:"seats" gorm:"default:0"`
+
+	Shape  string `json:"shape" gorm:"type:varchar(10);default:'square'"`
+	PosX   int    `json:"pos_x" gorm:"default:0"`
+	PosY   int    `json:"pos_y" gorm:"default:0"`
+	Width  int    `json:"width" gorm:"default:1"`
+	Height int    `json:"height" gorm:"default:1"`
+
+	IsActive  bool      `json:"is_active" gorm:"type:boolean;default:true"`
+	IsDeleted bool      `json:"is_deleted" gorm:"type:boolean;default:false"`
+	Timestamp time.Time `json:"timestamp" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
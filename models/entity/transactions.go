@@ -2,12 +2,59 @@ package entity
 
 import "time"
 
+const (
+	TransactionStatusCompleted = "completed"
+	// TransactionStatusPending waits for a payment gateway to confirm payment.
+	TransactionStatusPending = "pending"
+	// TransactionStatusCancelled was never paid, e.g. its payment intent expired.
+	TransactionStatusCancelled = "cancelled"
+	// TransactionStatusVoided was completed and then annulled by a manager.
+	TransactionStatusVoided = "voided"
+	// TransactionStatusRefunded was completed and has been paid back in full.
+	TransactionStatusRefunded = "refunded"
+)
+
 type Transactions struct {
 	IdTransaction string `json:"id_transaction" gorm:"type:varchar(36);unique;primaryKey;not null"`
 	IdUser        string `json:"id_user" gorm:"type:varchar(36);not null;index"`
+	// IdShift is the cashier's shift the sale was rung up in.
+	IdShift string `json:"id_shift,omitempty" gorm:"type:varchar(36);index"`
+
+	// IdCustomer links the sale to the customer directory. BuyerContact is
+	// the customer's E.164 phone for linked sales and free text otherwise.
+	IdCustomer   string `json:"id_customer,omitempty" gorm:"type:varchar(36);index"`
+	BuyerContact string `json:"buyer_contact" gorm:"type:varchar(120)"`
+	TotalPrice   Money  `json:"total_price" gorm:"type:decimal(12,2)"`
+	// DiscountTotal is the sum of the line discounts; TotalPrice is already
+	// net of it.
+	DiscountTotal Money `json:"discount_total" gorm:"type:decimal(12,2);default:0"`
+	// Subtotal is the discounted lines without tax; TotalPrice is the grand
+	// total Subtotal + ServiceCharge + TaxTotal. TaxInclusive records
+	// whether the prices charged already contained the tax.
+	Subtotal      Money `json:"subtotal" gorm:"type:decimal(12,2);default:0"`
+	ServiceCharge Money `json:"service_charge" gorm:"type:decimal(12,2);default:0"`
+	TaxTotal      Money `json:"tax_total" gorm:"type:decimal(12,2);default:0"`
+	TaxInclusive  bool  `json:"tax_inclusive" gorm:"type:boolean;default:false"`
+	// CashRounding is what rounding the cash part of the sale added to
+	// TotalPrice, negative when it was rounded down. The payments add up to
+	// TotalPrice + CashRounding.
+	CashRounding Money  `json:"cash_rounding" gorm:"type:decimal(12,2);default:0"`
+	Status       string `json:"status" gorm:"type:varchar(20);not null;default:'completed';index"`
+	VoucherCode  string `json:"voucher_code,omitempty" gorm:"type:varchar(40)"`
+	// A bill split equally between guests is rung up as SplitParts sales,
+	// this one being SplitPart. Each is charged an equal share of every line
+	// of the held order SplitOf but keeps the whole Quantity of the lines,
+	// so units are counted on part 1 only.
+	SplitOf    string `json:"split_of,omitempty" gorm:"type:varchar(36);index"`
+	SplitPart  int    `json:"split_part,omitempty" gorm:"default:0"`
+	SplitParts int    `json:"split_parts,omitempty" gorm:"default:0"`
+	// QueueNumber is called out when the order is ready. It starts at 1
+	// every day; the shares of a split bill after the first get none.
+	QueueNumber int `json:"queue_number,omitempty" gorm:"default:0"`
 
-	BuyerContact string  `json:"buyer_contact" gorm:"type:varchar(120)"`
-	TotalPrice   float64 `json:"total_price"`
+	VoidReason string     `json:"void_reason,omitempty" gorm:"type:varchar(255)"`
+	VoidedBy   string     `json:"voided_by,omitempty" gorm:"type:varchar(36)"`
+	VoidedAt   *time.Time `json:"voided_at,omitempty"`
 
 	IsDeleted bool `json:"is_deleted" gorm:"type:boolean;default:false"`
 
@@ -2,6 +2,12 @@ package entity
 
 import "time"
 
+const (
+	RoleAdmin   = "admin"
+	RoleManager = "manager"
+	RoleCashier = "cashier"
+)
+
 type Users struct {
 	IdUser   string `json:"id_user" gorm:"type:varchar(36);unique;primaryKey;not null"`
 	Email    string `json:"email" gorm:"type:varchar(255);not null;unique"`
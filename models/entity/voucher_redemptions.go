@@ -0,0 +1,16 @@
+package entity
+
+import "time"
+
+// VoucherRedemptions records a voucher used on a sale. Customer is the
+// normalized buyer contact the per-customer limit is counted on. Redemptions
+// of sales that are voided or never paid are released by deleting them.
+type VoucherRedemptions struct {
+	IdVoucherRedemption string `json:"id_voucher_redemption" gorm:"type:varchar(36);unique;primaryKey;not null"`
+	IdVoucher           string `json:"id_voucher" gorm:"type:varchar(36);not null;index"`
+	IdTransaction       string `json:"id_transaction" gorm:"type:varchar(36);not null;index"`
+	Customer            string `json:"customer" gorm:"type:varchar(120);index"`
+
+	IsDeleted bool      `json:"is_deleted" gorm:"type:boolean;default:false"`
+	Timestamp time.Time `json:"timestamp" gorm:"autoCreateTime"`
+}
@@ -0,0 +1,26 @@
+package entity
+
+import "time"
+
+// Vouchers is a coupon code that unlocks a voucher-only promotion at checkout.
+// MaxUses caps redemptions over all customers and MaxUsesPerCustomer those of
+// a single customer; 0 means unlimited. Codes generated in a batch share
+// Batch and are single use.
+type Vouchers struct {
+	IdVoucher   string `json:"id_voucher" gorm:"type:varchar(36);unique;primaryKey;not null"`
+	Code        string `json:"code" gorm:"type:varchar(40);uniqueIndex;not null"`
+	IdPromotion string `json:"id_promotion" gorm:"type:varchar(36);not null;index"`
+	Batch       string `json:"batch" gorm:"type:varchar(36);index"`
+
+	StartsAt *time.Time `json:"starts_at"`
+	EndsAt   *time.Time `json:"ends_at"`
+
+	MaxUses            int `json:"max_uses"`
+	MaxUsesPerCustomer int `json:"max_uses_per_customer"`
+	UsedCount          int `json:"used_count" gorm:"default:0"`
+
+	IsActive  bool      `json:"is_active" gorm:"type:boolean;default:true"`
+	IsDeleted bool      `json:"is_deleted" gorm:"type:boolean;default:false"`
+	Timestamp time.Time `json:"timestamp" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
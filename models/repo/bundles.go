@@ -0,0 +1,60 @@
+package repo
+
+import (
+	"faizalmaulana/lsp/models/entity"
+
+	"gorm.io/gorm"
+)
+
+type BundlesRepo interface {
+	ListComponents(idBundle string) ([]entity.BundleComponents, error)
+	ReplaceComponents(idBundle string, comps []entity.BundleComponents) error
+	CountBundlesUsing(idItem string) (int64, error)
+}
+
+type GormBundlesRepo struct {
+	db *gorm.DB
+}
+
+func NewGormBundlesRepo(db *gorm.DB) BundlesRepo {
+	return &GormBundlesRepo{db: db}
+}
+
+func (r *GormBundlesRepo) ListComponents(idBundle string) ([]entity.BundleComponents, error) {
+	var out []entity.BundleComponents
+	if err := r.db.Where("id_bundle = ? AND is_deleted = ?", idBundle, false).
+		Order("sort_order ASC").Find(&out).Error; err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ReplaceComponents swaps the component list of a bundle and keeps the item's
+// kind in step: an item with components is a bundle, one without is not.
+func (r *GormBundlesRepo) ReplaceComponents(idBundle string, comps []entity.BundleComponents) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&entity.BundleComponents{}).
+			Where("id_bundle = ? AND is_deleted = ?", idBundle, false).
+			Update("is_deleted", true).Error; err != nil {
+			return err
+		}
+		kind := entity.ItemKindSingle
+		if len(comps) > 0 {
+			kind = entity.ItemKindBundle
+			if err := tx.Create(&comps).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(&entity.Items{}).Where("id_item = ?", idBundle).Update("kind", kind).Error
+	})
+}
+
+// CountBundlesUsing returns how many live bundles list idItem as a component.
+func (r *GormBundlesRepo) CountBundlesUsing(idItem string) (int64, error) {
+	var n int64
+	err := r.db.Model(&entity.BundleComponents{}).
+		Joins("JOIN items ON items.id_item = bundle_components.id_bundle AND items.is_deleted = ?", false).
+		Where("bundle_components.id_item = ? AND bundle_components.is_deleted = ?", idItem, false).
+		Count(&n).Error
+	return n, err
+}
@@ -0,0 +1,84 @@
+package repo
+
+import (
+	"errors"
+	"faizalmaulana/lsp/models/entity"
+
+	"gorm.io/gorm"
+)
+
+type CategoriesRepo interface {
+	Create(u *entity.Categories) error
+	GetByID(id string) (*entity.Categories, error)
+	List(activeOnly bool) ([]*entity.Categories, error)
+	CountChildren(id string) (int64, error)
+	CountItems(id string) (int64, error)
+	Update(u *entity.Categories) error
+	Delete(id string) error
+}
+
+type GormCategoriesRepo struct {
+	db *gorm.DB
+}
+
+func NewGormCategoriesRepo(db *gorm.DB) CategoriesRepo {
+	return &GormCategoriesRepo{db: db}
+}
+
+func (r *GormCategoriesRepo) Create(u *entity.Categories) error {
+	return r.db.Create(u).Error
+}
+
+func (r *GormCategoriesRepo) GetByID(id string) (*entity.Categories, error) {
+	var u entity.Categories
+	if err := r.db.Where("id_category = ? AND is_deleted = ?", id, false).First(&u).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("not found")
+		}
+		return nil, err
+	}
+	return &u, nil
+}
+
+func (r *GormCategoriesRepo) List(activeOnly bool) ([]*entity.Categories, error) {
+	var out []*entity.Categories
+	query := r.db.Where("is_deleted = ?", false)
+	if activeOnly {
+		query = query.Where("is_active = ?", true)
+	}
+	if err := query.Order("sort_order ASC").Order("name ASC").Find(&out).Error; err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *GormCategoriesRepo) CountChildren(id string) (int64, error) {
+	var n int64
+	err := r.db.Model(&entity.Categories{}).Where("id_parent = ? AND is_deleted = ?", id, false).Count(&n).Error
+	return n, err
+}
+
+func (r *GormCategoriesRepo) CountItems(id string) (int64, error) {
+	var n int64
+	err := r.db.Model(&entity.Items{}).Where("id_category = ? AND is_deleted = ?", id, false).Count(&n).Error
+	return n, err
+}
+
+// Update selects the editable columns explicitly so is_active=false and a
+// cleared id_parent are written; a plain Updates would skip zero values.
+// The category's items get its name in item_type in the same transaction,
+// which also moves their updated_at and so the catalog version.
+func (r *GormCategoriesRepo) Update(u *entity.Categories) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&entity.Categories{}).Where("id_category = ?", u.IdCategory).
+			Select("id_parent", "name", "sort_order", "color", "id_image", "is_active", "tax_rate").Updates(u).Error; err != nil {
+			return err
+		}
+		return tx.Model(&entity.Items{}).Where("id_category = ? AND is_deleted = ?", u.IdCategory, false).
+			Update("item_type", u.Name).Error
+	})
+}
+
+func (r *GormCategoriesRepo) Delete(id string) error {
+	return r.db.Model(&entity.Categories{}).Where("id_category = ?", id).Update("is_deleted", true).Error
+}
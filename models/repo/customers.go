@@ -0,0 +1,97 @@
+package repo
+
+import (
+	"errors"
+	"faizalmaulana/lsp/models/entity"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type CustomersRepo interface {
+	WithTx(tx *gorm.DB) CustomersRepo
+	Create(c *entity.Customers) error
+	// CreateIfAbsent creates c unless a live customer already has its
+	// phone, reporting whether it did.
+	CreateIfAbsent(c *entity.Customers) (bool, error)
+	GetByID(id string) (*entity.Customers, error)
+	// GetByPhone finds the live customer with an E.164 phone.
+	GetByPhone(phone string) (*entity.Customers, error)
+	// ListPage matches search against name, phone and email when it is not
+	// empty, ordered by name.
+	ListPage(search string, limit, offset int) ([]*entity.Customers, error)
+	Update(c *entity.Customers) error
+	Delete(id string) error
+}
+
+type GormCustomersRepo struct{ db *gorm.DB }
+
+func NewGormCustomersRepo(db *gorm.DB) CustomersRepo {
+	return &GormCustomersRepo{db: db}
+}
+
+func (r *GormCustomersRepo) WithTx(tx *gorm.DB) CustomersRepo {
+	return &GormCustomersRepo{db: tx}
+}
+
+func (r *GormCustomersRepo) Create(c *entity.Customers) error {
+	return r.db.Create(c).Error
+}
+
+func (r *GormCustomersRepo) CreateIfAbsent(c *entity.Customers) (bool, error) {
+	res := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(c)
+	return res.RowsAffected > 0, res.Error
+}
+
+func (r *GormCustomersRepo) GetByID(id string) (*entity.Customers, error) {
+	return r.first("id_customer = ? AND is_deleted = ?", id, false)
+}
+
+func (r *GormCustomersRepo) GetByPhone(phone string) (*entity.Customers, error) {
+	return r.first("phone = ? AND is_deleted = ?", phone, false)
+}
+
+func (r *GormCustomersRepo) first(query string, args ...interface{}) (*entity.Customers, error) {
+	var c entity.Customers
+	if err := r.db.Where(query, args...).First(&c).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("not found")
+		}
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *GormCustomersRepo) ListPage(search string, limit, offset int) ([]*entity.Customers, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	var out []*entity.Customers
+	query := r.db.Where("is_deleted = ?", false)
+	if search != "" {
+		like := "%" + search + "%"
+		query = query.Where("name ILIKE ? OR phone LIKE ? OR email ILIKE ?", like, like, like)
+	}
+	if err := query.Order("name ASC").Order("phone ASC").Limit(limit).Offset(offset).Find(&out).Error; err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Update selects the editable columns explicitly so cleared fields and a
+// withdrawn consent are written too.
+func (r *GormCustomersRepo) Update(c *entity.Customers) error {
+	return r.db.Model(&entity.Customers{}).Where("id_customer = ?", c.IdCustomer).
+		Select("name", "phone", "email", "notes", "marketing_consent", "consent_at").
+		Updates(c).Error
+}
+
+func (r *GormCustomersRepo) Delete(id string) error {
+	return r.db.Model(&entity.Customers{}).Where("id_customer = ?", id).Update("is_deleted", true).Error
+}
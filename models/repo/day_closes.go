@@ -0,0 +1,104 @@
+package repo
+
+import (
+	"errors"
+	"faizalmaulana/lsp/models/entity"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DayClosesRepo has no update or delete: a close is final.
+type DayClosesRepo interface {
+	WithTx(tx *gorm.DB) DayClosesRepo
+	Create(d *entity.DayCloses) error
+	GetByID(id string) (*entity.DayCloses, error)
+	GetByNumber(number int) (*entity.DayCloses, error)
+	// GetLatest returns the close with the highest number.
+	GetLatest() (*entity.DayCloses, error)
+	// ListPage returns the closes newest first.
+	ListPage(limit, offset int) ([]entity.DayCloses, error)
+	// LockedUntil is the end of the latest closed period, or the zero time
+	// when no day has been closed.
+	LockedUntil() (time.Time, error)
+	// LockPeriod takes the period lock until the surrounding database
+	// transaction ends. Writes into the open period share it and a close
+	// takes it exclusively, so a close waits for the writes that checked
+	// the period and the writes after it see the new close.
+	LockPeriod(exclusive bool) error
+}
+
+// periodLockKey is the advisory lock key of the period lock.
+const periodLockKey = 4402
+
+type GormDayClosesRepo struct{ db *gorm.DB }
+
+func NewGormDayClosesRepo(db *gorm.DB) DayClosesRepo {
+	return &GormDayClosesRepo{db: db}
+}
+
+func (r *GormDayClosesRepo) WithTx(tx *gorm.DB) DayClosesRepo {
+	return &GormDayClosesRepo{db: tx}
+}
+
+func (r *GormDayClosesRepo) Create(d *entity.DayCloses) error {
+	return r.db.Create(d).Error
+}
+
+func (r *GormDayClosesRepo) first(query *gorm.DB, args ...interface{}) (*entity.DayCloses, error) {
+	var out entity.DayCloses
+	if err := query.First(&out, args...).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("not found")
+		}
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (r *GormDayClosesRepo) GetByID(id string) (*entity.DayCloses, error) {
+	return r.first(r.db, "id_day_close = ?", id)
+}
+
+func (r *GormDayClosesRepo) GetByNumber(number int) (*entity.DayCloses, error) {
+	return r.first(r.db, "number = ?", number)
+}
+
+func (r *GormDayClosesRepo) GetLatest() (*entity.DayCloses, error) {
+	return r.first(r.db.Order("number DESC"))
+}
+
+func (r *GormDayClosesRepo) ListPage(limit, offset int) ([]entity.DayCloses, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	var out []entity.DayCloses
+	if err := r.db.Order("number DESC").Limit(limit).Offset(offset).Find(&out).Error; err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *GormDayClosesRepo) LockedUntil() (time.Time, error) {
+	var until *time.Time
+	if err := r.db.Model(&entity.DayCloses{}).Select("MAX(period_to)").Scan(&until).Error; err != nil {
+		return time.Time{}, err
+	}
+	if until == nil {
+		return time.Time{}, nil
+	}
+	return *until, nil
+}
+
+func (r *GormDayClosesRepo) LockPeriod(exclusive bool) error {
+	if exclusive {
+		return r.db.Exec("SELECT pg_advisory_xact_lock(?)", periodLockKey).Error
+	}
+	return r.db.Exec("SELECT pg_advisory_xact_lock_shared(?)", periodLockKey).Error
+}
@@ -0,0 +1,55 @@
+package repo
+
+import (
+	"faizalmaulana/lsp/models/entity"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type GatewayCallbacksRepo interface {
+	WithTx(tx *gorm.DB) GatewayCallbacksRepo
+	// Record stores a callback and reports false when the same gateway event
+	// was stored before.
+	Record(c *entity.GatewayCallbacks) (bool, error)
+	SetOutcome(id, outcome string, refundNeeded bool) error
+	// List returns callbacks newest first, only those needing a refund when
+	// refundNeeded is set.
+	List(refundNeeded bool, limit, offset int) ([]entity.GatewayCallbacks, error)
+}
+
+type GormGatewayCallbacksRepo struct{ db *gorm.DB }
+
+func NewGormGatewayCallbacksRepo(db *gorm.DB) GatewayCallbacksRepo {
+	return &GormGatewayCallbacksRepo{db: db}
+}
+
+func (r *GormGatewayCallbacksRepo) WithTx(tx *gorm.DB) GatewayCallbacksRepo {
+	return &GormGatewayCallbacksRepo{db: tx}
+}
+
+func (r *GormGatewayCallbacksRepo) Record(c *entity.GatewayCallbacks) (bool, error) {
+	res := r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "gateway"}, {Name: "event_id"}},
+		DoNothing: true,
+	}).Create(c)
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected > 0, nil
+}
+
+func (r *GormGatewayCallbacksRepo) SetOutcome(id, outcome string, refundNeeded bool) error {
+	return r.db.Model(&entity.GatewayCallbacks{}).Where("id_gateway_callback = ?", id).
+		Updates(map[string]interface{}{"outcome": outcome, "refund_needed": refundNeeded}).Error
+}
+
+func (r *GormGatewayCallbacksRepo) List(refundNeeded bool, limit, offset int) ([]entity.GatewayCallbacks, error) {
+	var out []entity.GatewayCallbacks
+	q := r.db.Model(&entity.GatewayCallbacks{})
+	if refundNeeded {
+		q = q.Where("refund_needed = ?", true)
+	}
+	err := q.Order("timestamp DESC").Limit(limit).Offset(offset).Find(&out).Error
+	return out, err
+}
@@ -0,0 +1,158 @@
+package repo
+
+import (
+	"errors"
+	"faizalmaulana/lsp/models/entity"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GiftCardLiability is what is owed on the accounts of one kind at a point
+// in time.
+type GiftCardLiability struct {
+	Kind     string       `json:"kind"`
+	Accounts int64        `json:"accounts"`
+	Balance  entity.Money `json:"balance"`
+}
+
+type GiftCardsRepo interface {
+	WithTx(tx *gorm.DB) GiftCardsRepo
+	Create(g *entity.GiftCards) error
+	GetByID(id string) (*entity.GiftCards, error)
+	GetByCode(code string) (*entity.GiftCards, error)
+	// GetForUpdate locks the account until the surrounding database
+	// transaction ends, which serializes the writes to its balance.
+	GetForUpdate(id string) (*entity.GiftCards, error)
+	// GetStoreCredit returns the customer's store credit account.
+	GetStoreCredit(idCustomer string) (*entity.GiftCards, error)
+	// ListPage filters by kind and customer when they are set, newest first.
+	ListPage(kind, idCustomer string, limit, offset int) ([]entity.GiftCards, error)
+	SetBalance(id string, balance entity.Money) error
+	SetStatus(id, status string) error
+
+	CreateEntry(e *entity.GiftCardLedger) error
+	// ListEntries pages through an account's ledger, newest first.
+	ListEntries(idGiftCard string, limit, offset int) ([]entity.GiftCardLedger, error)
+	ListByTransaction(idTransaction string) ([]entity.GiftCardLedger, error)
+	// Liability sums the ledger up to at per kind of account, counting the
+	// accounts with money left on them.
+	Liability(at time.Time) ([]GiftCardLiability, error)
+}
+
+type GormGiftCardsRepo struct{ db *gorm.DB }
+
+func NewGormGiftCardsRepo(db *gorm.DB) GiftCardsRepo {
+	return &GormGiftCardsRepo{db: db}
+}
+
+func (r *GormGiftCardsRepo) WithTx(tx *gorm.DB) GiftCardsRepo {
+	return &GormGiftCardsRepo{db: tx}
+}
+
+func (r *GormGiftCardsRepo) Create(g *entity.GiftCards) error {
+	return r.db.Create(g).Error
+}
+
+func (r *GormGiftCardsRepo) first(query *gorm.DB, args ...interface{}) (*entity.GiftCards, error) {
+	var out entity.GiftCards
+	if err := query.First(&out, args...).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("not found")
+		}
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (r *GormGiftCardsRepo) GetByID(id string) (*entity.GiftCards, error) {
+	return r.first(r.db, "id_gift_card = ?", id)
+}
+
+func (r *GormGiftCardsRepo) GetByCode(code string) (*entity.GiftCards, error) {
+	return r.first(r.db, "code = ?", code)
+}
+
+func (r *GormGiftCardsRepo) GetForUpdate(id string) (*entity.GiftCards, error) {
+	return r.first(r.db.Clauses(clause.Locking{Strength: "UPDATE"}), "id_gift_card = ?", id)
+}
+
+func (r *GormGiftCardsRepo) GetStoreCredit(idCustomer string) (*entity.GiftCards, error) {
+	return r.first(r.db.Order("timestamp ASC"), "kind = ? AND id_customer = ?", entity.GiftCardKindStoreCredit, idCustomer)
+}
+
+func (r *GormGiftCardsRepo) ListPage(kind, idCustomer string, limit, offset int) ([]entity.GiftCards, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	query := r.db.Model(&entity.GiftCards{})
+	if kind != "" {
+		query = query.Where("kind = ?", kind)
+	}
+	if idCustomer != "" {
+		query = query.Where("id_customer = ?", idCustomer)
+	}
+	var out []entity.GiftCards
+	if err := query.Order("timestamp DESC").Limit(limit).Offset(offset).Find(&out).Error; err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *GormGiftCardsRepo) SetBalance(id string, balance entity.Money) error {
+	return r.db.Model(&entity.GiftCards{}).Where("id_gift_card = ?", id).Update("balance", balance).Error
+}
+
+func (r *GormGiftCardsRepo) SetStatus(id, status string) error {
+	return r.db.Model(&entity.GiftCards{}).Where("id_gift_card = ?", id).Update("status", status).Error
+}
+
+func (r *GormGiftCardsRepo) CreateEntry(e *entity.GiftCardLedger) error {
+	return r.db.Create(e).Error
+}
+
+func (r *GormGiftCardsRepo) ListEntries(idGiftCard string, limit, offset int) ([]entity.GiftCardLedger, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	var out []entity.GiftCardLedger
+	if err := r.db.Where("id_gift_card = ?", idGiftCard).
+		Order("timestamp DESC").Limit(limit).Offset(offset).Find(&out).Error; err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *GormGiftCardsRepo) ListByTransaction(idTransaction string) ([]entity.GiftCardLedger, error) {
+	var out []entity.GiftCardLedger
+	if err := r.db.Where("id_transaction = ?", idTransaction).Order("timestamp ASC").Find(&out).Error; err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *GormGiftCardsRepo) Liability(at time.Time) ([]GiftCardLiability, error) {
+	var out []GiftCardLiability
+	err := r.db.Raw(`SELECT kind, COUNT(*) FILTER (WHERE balance > 0) AS accounts, COALESCE(SUM(balance), 0) AS balance
+		FROM (
+			SELECT g.kind, SUM(l.amount) AS balance
+			FROM gift_card_ledgers l JOIN gift_cards g ON g.id_gift_card = l.id_gift_card
+			WHERE l.timestamp <= ?
+			GROUP BY g.id_gift_card, g.kind
+		) a
+		GROUP BY kind ORDER BY kind`, at).Scan(&out).Error
+	return out, err
+}
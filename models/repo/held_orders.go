@@ -0,0 +1,230 @@
+package repo
+
+import (
+	"errors"
+	"faizalmaulana/lsp/models/entity"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type HeldOrdersRepo interface {
+	WithTx(tx *gorm.DB) HeldOrdersRepo
+	Create(o *entity.HeldOrders) error
+	GetByID(id string) (*entity.HeldOrders, error)
+	// GetForUpdate locks the order until the surrounding database
+	// transaction ends; every change to an order or its lines takes it.
+	GetForUpdate(id string) (*entity.HeldOrders, error)
+	// GetByTransaction returns the order checked out as the sale
+	// idTransaction.
+	GetByTransaction(idTransaction string) (*entity.HeldOrders, error)
+	// ListPage filters by terminal and status when they are set, oldest
+	// first.
+	ListPage(terminal, status string, limit, offset int) ([]entity.HeldOrders, error)
+	// ListOpenOnTable returns the open orders on the table idTable, whether
+	// it is their own table or one joined to it, oldest first.
+	ListOpenOnTable(idTable string) ([]entity.HeldOrders, error)
+	// ListOpenOnTables returns every open order on a table.
+	ListOpenOnTables() ([]entity.HeldOrders, error)
+	// Update stores the terminal, label, note, guests and buyer of o.
+	Update(o *entity.HeldOrders) error
+	// SetTables stores the table and joined tables of o.
+	SetTables(o *entity.HeldOrders) error
+	// SetSplit stores which share of which split bill o is.
+	SetSplit(o *entity.HeldOrders) error
+	// Touch marks the order changed; a change to its lines touches it.
+	Touch(id string) error
+	// Close moves an open order to status and reports whether it was still
+	// open and, unless seen is zero, last changed at seen.
+	Close(id, status, idTransaction, by string, at, seen time.Time) (bool, error)
+	// Merge closes the open order id as merged into the order into.
+	Merge(id, into, by string, at time.Time) (bool, error)
+
+	CreateLine(l *entity.HeldOrderLines) error
+	GetLine(idHeldOrder, id string) (*entity.HeldOrderLines, error)
+	UpdateLine(l *entity.HeldOrderLines) error
+	DeleteLine(id string) error
+	// MoveLine moves the line id to the order idHeldOrder.
+	MoveLine(id, idHeldOrder string) error
+	ListLines(idHeldOrder string) ([]entity.HeldOrderLines, error)
+	ListLinesByOrders(ids []string) ([]entity.HeldOrderLines, error)
+}
+
+type GormHeldOrdersRepo struct{ db *gorm.DB }
+
+func NewGormHeldOrdersRepo(db *gorm.DB) HeldOrdersRepo {
+	return &GormHeldOrdersRepo{db: db}
+}
+
+func (r *GormHeldOrdersRepo) WithTx(tx *gorm.DB) HeldOrdersRepo {
+	return &GormHeldOrdersRepo{db: tx}
+}
+
+func (r *GormHeldOrdersRepo) Create(o *entity.HeldOrders) error {
+	return r.db.Create(o).Error
+}
+
+func (r *GormHeldOrdersRepo) first(query *gorm.DB, args ...interface{}) (*entity.HeldOrders, error) {
+	var out entity.HeldOrders
+	if err := query.First(&out, args...).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("not found")
+		}
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (r *GormHeldOrdersRepo) GetByID(id string) (*entity.HeldOrders, error) {
+	return r.first(r.db, "id_held_order = ?", id)
+}
+
+func (r *GormHeldOrdersRepo) GetForUpdate(id string) (*entity.HeldOrders, error) {
+	return r.first(r.db.Clauses(clause.Locking{Strength: "UPDATE"}), "id_held_order = ?", id)
+}
+
+func (r *GormHeldOrdersRepo) GetByTransaction(idTransaction string) (*entity.HeldOrders, error) {
+	return r.first(r.db, "id_transaction = ?", idTransaction)
+}
+
+func (r *GormHeldOrdersRepo) ListPage(terminal, status string, limit, offset int) ([]entity.HeldOrders, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	query := r.db.Model(&entity.HeldOrders{})
+	if terminal != "" {
+		query = query.Where("terminal = ?", terminal)
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var out []entity.HeldOrders
+	if err := query.Order("timestamp ASC").Limit(limit).Offset(offset).Find(&out).Error; err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *GormHeldOrdersRepo) ListOpenOnTable(idTable string) ([]entity.HeldOrders, error) {
+	var out []entity.HeldOrders
+	// joined_tables holds a JSON array of ids, so the quoted id only
+	// matches a whole element.
+	if err := r.db.Where("status = ? AND (id_table = ? OR joined_tables LIKE ?)", entity.HeldOrderStatusOpen, idTable, `%"`+idTable+`"%`).
+		Order("timestamp ASC").Find(&out).Error; err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *GormHeldOrdersRepo) ListOpenOnTables() ([]entity.HeldOrders, error) {
+	var out []entity.HeldOrders
+	if err := r.db.Where("status = ? AND id_table <> ''", entity.HeldOrderStatusOpen).Order("timestamp ASC").Find(&out).Error; err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *GormHeldOrdersRepo) Update(o *entity.HeldOrders) error {
+	return r.db.Model(&entity.HeldOrders{}).Where("id_held_order = ?", o.IdHeldOrder).Updates(map[string]interface{}{
+		"terminal":      o.Terminal,
+		"label":         o.Label,
+		"note":          o.Note,
+		"guests":        o.Guests,
+		"id_customer":   o.IdCustomer,
+		"buyer_contact": o.BuyerContact,
+	}).Error
+}
+
+func (r *GormHeldOrdersRepo) SetTables(o *entity.HeldOrders) error {
+	return r.db.Model(&entity.HeldOrders{}).Where("id_held_order = ?", o.IdHeldOrder).
+		Select("id_table", "joined_tables", "guests").Updates(o).Error
+}
+
+func (r *GormHeldOrdersRepo) SetSplit(o *entity.HeldOrders) error {
+	return r.db.Model(&entity.HeldOrders{}).Where("id_held_order = ?", o.IdHeldOrder).
+		Select("split_of", "split_part", "split_parts").Updates(o).Error
+}
+
+func (r *GormHeldOrdersRepo) Touch(id string) error {
+	return r.db.Model(&entity.HeldOrders{}).Where("id_held_order = ?", id).Update("updated_at", time.Now()).Error
+}
+
+func (r *GormHeldOrdersRepo) Close(id, status, idTransaction, by string, at, seen time.Time) (bool, error) {
+	query := r.db.Model(&entity.HeldOrders{}).Where("id_held_order = ? AND status = ?", id, entity.HeldOrderStatusOpen)
+	if !seen.IsZero() {
+		query = query.Where("updated_at = ?", seen)
+	}
+	res := query.Updates(map[string]interface{}{
+		"status":         status,
+		"id_transaction": idTransaction,
+		"closed_by":      by,
+		"closed_at":      at,
+	})
+	return res.RowsAffected > 0, res.Error
+}
+
+func (r *GormHeldOrdersRepo) Merge(id, into, by string, at time.Time) (bool, error) {
+	res := r.db.Model(&entity.HeldOrders{}).Where("id_held_order = ? AND status = ?", id, entity.HeldOrderStatusOpen).
+		Updates(map[string]interface{}{
+			"status":      entity.HeldOrderStatusMerged,
+			"merged_into": into,
+			"closed_by":   by,
+			"closed_at":   at,
+		})
+	return res.RowsAffected > 0, res.Error
+}
+
+func (r *GormHeldOrdersRepo) CreateLine(l *entity.HeldOrderLines) error {
+	return r.db.Create(l).Error
+}
+
+func (r *GormHeldOrdersRepo) GetLine(idHeldOrder, id string) (*entity.HeldOrderLines, error) {
+	var out entity.HeldOrderLines
+	if err := r.db.First(&out, "id_held_order_line = ? AND id_held_order = ?", id, idHeldOrder).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("not found")
+		}
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (r *GormHeldOrdersRepo) UpdateLine(l *entity.HeldOrderLines) error {
+	return r.db.Model(&entity.HeldOrderLines{}).Where("id_held_order_line = ?", l.IdHeldOrderLine).
+		Select("quantity", "modifiers", "base_price", "price", "gift_card", "discount_kind", "discount_value", "discount_reason").
+		Updates(l).Error
+}
+
+func (r *GormHeldOrdersRepo) DeleteLine(id string) error {
+	return r.db.Where("id_held_order_line = ?", id).Delete(&entity.HeldOrderLines{}).Error
+}
+
+func (r *GormHeldOrdersRepo) MoveLine(id, idHeldOrder string) error {
+	return r.db.Model(&entity.HeldOrderLines{}).Where("id_held_order_line = ?", id).Update("id_held_order", idHeldOrder).Error
+}
+
+func (r *GormHeldOrdersRepo) ListLines(idHeldOrder string) ([]entity.HeldOrderLines, error) {
+	var out []entity.HeldOrderLines
+	if err := r.db.Where("id_held_order = ?", idHeldOrder).Order("timestamp ASC").Find(&out).Error; err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *GormHeldOrdersRepo) ListLinesByOrders(ids []string) ([]entity.HeldOrderLines, error) {
+	var out []entity.HeldOrderLines
+	if len(ids) == 0 {
+		return out, nil
+	}
+	if err := r.db.Where("id_held_order IN ?", ids).Order("timestamp ASC").Find(&out).Error; err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -11,6 +11,8 @@ type ImagesRepo interface {
 	Create(img *entity.Images) error
 	GetByID(id string) (*entity.Images, error)
 	Delete(id string) error
+	CreateVariants(variants []entity.ImageVariants) error
+	GetVariant(idImage, variant string) (*entity.ImageVariants, error)
 }
 
 type gormImagesRepo struct{ db *gorm.DB }
@@ -33,5 +35,25 @@ func (r *gormImagesRepo) GetByID(id string) (*entity.Images, error) {
 }
 
 func (r *gormImagesRepo) Delete(id string) error {
-	return r.db.Model(&entity.Images{}).Where("id_image = ?", id).Update("is_deleted", true).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&entity.Images{}).Where("id_image = ?", id).Update("is_deleted", true).Error; err != nil {
+			return err
+		}
+		return tx.Model(&entity.ImageVariants{}).Where("id_image = ?", id).Update("is_deleted", true).Error
+	})
+}
+
+func (r *gormImagesRepo) CreateVariants(variants []entity.ImageVariants) error {
+	if len(variants) == 0 {
+		return nil
+	}
+	return r.db.Create(&variants).Error
+}
+
+func (r *gormImagesRepo) GetVariant(idImage, variant string) (*entity.ImageVariants, error) {
+	var out entity.ImageVariants
+	if err := r.db.Where("id_image = ? AND variant = ? AND is_deleted = false", idImage, variant).First(&out).Error; err != nil {
+		return nil, err
+	}
+	return &out, nil
 }
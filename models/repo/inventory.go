@@ -0,0 +1,166 @@
+package repo
+
+import (
+	"errors"
+	"faizalmaulana/lsp/models/entity"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// StockLevel is what is on hand of an item, with the item's name.
+type StockLevel struct {
+	IdItem    string       `json:"id_item"`
+	ItemName  string       `json:"item_name"`
+	OnHand    int          `json:"on_hand"`
+	LastCost  entity.Money `json:"last_cost"`
+	UpdatedAt time.Time    `json:"updated_at"`
+}
+
+type InventoryRepo interface {
+	WithTx(tx *gorm.DB) InventoryRepo
+	GetStock(idItem string) (*entity.ItemStocks, error)
+	// GetStockForUpdate locks the stock of the item until the surrounding
+	// database transaction ends, which serializes the writes to it. An item
+	// without stock gets an empty row first.
+	GetStockForUpdate(idItem string) (*entity.ItemStocks, error)
+	// LockStock locks the stock of the item like GetStockForUpdate, but
+	// returns nil for an item without stock instead of adding a row.
+	LockStock(idItem string) (*entity.ItemStocks, error)
+	SetStock(s *entity.ItemStocks) error
+	// ListLevels pages through the stock of the items that have any, by
+	// item name.
+	ListLevels(limit, offset int) ([]StockLevel, error)
+
+	CreateEntry(e *entity.StockLedger) error
+	// ListEntries pages through an item's ledger, newest first.
+	ListEntries(idItem string, limit, offset int) ([]entity.StockLedger, error)
+	// ListEntriesByTransaction returns the entries of a sale: what it took
+	// and what its refunds and its void put back.
+	ListEntriesByTransaction(idTransaction string) ([]entity.StockLedger, error)
+
+	CreateCost(c *entity.ItemCosts) error
+	// ListCosts pages through an item's cost history, newest first.
+	ListCosts(idItem string, limit, offset int) ([]entity.ItemCosts, error)
+}
+
+type GormInventoryRepo struct{ db *gorm.DB }
+
+func NewGormInventoryRepo(db *gorm.DB) InventoryRepo {
+	return &GormInventoryRepo{db: db}
+}
+
+func (r *GormInventoryRepo) WithTx(tx *gorm.DB) InventoryRepo {
+	return &GormInventoryRepo{db: tx}
+}
+
+func (r *GormInventoryRepo) first(query *gorm.DB, idItem string) (*entity.ItemStocks, error) {
+	var out entity.ItemStocks
+	if err := query.First(&out, "id_item = ?", idItem).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("not found")
+		}
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (r *GormInventoryRepo) GetStock(idItem string) (*entity.ItemStocks, error) {
+	return r.first(r.db, idItem)
+}
+
+func (r *GormInventoryRepo) GetStockForUpdate(idItem string) (*entity.ItemStocks, error) {
+	if err := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&entity.ItemStocks{IdItem: idItem}).Error; err != nil {
+		return nil, err
+	}
+	return r.first(r.db.Clauses(clause.Locking{Strength: "UPDATE"}), idItem)
+}
+
+func (r *GormInventoryRepo) LockStock(idItem string) (*entity.ItemStocks, error) {
+	var out []entity.ItemStocks
+	if err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id_item = ?", idItem).Limit(1).Find(&out).Error; err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, nil
+	}
+	return &out[0], nil
+}
+
+func (r *GormInventoryRepo) SetStock(s *entity.ItemStocks) error {
+	return r.db.Model(&entity.ItemStocks{}).Where("id_item = ?", s.IdItem).Updates(map[string]interface{}{
+		"on_hand":   s.OnHand,
+		"last_cost": s.LastCost,
+	}).Error
+}
+
+func (r *GormInventoryRepo) ListLevels(limit, offset int) ([]StockLevel, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	var out []StockLevel
+	err := r.db.Table("item_stocks s").
+		Select("s.id_item, i.item_name, s.on_hand, s.last_cost, s.updated_at").
+		Joins("JOIN items i ON i.id_item = s.id_item").
+		Order("i.item_name ASC").Limit(limit).Offset(offset).Scan(&out).Error
+	return out, err
+}
+
+func (r *GormInventoryRepo) CreateEntry(e *entity.StockLedger) error {
+	return r.db.Create(e).Error
+}
+
+func (r *GormInventoryRepo) ListEntries(idItem string, limit, offset int) ([]entity.StockLedger, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	var out []entity.StockLedger
+	if err := r.db.Where("id_item = ?", idItem).
+		Order("timestamp DESC").Limit(limit).Offset(offset).Find(&out).Error; err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *GormInventoryRepo) ListEntriesByTransaction(idTransaction string) ([]entity.StockLedger, error) {
+	var out []entity.StockLedger
+	if err := r.db.Where("id_transaction = ?", idTransaction).Order("timestamp ASC").Find(&out).Error; err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *GormInventoryRepo) CreateCost(c *entity.ItemCosts) error {
+	return r.db.Create(c).Error
+}
+
+func (r *GormInventoryRepo) ListCosts(idItem string, limit, offset int) ([]entity.ItemCosts, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	var out []entity.ItemCosts
+	if err := r.db.Where("id_item = ?", idItem).
+		Order("timestamp DESC").Limit(limit).Offset(offset).Find(&out).Error; err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -1,8 +1,10 @@
 package repo
 
 import (
+	"database/sql"
 	"errors"
 	"faizalmaulana/lsp/models/entity"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -13,8 +15,12 @@ type ItemsRepo interface {
 	List() ([]*entity.Items, error)
 	ListPage(limit, offset int) ([]*entity.Items, error)
 	ListPageByType(limit, offset int, itemType string) ([]*entity.Items, error)
+	ListPageByCategories(limit, offset int, categoryIDs []string) ([]*entity.Items, error)
 	Update(u *entity.Items) error
 	Delete(id string) error
+	CatalogVersion() (time.Time, int64, error)
+	// Kinds returns the kind of each of ids, deleted items included.
+	Kinds(ids []string) (map[string]string, error)
 }
 
 type GormItemsRepo struct {
@@ -88,10 +94,74 @@ func (r *GormItemsRepo) ListPageByType(limit, offset int, itemType string) ([]*e
 	return out, nil
 }
 
+func (r *GormItemsRepo) ListPageByCategories(limit, offset int, categoryIDs []string) ([]*entity.Items, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	var out []*entity.Items
+	if len(categoryIDs) == 0 {
+		return out, nil
+	}
+	if err := r.db.Where("is_deleted = ? AND id_category IN ?", false, categoryIDs).
+		Limit(limit).Offset(offset).Find(&out).Error; err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (r *GormItemsRepo) Update(u *entity.Items) error {
-	return r.db.Model(&entity.Items{}).Where("id_item = ?", u.IdItem).Updates(u).Error
+	if err := r.db.Model(&entity.Items{}).Where("id_item = ?", u.IdItem).Updates(u).Error; err != nil {
+		return err
+	}
+	// Updates skips zero fields, but a nil tax rate (inherit) and a cleared
+	// category must be written.
+	return r.db.Model(&entity.Items{}).Where("id_item = ?", u.IdItem).
+		Updates(map[string]interface{}{"tax_rate": u.TaxRate, "id_category": u.IdCategory, "item_type": u.ItemType}).Error
 }
 
 func (r *GormItemsRepo) Delete(id string) error {
 	return r.db.Model(&entity.Items{}).Where("id_item = ?", id).Update("is_deleted", true).Error
 }
+
+// CatalogVersion returns the most recent change to any item or category
+// (soft deletes included) and the number of live items and categories,
+// which together identify the current state of the catalog for cache
+// validation. Categories count because filtering by one takes in its
+// subcategories.
+func (r *GormItemsRepo) CatalogVersion() (time.Time, int64, error) {
+	var row struct {
+		LastChange sql.NullTime
+		Total      int64
+	}
+	err := r.db.Raw(`SELECT GREATEST(
+			(SELECT MAX(GREATEST(updated_at, timestamp)) FROM items),
+			(SELECT MAX(GREATEST(updated_at, timestamp)) FROM categories)) AS last_change,
+		(SELECT COUNT(*) FROM items WHERE is_deleted = false) +
+			(SELECT COUNT(*) FROM categories WHERE is_deleted = false) AS total`).
+		Scan(&row).Error
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+	return row.LastChange.Time, row.Total, nil
+}
+
+func (r *GormItemsRepo) Kinds(ids []string) (map[string]string, error) {
+	var rows []struct {
+		IdItem string
+		Kind   string
+	}
+	if err := r.db.Model(&entity.Items{}).Select("id_item", "kind").Where("id_item IN ?", ids).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	out := make(map[string]string, len(rows))
+	for _, row := range rows {
+		out[row.IdItem] = row.Kind
+	}
+	return out, nil
+}
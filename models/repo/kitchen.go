@@ -0,0 +1,183 @@
+package repo
+
+import (
+	"errors"
+	"faizalmaulana/lsp/models/entity"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type KitchenRepo interface {
+	WithTx(tx *gorm.DB) KitchenRepo
+
+	CreateStation(s *entity.KitchenStations) error
+	GetStation(id string) (*entity.KitchenStations, error)
+	ListStations() ([]entity.KitchenStations, error)
+	UpdateStation(s *entity.KitchenStations) error
+	DeleteStation(id string) error
+
+	// CreateTicket stores t with its items.
+	CreateTicket(t *entity.KitchenTickets) error
+	GetTicket(id string) (*entity.KitchenTickets, error)
+	// ListTickets filters by station, statuses and sale when they are set,
+	// oldest first.
+	ListTickets(idStation string, statuses []string, idTransaction string, limit, offset int) ([]entity.KitchenTickets, error)
+	ListByTransaction(idTransaction string) ([]entity.KitchenTickets, error)
+	// SetStatus moves the ticket id from one of the statuses from to status
+	// and reports whether it did.
+	SetStatus(id string, from []string, status string, fields map[string]interface{}) (bool, error)
+	// CancelByTransaction cancels the tickets of a sale that are not
+	// cancelled yet and returns how many it did.
+	CancelByTransaction(idTransaction string) (int64, error)
+	ListItems(idTickets []string) ([]entity.KitchenTicketItems, error)
+	// ListQueued returns the tickets opened since from for sales with a
+	// queue number, leaving out cancelled ones.
+	ListQueued(from time.Time) ([]entity.KitchenTickets, error)
+	// Collect marks the tickets opened since from for queue number number
+	// as handed over and returns how many it marked.
+	Collect(from time.Time, number int, at time.Time) (int64, error)
+}
+
+type GormKitchenRepo struct{ db *gorm.DB }
+
+func NewGormKitchenRepo(db *gorm.DB) KitchenRepo {
+	return &GormKitchenRepo{db: db}
+}
+
+func (r *GormKitchenRepo) WithTx(tx *gorm.DB) KitchenRepo {
+	return &GormKitchenRepo{db: tx}
+}
+
+func (r *GormKitchenRepo) CreateStation(s *entity.KitchenStations) error {
+	return r.db.Create(s).Error
+}
+
+func (r *GormKitchenRepo) GetStation(id string) (*entity.KitchenStations, error) {
+	var out entity.KitchenStations
+	if err := r.db.First(&out, "id_station = ? AND is_deleted = ?", id, false).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("not found")
+		}
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (r *GormKitchenRepo) ListStations() ([]entity.KitchenStations, error) {
+	var out []entity.KitchenStations
+	if err := r.db.Where("is_deleted = ?", false).Order("sort_order ASC").Order("name ASC").Find(&out).Error; err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *GormKitchenRepo) UpdateStation(s *entity.KitchenStations) error {
+	return r.db.Model(&entity.KitchenStations{}).Where("id_station = ?", s.IdStation).
+		Select("name", "categories", "is_default", "sort_order").Updates(s).Error
+}
+
+func (r *GormKitchenRepo) DeleteStation(id string) error {
+	return r.db.Model(&entity.KitchenStations{}).Where("id_station = ?", id).Update("is_deleted", true).Error
+}
+
+func (r *GormKitchenRepo) CreateTicket(t *entity.KitchenTickets) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(t).Error; err != nil {
+			return err
+		}
+		if len(t.Items) == 0 {
+			return nil
+		}
+		return tx.Create(&t.Items).Error
+	})
+}
+
+func (r *GormKitchenRepo) GetTicket(id string) (*entity.KitchenTickets, error) {
+	var out entity.KitchenTickets
+	if err := r.db.First(&out, "id_ticket = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("not found")
+		}
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (r *GormKitchenRepo) ListTickets(idStation string, statuses []string, idTransaction string, limit, offset int) ([]entity.KitchenTickets, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	if limit > 200 {
+		limit = 200
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	query := r.db.Model(&entity.KitchenTickets{})
+	if idStation != "" {
+		query = query.Where("id_station = ?", idStation)
+	}
+	if len(statuses) > 0 {
+		query = query.Where("status IN ?", statuses)
+	}
+	if idTransaction != "" {
+		query = query.Where("id_transaction = ?", idTransaction)
+	}
+	var out []entity.KitchenTickets
+	if err := query.Order("timestamp ASC").Limit(limit).Offset(offset).Find(&out).Error; err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *GormKitchenRepo) ListByTransaction(idTransaction string) ([]entity.KitchenTickets, error) {
+	var out []entity.KitchenTickets
+	if err := r.db.Where("id_transaction = ?", idTransaction).Order("timestamp ASC").Find(&out).Error; err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *GormKitchenRepo) SetStatus(id string, from []string, status string, fields map[string]interface{}) (bool, error) {
+	updates := map[string]interface{}{"status": status, "updated_at": time.Now()}
+	for k, v := range fields {
+		updates[k] = v
+	}
+	res := r.db.Model(&entity.KitchenTickets{}).Where("id_ticket = ? AND status IN ?", id, from).Updates(updates)
+	return res.RowsAffected > 0, res.Error
+}
+
+func (r *GormKitchenRepo) CancelByTransaction(idTransaction string) (int64, error) {
+	res := r.db.Model(&entity.KitchenTickets{}).
+		Where("id_transaction = ? AND status <> ?", idTransaction, entity.KitchenTicketCancelled).
+		Updates(map[string]interface{}{"status": entity.KitchenTicketCancelled, "updated_at": time.Now()})
+	return res.RowsAffected, res.Error
+}
+
+func (r *GormKitchenRepo) ListItems(idTickets []string) ([]entity.KitchenTicketItems, error) {
+	var out []entity.KitchenTicketItems
+	if len(idTickets) == 0 {
+		return out, nil
+	}
+	if err := r.db.Where("id_ticket IN ?", idTickets).Order("position ASC").Find(&out).Error; err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *GormKitchenRepo) ListQueued(from time.Time) ([]entity.KitchenTickets, error) {
+	var out []entity.KitchenTickets
+	if err := r.db.Where("timestamp >= ? AND queue_number > 0 AND status <> ?", from, entity.KitchenTicketCancelled).
+		Order("timestamp ASC").Find(&out).Error; err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *GormKitchenRepo) Collect(from time.Time, number int, at time.Time) (int64, error) {
+	res := r.db.Model(&entity.KitchenTickets{}).
+		Where("timestamp >= ? AND queue_number = ? AND status <> ? AND collected_at IS NULL", from, number, entity.KitchenTicketCancelled).
+		Updates(map[string]interface{}{"collected_at": at, "updated_at": at})
+	return res.RowsAffected, res.Error
+}
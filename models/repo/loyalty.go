@@ -0,0 +1,191 @@
+package repo
+
+import (
+	"errors"
+	"faizalmaulana/lsp/models/entity"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type LoyaltyRepo interface {
+	WithTx(tx *gorm.DB) LoyaltyRepo
+
+	CreateRule(r *entity.LoyaltyRules) error
+	GetRule(id string) (*entity.LoyaltyRules, error)
+	// ListRules returns the rules, only the active ones when activeOnly.
+	ListRules(activeOnly bool) ([]entity.LoyaltyRules, error)
+	UpdateRule(r *entity.LoyaltyRules) error
+	DeleteRule(id string) error
+
+	CreateTier(t *entity.LoyaltyTiers) error
+	GetTier(id string) (*entity.LoyaltyTiers, error)
+	// ListTiers returns the tiers from the lowest MinSpend up.
+	ListTiers() ([]entity.LoyaltyTiers, error)
+	UpdateTier(t *entity.LoyaltyTiers) error
+	DeleteTier(id string) error
+
+	// LockCustomer locks the customer's row until the surrounding database
+	// transaction ends, which serializes the writes to their points.
+	LockCustomer(idCustomer string) error
+	CreateEntry(e *entity.LoyaltyLedger) error
+	// OpenLots returns the customer's entries with points left or owed: the
+	// lots expiring soonest first, then those that never expire, then debts.
+	OpenLots(idCustomer string) ([]entity.LoyaltyLedger, error)
+	SetRemaining(id string, remaining int) error
+	ListByTransaction(idTransaction string) ([]entity.LoyaltyLedger, error)
+	// ListByCustomer pages through the customer's ledger, newest first.
+	ListByCustomer(idCustomer string, limit, offset int) ([]entity.LoyaltyLedger, error)
+	// QualifyingSpend is what the customer paid for the completed and
+	// refunded sales made since since, less what was refunded on them.
+	QualifyingSpend(idCustomer string, since time.Time) (entity.Money, error)
+}
+
+type GormLoyaltyRepo struct{ db *gorm.DB }
+
+func NewGormLoyaltyRepo(db *gorm.DB) LoyaltyRepo {
+	return &GormLoyaltyRepo{db: db}
+}
+
+func (r *GormLoyaltyRepo) WithTx(tx *gorm.DB) LoyaltyRepo {
+	return &GormLoyaltyRepo{db: tx}
+}
+
+func (r *GormLoyaltyRepo) CreateRule(rule *entity.LoyaltyRules) error {
+	return r.db.Create(rule).Error
+}
+
+func (r *GormLoyaltyRepo) GetRule(id string) (*entity.LoyaltyRules, error) {
+	var out entity.LoyaltyRules
+	if err := r.db.First(&out, "id_loyalty_rule = ? AND is_deleted = ?", id, false).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("not found")
+		}
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (r *GormLoyaltyRepo) ListRules(activeOnly bool) ([]entity.LoyaltyRules, error) {
+	var out []entity.LoyaltyRules
+	query := r.db.Where("is_deleted = ?", false)
+	if activeOnly {
+		query = query.Where("is_active = ?", true)
+	}
+	if err := query.Order("timestamp ASC").Find(&out).Error; err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// UpdateRule selects the editable columns explicitly so zero values are
+// written too.
+func (r *GormLoyaltyRepo) UpdateRule(rule *entity.LoyaltyRules) error {
+	return r.db.Model(&entity.LoyaltyRules{}).Where("id_loyalty_rule = ?", rule.IdLoyaltyRule).
+		Select("name", "kind", "step", "id_item", "points", "is_active").
+		Updates(rule).Error
+}
+
+func (r *GormLoyaltyRepo) DeleteRule(id string) error {
+	return r.db.Model(&entity.LoyaltyRules{}).Where("id_loyalty_rule = ?", id).Update("is_deleted", true).Error
+}
+
+func (r *GormLoyaltyRepo) CreateTier(t *entity.LoyaltyTiers) error {
+	return r.db.Create(t).Error
+}
+
+func (r *GormLoyaltyRepo) GetTier(id string) (*entity.LoyaltyTiers, error) {
+	var out entity.LoyaltyTiers
+	if err := r.db.First(&out, "id_loyalty_tier = ? AND is_deleted = ?", id, false).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("not found")
+		}
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (r *GormLoyaltyRepo) ListTiers() ([]entity.LoyaltyTiers, error) {
+	var out []entity.LoyaltyTiers
+	if err := r.db.Where("is_deleted = ?", false).Order("min_spend ASC").Find(&out).Error; err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *GormLoyaltyRepo) UpdateTier(t *entity.LoyaltyTiers) error {
+	return r.db.Model(&entity.LoyaltyTiers{}).Where("id_loyalty_tier = ?", t.IdLoyaltyTier).
+		Select("name", "min_spend", "multiplier").
+		Updates(t).Error
+}
+
+func (r *GormLoyaltyRepo) DeleteTier(id string) error {
+	return r.db.Model(&entity.LoyaltyTiers{}).Where("id_loyalty_tier = ?", id).Update("is_deleted", true).Error
+}
+
+func (r *GormLoyaltyRepo) LockCustomer(idCustomer string) error {
+	var c entity.Customers
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id_customer").
+		First(&c, "id_customer = ?", idCustomer).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return errors.New("not found")
+	}
+	return err
+}
+
+func (r *GormLoyaltyRepo) CreateEntry(e *entity.LoyaltyLedger) error {
+	return r.db.Create(e).Error
+}
+
+func (r *GormLoyaltyRepo) OpenLots(idCustomer string) ([]entity.LoyaltyLedger, error) {
+	var out []entity.LoyaltyLedger
+	if err := r.db.Where("id_customer = ? AND remaining <> 0", idCustomer).
+		Order("remaining < 0 ASC").Order("expires_at ASC NULLS LAST").Order("timestamp ASC").
+		Find(&out).Error; err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *GormLoyaltyRepo) SetRemaining(id string, remaining int) error {
+	return r.db.Model(&entity.LoyaltyLedger{}).Where("id_loyalty_entry = ?", id).Update("remaining", remaining).Error
+}
+
+func (r *GormLoyaltyRepo) ListByTransaction(idTransaction string) ([]entity.LoyaltyLedger, error) {
+	var out []entity.LoyaltyLedger
+	if err := r.db.Where("id_transaction = ?", idTransaction).Order("timestamp ASC").Find(&out).Error; err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *GormLoyaltyRepo) ListByCustomer(idCustomer string, limit, offset int) ([]entity.LoyaltyLedger, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	var out []entity.LoyaltyLedger
+	if err := r.db.Where("id_customer = ?", idCustomer).
+		Order("timestamp DESC").Limit(limit).Offset(offset).Find(&out).Error; err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *GormLoyaltyRepo) QualifyingSpend(idCustomer string, since time.Time) (entity.Money, error) {
+	var spend entity.Money
+	err := r.db.Raw(`SELECT COALESCE(SUM(t.total_price), 0) - COALESCE((
+			SELECT SUM(f.amount) FROM refunds f JOIN transactions ft ON ft.id_transaction = f.id_transaction
+			WHERE ft.id_customer = ? AND ft.timestamp >= ? AND f.is_deleted = false), 0)
+		FROM transactions t
+		WHERE t.id_customer = ? AND t.timestamp >= ? AND t.is_deleted = false AND t.status IN ?`,
+		idCustomer, since, idCustomer, since, []string{entity.TransactionStatusCompleted, entity.TransactionStatusRefunded}).
+		Scan(&spend).Error
+	return spend, err
+}
@@ -0,0 +1,97 @@
+package repo
+
+import (
+	"errors"
+	"faizalmaulana/lsp/models/entity"
+
+	"gorm.io/gorm"
+)
+
+type ModifiersRepo interface {
+	CreateGroup(g *entity.ModifierGroups) error
+	GetGroup(id string) (*entity.ModifierGroups, error)
+	ListGroupsByItem(idItem string) ([]*entity.ModifierGroups, error)
+	UpdateGroup(g *entity.ModifierGroups) error
+	DeleteGroup(id string) error
+
+	CreateOption(o *entity.ModifierOptions) error
+	GetOption(id string) (*entity.ModifierOptions, error)
+	UpdateOption(o *entity.ModifierOptions) error
+	DeleteOption(id string) error
+}
+
+type GormModifiersRepo struct {
+	db *gorm.DB
+}
+
+func NewGormModifiersRepo(db *gorm.DB) ModifiersRepo {
+	return &GormModifiersRepo{db: db}
+}
+
+func (r *GormModifiersRepo) liveOptions(db *gorm.DB) *gorm.DB {
+	return db.Where("is_deleted = ?", false).Order("sort_order ASC").Order("name ASC")
+}
+
+func (r *GormModifiersRepo) CreateGroup(g *entity.ModifierGroups) error {
+	return r.db.Omit("Options").Create(g).Error
+}
+
+func (r *GormModifiersRepo) GetGroup(id string) (*entity.ModifierGroups, error) {
+	var g entity.ModifierGroups
+	if err := r.db.Preload("Options", r.liveOptions).
+		Where("id_modifier_group = ? AND is_deleted = ?", id, false).First(&g).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("not found")
+		}
+		return nil, err
+	}
+	return &g, nil
+}
+
+func (r *GormModifiersRepo) ListGroupsByItem(idItem string) ([]*entity.ModifierGroups, error) {
+	var out []*entity.ModifierGroups
+	if err := r.db.Preload("Options", r.liveOptions).
+		Where("id_item = ? AND is_deleted = ?", idItem, false).
+		Order("sort_order ASC").Order("name ASC").Find(&out).Error; err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *GormModifiersRepo) UpdateGroup(g *entity.ModifierGroups) error {
+	return r.db.Model(&entity.ModifierGroups{}).Where("id_modifier_group = ?", g.IdModifierGroup).
+		Select("name", "kind", "is_required", "min_select", "max_select", "sort_order").Updates(g).Error
+}
+
+func (r *GormModifiersRepo) DeleteGroup(id string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&entity.ModifierGroups{}).Where("id_modifier_group = ?", id).Update("is_deleted", true).Error; err != nil {
+			return err
+		}
+		return tx.Model(&entity.ModifierOptions{}).Where("id_modifier_group = ?", id).Update("is_deleted", true).Error
+	})
+}
+
+func (r *GormModifiersRepo) CreateOption(o *entity.ModifierOptions) error {
+	return r.db.Create(o).Error
+}
+
+func (r *GormModifiersRepo) GetOption(id string) (*entity.ModifierOptions, error) {
+	var o entity.ModifierOptions
+	if err := r.db.Where("id_modifier_option = ? AND is_deleted = ?", id, false).First(&o).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("not found")
+		}
+		return nil, err
+	}
+	return &o, nil
+}
+
+func (r *GormModifiersRepo) UpdateOption(o *entity.ModifierOptions) error {
+	return r.db.Model(&entity.ModifierOptions{}).Where("id_modifier_option = ?", o.IdModifierOption).
+		Select("name", "price_delta", "is_default", "is_available", "sort_order").Updates(o).Error
+}
+
+func (r *GormModifiersRepo) DeleteOption(id string) error {
+	return r.db.Model(&entity.ModifierOptions{}).Where("id_modifier_option = ?", id).Update("is_deleted", true).Error
+}
@@ -0,0 +1,85 @@
+package repo
+
+import (
+	"errors"
+	"faizalmaulana/lsp/models/entity"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PaymentIntentsRepo interface {
+	WithTx(tx *gorm.DB) PaymentIntentsRepo
+	BulkCreate(intents []entity.PaymentIntents) error
+	GetByID(id string) (*entity.PaymentIntents, error)
+	// GetByExternalForUpdate locks the intent row until the surrounding
+	// transaction ends so concurrent callbacks are applied one at a time.
+	GetByExternalForUpdate(gateway, externalID string) (*entity.PaymentIntents, error)
+	ListByTransaction(idTransaction string) ([]entity.PaymentIntents, error)
+	ListExpired(now time.Time, limit int) ([]entity.PaymentIntents, error)
+	UpdateStatus(i *entity.PaymentIntents) error
+}
+
+type GormPaymentIntentsRepo struct{ db *gorm.DB }
+
+func NewGormPaymentIntentsRepo(db *gorm.DB) PaymentIntentsRepo {
+	return &GormPaymentIntentsRepo{db: db}
+}
+
+func (r *GormPaymentIntentsRepo) WithTx(tx *gorm.DB) PaymentIntentsRepo {
+	return &GormPaymentIntentsRepo{db: tx}
+}
+
+func (r *GormPaymentIntentsRepo) BulkCreate(intents []entity.PaymentIntents) error {
+	if len(intents) == 0 {
+		return nil
+	}
+	return r.db.Create(&intents).Error
+}
+
+func (r *GormPaymentIntentsRepo) GetByID(id string) (*entity.PaymentIntents, error) {
+	var i entity.PaymentIntents
+	if err := r.db.Where("id_payment_intent = ?", id).First(&i).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("not found")
+		}
+		return nil, err
+	}
+	return &i, nil
+}
+
+func (r *GormPaymentIntentsRepo) GetByExternalForUpdate(gateway, externalID string) (*entity.PaymentIntents, error) {
+	var i entity.PaymentIntents
+	if err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("gateway = ? AND external_id = ?", gateway, externalID).First(&i).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("not found")
+		}
+		return nil, err
+	}
+	return &i, nil
+}
+
+func (r *GormPaymentIntentsRepo) ListByTransaction(idTransaction string) ([]entity.PaymentIntents, error) {
+	var out []entity.PaymentIntents
+	if err := r.db.Where("id_transaction = ?", idTransaction).Order("timestamp ASC").Find(&out).Error; err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListExpired returns pending intents whose expiry has passed, oldest first.
+func (r *GormPaymentIntentsRepo) ListExpired(now time.Time, limit int) ([]entity.PaymentIntents, error) {
+	var out []entity.PaymentIntents
+	if err := r.db.Where("status = ? AND expires_at < ?", entity.PaymentIntentPending, now).
+		Order("expires_at ASC").Limit(limit).Find(&out).Error; err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *GormPaymentIntentsRepo) UpdateStatus(i *entity.PaymentIntents) error {
+	return r.db.Model(&entity.PaymentIntents{}).Where("id_payment_intent = ?", i.IdPaymentIntent).
+		Select("status", "paid_at", "updated_at").Updates(i).Error
+}
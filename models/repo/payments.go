@@ -0,0 +1,51 @@
+package repo
+
+import (
+	"faizalmaulana/lsp/models/entity"
+
+	"gorm.io/gorm"
+)
+
+type PaymentsRepo interface {
+	WithTx(tx *gorm.DB) PaymentsRepo
+	BulkCreate(payments []entity.Payments) error
+	ListByTransaction(idTransaction string) ([]entity.Payments, error)
+	ListByTransactions(idTransactions []string) ([]entity.Payments, error)
+	SetReference(idPayment, reference string) error
+}
+
+type GormPaymentsRepo struct{ db *gorm.DB }
+
+func NewGormPaymentsRepo(db *gorm.DB) PaymentsRepo {
+	return &GormPaymentsRepo{db: db}
+}
+
+func (r *GormPaymentsRepo) WithTx(tx *gorm.DB) PaymentsRepo {
+	return &GormPaymentsRepo{db: tx}
+}
+
+func (r *GormPaymentsRepo) BulkCreate(payments []entity.Payments) error {
+	if len(payments) == 0 {
+		return nil
+	}
+	return r.db.Create(&payments).Error
+}
+
+func (r *GormPaymentsRepo) ListByTransaction(idTransaction string) ([]entity.Payments, error) {
+	return r.ListByTransactions([]string{idTransaction})
+}
+
+func (r *GormPaymentsRepo) ListByTransactions(idTransactions []string) ([]entity.Payments, error) {
+	var out []entity.Payments
+	if len(idTransactions) == 0 {
+		return out, nil
+	}
+	if err := r.db.Where("id_transaction IN ? AND is_deleted = ?", idTransactions, false).Find(&out).Error; err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *GormPaymentsRepo) SetReference(idPayment, reference string) error {
+	return r.db.Model(&entity.Payments{}).Where("id_payment = ?", idPayment).Update("reference", reference).Error
+}
@@ -7,8 +7,10 @@ import (
 )
 
 type PivotItemsToTransactionsRepo interface {
+	WithTx(tx *gorm.DB) PivotItemsToTransactionsRepo
 	BulkCreate(items []entity.PivotItemsToTransaction) error
 	ListByTransaction(idTransaction string) ([]entity.PivotItemsToTransaction, error)
+	ListByTransactions(idTransactions []string) ([]entity.PivotItemsToTransaction, error)
 	DeleteByTransaction(idTransaction string) error
 }
 
@@ -18,6 +20,10 @@ func NewGormPivotItemsToTransactionsRepo(db *gorm.DB) PivotItemsToTransactionsRe
 	return &GormPivotItemsToTransactionsRepo{db: db}
 }
 
+func (r *GormPivotItemsToTransactionsRepo) WithTx(tx *gorm.DB) PivotItemsToTransactionsRepo {
+	return &GormPivotItemsToTransactionsRepo{db: tx}
+}
+
 func (r *GormPivotItemsToTransactionsRepo) BulkCreate(items []entity.PivotItemsToTransaction) error {
 	if len(items) == 0 {
 		return nil
@@ -33,6 +39,17 @@ func (r *GormPivotItemsToTransactionsRepo) ListByTransaction(idTransaction strin
 	return out, nil
 }
 
+func (r *GormPivotItemsToTransactionsRepo) ListByTransactions(idTransactions []string) ([]entity.PivotItemsToTransaction, error) {
+	var out []entity.PivotItemsToTransaction
+	if len(idTransactions) == 0 {
+		return out, nil
+	}
+	if err := r.db.Where("id_transaction IN ? AND is_deleted = ?", idTransactions, false).Find(&out).Error; err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (r *GormPivotItemsToTransactionsRepo) DeleteByTransaction(idTransaction string) error {
 	return r.db.Model(&entity.PivotItemsToTransaction{}).
 		Where("id_transaction = ?", idTransaction).
@@ -0,0 +1,46 @@
+package repo
+
+import (
+	"faizalmaulana/lsp/models/entity"
+
+	"gorm.io/gorm"
+)
+
+type PivotLineComponentsRepo interface {
+	WithTx(tx *gorm.DB) PivotLineComponentsRepo
+	BulkCreate(comps []entity.PivotLineComponents) error
+	ListByTransaction(idTransaction string) ([]entity.PivotLineComponents, error)
+	ListByTransactions(idTransactions []string) ([]entity.PivotLineComponents, error)
+}
+
+type GormPivotLineComponentsRepo struct{ db *gorm.DB }
+
+func NewGormPivotLineComponentsRepo(db *gorm.DB) PivotLineComponentsRepo {
+	return &GormPivotLineComponentsRepo{db: db}
+}
+
+func (r *GormPivotLineComponentsRepo) WithTx(tx *gorm.DB) PivotLineComponentsRepo {
+	return &GormPivotLineComponentsRepo{db: tx}
+}
+
+func (r *GormPivotLineComponentsRepo) BulkCreate(comps []entity.PivotLineComponents) error {
+	if len(comps) == 0 {
+		return nil
+	}
+	return r.db.Create(&comps).Error
+}
+
+func (r *GormPivotLineComponentsRepo) ListByTransaction(idTransaction string) ([]entity.PivotLineComponents, error) {
+	return r.ListByTransactions([]string{idTransaction})
+}
+
+func (r *GormPivotLineComponentsRepo) ListByTransactions(idTransactions []string) ([]entity.PivotLineComponents, error) {
+	var out []entity.PivotLineComponents
+	if len(idTransactions) == 0 {
+		return out, nil
+	}
+	if err := r.db.Where("id_transaction IN ? AND is_deleted = ?", idTransactions, false).Find(&out).Error; err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,46 @@
+package repo
+
+import (
+	"faizalmaulana/lsp/models/entity"
+
+	"gorm.io/gorm"
+)
+
+type PivotLineDiscountsRepo interface {
+	WithTx(tx *gorm.DB) PivotLineDiscountsRepo
+	BulkCreate(discounts []entity.PivotLineDiscounts) error
+	ListByTransaction(idTransaction string) ([]entity.PivotLineDiscounts, error)
+	ListByTransactions(idTransactions []string) ([]entity.PivotLineDiscounts, error)
+}
+
+type GormPivotLineDiscountsRepo struct{ db *gorm.DB }
+
+func NewGormPivotLineDiscountsRepo(db *gorm.DB) PivotLineDiscountsRepo {
+	return &GormPivotLineDiscountsRepo{db: db}
+}
+
+func (r *GormPivotLineDiscountsRepo) WithTx(tx *gorm.DB) PivotLineDiscountsRepo {
+	return &GormPivotLineDiscountsRepo{db: tx}
+}
+
+func (r *GormPivotLineDiscountsRepo) BulkCreate(discounts []entity.PivotLineDiscounts) error {
+	if len(discounts) == 0 {
+		return nil
+	}
+	return r.db.Create(&discounts).Error
+}
+
+func (r *GormPivotLineDiscountsRepo) ListByTransaction(idTransaction string) ([]entity.PivotLineDiscounts, error) {
+	return r.ListByTransactions([]string{idTransaction})
+}
+
+func (r *GormPivotLineDiscountsRepo) ListByTransactions(idTransactions []string) ([]entity.PivotLineDiscounts, error) {
+	var out []entity.PivotLineDiscounts
+	if len(idTransactions) == 0 {
+		return out, nil
+	}
+	if err := r.db.Where("id_transaction IN ? AND is_deleted = ?", idTransactions, false).Find(&out).Error; err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,46 @@
+package repo
+
+import (
+	"faizalmaulana/lsp/models/entity"
+
+	"gorm.io/gorm"
+)
+
+type PivotLineModifiersRepo interface {
+	WithTx(tx *gorm.DB) PivotLineModifiersRepo
+	BulkCreate(mods []entity.PivotLineModifiers) error
+	ListByTransaction(idTransaction string) ([]entity.PivotLineModifiers, error)
+	ListByTransactions(idTransactions []string) ([]entity.PivotLineModifiers, error)
+}
+
+type GormPivotLineModifiersRepo struct{ db *gorm.DB }
+
+func NewGormPivotLineModifiersRepo(db *gorm.DB) PivotLineModifiersRepo {
+	return &GormPivotLineModifiersRepo{db: db}
+}
+
+func (r *GormPivotLineModifiersRepo) WithTx(tx *gorm.DB) PivotLineModifiersRepo {
+	return &GormPivotLineModifiersRepo{db: tx}
+}
+
+func (r *GormPivotLineModifiersRepo) BulkCreate(mods []entity.PivotLineModifiers) error {
+	if len(mods) == 0 {
+		return nil
+	}
+	return r.db.Create(&mods).Error
+}
+
+func (r *GormPivotLineModifiersRepo) ListByTransaction(idTransaction string) ([]entity.PivotLineModifiers, error) {
+	return r.ListByTransactions([]string{idTransaction})
+}
+
+func (r *GormPivotLineModifiersRepo) ListByTransactions(idTransactions []string) ([]entity.PivotLineModifiers, error) {
+	var out []entity.PivotLineModifiers
+	if len(idTransactions) == 0 {
+		return out, nil
+	}
+	if err := r.db.Where("id_transaction IN ? AND is_deleted = ?", idTransactions, false).Find(&out).Error; err != nil {
+		return nil, err
+	}
+	return out, nil
+}
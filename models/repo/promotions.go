@@ -0,0 +1,66 @@
+package repo
+
+import (
+	"errors"
+	"faizalmaulana/lsp/models/entity"
+
+	"gorm.io/gorm"
+)
+
+type PromotionsRepo interface {
+	Create(p *entity.Promotions) error
+	GetByID(id string) (*entity.Promotions, error)
+	List(activeOnly bool) ([]*entity.Promotions, error)
+	Update(p *entity.Promotions) error
+	Delete(id string) error
+}
+
+type GormPromotionsRepo struct {
+	db *gorm.DB
+}
+
+func NewGormPromotionsRepo(db *gorm.DB) PromotionsRepo {
+	return &GormPromotionsRepo{db: db}
+}
+
+func (r *GormPromotionsRepo) Create(p *entity.Promotions) error {
+	return r.db.Create(p).Error
+}
+
+func (r *GormPromotionsRepo) GetByID(id string) (*entity.Promotions, error) {
+	var p entity.Promotions
+	if err := r.db.Where("id_promotion = ? AND is_deleted = ?", id, false).First(&p).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("not found")
+		}
+		return nil, err
+	}
+	return &p, nil
+}
+
+// List returns promotions by descending priority, oldest first within a
+// priority, which is the order they are applied in.
+func (r *GormPromotionsRepo) List(activeOnly bool) ([]*entity.Promotions, error) {
+	var out []*entity.Promotions
+	query := r.db.Where("is_deleted = ?", false)
+	if activeOnly {
+		query = query.Where("is_active = ?", true)
+	}
+	if err := query.Order("priority DESC").Order("timestamp ASC").Order("id_promotion ASC").Find(&out).Error; err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Update selects the editable columns explicitly so zero values (a cleared
+// window, stackable=false, ...) are written too.
+func (r *GormPromotionsRepo) Update(p *entity.Promotions) error {
+	return r.db.Model(&entity.Promotions{}).Where("id_promotion = ?", p.IdPromotion).
+		Select("name", "kind", "scope", "id_target", "value", "buy_quantity", "get_quantity", "min_spend",
+			"starts_at", "ends_at", "days_of_week", "start_time", "end_time", "priority", "stackable", "voucher_only", "is_active").
+		Updates(p).Error
+}
+
+func (r *GormPromotionsRepo) Delete(id string) error {
+	return r.db.Model(&entity.Promotions{}).Where("id_promotion = ?", id).Update("is_deleted", true).Error
+}
@@ -0,0 +1,173 @@
+package repo
+
+import (
+	"errors"
+	"faizalmaulana/lsp/models/entity"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PurchaseOrdersRepo interface {
+	WithTx(tx *gorm.DB) PurchaseOrdersRepo
+	Create(o *entity.PurchaseOrders) error
+	GetByID(id string) (*entity.PurchaseOrders, error)
+	// GetForUpdate locks the order until the surrounding database
+	// transaction ends; every change to an order, its lines and its
+	// receipts takes it.
+	GetForUpdate(id string) (*entity.PurchaseOrders, error)
+	// ListPage filters by supplier and status when they are set, newest
+	// first.
+	ListPage(idSupplier, status string, limit, offset int) ([]entity.PurchaseOrders, error)
+	// Update stores the supplier, note, expected date and total of o.
+	Update(o *entity.PurchaseOrders) error
+	// SetStatus stores the status of o and when it was sent and closed.
+	SetStatus(o *entity.PurchaseOrders) error
+
+	CreateLines(lines []entity.PurchaseOrderLines) error
+	ListLines(idPurchaseOrder string) ([]entity.PurchaseOrderLines, error)
+	DeleteLines(idPurchaseOrder string) error
+	SetReceived(idLine string, received int) error
+
+	CreateReceipt(g *entity.GoodsReceipts) error
+	CreateReceiptLines(lines []entity.GoodsReceiptLines) error
+	// ListReceipts returns the receipts of the order with their lines,
+	// oldest first.
+	ListReceipts(idPurchaseOrder string) ([]entity.GoodsReceipts, error)
+}
+
+type GormPurchaseOrdersRepo struct{ db *gorm.DB }
+
+func NewGormPurchaseOrdersRepo(db *gorm.DB) PurchaseOrdersRepo {
+	return &GormPurchaseOrdersRepo{db: db}
+}
+
+func (r *GormPurchaseOrdersRepo) WithTx(tx *gorm.DB) PurchaseOrdersRepo {
+	return &GormPurchaseOrdersRepo{db: tx}
+}
+
+func (r *GormPurchaseOrdersRepo) Create(o *entity.PurchaseOrders) error {
+	return r.db.Create(o).Error
+}
+
+func (r *GormPurchaseOrdersRepo) first(query *gorm.DB, args ...interface{}) (*entity.PurchaseOrders, error) {
+	var out entity.PurchaseOrders
+	if err := query.First(&out, args...).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("not found")
+		}
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (r *GormPurchaseOrdersRepo) GetByID(id string) (*entity.PurchaseOrders, error) {
+	return r.first(r.db, "id_purchase_order = ?", id)
+}
+
+func (r *GormPurchaseOrdersRepo) GetForUpdate(id string) (*entity.PurchaseOrders, error) {
+	return r.first(r.db.Clauses(clause.Locking{Strength: "UPDATE"}), "id_purchase_order = ?", id)
+}
+
+func (r *GormPurchaseOrdersRepo) ListPage(idSupplier, status string, limit, offset int) ([]entity.PurchaseOrders, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	query := r.db.Model(&entity.PurchaseOrders{})
+	if idSupplier != "" {
+		query = query.Where("id_supplier = ?", idSupplier)
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var out []entity.PurchaseOrders
+	if err := query.Order("number DESC").Limit(limit).Offset(offset).Find(&out).Error; err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *GormPurchaseOrdersRepo) Update(o *entity.PurchaseOrders) error {
+	return r.db.Model(&entity.PurchaseOrders{}).Where("id_purchase_order = ?", o.IdPurchaseOrder).Updates(map[string]interface{}{
+		"id_supplier": o.IdSupplier,
+		"note":        o.Note,
+		"expected_at": o.ExpectedAt,
+		"total":       o.Total,
+	}).Error
+}
+
+func (r *GormPurchaseOrdersRepo) SetStatus(o *entity.PurchaseOrders) error {
+	return r.db.Model(&entity.PurchaseOrders{}).Where("id_purchase_order = ?", o.IdPurchaseOrder).Updates(map[string]interface{}{
+		"status":    o.Status,
+		"sent_at":   o.SentAt,
+		"closed_by": o.ClosedBy,
+		"closed_at": o.ClosedAt,
+	}).Error
+}
+
+func (r *GormPurchaseOrdersRepo) CreateLines(lines []entity.PurchaseOrderLines) error {
+	if len(lines) == 0 {
+		return nil
+	}
+	return r.db.Create(&lines).Error
+}
+
+func (r *GormPurchaseOrdersRepo) ListLines(idPurchaseOrder string) ([]entity.PurchaseOrderLines, error) {
+	var out []entity.PurchaseOrderLines
+	if err := r.db.Where("id_purchase_order = ?", idPurchaseOrder).Order("position ASC").Find(&out).Error; err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *GormPurchaseOrdersRepo) DeleteLines(idPurchaseOrder string) error {
+	return r.db.Where("id_purchase_order = ?", idPurchaseOrder).Delete(&entity.PurchaseOrderLines{}).Error
+}
+
+func (r *GormPurchaseOrdersRepo) SetReceived(idLine string, received int) error {
+	return r.db.Model(&entity.PurchaseOrderLines{}).Where("id_purchase_order_line = ?", idLine).Update("received", received).Error
+}
+
+func (r *GormPurchaseOrdersRepo) CreateReceipt(g *entity.GoodsReceipts) error {
+	return r.db.Create(g).Error
+}
+
+func (r *GormPurchaseOrdersRepo) CreateReceiptLines(lines []entity.GoodsReceiptLines) error {
+	if len(lines) == 0 {
+		return nil
+	}
+	return r.db.Create(&lines).Error
+}
+
+func (r *GormPurchaseOrdersRepo) ListReceipts(idPurchaseOrder string) ([]entity.GoodsReceipts, error) {
+	var out []entity.GoodsReceipts
+	if err := r.db.Where("id_purchase_order = ?", idPurchaseOrder).Order("timestamp ASC").Find(&out).Error; err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return out, nil
+	}
+	ids := make([]string, 0, len(out))
+	for _, g := range out {
+		ids = append(ids, g.IdGoodsReceipt)
+	}
+	var lines []entity.GoodsReceiptLines
+	if err := r.db.Where("id_goods_receipt IN ?", ids).Find(&lines).Error; err != nil {
+		return nil, err
+	}
+	index := make(map[string]int, len(out))
+	for i, g := range out {
+		index[g.IdGoodsReceipt] = i
+	}
+	for _, l := range lines {
+		i := index[l.IdGoodsReceipt]
+		out[i].Lines = append(out[i].Lines, l)
+	}
+	return out, nil
+}
@@ -0,0 +1,30 @@
+package repo
+
+import "gorm.io/gorm"
+
+type QueueRepo interface {
+	WithTx(tx *gorm.DB) QueueRepo
+	// Next hands out the next queue number of day. The counter stays locked
+	// until the surrounding transaction ends, so concurrent sales get
+	// numbers one after the other and a sale that is rolled back gives its
+	// number back.
+	Next(day string) (int, error)
+}
+
+type GormQueueRepo struct{ db *gorm.DB }
+
+func NewGormQueueRepo(db *gorm.DB) QueueRepo {
+	return &GormQueueRepo{db: db}
+}
+
+func (r *GormQueueRepo) WithTx(tx *gorm.DB) QueueRepo {
+	return &GormQueueRepo{db: tx}
+}
+
+func (r *GormQueueRepo) Next(day string) (int, error) {
+	var n int
+	err := r.db.Raw(`INSERT INTO queue_counters (day, last_number, updated_at) VALUES (?, 1, NOW())
+		ON CONFLICT (day) DO UPDATE SET last_number = queue_counters.last_number + 1, updated_at = NOW()
+		RETURNING last_number`, day).Scan(&n).Error
+	return n, err
+}
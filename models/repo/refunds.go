@@ -0,0 +1,111 @@
+package repo
+
+import (
+	"errors"
+	"faizalmaulana/lsp/models/entity"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type RefundsRepo interface {
+	WithTx(tx *gorm.DB) RefundsRepo
+	Create(r *entity.Refunds) error
+	GetByID(id string) (*entity.Refunds, error)
+	ListByTransaction(idTransaction string) ([]entity.Refunds, error)
+	// ListBetween returns the refunds made with from <= timestamp < to.
+	ListBetween(from, to time.Time) ([]entity.Refunds, error)
+	// ListByShift returns the refunds made in a shift, oldest first.
+	ListByShift(idShift string) ([]entity.Refunds, error)
+	ListLinesByTransaction(idTransaction string) ([]entity.RefundLines, error)
+	ListLinesByRefunds(idRefunds []string) ([]entity.RefundLines, error)
+	// SumByCustomer adds up the refunds of the sales linked to a customer.
+	SumByCustomer(idCustomer string) (entity.Money, error)
+}
+
+type GormRefundsRepo struct{ db *gorm.DB }
+
+func NewGormRefundsRepo(db *gorm.DB) RefundsRepo {
+	return &GormRefundsRepo{db: db}
+}
+
+func (r *GormRefundsRepo) WithTx(tx *gorm.DB) RefundsRepo {
+	return &GormRefundsRepo{db: tx}
+}
+
+// Create stores the refund together with its lines.
+func (r *GormRefundsRepo) Create(refund *entity.Refunds) error {
+	if err := r.db.Create(refund).Error; err != nil {
+		return err
+	}
+	if len(refund.Lines) == 0 {
+		return nil
+	}
+	return r.db.Create(&refund.Lines).Error
+}
+
+func (r *GormRefundsRepo) GetByID(id string) (*entity.Refunds, error) {
+	var out entity.Refunds
+	if err := r.db.First(&out, "id_refund = ? AND is_deleted = ?", id, false).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("not found")
+		}
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (r *GormRefundsRepo) ListByTransaction(idTransaction string) ([]entity.Refunds, error) {
+	var out []entity.Refunds
+	if err := r.db.Where("id_transaction = ? AND is_deleted = ?", idTransaction, false).
+		Order("timestamp ASC").Find(&out).Error; err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *GormRefundsRepo) ListByShift(idShift string) ([]entity.Refunds, error) {
+	var out []entity.Refunds
+	if err := r.db.Where("is_deleted = ? AND id_shift = ?", false, idShift).
+		Order("timestamp ASC").Find(&out).Error; err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *GormRefundsRepo) ListBetween(from, to time.Time) ([]entity.Refunds, error) {
+	var out []entity.Refunds
+	if err := r.db.Where("is_deleted = ? AND timestamp >= ? AND timestamp < ?", false, from, to).
+		Order("timestamp ASC").Find(&out).Error; err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *GormRefundsRepo) ListLinesByTransaction(idTransaction string) ([]entity.RefundLines, error) {
+	var out []entity.RefundLines
+	if err := r.db.Where("id_transaction = ? AND is_deleted = ?", idTransaction, false).Find(&out).Error; err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *GormRefundsRepo) ListLinesByRefunds(idRefunds []string) ([]entity.RefundLines, error) {
+	var out []entity.RefundLines
+	if len(idRefunds) == 0 {
+		return out, nil
+	}
+	if err := r.db.Where("id_refund IN ? AND is_deleted = ?", idRefunds, false).Find(&out).Error; err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *GormRefundsRepo) SumByCustomer(idCustomer string) (entity.Money, error) {
+	var total entity.Money
+	err := r.db.Model(&entity.Refunds{}).
+		Joins("JOIN transactions ON transactions.id_transaction = refunds.id_transaction").
+		Where("transactions.id_customer = ? AND refunds.is_deleted = ?", idCustomer, false).
+		Select("COALESCE(SUM(refunds.amount), 0)").Scan(&total).Error
+	return total, err
+}
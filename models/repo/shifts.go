@@ -0,0 +1,158 @@
+package repo
+
+import (
+	"errors"
+	"faizalmaulana/lsp/models/entity"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ShiftsRepo interface {
+	WithTx(tx *gorm.DB) ShiftsRepo
+	Create(s *entity.Shifts) error
+	GetByID(id string) (*entity.Shifts, error)
+	// GetForUpdate locks the shift until the surrounding database
+	// transaction ends; closing it takes this lock.
+	GetForUpdate(id string) (*entity.Shifts, error)
+	// GetOpenByUser returns the user's open shift.
+	GetOpenByUser(idUser string) (*entity.Shifts, error)
+	// GetOpenForShare returns the user's open shift and keeps it from being
+	// closed until the surrounding database transaction ends, without
+	// blocking other sales of the shift.
+	GetOpenForShare(idUser string) (*entity.Shifts, error)
+	// ListPage filters by status and user when they are set, newest first.
+	ListPage(status, idUser string, limit, offset int) ([]entity.Shifts, error)
+	// ListOpenedBetween returns the shifts opened with from <= timestamp < to,
+	// oldest first.
+	ListOpenedBetween(from, to time.Time) ([]entity.Shifts, error)
+	// CountOpenBefore counts the shifts opened before t that are still open.
+	CountOpenBefore(t time.Time) (int64, error)
+	// Close stores the closing fields of s and marks it closed.
+	Close(s *entity.Shifts) error
+
+	CreateMovement(m *entity.ShiftMovements) error
+	ListMovements(idShift string) ([]entity.ShiftMovements, error)
+	BulkCreateCounts(counts []entity.ShiftCounts) error
+	ListCounts(idShift string) ([]entity.ShiftCounts, error)
+}
+
+type GormShiftsRepo struct{ db *gorm.DB }
+
+func NewGormShiftsRepo(db *gorm.DB) ShiftsRepo {
+	return &GormShiftsRepo{db: db}
+}
+
+func (r *GormShiftsRepo) WithTx(tx *gorm.DB) ShiftsRepo {
+	return &GormShiftsRepo{db: tx}
+}
+
+func (r *GormShiftsRepo) Create(s *entity.Shifts) error {
+	return r.db.Create(s).Error
+}
+
+func (r *GormShiftsRepo) first(query *gorm.DB, args ...interface{}) (*entity.Shifts, error) {
+	var out entity.Shifts
+	if err := query.First(&out, args...).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("not found")
+		}
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (r *GormShiftsRepo) GetByID(id string) (*entity.Shifts, error) {
+	return r.first(r.db, "id_shift = ?", id)
+}
+
+func (r *GormShiftsRepo) GetForUpdate(id string) (*entity.Shifts, error) {
+	return r.first(r.db.Clauses(clause.Locking{Strength: "UPDATE"}), "id_shift = ?", id)
+}
+
+func (r *GormShiftsRepo) GetOpenByUser(idUser string) (*entity.Shifts, error) {
+	return r.first(r.db, "id_user = ? AND status = ?", idUser, entity.ShiftStatusOpen)
+}
+
+func (r *GormShiftsRepo) GetOpenForShare(idUser string) (*entity.Shifts, error) {
+	return r.first(r.db.Clauses(clause.Locking{Strength: "SHARE"}), "id_user = ? AND status = ?", idUser, entity.ShiftStatusOpen)
+}
+
+func (r *GormShiftsRepo) ListPage(status, idUser string, limit, offset int) ([]entity.Shifts, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	query := r.db.Model(&entity.Shifts{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if idUser != "" {
+		query = query.Where("id_user = ?", idUser)
+	}
+	var out []entity.Shifts
+	if err := query.Order("timestamp DESC").Limit(limit).Offset(offset).Find(&out).Error; err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *GormShiftsRepo) ListOpenedBetween(from, to time.Time) ([]entity.Shifts, error) {
+	var out []entity.Shifts
+	if err := r.db.Where("timestamp >= ? AND timestamp < ?", from, to).
+		Order("timestamp ASC").Find(&out).Error; err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *GormShiftsRepo) CountOpenBefore(t time.Time) (int64, error) {
+	var n int64
+	err := r.db.Model(&entity.Shifts{}).Where("status = ? AND timestamp < ?", entity.ShiftStatusOpen, t).Count(&n).Error
+	return n, err
+}
+
+func (r *GormShiftsRepo) Close(s *entity.Shifts) error {
+	return r.db.Model(&entity.Shifts{}).Where("id_shift = ?", s.IdShift).Updates(map[string]interface{}{
+		"status":        entity.ShiftStatusClosed,
+		"closed_at":     s.ClosedAt,
+		"closed_by":     s.ClosedBy,
+		"expected_cash": s.ExpectedCash,
+		"counted_cash":  s.CountedCash,
+		"difference":    s.Difference,
+		"close_note":    s.CloseNote,
+	}).Error
+}
+
+func (r *GormShiftsRepo) CreateMovement(m *entity.ShiftMovements) error {
+	return r.db.Create(m).Error
+}
+
+func (r *GormShiftsRepo) ListMovements(idShift string) ([]entity.ShiftMovements, error) {
+	var out []entity.ShiftMovements
+	if err := r.db.Where("id_shift = ?", idShift).Order("timestamp ASC").Find(&out).Error; err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *GormShiftsRepo) BulkCreateCounts(counts []entity.ShiftCounts) error {
+	if len(counts) == 0 {
+		return nil
+	}
+	return r.db.Create(&counts).Error
+}
+
+func (r *GormShiftsRepo) ListCounts(idShift string) ([]entity.ShiftCounts, error) {
+	var out []entity.ShiftCounts
+	if err := r.db.Where("id_shift = ?", idShift).Order("denomination DESC").Find(&out).Error; err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,61 @@
+package repo
+
+import (
+	"errors"
+	"faizalmaulana/lsp/models/entity"
+
+	"gorm.io/gorm"
+)
+
+type SuppliersRepo interface {
+	Create(s *entity.Suppliers) error
+	GetByID(id string) (*entity.Suppliers, error)
+	// List returns the suppliers by name; search matches the name or the
+	// contact name when it is set.
+	List(search string) ([]entity.Suppliers, error)
+	Update(s *entity.Suppliers) error
+	Delete(id string) error
+}
+
+type GormSuppliersRepo struct{ db *gorm.DB }
+
+func NewGormSuppliersRepo(db *gorm.DB) SuppliersRepo {
+	return &GormSuppliersRepo{db: db}
+}
+
+func (r *GormSuppliersRepo) Create(s *entity.Suppliers) error {
+	return r.db.Create(s).Error
+}
+
+func (r *GormSuppliersRepo) GetByID(id string) (*entity.Suppliers, error) {
+	var out entity.Suppliers
+	if err := r.db.First(&out, "id_supplier = ? AND is_deleted = ?", id, false).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("not found")
+		}
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (r *GormSuppliersRepo) List(search string) ([]entity.Suppliers, error) {
+	query := r.db.Where("is_deleted = ?", false)
+	if search != "" {
+		like := "%" + search + "%"
+		query = query.Where("name ILIKE ? OR contact_name ILIKE ?", like, like)
+	}
+	var out []entity.Suppliers
+	if err := query.Order("name ASC").Find(&out).Error; err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *GormSuppliersRepo) Update(s *entity.Suppliers) error {
+	return r.db.Model(&entity.Suppliers{}).Where("id_supplier = ?", s.IdSupplier).
+		Select("name", "contact_name", "phone", "email", "address", "note").Updates(s).Error
+}
+
+func (r *GormSuppliersRepo) Delete(id string) error {
+	return r.db.Model(&entity.Suppliers{}).Where("id_supplier = ?", id).Update("is_deleted", true).Error
+}
@@ -0,0 +1,124 @@
+package repo
+
+import (
+	"errors"
+	"faizalmaulana/lsp/models/entity"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type TablesRepo interface {
+	WithTx(tx *gorm.DB) TablesRepo
+
+	CreateArea(a *entity.TableAreas) error
+	GetArea(id string) (*entity.TableAreas, error)
+	ListAreas() ([]entity.TableAreas, error)
+	UpdateArea(a *entity.TableAreas) error
+	DeleteArea(id string) error
+
+	Create(t *entity.DiningTables) error
+	GetByID(id string) (*entity.DiningTables, error)
+	// GetForUpdate locks the table until the surrounding database
+	// transaction ends; opening, moving and merging orders on a table take
+	// it.
+	GetForUpdate(id string) (*entity.DiningTables, error)
+	// List returns the tables of the area idTableArea, or of every area when
+	// it is empty, in floor order.
+	List(idTableArea string) ([]entity.DiningTables, error)
+	CountInArea(idTableArea string) (int64, error)
+	Update(t *entity.DiningTables) error
+	Delete(id string) error
+}
+
+type GormTablesRepo struct{ db *gorm.DB }
+
+func NewGormTablesRepo(db *gorm.DB) TablesRepo {
+	return &GormTablesRepo{db: db}
+}
+
+func (r *GormTablesRepo) WithTx(tx *gorm.DB) TablesRepo {
+	return &GormTablesRepo{db: tx}
+}
+
+func (r *GormTablesRepo) CreateArea(a *entity.TableAreas) error {
+	return r.db.Create(a).Error
+}
+
+func (r *GormTablesRepo) GetArea(id string) (*entity.TableAreas, error) {
+	var out entity.TableAreas
+	if err := r.db.First(&out, "id_table_area = ? AND is_deleted = ?", id, false).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("not found")
+		}
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (r *GormTablesRepo) ListAreas() ([]entity.TableAreas, error) {
+	var out []entity.TableAreas
+	if err := r.db.Where("is_deleted = ?", false).Order("sort_order ASC").Order("name ASC").Find(&out).Error; err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *GormTablesRepo) UpdateArea(a *entity.TableAreas) error {
+	return r.db.Model(&entity.TableAreas{}).Where("id_table_area = ?", a.IdTableArea).
+		Select("name", "sort_order").Updates(a).Error
+}
+
+func (r *GormTablesRepo) DeleteArea(id string) error {
+	return r.db.Model(&entity.TableAreas{}).Where("id_table_area = ?", id).Update("is_deleted", true).Error
+}
+
+func (r *GormTablesRepo) Create(t *entity.DiningTables) error {
+	return r.db.Create(t).Error
+}
+
+func (r *GormTablesRepo) first(query *gorm.DB, args ...interface{}) (*entity.DiningTables, error) {
+	var out entity.DiningTables
+	if err := query.Where("is_deleted = ?", false).First(&out, args...).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("not found")
+		}
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (r *GormTablesRepo) GetByID(id string) (*entity.DiningTables, error) {
+	return r.first(r.db, "id_table = ?", id)
+}
+
+func (r *GormTablesRepo) GetForUpdate(id string) (*entity.DiningTables, error) {
+	return r.first(r.db.Clauses(clause.Locking{Strength: "UPDATE"}), "id_table = ?", id)
+}
+
+func (r *GormTablesRepo) List(idTableArea string) ([]entity.DiningTables, error) {
+	query := r.db.Where("is_deleted = ?", false)
+	if idTableArea != "" {
+		query = query.Where("id_table_area = ?", idTableArea)
+	}
+	var out []entity.DiningTables
+	if err := query.Order("pos_y ASC").Order("pos_x ASC").Order("name ASC").Find(&out).Error; err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *GormTablesRepo) CountInArea(idTableArea string) (int64, error) {
+	var n int64
+	err := r.db.Model(&entity.DiningTables{}).Where("id_table_area = ? AND is_deleted = ?", idTableArea, false).Count(&n).Error
+	return n, err
+}
+
+func (r *GormTablesRepo) Update(t *entity.DiningTables) error {
+	return r.db.Model(&entity.DiningTables{}).Where("id_table = ?", t.IdTable).
+		Select("id_table_area", "name", "seats", "shape", "pos_x", "pos_y", "width", "height", "is_active").Updates(t).Error
+}
+
+func (r *GormTablesRepo) Delete(id string) error {
+	return r.db.Model(&entity.DiningTables{}).Where("id_table = ?", id).Update("is_deleted", true).Error
+}
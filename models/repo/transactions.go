@@ -3,19 +3,46 @@ package repo
 import (
 	"errors"
 	"faizalmaulana/lsp/models/entity"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type TransactionsRepo interface {
+	WithTx(tx *gorm.DB) TransactionsRepo
 	Create(u *entity.Transactions) error
 	GetByID(id string) (*entity.Transactions, error)
+	// GetByIDForUpdate reads the transaction and locks its row until the
+	// surrounding database transaction ends.
+	GetByIDForUpdate(id string) (*entity.Transactions, error)
 	List() ([]*entity.Transactions, error)
 	ListPage(limit, offset int) ([]*entity.Transactions, error)
-	Update(u *entity.Transactions) error
+	ListBetween(from, to time.Time, statuses ...string) ([]*entity.Transactions, error)
+	// ListByShift returns the live transactions of a shift, oldest first.
+	ListByShift(idShift string) ([]*entity.Transactions, error)
+	// ListByCustomer pages through the live transactions of a customer,
+	// newest first.
+	ListByCustomer(idCustomer string, limit, offset int) ([]*entity.Transactions, error)
+	// CustomerStats sums up the live transactions of a customer that are in
+	// one of statuses.
+	CustomerStats(idCustomer string, statuses ...string) (*CustomerStats, error)
+	SetBuyerContact(id, contact string) error
+	// Transition moves a transaction to status `to` only when its current
+	// status is one of from, setting fields alongside. It reports false when
+	// the transaction was not in one of those statuses.
+	Transition(id string, from []string, to string, fields map[string]interface{}) (bool, error)
 	Delete(id string) error
 }
 
+// CustomerStats is what a customer bought over their lifetime.
+type CustomerStats struct {
+	Visits     int64
+	Total      entity.Money
+	FirstVisit *time.Time
+	LastVisit  *time.Time
+}
+
 type GormTransactionsRepo struct {
 	db *gorm.DB
 }
@@ -24,6 +51,10 @@ func NewGormTransactionsRepo(db *gorm.DB) TransactionsRepo {
 	return &GormTransactionsRepo{db: db}
 }
 
+func (r *GormTransactionsRepo) WithTx(tx *gorm.DB) TransactionsRepo {
+	return &GormTransactionsRepo{db: tx}
+}
+
 func (r *GormTransactionsRepo) Create(u *entity.Transactions) error {
 	return r.db.Create(u).Error
 }
@@ -39,6 +70,18 @@ func (r *GormTransactionsRepo) GetByID(id string) (*entity.Transactions, error)
 	return &u, nil
 }
 
+func (r *GormTransactionsRepo) GetByIDForUpdate(id string) (*entity.Transactions, error) {
+	var u entity.Transactions
+	if err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&u, "id_transaction = ? AND is_deleted = ?", id, false).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("not found")
+		}
+		return nil, err
+	}
+	return &u, nil
+}
+
 func (r *GormTransactionsRepo) List() ([]*entity.Transactions, error) {
 	var out []*entity.Transactions
 	if err := r.db.Where("is_deleted = ?", false).Find(&out).Error; err != nil {
@@ -64,11 +107,70 @@ func (r *GormTransactionsRepo) ListPage(limit, offset int) ([]*entity.Transactio
 	return out, nil
 }
 
-func (r *GormTransactionsRepo) Update(u *entity.Transactions) error {
-	if err := r.db.Model(&entity.Transactions{}).Where("id_transaction = ?", u.IdTransaction).Updates(u).Error; err != nil {
-		return err
+// ListBetween returns live transactions in one of statuses with
+// from <= timestamp < to, oldest first.
+func (r *GormTransactionsRepo) ListBetween(from, to time.Time, statuses ...string) ([]*entity.Transactions, error) {
+	var out []*entity.Transactions
+	if err := r.db.Where("is_deleted = ? AND status IN ? AND timestamp >= ? AND timestamp < ?", false, statuses, from, to).
+		Order("timestamp ASC").Find(&out).Error; err != nil {
+		return nil, err
 	}
-	return nil
+	return out, nil
+}
+
+func (r *GormTransactionsRepo) ListByShift(idShift string) ([]*entity.Transactions, error) {
+	var out []*entity.Transactions
+	if err := r.db.Where("is_deleted = ? AND id_shift = ?", false, idShift).
+		Order("timestamp ASC").Find(&out).Error; err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *GormTransactionsRepo) ListByCustomer(idCustomer string, limit, offset int) ([]*entity.Transactions, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	var out []*entity.Transactions
+	if err := r.db.Where("id_customer = ? AND is_deleted = ?", idCustomer, false).
+		Order("timestamp DESC").Limit(limit).Offset(offset).Find(&out).Error; err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *GormTransactionsRepo) CustomerStats(idCustomer string, statuses ...string) (*CustomerStats, error) {
+	var out CustomerStats
+	if err := r.db.Model(&entity.Transactions{}).
+		Select("COUNT(*) AS visits, COALESCE(SUM(total_price), 0) AS total, MIN(timestamp) AS first_visit, MAX(timestamp) AS last_visit").
+		Where("id_customer = ? AND is_deleted = ? AND status IN ?", idCustomer, false, statuses).
+		Scan(&out).Error; err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SetBuyerContact writes the contact alone, so the status and totals the
+// row was read with can never be written back over a void or refund.
+func (r *GormTransactionsRepo) SetBuyerContact(id, contact string) error {
+	return r.db.Model(&entity.Transactions{}).Where("id_transaction = ?", id).Update("buyer_contact", contact).Error
+}
+
+func (r *GormTransactionsRepo) Transition(id string, from []string, to string, fields map[string]interface{}) (bool, error) {
+	updates := map[string]interface{}{"status": to}
+	for k, v := range fields {
+		updates[k] = v
+	}
+	res := r.db.Model(&entity.Transactions{}).
+		Where("id_transaction = ? AND is_deleted = ? AND status IN ?", id, false, from).
+		Updates(updates)
+	return res.RowsAffected > 0, res.Error
 }
 
 func (r *GormTransactionsRepo) Delete(id string) error {
@@ -0,0 +1,17 @@
+package repo
+
+import "gorm.io/gorm"
+
+// UnitOfWork runs several repository calls in one database transaction.
+// Repositories taking part expose WithTx(tx) to bind themselves to it.
+type UnitOfWork interface {
+	Do(fn func(tx *gorm.DB) error) error
+}
+
+type gormUnitOfWork struct{ db *gorm.DB }
+
+func NewGormUnitOfWork(db *gorm.DB) UnitOfWork { return &gormUnitOfWork{db: db} }
+
+func (u *gormUnitOfWork) Do(fn func(tx *gorm.DB) error) error {
+	return u.db.Transaction(fn)
+}
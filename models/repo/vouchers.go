@@ -0,0 +1,144 @@
+package repo
+
+import (
+	"errors"
+	"faizalmaulana/lsp/models/entity"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type VouchersRepo interface {
+	WithTx(tx *gorm.DB) VouchersRepo
+	Create(v *entity.Vouchers) error
+	BulkCreate(vouchers []entity.Vouchers) error
+	GetByID(id string) (*entity.Vouchers, error)
+	GetByCode(code string) (*entity.Vouchers, error)
+	// CodeExists also sees deleted vouchers, whose codes stay taken.
+	CodeExists(code string) (bool, error)
+	// GetByIDForUpdate reads the voucher and locks its row until the
+	// surrounding database transaction ends, which serializes redemptions.
+	GetByIDForUpdate(id string) (*entity.Vouchers, error)
+	// List filters by batch and promotion when they are not empty.
+	List(batch, idPromotion string) ([]*entity.Vouchers, error)
+	Update(v *entity.Vouchers) error
+	Delete(id string) error
+	CreateRedemption(r *entity.VoucherRedemptions) error
+	// CountRedemptions counts the live redemptions of a voucher by customer.
+	CountRedemptions(idVoucher, customer string) (int64, error)
+	// ReleaseByTransaction deletes the redemptions of a sale and gives the
+	// uses back to their vouchers.
+	ReleaseByTransaction(idTransaction string) error
+}
+
+type GormVouchersRepo struct{ db *gorm.DB }
+
+func NewGormVouchersRepo(db *gorm.DB) VouchersRepo {
+	return &GormVouchersRepo{db: db}
+}
+
+func (r *GormVouchersRepo) WithTx(tx *gorm.DB) VouchersRepo {
+	return &GormVouchersRepo{db: tx}
+}
+
+func (r *GormVouchersRepo) Create(v *entity.Vouchers) error {
+	return r.db.Create(v).Error
+}
+
+func (r *GormVouchersRepo) BulkCreate(vouchers []entity.Vouchers) error {
+	if len(vouchers) == 0 {
+		return nil
+	}
+	return r.db.CreateInBatches(&vouchers, 200).Error
+}
+
+func (r *GormVouchersRepo) GetByID(id string) (*entity.Vouchers, error) {
+	return r.first(r.db, "id_voucher = ? AND is_deleted = ?", id, false)
+}
+
+func (r *GormVouchersRepo) GetByCode(code string) (*entity.Vouchers, error) {
+	return r.first(r.db, "code = ? AND is_deleted = ?", code, false)
+}
+
+func (r *GormVouchersRepo) CodeExists(code string) (bool, error) {
+	var n int64
+	err := r.db.Model(&entity.Vouchers{}).Where("code = ?", code).Count(&n).Error
+	return n > 0, err
+}
+
+func (r *GormVouchersRepo) GetByIDForUpdate(id string) (*entity.Vouchers, error) {
+	return r.first(r.db.Clauses(clause.Locking{Strength: "UPDATE"}), "id_voucher = ? AND is_deleted = ?", id, false)
+}
+
+func (r *GormVouchersRepo) first(db *gorm.DB, query string, args ...interface{}) (*entity.Vouchers, error) {
+	var v entity.Vouchers
+	if err := db.Where(query, args...).First(&v).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("not found")
+		}
+		return nil, err
+	}
+	return &v, nil
+}
+
+func (r *GormVouchersRepo) List(batch, idPromotion string) ([]*entity.Vouchers, error) {
+	var out []*entity.Vouchers
+	query := r.db.Where("is_deleted = ?", false)
+	if batch != "" {
+		query = query.Where("batch = ?", batch)
+	}
+	if idPromotion != "" {
+		query = query.Where("id_promotion = ?", idPromotion)
+	}
+	if err := query.Order("timestamp ASC").Order("code ASC").Find(&out).Error; err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Update selects the editable columns explicitly so zero values (a cleared
+// window, unlimited uses, ...) are written too.
+func (r *GormVouchersRepo) Update(v *entity.Vouchers) error {
+	return r.db.Model(&entity.Vouchers{}).Where("id_voucher = ?", v.IdVoucher).
+		Select("starts_at", "ends_at", "max_uses", "max_uses_per_customer", "is_active").
+		Updates(v).Error
+}
+
+func (r *GormVouchersRepo) Delete(id string) error {
+	return r.db.Model(&entity.Vouchers{}).Where("id_voucher = ?", id).Update("is_deleted", true).Error
+}
+
+// CreateRedemption stores the redemption and counts the use on its voucher.
+func (r *GormVouchersRepo) CreateRedemption(red *entity.VoucherRedemptions) error {
+	if err := r.db.Create(red).Error; err != nil {
+		return err
+	}
+	return r.db.Model(&entity.Vouchers{}).Where("id_voucher = ?", red.IdVoucher).
+		Update("used_count", gorm.Expr("used_count + 1")).Error
+}
+
+func (r *GormVouchersRepo) CountRedemptions(idVoucher, customer string) (int64, error) {
+	var n int64
+	err := r.db.Model(&entity.VoucherRedemptions{}).
+		Where("id_voucher = ? AND customer = ? AND is_deleted = ?", idVoucher, customer, false).
+		Count(&n).Error
+	return n, err
+}
+
+func (r *GormVouchersRepo) ReleaseByTransaction(idTransaction string) error {
+	var list []entity.VoucherRedemptions
+	if err := r.db.Where("id_transaction = ? AND is_deleted = ?", idTransaction, false).Find(&list).Error; err != nil {
+		return err
+	}
+	for _, red := range list {
+		if err := r.db.Model(&entity.VoucherRedemptions{}).Where("id_voucher_redemption = ?", red.IdVoucherRedemption).
+			Update("is_deleted", true).Error; err != nil {
+			return err
+		}
+		if err := r.db.Model(&entity.Vouchers{}).Where("id_voucher = ? AND used_count > 0", red.IdVoucher).
+			Update("used_count", gorm.Expr("used_count - 1")).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}